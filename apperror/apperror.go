@@ -10,6 +10,7 @@
 //   - Supports wrapping and chaining of multiple related errors
 //   - Automatically includes detailed trace and error info when debug mode is enabled
 //   - Implements the standard error interface
+//   - Categorizes errors by Kind for mapping to HTTP and gRPC status codes
 //
 // Usage:
 //
@@ -22,16 +23,23 @@
 //	// Add related errors for context
 //	err = err.(apperror.Error).AddError(io.EOF)
 //
+//	// Categorize the error so transport layers can map it to a status code
+//	err = apperror.NewError("user not found").WithKind(apperror.KindNotFound)
+//	status := apperror.KindOf(err).HTTPStatus() // 404
+//
 //	// Print with trace and nested errors if debug mode is enabled
 //	fmt.Println(err)
 //
 // To enable debug output (stack traces), set `flag.Debug = true` before printing errors.
+// To record the full caller stack on creation, call `apperror.SetCaptureStack(true)`
+// and retrieve it with `Error.Stack()`. Loggers include the stack of logged errors.
 //
 // Note: If you're wrapping errors that are already of type `apperror.Error`,
 // prefer `Wrap` over creating a new instance to preserve the trace history.
 package apperror
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -88,6 +96,9 @@ type Error struct {
 	Errors  []error
 	Context map[string]interface{} // Additional context for the error
 	Message string
+	kind    Kind
+	cause   error     // original error passed to Wrap
+	stack   []uintptr // caller stack recorded when capturing is enabled
 }
 
 // NewError creates a new Error instance with the given message
@@ -95,6 +106,7 @@ type Error struct {
 func NewError(msg string) Error {
 	e := Error{
 		Message: msg,
+		stack:   callers(),
 	}
 	e.Trace = trace(e)
 	return e
@@ -105,6 +117,7 @@ func NewError(msg string) Error {
 func NewErrorf(format string, a ...interface{}) Error {
 	e := Error{
 		Message: fmt.Sprintf(format, a...),
+		stack:   callers(),
 	}
 	e.Trace = trace(e)
 	return e
@@ -118,10 +131,15 @@ func Wrap(err error) error {
 	}
 	if e, ok := err.(Error); ok {
 		e.Trace = trace(e)
+		if e.stack == nil {
+			e.stack = callers()
+		}
 		return e
 	}
 	e := Error{
 		Message: err.Error(),
+		cause:   err,
+		stack:   callers(),
 	}
 	e.Trace = trace(e)
 	return e
@@ -210,14 +228,24 @@ func (e Error) Is(target error) bool {
 	return e.Message == target.Error()
 }
 
-// Unwrap implements the error unwrapping interface for errors.Is() and errors.As()
-// It returns the first additional error if any exist, allowing the standard library
-// to traverse the error chain when looking for specific error types
-func (e Error) Unwrap() error {
-	if len(e.Errors) > 0 {
-		return e.Errors[0]
+// Unwrap implements the multi-error unwrapping interface for errors.Is() and errors.As()
+// It returns the error passed to Wrap followed by all additional errors, allowing the
+// standard library to traverse the whole error chain when looking for specific errors
+func (e Error) Unwrap() []error {
+	if e.cause == nil && len(e.Errors) == 0 {
+		return nil
 	}
-	return nil
+
+	errs := make([]error, 0, len(e.Errors)+1)
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
 }
 
 // Error implements the error interface and returns the error message
@@ -367,6 +395,32 @@ func Catch(f func() error, msg string) {
 	}
 }
 
+// CatchInto calls f and stores its error in dst instead of handling it.
+// It is meant for deferred cleanup with a named error return value, so that the
+// error of the cleanup is returned to the caller. Deferred functions run after the
+// return values are set, so an error already returned by the function is kept and
+// the cleanup error is joined to it.
+//
+//	func write(path string) (err error) {
+//		f, err := os.Create(path)
+//		if err != nil {
+//			return err
+//		}
+//		defer apperror.CatchInto(f.Close, &err)
+//		...
+//	}
+func CatchInto(f func() error, dst *error) {
+	err := f()
+	if err == nil {
+		return
+	}
+	if *dst == nil {
+		*dst = err
+		return
+	}
+	*dst = errors.Join(*dst, err)
+}
+
 // CatchCustom is a utility function to handle deferred error checks with a custom handler
 // It takes a function that returns an error, a message, and a custom handler.
 func CatchCustom(f func() error, msg string, handler func(error, string)) {
@@ -1,8 +1,13 @@
 package apperror_test
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/valentin-kaiser/go-core/apperror"
@@ -361,6 +366,30 @@ func TestCatchFunctionSuccess(_ *testing.T) {
 	// If we reach here, the test passed
 }
 
+func TestCatchInto(t *testing.T) {
+	errClose := errors.New("close failed")
+	errWork := errors.New("work failed")
+
+	run := func(work, cleanup error) (err error) {
+		defer apperror.CatchInto(func() error { return cleanup }, &err)
+		return work
+	}
+
+	if err := run(nil, nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := run(nil, errClose); !errors.Is(err, errClose) {
+		t.Errorf("Expected cleanup error, got %v", err)
+	}
+	if err := run(errWork, nil); !errors.Is(err, errWork) {
+		t.Errorf("Expected work error, got %v", err)
+	}
+	err := run(errWork, errClose)
+	if !errors.Is(err, errWork) || !errors.Is(err, errClose) {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+}
+
 func TestHandle(t *testing.T) {
 	// Test Handle with nil error (should not panic)
 	apperror.Handle(nil, "no error")
@@ -440,3 +469,233 @@ func TestWhereFunction(t *testing.T) {
 		t.Error("Expected location to contain file:line format")
 	}
 }
+
+func TestErrorKind(t *testing.T) {
+	err := apperror.NewError("plain error")
+	if err.Kind() != apperror.KindUnknown {
+		t.Errorf("Expected kind %q, got %q", apperror.KindUnknown, err.Kind())
+	}
+
+	err = apperror.NewError("user not found").WithKind(apperror.KindNotFound)
+	if err.Kind() != apperror.KindNotFound {
+		t.Errorf("Expected kind %q, got %q", apperror.KindNotFound, err.Kind())
+	}
+
+	// Kind must survive wrapping and nesting
+	if kind := apperror.KindOf(apperror.Wrap(err)); kind != apperror.KindNotFound {
+		t.Errorf("Expected wrapped kind %q, got %q", apperror.KindNotFound, kind)
+	}
+
+	nested := apperror.NewError("lookup failed").AddError(apperror.NewError("bad id").WithKind(apperror.KindInvalidArgument))
+	if kind := apperror.KindOf(nested); kind != apperror.KindInvalidArgument {
+		t.Errorf("Expected nested kind %q, got %q", apperror.KindInvalidArgument, kind)
+	}
+
+	if kind := apperror.KindOf(errors.New("standard error")); kind != apperror.KindUnknown {
+		t.Errorf("Expected kind %q for standard error, got %q", apperror.KindUnknown, kind)
+	}
+}
+
+func TestKindStatusMapping(t *testing.T) {
+	tests := []struct {
+		kind   apperror.Kind
+		status int
+		code   uint32
+	}{
+		{apperror.KindNotFound, 404, 5},
+		{apperror.KindInvalidArgument, 400, 3},
+		{apperror.KindUnauthenticated, 401, 16},
+		{apperror.KindPermissionDenied, 403, 7},
+		{apperror.KindInternal, 500, 13},
+		{apperror.KindUnknown, 500, 2},
+	}
+
+	for _, tt := range tests {
+		if status := tt.kind.HTTPStatus(); status != tt.status {
+			t.Errorf("Expected HTTP status %d for %q, got %d", tt.status, tt.kind, status)
+		}
+		if code := tt.kind.GRPCCode(); code != tt.code {
+			t.Errorf("Expected gRPC code %d for %q, got %d", tt.code, tt.kind, code)
+		}
+	}
+}
+
+func TestErrorsIsThroughChain(t *testing.T) {
+	// Wrapped standard errors must remain reachable
+	if !errors.Is(apperror.Wrap(io.EOF), io.EOF) {
+		t.Error("Expected errors.Is to find io.EOF through Wrap")
+	}
+
+	// Sentinel errors added as additional errors must be reachable
+	err := apperror.NewError("read failed").AddError(fmt.Errorf("reading header: %w", io.EOF))
+	if !errors.Is(err, io.EOF) {
+		t.Error("Expected errors.Is to find io.EOF through AddError")
+	}
+
+	// Every additional error participates, not only the first one
+	err = apperror.NewError("multiple failures").AddErrors([]error{
+		errors.New("first failure"),
+		io.ErrUnexpectedEOF,
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("Expected errors.Is to find io.ErrUnexpectedEOF in the second additional error")
+	}
+
+	// Wrapping an apperror again keeps the chain intact
+	if !errors.Is(apperror.Wrap(err), io.ErrUnexpectedEOF) {
+		t.Error("Expected errors.Is to find io.ErrUnexpectedEOF through a rewrap")
+	}
+
+	if errors.Is(err, io.EOF) {
+		t.Error("Expected errors.Is to not find io.EOF in unrelated chain")
+	}
+}
+
+func TestErrorsAsThroughChain(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/apperror/test")
+	if statErr == nil {
+		t.Fatal("Expected os.Stat to fail")
+	}
+
+	var pathErr *os.PathError
+	if !errors.As(apperror.Wrap(statErr), &pathErr) {
+		t.Fatal("Expected errors.As to find *os.PathError through Wrap")
+	}
+	if pathErr.Path != "/nonexistent/apperror/test" {
+		t.Errorf("Expected path '/nonexistent/apperror/test', got '%s'", pathErr.Path)
+	}
+
+	err := apperror.NewError("outer").AddError(errors.New("first")).AddError(statErr)
+	pathErr = nil
+	if !errors.As(err, &pathErr) {
+		t.Error("Expected errors.As to find *os.PathError in additional errors")
+	}
+
+	var appErr apperror.Error
+	if !errors.As(fmt.Errorf("context: %w", apperror.NewError("inner")), &appErr) {
+		t.Fatal("Expected errors.As to find apperror.Error through fmt wrap")
+	}
+	if appErr.Message != "inner" {
+		t.Errorf("Expected message 'inner', got '%s'", appErr.Message)
+	}
+}
+
+func TestErrorMarshalJSON(t *testing.T) {
+	err := apperror.NewError("user not found").
+		WithKind(apperror.KindNotFound).
+		AddDetail("user_id", "12345").
+		AddError(errors.New("record not found"))
+
+	data, jerr := json.Marshal(err)
+	if jerr != nil {
+		t.Fatalf("Failed to marshal error: %v", jerr)
+	}
+
+	var out map[string]interface{}
+	if jerr := json.Unmarshal(data, &out); jerr != nil {
+		t.Fatalf("Failed to unmarshal error JSON: %v", jerr)
+	}
+
+	if out["message"] != "user not found" {
+		t.Errorf("Expected message 'user not found', got %v", out["message"])
+	}
+	if out["kind"] != "not_found" {
+		t.Errorf("Expected kind 'not_found', got %v", out["kind"])
+	}
+	if out["cause"] != "record not found" {
+		t.Errorf("Expected cause 'record not found', got %v", out["cause"])
+	}
+	details, ok := out["details"].(map[string]interface{})
+	if !ok || details["user_id"] != "12345" {
+		t.Errorf("Expected details with user_id '12345', got %v", out["details"])
+	}
+
+	expected := "user not found {user_id: 12345} [record not found]"
+	if err.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, err.String())
+	}
+
+	// Unmarshalable details fall back to their default format
+	data, jerr = json.Marshal(apperror.NewError("bad detail").AddDetail("ch", make(chan int)))
+	if jerr != nil {
+		t.Fatalf("Failed to marshal error with unmarshalable detail: %v", jerr)
+	}
+	if !strings.Contains(string(data), `"ch":"0x`) {
+		t.Errorf("Expected stringified channel detail, got %s", data)
+	}
+}
+
+func TestErrorMarshalJSONOmitCause(t *testing.T) {
+	apperror.OmitCause = true
+	defer func() { apperror.OmitCause = false }()
+
+	err := apperror.NewError("request failed").AddError(errors.New("dial tcp 10.0.0.1:5432: connection refused"))
+
+	data, jerr := json.Marshal(err)
+	if jerr != nil {
+		t.Fatalf("Failed to marshal error: %v", jerr)
+	}
+
+	if strings.Contains(string(data), "connection refused") {
+		t.Errorf("Expected cause to be omitted, got %s", data)
+	}
+
+	if strings.Contains(err.String(), "connection refused") {
+		t.Errorf("Expected cause to be omitted from String(), got %s", err.String())
+	}
+}
+
+func TestCaptureStack(t *testing.T) {
+	if stack := apperror.NewError("no stack").Stack(); stack != nil {
+		t.Errorf("Expected no stack when capturing is disabled, got %d frames", len(stack))
+	}
+
+	apperror.SetCaptureStack(true)
+	defer apperror.SetCaptureStack(false)
+
+	err := apperror.NewError("with stack")
+	stack := err.Stack()
+	if len(stack) == 0 {
+		t.Fatal("Expected stack when capturing is enabled")
+	}
+	if !strings.HasSuffix(stack[0].Function, "TestCaptureStack") {
+		t.Errorf("Expected first frame to be the caller, got %s", stack[0].Function)
+	}
+
+	// Rewrapping keeps the original stack
+	wrapped, ok := apperror.Wrap(err).(apperror.Error)
+	if !ok {
+		t.Fatal("Wrapped error should be of type Error")
+	}
+	if len(wrapped.Stack()) != len(stack) || wrapped.Stack()[0].Line != stack[0].Line {
+		t.Error("Expected Wrap to preserve the original stack")
+	}
+
+	wrapped, ok = apperror.Wrap(io.EOF).(apperror.Error)
+	if !ok {
+		t.Fatal("Wrapped error should be of type Error")
+	}
+	if len(wrapped.Stack()) == 0 {
+		t.Error("Expected Wrap of a standard error to capture a stack")
+	}
+}
+
+func TestCaptureStackConcurrent(_ *testing.T) {
+	defer apperror.SetCaptureStack(false)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				if i == 0 {
+					apperror.SetCaptureStack(j%2 == 0)
+					continue
+				}
+				_ = apperror.NewError("concurrent").Stack()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,92 @@
+package apperror
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OmitCause controls whether nested causes are left out of the JSON and String output
+// Enable it for production responses to avoid leaking internal error messages to clients
+var OmitCause = false
+
+// jsonError is the canonical serialized form of an Error
+type jsonError struct {
+	Message string                 `json:"message"`
+	Kind    Kind                   `json:"kind,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Cause   string                 `json:"cause,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// The error is rendered as {"message": "...", "kind": "...", "details": {...}, "cause": "..."}
+// Detail values that cannot be marshaled are rendered using their default format
+func (e Error) MarshalJSON() ([]byte, error) {
+	out := jsonError{
+		Message: e.Message,
+		Kind:    e.kind,
+		Details: e.Context,
+	}
+	if !OmitCause {
+		out.Cause = e.causes()
+	}
+
+	data, err := json.Marshal(out)
+	if err == nil {
+		return data, nil
+	}
+
+	details := make(map[string]interface{}, len(e.Context))
+	for key, value := range e.Context {
+		if _, err := json.Marshal(value); err != nil {
+			details[key] = fmt.Sprintf("%v", value)
+			continue
+		}
+		details[key] = value
+	}
+	out.Details = details
+	return json.Marshal(out)
+}
+
+// String returns the message, the details sorted by key and the cause of the error
+// in the same shape as MarshalJSON, without trace information
+func (e Error) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+
+	if len(e.Context) > 0 {
+		keys := make([]string, 0, len(e.Context))
+		for key := range e.Context {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		sb.WriteString(" {")
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s: %v", key, e.Context[key])
+		}
+		sb.WriteString("}")
+	}
+
+	if cause := e.causes(); cause != "" && !OmitCause {
+		fmt.Fprintf(&sb, " [%s]", cause)
+	}
+
+	return sb.String()
+}
+
+// causes joins the messages of the additional errors using the ErrorDelimiter
+func (e Error) causes() string {
+	causes := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err == nil {
+			continue
+		}
+		causes = append(causes, err.Error())
+	}
+	return strings.Join(causes, ErrorDelimiter)
+}
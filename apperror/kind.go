@@ -0,0 +1,141 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind categorizes an error so that transport layers can map it to a status code
+type Kind string
+
+const (
+	// KindUnknown is the kind of errors that have not been categorized
+	KindUnknown Kind = "unknown"
+	// KindCanceled indicates that the operation was canceled by the caller
+	KindCanceled Kind = "canceled"
+	// KindInvalidArgument indicates that the caller specified an invalid argument
+	KindInvalidArgument Kind = "invalid_argument"
+	// KindDeadlineExceeded indicates that the operation expired before completion
+	KindDeadlineExceeded Kind = "deadline_exceeded"
+	// KindNotFound indicates that a requested entity was not found
+	KindNotFound Kind = "not_found"
+	// KindAlreadyExists indicates that an entity the caller attempted to create already exists
+	KindAlreadyExists Kind = "already_exists"
+	// KindPermissionDenied indicates that the caller is not allowed to execute the operation
+	KindPermissionDenied Kind = "permission_denied"
+	// KindResourceExhausted indicates that a resource or quota has been exhausted
+	KindResourceExhausted Kind = "resource_exhausted"
+	// KindFailedPrecondition indicates that the system is not in a state required for the operation
+	KindFailedPrecondition Kind = "failed_precondition"
+	// KindConflict indicates that the operation was aborted due to a concurrency conflict
+	KindConflict Kind = "conflict"
+	// KindUnimplemented indicates that the operation is not implemented or supported
+	KindUnimplemented Kind = "unimplemented"
+	// KindInternal indicates an internal error
+	KindInternal Kind = "internal"
+	// KindUnavailable indicates that the service is currently unavailable
+	KindUnavailable Kind = "unavailable"
+	// KindUnauthenticated indicates that the request does not have valid authentication credentials
+	KindUnauthenticated Kind = "unauthenticated"
+)
+
+// WithKind sets the kind of the error
+func (e Error) WithKind(kind Kind) Error {
+	e.kind = kind
+	return e
+}
+
+// Kind returns the kind of the error or KindUnknown if none was set
+func (e Error) Kind() Kind {
+	if e.kind == "" {
+		return KindUnknown
+	}
+	return e.kind
+}
+
+// KindOf returns the kind of the first categorized error in the chain of err
+// It returns KindUnknown if no error in the chain has a kind
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var e Error
+	if errors.As(err, &e) {
+		if e.kind != "" {
+			return e.kind
+		}
+		for _, nested := range e.Errors {
+			if kind := KindOf(nested); kind != KindUnknown {
+				return kind
+			}
+		}
+	}
+
+	return KindUnknown
+}
+
+// HTTPStatus returns the HTTP status code corresponding to the kind
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindCanceled:
+		return 499 // Client Closed Request
+	case KindInvalidArgument:
+		return http.StatusBadRequest
+	case KindDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindAlreadyExists, KindConflict:
+		return http.StatusConflict
+	case KindPermissionDenied:
+		return http.StatusForbidden
+	case KindResourceExhausted:
+		return http.StatusTooManyRequests
+	case KindFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case KindUnimplemented:
+		return http.StatusNotImplemented
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode returns the gRPC status code corresponding to the kind
+// The values match the codes defined in google.golang.org/grpc/codes
+func (k Kind) GRPCCode() uint32 {
+	switch k {
+	case KindCanceled:
+		return 1
+	case KindInvalidArgument:
+		return 3
+	case KindDeadlineExceeded:
+		return 4
+	case KindNotFound:
+		return 5
+	case KindAlreadyExists:
+		return 6
+	case KindPermissionDenied:
+		return 7
+	case KindResourceExhausted:
+		return 8
+	case KindFailedPrecondition:
+		return 9
+	case KindConflict:
+		return 10
+	case KindUnimplemented:
+		return 12
+	case KindInternal:
+		return 13
+	case KindUnavailable:
+		return 14
+	case KindUnauthenticated:
+		return 16
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,49 @@
+package apperror
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// maxStackDepth is the maximum number of frames recorded when stack capturing is enabled
+const maxStackDepth = 64
+
+// captureStack controls whether NewError, NewErrorf and Wrap record the caller stack
+var captureStack atomic.Bool
+
+// SetCaptureStack enables or disables capturing of the full caller stack when errors are created
+// Capturing is disabled by default to keep error creation cheap on hot paths, it can be toggled at any time
+func SetCaptureStack(enable bool) {
+	captureStack.Store(enable)
+}
+
+// Stack returns the call stack recorded when the error was created
+// It returns nil if stack capturing was disabled at creation time
+func (e Error) Stack() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	frames := runtime.CallersFrames(e.stack)
+	stack := make([]runtime.Frame, 0, len(e.stack))
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// callers records the program counters of the caller of the function creating the error
+func callers() []uintptr {
+	if !captureStack.Load() {
+		return nil
+	}
+
+	pc := make([]uintptr, maxStackDepth)
+	// Skip runtime.Callers, callers and the constructor itself
+	n := runtime.Callers(3, pc)
+	return pc[:n]
+}
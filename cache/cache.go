@@ -8,11 +8,21 @@
 //   - Automatic serialization/deserialization
 //   - Cache statistics and monitoring
 //   - Namespace support for multi-tenant applications
+//   - Hashing of long keys to bound their length
 //   - Bulk operations (GetMulti, SetMulti, DeleteMulti)
+//   - Atomic pipelines of Set, Get, Delete and Incr, for Redis as MULTI/EXEC transaction
 //   - Cache warming and preloading
 //   - Event callbacks (OnSet, OnGet, OnDelete, OnEvict)
+//   - Expiration events, for Redis via keyspace notifications
 //   - Compression support for large values
+//   - Schema versions of stored values, values of another version are misses
 //   - Circuit breaker pattern for external cache failures
+//   - Health checks of the backend with Ping for readiness probes
+//   - Distributed locks backed by Redis
+//   - Stale-while-revalidate loading with RememberSWR
+//   - Batch loading of missing keys with GetOrSetMulti
+//   - Generic GetTyped and SetTyped helpers returning values of a concrete type
+//   - MustGet returning ErrNotFound for missing keys instead of a found flag
 //
 // Example usage:
 //
@@ -66,10 +76,15 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/valentin-kaiser/go-core/apperror"
 	"github.com/valentin-kaiser/go-core/config"
@@ -110,24 +125,34 @@ type Cache interface {
 	// GetStats returns cache statistics
 	GetStats() Stats
 
-	// Close closes the cache and releases resources
+	// Ping checks that the cache backend is reachable, e.g. for readiness probes
+	Ping(ctx context.Context) error
+
+	// Close closes the cache and releases resources, like CloseContext without a deadline
 	Close() error
+
+	// CloseContext waits for pending event handlers, stops background goroutines and
+	// releases resources. It returns once the cache is quiesced or the context is done.
+	CloseContext(ctx context.Context) error
 }
 
 // Stats represents cache statistics
 type Stats struct {
-	Hits        int64     `json:"hits"`
-	Misses      int64     `json:"misses"`
-	Sets        int64     `json:"sets"`
-	Deletes     int64     `json:"deletes"`
-	Evictions   int64     `json:"evictions"`
-	Size        int64     `json:"size"`
-	MaxSize     int64     `json:"max_size"`
-	HitRatio    float64   `json:"hit_ratio"`
-	Memory      int64     `json:"memory_bytes"`
-	Errors      int64     `json:"errors"`
-	LastError   string    `json:"last_error,omitempty"`
-	LastErrorAt time.Time `json:"last_error_at,omitempty"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	Sets       int64   `json:"sets"`
+	Deletes    int64   `json:"deletes"`
+	Evictions  int64   `json:"evictions"`
+	Size       int64   `json:"size"`
+	MaxSize    int64   `json:"max_size"`
+	HitRatio   float64 `json:"hit_ratio"`
+	Memory     int64   `json:"memory_bytes"`
+	Errors     int64   `json:"errors"`
+	Compressed int64   `json:"compressed"`
+	// StaleVersions counts the values read with another schema version, they are counted as misses as well
+	StaleVersions int64     `json:"stale_versions"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorAt   time.Time `json:"last_error_at,omitempty"`
 }
 
 // Item represents a cache item with metadata
@@ -160,7 +185,9 @@ const (
 	EventDelete
 	// EventEvict represents a cache eviction event
 	EventEvict
-	// EventExpire represents a cache expiration event
+	// EventExpire represents a cache expiration event, it is emitted when an expired
+	// entry is read or removed by the cleanup of a memory cache and for Redis caches
+	// by SubscribeExpirations
 	EventExpire
 	// EventClear represents a cache clear event
 	EventClear
@@ -208,8 +235,29 @@ type Config struct {
 	EnableStats     bool          `json:"enable_stats"`
 	EnableEvents    bool          `json:"enable_events"`
 	Namespace       string        `json:"namespace"`
-	Serializer      Serializer    `json:"-"`
-	EventHandler    EventHandler  `json:"-"`
+	// CompressThreshold is the size in bytes above which serialized values are stored gzip compressed
+	// by the Redis cache, zero disables compression. Compressed values are decompressed transparently.
+	CompressThreshold int `json:"compress_threshold"`
+	// HashKeysOver is the length in bytes above which keys including the namespace are shortened
+	// by replacing their end with a hash, zero disables hashing. The namespace and the beginning
+	// of the key are kept readable. Keys are hashed transparently on every operation, but events
+	// of hashed keys carry the hashed key.
+	HashKeysOver int `json:"hash_keys_over"`
+	// SchemaVersion is stored with every value and checked when the value is read. Values of another
+	// version are misses, so cached structs whose shape changed between deployments, e.g. by a renamed
+	// field, are not decoded into wrong or partial objects. Values stored without a version are misses
+	// once a version is set, and values stored with a version are misses if it is removed again.
+	// Exists and GetTTL do not check the version. Counters of Increment and pipeline increments are
+	// stored as plain integers by both caches, they are not versioned and match every version.
+	SchemaVersion string `json:"schema_version"`
+	// SchemaVersions overrides the schema version for keys starting with a prefix, the longest
+	// matching prefix wins. It allows migrating the values of one type at a time.
+	SchemaVersions map[string]string `json:"schema_versions"`
+	// DeleteStaleVersions deletes values of another schema version when they are read instead of
+	// leaving them until they expire or are overwritten
+	DeleteStaleVersions bool         `json:"delete_stale_versions"`
+	Serializer          Serializer   `json:"-"`
+	EventHandler        EventHandler `json:"-"`
 }
 
 // Changed checks if the cache configuration has changed compared to another configuration.
@@ -283,6 +331,8 @@ type BaseCache struct {
 	config Config
 	stats  Stats
 	mutex  sync.RWMutex
+	events sync.WaitGroup // running event handlers
+	closed bool           // set when the cache is closed, later events are dropped
 }
 
 // NewBaseCache creates a new base cache with the given configuration
@@ -341,8 +391,39 @@ func (bc *BaseCache) emitEvent(eventType EventType, key string, value interface{
 		Error:     err,
 	}
 
+	bc.mutex.RLock()
+	defer bc.mutex.RUnlock()
+	if bc.closed {
+		return
+	}
+
 	// Run event handler in a goroutine to avoid blocking
-	go bc.config.EventHandler(event)
+	bc.events.Add(1)
+	go func() {
+		defer bc.events.Done()
+		bc.config.EventHandler(event)
+	}()
+}
+
+// drain stops emitting events and waits until the handlers of all emitted events returned
+// It returns an error if the context is done before
+func (bc *BaseCache) drain(ctx context.Context) error {
+	bc.mutex.Lock()
+	bc.closed = true
+	bc.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		bc.events.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return NewCacheError("close", "", apperror.NewError("waiting for event handlers failed").AddError(ctx.Err()))
+	}
 }
 
 // recordError records an error in the statistics
@@ -355,11 +436,42 @@ func (bc *BaseCache) recordError(err error) {
 }
 
 // formatKey formats a cache key with namespace if configured
+// Keys longer than HashKeysOver are shortened with a hash, see hashKey
 func (bc *BaseCache) formatKey(key string) string {
+	if bc.config.Namespace != "" {
+		key = fmt.Sprintf("%s:%s", bc.config.Namespace, key)
+	}
+	if bc.config.HashKeysOver > 0 && len(key) > bc.config.HashKeysOver {
+		return bc.hashKey(key)
+	}
+	return key
+}
+
+// hashKey shortens the key to HashKeysOver bytes by replacing its end with a hash of the
+// whole key, e.g. "app:report:2024-01-01:...#9f86d081884c7d659a2feaa0c55ad015".
+// The namespace is always kept, so hashed keys still belong to it.
+func (bc *BaseCache) hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:keyHashSize])
+
+	keep := bc.config.HashKeysOver - len(hash) - 1
+	if bc.config.Namespace != "" && keep < len(bc.config.Namespace)+1 {
+		keep = len(bc.config.Namespace) + 1
+	}
+	keep = min(max(keep, 0), len(key))
+	for keep > 0 && keep < len(key) && !utf8.RuneStart(key[keep]) {
+		keep--
+	}
+	return key[:keep] + "#" + hash
+}
+
+// trimKey removes the namespace from a formatted cache key, it reports false
+// if the key does not belong to the namespace of the cache
+func (bc *BaseCache) trimKey(key string) (string, bool) {
 	if bc.config.Namespace == "" {
-		return key
+		return key, true
 	}
-	return fmt.Sprintf("%s:%s", bc.config.Namespace, key)
+	return strings.CutPrefix(key, bc.config.Namespace+":")
 }
 
 // calculateTTL calculates the effective TTL for a cache entry
@@ -370,6 +482,12 @@ func (bc *BaseCache) calculateTTL(ttl time.Duration) time.Duration {
 	return ttl
 }
 
+// keyHashSize is the number of bytes of the hash that replaces the end of long keys
+const keyHashSize = 16
+
+// ErrNotFound is returned by MustGet if the key is not found in the cache
+var ErrNotFound = errors.New("key not found")
+
 // Error represents a cache-specific error
 type Error struct {
 	Op  string
@@ -1,7 +1,11 @@
 package cache_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -284,7 +288,7 @@ func TestMemoryCache_Events(t *testing.T) {
 			eventsChan <- event
 		})
 
-	apperror.Catch(c.Close, "failed to close cache")
+	defer apperror.Catch(c.Close, "failed to close cache")
 
 	ctx := t.Context()
 
@@ -336,6 +340,107 @@ func TestMemoryCache_Events(t *testing.T) {
 	}
 }
 
+func TestMemoryCache_ExpireEvents(t *testing.T) {
+	expired := make(chan cache.Event, 1)
+	c := cache.NewMemoryCacheWithConfig(cache.Config{
+		Namespace:       "sessions",
+		DefaultTTL:      time.Hour,
+		CleanupInterval: 10 * time.Millisecond,
+		EnableEvents:    true,
+		EventHandler: func(event cache.Event) {
+			if event.Type == cache.EventExpire {
+				expired <- event
+			}
+		},
+	})
+	defer apperror.Catch(c.Close, "Failed to close memory cache")
+
+	err := c.Set(t.Context(), "token", "value", 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	select {
+	case event := <-expired:
+		if event.Key != "token" {
+			t.Errorf("Expected key without namespace, got %s", event.Key)
+		}
+		if event.Namespace != "sessions" {
+			t.Errorf("Expected namespace sessions, got %s", event.Namespace)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expiration was not emitted by the cleanup")
+	}
+}
+
+func TestMemoryCache_CloseDrainsEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []cache.Event
+	c := cache.NewMemoryCache().
+		WithEventHandler(func(event cache.Event) {
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		})
+
+	ctx := t.Context()
+	for i := 0; i < 50; i++ {
+		err := c.Set(ctx, fmt.Sprintf("key%d", i), i, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	err := c.Close()
+	if err != nil {
+		t.Fatalf("Failed to close cache: %v", err)
+	}
+
+	mu.Lock()
+	received := len(events)
+	mu.Unlock()
+	if received != 50 {
+		t.Errorf("Expected all 50 events to be handled before Close returned, got %d", received)
+	}
+
+	// Events of a closed cache are dropped and closing again is safe
+	err = c.Set(ctx, "late", 1, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	err = c.Close()
+	if err != nil {
+		t.Fatalf("Failed to close cache again: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 50 {
+		t.Errorf("Expected no events after Close, got %d", len(events)-50)
+	}
+}
+
+func TestMemoryCache_CloseContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	c := cache.NewMemoryCache().
+		WithEventHandler(func(_ cache.Event) {
+			<-release
+		})
+
+	err := c.Set(t.Context(), "key", "value", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	err = c.CloseContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while an event handler blocks, got %v", err)
+	}
+}
+
 func TestMemoryCache_Namespace(t *testing.T) {
 	config := cache.DefaultConfig()
 	config.Namespace = "test"
@@ -375,6 +480,62 @@ func TestMemoryCache_Namespace(t *testing.T) {
 	}
 }
 
+func TestMemoryCache_KeyHashing(t *testing.T) {
+	config := cache.DefaultConfig()
+	config.Namespace = "test"
+	config.HashKeysOver = 64
+
+	c := cache.NewMemoryCacheWithConfig(config)
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	ctx := t.Context()
+	long := "report:" + strings.Repeat("x", 200)
+	items := map[string]interface{}{
+		"short":    "a",
+		long + "1": "b",
+		long + "2": "c",
+	}
+	err := c.SetMulti(ctx, items, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	keys := c.GetKeys()
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 distinct keys, got %v", keys)
+	}
+	for _, key := range keys {
+		if len(key) > 64 || !strings.HasPrefix(key, "test:") {
+			t.Errorf("Expected namespaced key of at most 64 bytes, got %q", key)
+		}
+		if key != "test:short" && !strings.HasPrefix(key, "test:report:xxx") {
+			t.Errorf("Expected readable beginning of hashed key, got %q", key)
+		}
+	}
+
+	var value string
+	found, err := c.Get(ctx, long+"2", &value)
+	if err != nil || !found || value != "c" {
+		t.Errorf("Expected to get value of hashed key, got %q (found %v, err %v)", value, found, err)
+	}
+
+	values, err := c.GetMulti(ctx, []string{"short", long + "1", long + "2"})
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if len(values) != 3 || values[long+"1"] != "b" {
+		t.Errorf("Expected values by their original keys, got %v", values)
+	}
+
+	err = c.DeleteMulti(ctx, []string{long + "1", long + "2"})
+	if err != nil {
+		t.Fatalf("Failed to delete values: %v", err)
+	}
+	if keys := c.GetKeys(); len(keys) != 1 || keys[0] != "test:short" {
+		t.Errorf("Expected only the short key to remain, got %v", keys)
+	}
+}
+
 func TestMemoryCache_Clear(t *testing.T) {
 	c := cache.NewMemoryCache()
 	apperror.Catch(c.Close, "failed to close cache")
@@ -530,3 +691,181 @@ func TestNoOpSerializer(t *testing.T) {
 		t.Error("Expected error for unsupported type")
 	}
 }
+
+func TestMemoryCache_Pipeline(t *testing.T) {
+	c := cache.NewMemoryCache()
+	defer apperror.Catch(c.Close, "failed to close cache")
+	ctx := t.Context()
+
+	err := c.Set(ctx, "user:1", TestUser{ID: 1, Name: "John Doe"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var user TestUser
+	var get, missing, incr *cache.PipeResult
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		get = p.Get("user:1", &user)
+		var other TestUser
+		missing = p.Get("user:2", &other)
+		p.Delete("user:1")
+		p.Set("user:2", TestUser{ID: 2, Name: "Jane Doe"}, time.Hour)
+		p.Incr("users", 2)
+		incr = p.Incr("users", 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if !get.Found || user.Name != "John Doe" {
+		t.Errorf("Expected queued get to find the user, got %+v (%+v)", user, get)
+	}
+	if missing.Found {
+		t.Error("Expected queued get of a missing key not to find it")
+	}
+	if incr.Value != 5 {
+		t.Errorf("Expected counter to be 5, got %d", incr.Value)
+	}
+	if exists, _ := c.Exists(ctx, "user:1"); exists {
+		t.Error("Expected user:1 to be deleted")
+	}
+	if exists, _ := c.Exists(ctx, "user:2"); !exists {
+		t.Error("Expected user:2 to be set")
+	}
+
+	// Nothing is executed if the function fails
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		p.Delete("user:2")
+		return errors.New("aborted")
+	})
+	if err == nil {
+		t.Error("Expected error of the function to be returned")
+	}
+	if exists, _ := c.Exists(ctx, "user:2"); !exists {
+		t.Error("Expected aborted pipeline not to delete user:2")
+	}
+
+	// Failed operations report their error
+	var fail *cache.PipeResult
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		fail = p.Incr("user:2", 1)
+		return nil
+	})
+	if err == nil || fail.Err == nil {
+		t.Errorf("Expected incrementing a non-numeric value to fail, got %v", err)
+	}
+
+	tiered := cache.NewTieredCache(cache.NewMemoryCache(), cache.NewMemoryCache())
+	defer apperror.Catch(tiered.Close, "failed to close cache")
+	err = cache.Pipeline(ctx, tiered, func(_ cache.Pipe) error { return nil })
+	if err == nil {
+		t.Error("Expected pipeline of a cache without pipeline support to fail")
+	}
+}
+
+func TestMemoryCache_SchemaVersion(t *testing.T) {
+	c := cache.NewMemoryCache().WithSchemaVersion("1")
+	defer apperror.Catch(c.Close, "failed to close cache")
+	ctx := t.Context()
+
+	err := c.Set(ctx, "user:1", TestUser{ID: 1, Name: "John Doe"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	err = c.Set(ctx, "order:1", TestUser{ID: 1, Name: "Order"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var user TestUser
+	found, err := c.Get(ctx, "user:1", &user)
+	if err != nil || !found || user.Name != "John Doe" {
+		t.Fatalf("Expected value of the same schema version, got %v (%v)", user, err)
+	}
+
+	// A new schema version turns the stored values into misses
+	c.WithSchemaVersion("2")
+	user = TestUser{}
+	found, err = c.Get(ctx, "user:1", &user)
+	if err != nil || found {
+		t.Errorf("Expected value of another schema version to be a miss, got %v (%v)", user, err)
+	}
+	if stats := c.GetStats(); stats.StaleVersions != 1 {
+		t.Errorf("Expected 1 stale version, got %d", stats.StaleVersions)
+	}
+
+	// Keys can keep their schema version during a migration
+	c.WithKeySchemaVersion("user:", "1")
+	found, err = c.Get(ctx, "user:1", &user)
+	if err != nil || !found {
+		t.Errorf("Expected value of the key schema version to be found, got %v", err)
+	}
+	found, err = c.Get(ctx, "order:1", &user)
+	if err != nil || found {
+		t.Errorf("Expected value of another schema version to be a miss, got %v", err)
+	}
+
+	// Counters are stored without schema version and survive a new one like those of RedisCache
+	incr := func() int64 {
+		var result *cache.PipeResult
+		err := cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+			result = p.Incr("visits", 5)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Pipeline failed: %v", err)
+		}
+		return result.Value
+	}
+	if n := incr(); n != 5 {
+		t.Fatalf("Expected counter to be 5, got %d", n)
+	}
+	if n := incr(); n != 10 {
+		t.Fatalf("Expected counter to be 10, got %d", n)
+	}
+	c.WithSchemaVersion("3")
+	if n := incr(); n != 15 {
+		t.Errorf("Expected counter to match every schema version, got %d", n)
+	}
+}
+
+func TestMemoryCache_DeleteStaleVersions(t *testing.T) {
+	config := cache.DefaultConfig()
+	config.DeleteStaleVersions = true
+	c := cache.NewMemoryCacheWithConfig(config)
+	defer apperror.Catch(c.Close, "failed to close cache")
+	ctx := t.Context()
+
+	// Values stored before versioning was enabled are stale as well
+	err := c.Set(ctx, "user:1", TestUser{ID: 1, Name: "John Doe"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	c.WithSchemaVersion("1")
+
+	var user TestUser
+	found, err := c.Get(ctx, "user:1", &user)
+	if err != nil || found {
+		t.Fatalf("Expected unversioned value to be a miss, got %v (%v)", user, err)
+	}
+	if c.GetSize() != 0 {
+		t.Errorf("Expected stale value to be deleted, %d values left", c.GetSize())
+	}
+
+	// Counters are no stale versions, reading them must not reset them
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		p.Incr("ratelimit:client", 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	var count int64
+	found, err = c.Get(ctx, "ratelimit:client", &count)
+	if err != nil || !found || count != 3 {
+		t.Errorf("Expected counter to be read, got %d, %v (%v)", count, found, err)
+	}
+	if c.GetSize() != 1 || c.GetStats().StaleVersions != 1 {
+		t.Errorf("Expected counter to be kept, %d values and %d stale versions", c.GetSize(), c.GetStats().StaleVersions)
+	}
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// compressedMarker prefixes compressed values, it is followed by a gzip stream.
+// Serialized values never start with a zero byte followed by the gzip magic number,
+// so compressed and uncompressed values can be stored side by side.
+const compressedMarker = 0x00
+
+// compress compresses serialized data larger than the compression threshold
+// Data that does not shrink is returned unchanged
+func (bc *BaseCache) compress(data []byte) ([]byte, error) {
+	if bc.config.CompressThreshold <= 0 || len(data) <= bc.config.CompressThreshold {
+		return data, nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)/2))
+	buf.WriteByte(compressedMarker)
+	writer := gzip.NewWriter(buf)
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, apperror.NewError("failed to compress value").AddError(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, apperror.NewError("failed to compress value").AddError(err)
+	}
+
+	if buf.Len() >= len(data) {
+		return data, nil
+	}
+
+	bc.updateStats(func(s *Stats) { s.Compressed++ })
+	return buf.Bytes(), nil
+}
+
+// decompress returns the serialized data of a value that may be compressed
+// Values are decompressed regardless of the threshold, so it can be changed at any time
+func decompress(data []byte) ([]byte, error) {
+	if !isCompressed(data) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data[1:]))
+	if err != nil {
+		return nil, apperror.NewError("failed to decompress value").AddError(err)
+	}
+	defer apperror.Catch(reader.Close, "failed to close decompressor")
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, apperror.NewError("failed to decompress value").AddError(err)
+	}
+	return out, nil
+}
+
+// isCompressed reports whether the data starts with the compression marker and the gzip magic number
+func isCompressed(data []byte) bool {
+	return len(data) > 3 && data[0] == compressedMarker && data[1] == 0x1f && data[2] == 0x8b
+}
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+const (
+	// jsonUnknown means the RedisJSON capability was not detected yet
+	jsonUnknown int32 = iota
+	jsonAvailable
+	jsonUnavailable
+)
+
+// maxFieldUpdateRetries limits the optimistic read-modify-write attempts of UpdateField
+const maxFieldUpdateRetries = 16
+
+// SupportsJSON reports whether the Redis server has the RedisJSON module loaded.
+// If it does, UpdateField modifies documents stored as RedisJSON type with JSON.SET on the server.
+// Values stored with Set are plain strings and are always updated with a read-modify-write
+// transaction. The result is detected once per cache and reused afterwards.
+func (rc *RedisCache) SupportsJSON(ctx context.Context) (bool, error) {
+	switch rc.jsonSupport.Load() {
+	case jsonAvailable:
+		return true, nil
+	case jsonUnavailable:
+		return false, nil
+	}
+
+	// COMMAND INFO returns a nil entry for commands the server does not know
+	info, err := rc.client.Do(ctx, "COMMAND", "INFO", "JSON.SET").Slice()
+	if err != nil {
+		rc.recordError(err)
+		return false, NewCacheError("json capability", "", err)
+	}
+
+	supported := len(info) > 0 && info[0] != nil
+	state := jsonUnavailable
+	if supported {
+		state = jsonAvailable
+	}
+	rc.jsonSupport.Store(state)
+	return supported, nil
+}
+
+// UpdateField sets the value at the path of the JSON document stored under the key,
+// without replacing the rest of the document. The path addresses object fields and
+// array indexes separated by dots, e.g. "address.city" or "tags.0".
+//
+// Documents stored as RedisJSON type are updated with JSON.SET if the module is available,
+// see SupportsJSON. All other values are decoded with the configured serializer, modified
+// and written back in a WATCH/MULTI transaction that is retried if the key is modified
+// concurrently. The remaining TTL of the key is kept in both cases.
+//
+// Example:
+//
+//	err := redisCache.UpdateField(ctx, "user:123", "address.city", "Berlin")
+func (rc *RedisCache) UpdateField(ctx context.Context, key, path string, value interface{}) error {
+	segments, err := splitFieldPath(path)
+	if err != nil {
+		return NewCacheError("update field", key, err)
+	}
+
+	formattedKey := rc.formatKey(key)
+	supported, err := rc.SupportsJSON(ctx)
+	if err != nil {
+		return err
+	}
+
+	if supported {
+		kind, err := rc.client.Type(ctx, formattedKey).Result()
+		if err != nil {
+			rc.recordError(err)
+			return NewCacheError("update field", key, err)
+		}
+		if kind == "ReJSON-RL" {
+			return rc.setJSONField(ctx, key, formattedKey, segments, value)
+		}
+	}
+
+	return rc.updateFieldCAS(ctx, key, formattedKey, segments, value)
+}
+
+// setJSONField updates a RedisJSON document on the server
+func (rc *RedisCache) setJSONField(ctx context.Context, key, formattedKey string, segments []string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("update field", key, err)
+	}
+
+	var jsonPath strings.Builder
+	jsonPath.WriteString("$")
+	for _, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			jsonPath.WriteString("[" + segment + "]")
+			continue
+		}
+		jsonPath.WriteString("[" + strconv.Quote(segment) + "]")
+	}
+
+	err = rc.client.Do(ctx, "JSON.SET", formattedKey, jsonPath.String(), string(data)).Err()
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("update field", key, err)
+	}
+
+	rc.updateStats(func(s *Stats) { s.Sets++ })
+	rc.emitEvent(EventSet, key, value, nil)
+	return nil
+}
+
+// updateFieldCAS updates a serialized value with an optimistic read-modify-write transaction
+func (rc *RedisCache) updateFieldCAS(ctx context.Context, key, formattedKey string, segments []string, value interface{}) error {
+	// Round trip the value through JSON so that structs are modified like decoded documents
+	data, err := json.Marshal(value)
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("update field", key, err)
+	}
+	var field interface{}
+	err = decodeJSON(data, &field)
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("update field", key, err)
+	}
+
+	update := func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, formattedKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return apperror.NewError("key does not exist").WithKind(apperror.KindNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		raw, current := rc.checkVersion(key, raw)
+		if !current {
+			return apperror.NewError("key does not exist with the current schema version").WithKind(apperror.KindNotFound)
+		}
+
+		raw, err = decompress(raw)
+		if err != nil {
+			return err
+		}
+
+		var document interface{}
+		err = rc.decodeDocument(raw, &document)
+		if err != nil {
+			return apperror.NewError("failed to decode the stored value").AddError(err)
+		}
+
+		document, err = setFieldPath(document, segments, field)
+		if err != nil {
+			return err
+		}
+
+		raw, err = rc.config.Serializer.Serialize(document)
+		if err != nil {
+			return apperror.NewError("failed to encode the updated value").AddError(err)
+		}
+
+		raw, err = rc.compress(raw)
+		if err != nil {
+			return err
+		}
+		raw = rc.withVersion(key, raw)
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, formattedKey, raw, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}
+
+	for range maxFieldUpdateRetries {
+		err = rc.client.Watch(ctx, update, formattedKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			rc.recordError(err)
+			rc.emitEvent(EventSet, key, value, err)
+			return NewCacheError("update field", key, err)
+		}
+
+		rc.updateStats(func(s *Stats) { s.Sets++ })
+		rc.emitEvent(EventSet, key, value, nil)
+		return nil
+	}
+
+	err = apperror.NewErrorf("key was modified concurrently %d times", maxFieldUpdateRetries).WithKind(apperror.KindConflict)
+	rc.recordError(err)
+	rc.emitEvent(EventSet, key, value, err)
+	return NewCacheError("update field", key, err)
+}
+
+// splitFieldPath splits a dot separated field path into its segments
+func splitFieldPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, apperror.NewError("field path must not be empty").WithKind(apperror.KindInvalidArgument)
+	}
+
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, apperror.NewErrorf("invalid field path %q", path).WithKind(apperror.KindInvalidArgument)
+		}
+	}
+	return segments, nil
+}
+
+// decodeDocument decodes a stored value to modify it, JSON numbers are kept as json.Number
+// so that integers beyond the precision of float64 are written back unchanged
+func (rc *RedisCache) decodeDocument(raw []byte, document *interface{}) error {
+	if _, ok := rc.config.Serializer.(*JSONSerializer); ok {
+		return decodeJSON(raw, document)
+	}
+	return rc.config.Serializer.Deserialize(raw, document)
+}
+
+// decodeJSON decodes JSON data with numbers as json.Number
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// setFieldPath sets the value at the path of a decoded JSON document.
+// Missing object fields are created, array indexes must exist.
+func setFieldPath(document interface{}, segments []string, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment := segments[0]
+	switch node := document.(type) {
+	case map[string]interface{}:
+		child, err := setFieldPath(node[segment], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[segment] = child
+		return node, nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(node) {
+			return nil, apperror.NewErrorf("array index %q out of range", segment).WithKind(apperror.KindInvalidArgument)
+		}
+		child, err := setFieldPath(node[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	case nil:
+		child, err := setFieldPath(nil, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{segment: child}, nil
+	default:
+		return nil, apperror.NewErrorf("field %q is not an object or array", segment).WithKind(apperror.KindInvalidArgument)
+	}
+}
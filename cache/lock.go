@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// unlockScript deletes the lock key only if it still holds the token of the caller
+// This prevents releasing a lock that expired and was acquired by another node
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock tries to acquire a distributed lock on the key for the given TTL.
+// It is implemented with SET key token NX PX ttl, the returned unlock function
+// releases the lock only if it is still held by this caller. If the lock is held
+// by someone else, acquired is false and unlock is nil.
+//
+// The lock is suited for coordination like leader election or ensuring that a scheduled
+// task runs on only one node. It is not a fencing mechanism: if the holder pauses longer
+// than the TTL (GC pause, network partition) the lock expires and another node can acquire
+// it while the first one still believes it holds it. Work that must never run concurrently
+// has to be protected by the resource itself, e.g. with version checks in the database.
+// Redis replication is asynchronous, so a failover can also lose an acquired lock.
+//
+// Example ensuring a scheduled task runs on only one node:
+//
+//	scheduler.RegisterCronTask("cleanup", "0 * * * *", func(ctx context.Context) error {
+//		unlock, acquired, err := redisCache.Lock(ctx, "lock:cleanup", 5*time.Minute)
+//		if err != nil {
+//			return err
+//		}
+//		if !acquired {
+//			return nil // another node runs the task
+//		}
+//		defer apperror.Catch(unlock, "releasing cleanup lock failed")
+//		return cleanup(ctx)
+//	})
+func (rc *RedisCache) Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, acquired bool, err error) {
+	if ttl <= 0 {
+		return nil, false, NewCacheError("lock", key, apperror.NewError("lock ttl must be positive"))
+	}
+
+	formattedKey := rc.formatKey(key)
+	token, err := lockToken()
+	if err != nil {
+		rc.recordError(err)
+		return nil, false, NewCacheError("lock", key, err)
+	}
+
+	acquired, err = rc.client.SetNX(ctx, formattedKey, token, ttl).Result()
+	if err != nil {
+		rc.recordError(err)
+		return nil, false, NewCacheError("lock", key, err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	unlock = func() error {
+		// Use a fresh context so that the lock is released even if ctx was canceled
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := unlockScript.Run(ctx, rc.client, []string{formattedKey}, token).Err()
+		if err != nil {
+			rc.recordError(err)
+			return NewCacheError("unlock", key, err)
+		}
+		return nil
+	}
+
+	return unlock, true, nil
+}
+
+// lockToken generates a random token identifying the holder of a lock
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", apperror.NewError("generating lock token failed").AddError(err)
+	}
+	return hex.EncodeToString(b), nil
+}
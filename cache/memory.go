@@ -18,6 +18,7 @@ type MemoryCache struct {
 	lruList   *list.List
 	mutex     sync.RWMutex
 	stopChan  chan struct{}
+	closeOnce sync.Once
 	cleanupWg sync.WaitGroup
 }
 
@@ -93,6 +94,21 @@ func (mc *MemoryCache) WithCleanupInterval(interval time.Duration) *MemoryCache
 	return mc
 }
 
+// WithSchemaVersion sets the schema version stored with every value, see Config.SchemaVersion
+func (mc *MemoryCache) WithSchemaVersion(version string) *MemoryCache {
+	mc.config.SchemaVersion = version
+	return mc
+}
+
+// WithKeySchemaVersion sets the schema version of the keys starting with the prefix, see Config.SchemaVersions
+func (mc *MemoryCache) WithKeySchemaVersion(prefix, version string) *MemoryCache {
+	if mc.config.SchemaVersions == nil {
+		mc.config.SchemaVersions = make(map[string]string)
+	}
+	mc.config.SchemaVersions[prefix] = version
+	return mc
+}
+
 // WithEventHandler sets the event handler for cache events
 func (mc *MemoryCache) WithEventHandler(handler EventHandler) *MemoryCache {
 	mc.config.EventHandler = handler
@@ -105,20 +121,30 @@ func (mc *MemoryCache) Get(_ context.Context, key string, dest interface{}) (boo
 	formattedKey := mc.formatKey(key)
 
 	mc.mutex.Lock()
+	data, found, err := mc.load(key, formattedKey)
+	mc.mutex.Unlock()
+	if err != nil || !found {
+		return false, err
+	}
+
+	return mc.decode(key, data, dest)
+}
+
+// load returns the serialized value of the key (must be called with lock held)
+// Expired items are removed, misses are recorded in the statistics
+func (mc *MemoryCache) load(key, formattedKey string) ([]byte, bool, error) {
 	element, exists := mc.items[formattedKey]
 	if !exists {
-		mc.mutex.Unlock()
 		mc.updateStats(func(s *Stats) { s.Misses++ })
 		mc.emitEvent(EventGet, key, nil, nil)
-		return false, nil
+		return nil, false, nil
 	}
 
 	memItem, ok := element.Value.(*memoryItem)
 	if !ok {
-		mc.mutex.Unlock()
 		mc.updateStats(func(s *Stats) { s.Misses++ })
 		mc.emitEvent(EventGet, key, nil, nil)
-		return false, NewCacheError("get", key, errors.New("invalid cache item type"))
+		return nil, false, NewCacheError("get", key, errors.New("invalid cache item type"))
 	}
 	item := memItem.item
 
@@ -126,10 +152,26 @@ func (mc *MemoryCache) Get(_ context.Context, key string, dest interface{}) (boo
 	if item.IsExpired() {
 		// Remove expired item
 		mc.removeElement(element, formattedKey)
-		mc.mutex.Unlock()
 		mc.updateStats(func(s *Stats) { s.Misses++ })
 		mc.emitEvent(EventExpire, key, nil, nil)
-		return false, nil
+		return nil, false, nil
+	}
+
+	data, ok := item.Value.([]byte)
+	if !ok {
+		mc.updateStats(func(s *Stats) { s.Misses++ })
+		return nil, false, NewCacheError("get", key, errors.New("invalid item value type"))
+	}
+
+	// Values of another schema version are misses
+	data, current := mc.checkVersion(key, data)
+	if !current {
+		if mc.config.DeleteStaleVersions {
+			mc.removeElement(element, formattedKey)
+		}
+		mc.updateStats(func(s *Stats) { s.Misses++ })
+		mc.emitEvent(EventGet, key, nil, nil)
+		return nil, false, nil
 	}
 
 	// Update access time for LRU
@@ -137,15 +179,11 @@ func (mc *MemoryCache) Get(_ context.Context, key string, dest interface{}) (boo
 	if mc.config.EnableLRU {
 		mc.lruList.MoveToFront(element)
 	}
+	return data, true, nil
+}
 
-	mc.mutex.Unlock()
-
-	// Deserialize the value
-	data, ok := item.Value.([]byte)
-	if !ok {
-		mc.updateStats(func(s *Stats) { s.Misses++ })
-		return false, NewCacheError("get", key, errors.New("invalid item value type"))
-	}
+// decode deserializes a value loaded from the cache into dest and records the hit
+func (mc *MemoryCache) decode(key string, data []byte, dest interface{}) (bool, error) {
 	err := mc.config.Serializer.Deserialize(data, dest)
 	if err != nil {
 		mc.recordError(err)
@@ -161,7 +199,6 @@ func (mc *MemoryCache) Get(_ context.Context, key string, dest interface{}) (boo
 // Set stores a value in the cache
 func (mc *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
 	formattedKey := mc.formatKey(key)
-	effectiveTTL := mc.calculateTTL(ttl)
 
 	// Serialize the value
 	data, err := mc.config.Serializer.Serialize(value)
@@ -171,6 +208,18 @@ func (mc *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl
 		return NewCacheError("set", key, err)
 	}
 
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.store(key, formattedKey, value, data, mc.calculateTTL(ttl))
+}
+
+// store stores the serialized value under the key with its schema version (must be called with lock held)
+func (mc *MemoryCache) store(key, formattedKey string, value interface{}, data []byte, ttl time.Duration) error {
+	return mc.put(key, formattedKey, value, mc.withVersion(key, data), ttl)
+}
+
+// put stores the data under the key as is (must be called with lock held)
+func (mc *MemoryCache) put(key, formattedKey string, value interface{}, data []byte, ttl time.Duration) error {
 	dataSize := int64(len(data))
 	now := time.Now()
 
@@ -180,14 +229,14 @@ func (mc *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl
 		CreatedAt: now,
 		UpdatedAt: now,
 		AccessAt:  now,
-		TTL:       effectiveTTL,
+		TTL:       ttl,
 		Size:      dataSize,
 		Namespace: mc.config.Namespace,
 		ExpiresAt: time.Time{}, // Default to no expiration
 	}
 
-	if effectiveTTL > 0 {
-		item.ExpiresAt = now.Add(effectiveTTL)
+	if ttl > 0 {
+		item.ExpiresAt = now.Add(ttl)
 	}
 
 	memItem := &memoryItem{
@@ -195,9 +244,6 @@ func (mc *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl
 		dataSize: dataSize,
 	}
 
-	mc.mutex.Lock()
-	defer mc.mutex.Unlock()
-
 	// Check if key already exists
 	element, exists := mc.items[formattedKey]
 	if exists {
@@ -246,16 +292,21 @@ func (mc *MemoryCache) Delete(_ context.Context, key string) error {
 
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
+	mc.remove(key, formattedKey)
+	return nil
+}
 
+// remove removes the key from the cache (must be called with lock held)
+// Removing a key that does not exist is considered a successful deletion
+func (mc *MemoryCache) remove(key, formattedKey string) {
 	element, exists := mc.items[formattedKey]
 	if !exists {
-		return nil // Key doesn't exist, consider it a successful deletion
+		return
 	}
 
 	mc.removeElement(element, formattedKey)
 	mc.updateStats(func(s *Stats) { s.Deletes++ })
 	mc.emitEvent(EventDelete, key, nil, nil)
-	return nil
 }
 
 // Exists checks if a key exists in the cache
@@ -402,11 +453,22 @@ func (mc *MemoryCache) SetTTL(_ context.Context, key string, ttl time.Duration)
 	return nil
 }
 
+// Ping always succeeds, the memory cache has no backend that can become unreachable
+func (mc *MemoryCache) Ping(_ context.Context) error {
+	return nil
+}
+
 // Close closes the cache and stops the cleanup goroutine
 func (mc *MemoryCache) Close() error {
-	close(mc.stopChan)
+	return mc.CloseContext(context.Background())
+}
+
+// CloseContext stops the cleanup goroutine and waits for the handlers of emitted events
+// Closing the cache again only waits for the event handlers
+func (mc *MemoryCache) CloseContext(ctx context.Context) error {
+	mc.closeOnce.Do(func() { close(mc.stopChan) })
 	mc.cleanupWg.Wait()
-	return nil
+	return mc.drain(ctx)
 }
 
 // GetKeys returns all keys in the cache (useful for debugging)
@@ -468,6 +530,7 @@ func (mc *MemoryCache) evictLRU() {
 	mc.removeElement(element, key)
 
 	mc.updateStats(func(s *Stats) { s.Evictions++ })
+	key, _ = mc.trimKey(key)
 	mc.emitEvent(EventEvict, key, nil, nil)
 }
 
@@ -521,6 +584,7 @@ func (mc *MemoryCache) cleanupExpired() {
 		}
 
 		mc.removeElement(element, key)
+		key, _ = mc.trimKey(key)
 		mc.emitEvent(EventExpire, key, nil, nil)
 	}
 }
@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Pipe queues the operations of a pipeline. The operations are executed together once the
+// function passed to Pipeline returned, their results are available afterwards.
+type Pipe interface {
+	// Set queues storing a value with the specified TTL
+	Set(key string, value interface{}, ttl time.Duration) *PipeResult
+	// Get queues retrieving a value, it is deserialized into dest when the pipeline is executed
+	Get(key string, dest interface{}) *PipeResult
+	// Delete queues removing a value
+	Delete(key string) *PipeResult
+	// Incr queues incrementing a numeric value by delta, a missing key is created with the value delta
+	Incr(key string, delta int64) *PipeResult
+}
+
+// PipeResult is the result of an operation queued on a Pipe
+type PipeResult struct {
+	// Found reports whether Get found the key
+	Found bool
+	// Value is the value of the key after Incr
+	Value int64
+	// Err is the error of the operation
+	Err error
+}
+
+// pipeliner is implemented by caches that execute pipelines
+type pipeliner interface {
+	Pipeline(ctx context.Context, fn func(p Pipe) error) error
+}
+
+// Pipeline executes the operations queued by fn atomically: with Redis as MULTI/EXEC
+// transaction, in memory under a single lock. Other clients never observe a part of
+// the operations. Nothing is executed if fn returns an error or a value cannot be serialized.
+// The transaction is not rolled back if an operation fails, e.g. Incr of a value that is not
+// a number, the failed operation reports its error in its result and Pipeline returns it.
+//
+// Example moving a value between keys:
+//
+//	var job Job
+//	var get *cache.PipeResult
+//	err := cache.Pipeline(ctx, redisCache, func(p cache.Pipe) error {
+//		get = p.Get("jobs:pending:42", &job)
+//		p.Delete("jobs:pending:42")
+//		p.Incr("jobs:pending:count", -1)
+//		return nil
+//	})
+//	if err == nil && get.Found {
+//		err = redisCache.Set(ctx, "jobs:running:42", job, time.Hour)
+//	}
+func Pipeline(ctx context.Context, c Cache, fn func(p Pipe) error) error {
+	p, ok := c.(pipeliner)
+	if !ok {
+		return NewCacheError("pipeline", "", apperror.NewErrorf("%T does not support pipelines", c))
+	}
+	return p.Pipeline(ctx, fn)
+}
+
+// pipeOp is an operation queued on a pipe
+type pipeOp struct {
+	op     string
+	key    string
+	value  interface{}
+	data   []byte
+	ttl    time.Duration
+	dest   interface{}
+	delta  int64
+	result *PipeResult
+}
+
+// pipe collects the operations of a pipeline until it is executed by the cache
+type pipe struct {
+	ops []*pipeOp
+}
+
+func (p *pipe) Set(key string, value interface{}, ttl time.Duration) *PipeResult {
+	return p.queue(&pipeOp{op: "set", key: key, value: value, ttl: ttl})
+}
+
+func (p *pipe) Get(key string, dest interface{}) *PipeResult {
+	return p.queue(&pipeOp{op: "get", key: key, dest: dest})
+}
+
+func (p *pipe) Delete(key string) *PipeResult {
+	return p.queue(&pipeOp{op: "delete", key: key})
+}
+
+func (p *pipe) Incr(key string, delta int64) *PipeResult {
+	return p.queue(&pipeOp{op: "incr", key: key, delta: delta})
+}
+
+func (p *pipe) queue(op *pipeOp) *PipeResult {
+	op.result = &PipeResult{}
+	p.ops = append(p.ops, op)
+	return op.result
+}
+
+// collect runs fn and serializes the values of the queued Set operations with the serializer of the cache
+func (bc *BaseCache) collect(fn func(p Pipe) error) (*pipe, error) {
+	p := &pipe{}
+	err := fn(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range p.ops {
+		if op.op != "set" {
+			continue
+		}
+		op.data, err = bc.config.Serializer.Serialize(op.value)
+		if err != nil {
+			bc.recordError(err)
+			return nil, NewCacheError("pipeline", op.key, err)
+		}
+	}
+	return p, nil
+}
+
+// failed returns the errors of the operations of the pipeline
+func (p *pipe) failed() error {
+	var errs []error
+	for _, op := range p.ops {
+		if op.result.Err != nil {
+			errs = append(errs, op.result.Err)
+		}
+	}
+	if len(errs) > 0 {
+		return NewCacheError("pipeline", "", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Pipeline executes the operations queued by fn under a single lock, see Pipeline
+func (mc *MemoryCache) Pipeline(_ context.Context, fn func(p Pipe) error) error {
+	p, err := mc.collect(fn)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		op   *pipeOp
+		data []byte
+	}
+	var loaded []pending
+
+	mc.mutex.Lock()
+	for _, op := range p.ops {
+		formattedKey := mc.formatKey(op.key)
+		switch op.op {
+		case "set":
+			op.result.Err = mc.store(op.key, formattedKey, op.value, op.data, mc.calculateTTL(op.ttl))
+		case "get":
+			var data []byte
+			data, op.result.Found, op.result.Err = mc.load(op.key, formattedKey)
+			if op.result.Found {
+				loaded = append(loaded, pending{op: op, data: data})
+			}
+		case "delete":
+			mc.remove(op.key, formattedKey)
+		case "incr":
+			op.result.Value, op.result.Err = mc.increment(op.key, formattedKey, op.delta)
+		}
+	}
+	mc.mutex.Unlock()
+
+	// Values are deserialized outside of the lock, they are copies of the stored data
+	for _, l := range loaded {
+		l.op.result.Found, l.op.result.Err = mc.decode(l.op.key, l.data, l.op.dest)
+	}
+	return p.failed()
+}
+
+// increment increments the numeric value of the key by delta (must be called with lock held)
+// A missing key is created without expiration, an existing key keeps its expiration like INCRBY
+func (mc *MemoryCache) increment(key, formattedKey string, delta int64) (int64, error) {
+	var value int64
+	ttl := time.Duration(0)
+	if element, exists := mc.items[formattedKey]; exists {
+		memItem, ok := element.Value.(*memoryItem)
+		if !ok {
+			return 0, NewCacheError("incr", key, errors.New("invalid item type"))
+		}
+		data, _ := memItem.item.Value.([]byte)
+		// Expired values and values of another schema version start over at zero, counters match every version
+		data, current := mc.checkVersion(key, data)
+		if !memItem.item.IsExpired() && current {
+			err := mc.config.Serializer.Deserialize(data, &value)
+			if err != nil {
+				return 0, NewCacheError("incr", key, apperror.NewError("value is not an integer").AddError(err))
+			}
+			if !memItem.item.ExpiresAt.IsZero() {
+				ttl = time.Until(memItem.item.ExpiresAt)
+			}
+		}
+	}
+
+	value += delta
+	data, err := mc.config.Serializer.Serialize(value)
+	if err != nil {
+		return 0, NewCacheError("incr", key, err)
+	}
+	// Counters are stored without schema version like those of RedisCache.Increment, see isCounter
+	return value, mc.put(key, formattedKey, value, data, ttl)
+}
+
+// Pipeline executes the operations queued by fn as MULTI/EXEC transaction, see Pipeline
+func (rc *RedisCache) Pipeline(ctx context.Context, fn func(p Pipe) error) error {
+	p, err := rc.collect(fn)
+	if err != nil {
+		return err
+	}
+	if len(p.ops) == 0 {
+		return nil
+	}
+
+	tx := rc.client.TxPipeline()
+	cmds := make([]redis.Cmder, len(p.ops))
+	for i, op := range p.ops {
+		formattedKey := rc.formatKey(op.key)
+		switch op.op {
+		case "set":
+			data, err := rc.compress(op.data)
+			if err != nil {
+				rc.recordError(err)
+				return NewCacheError("pipeline", op.key, err)
+			}
+			cmds[i] = tx.Set(ctx, formattedKey, rc.withVersion(op.key, data), rc.calculateTTL(op.ttl))
+		case "get":
+			cmds[i] = tx.Get(ctx, formattedKey)
+		case "delete":
+			cmds[i] = tx.Del(ctx, formattedKey)
+		case "incr":
+			cmds[i] = tx.IncrBy(ctx, formattedKey, op.delta)
+		}
+	}
+
+	// The error of the first failed command is returned, the commands are checked one by one
+	_, err = tx.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) && !failedCommand(cmds, err) {
+		rc.recordError(err)
+		return NewCacheError("pipeline", "", err)
+	}
+
+	for i, op := range p.ops {
+		op.result.Err = rc.pipeResult(op, cmds[i])
+		if op.result.Err != nil {
+			rc.recordError(op.result.Err)
+		}
+	}
+	return p.failed()
+}
+
+// failedCommand reports whether the error is the error of one of the commands
+func failedCommand(cmds []redis.Cmder, err error) bool {
+	for _, cmd := range cmds {
+		if cmd.Err() == err {
+			return true
+		}
+	}
+	return false
+}
+
+// pipeResult fills the result of the operation from its executed command
+func (rc *RedisCache) pipeResult(op *pipeOp, cmd redis.Cmder) error {
+	switch c := cmd.(type) {
+	case *redis.StringCmd:
+		data, err := c.Bytes()
+		if errors.Is(err, redis.Nil) {
+			rc.updateStats(func(s *Stats) { s.Misses++ })
+			rc.emitEvent(EventGet, op.key, nil, nil)
+			return nil
+		}
+		if err != nil {
+			return NewCacheError("get", op.key, err)
+		}
+		payload, current := rc.checkVersion(op.key, data)
+		if !current {
+			rc.updateStats(func(s *Stats) { s.Misses++ })
+			rc.emitEvent(EventGet, op.key, nil, nil)
+			return nil
+		}
+		raw, err := decompress(payload)
+		if err != nil {
+			return NewCacheError("get", op.key, err)
+		}
+		err = rc.config.Serializer.Deserialize(raw, op.dest)
+		if err != nil {
+			return NewCacheError("get", op.key, err)
+		}
+		op.result.Found = true
+		rc.updateStats(func(s *Stats) { s.Hits++ })
+		rc.emitEvent(EventGet, op.key, op.dest, nil)
+	case *redis.StatusCmd:
+		if c.Err() != nil {
+			return NewCacheError("set", op.key, c.Err())
+		}
+		rc.updateStats(func(s *Stats) { s.Sets++ })
+		rc.emitEvent(EventSet, op.key, op.value, nil)
+	case *redis.IntCmd:
+		if c.Err() != nil {
+			return NewCacheError(op.op, op.key, c.Err())
+		}
+		if op.op == "delete" {
+			rc.updateStats(func(s *Stats) { s.Deletes++ })
+			rc.emitEvent(EventDelete, op.key, nil, nil)
+			return nil
+		}
+		op.result.Value = c.Val()
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Publish notifies all nodes subscribed to the channel with SubscribeInvalidations
+// that the given keys changed and their local copies must be dropped.
+// The channel is prefixed with the namespace of the cache like a key.
+//
+// Example pairing a memory cache (L1) on every node with a shared Redis cache (L2):
+//
+//	err := redisCache.Set(ctx, "user:123", user, time.Hour)
+//	if err == nil {
+//		err = redisCache.Publish(ctx, "invalidations", "user:123")
+//	}
+func (rc *RedisCache) Publish(ctx context.Context, channel string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("publish", channel, err)
+	}
+
+	err = rc.client.Publish(ctx, rc.formatKey(channel), payload).Err()
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("publish", channel, err)
+	}
+	return nil
+}
+
+// SubscribeInvalidations subscribes to the channel and calls onInvalidate with the keys
+// of every message published with Publish, until the context is canceled.
+// It returns after the subscription is confirmed by Redis, the messages are processed
+// in a background goroutine one at a time.
+//
+// Delivery follows Redis pub/sub: a message reaches every node that is subscribed at the
+// time it is published. The connection is re-established automatically, but messages
+// published while a node is disconnected are not replayed, and a publisher that gets an
+// error has to retry, which may deliver the keys twice. onInvalidate must therefore be
+// idempotent and local copies should still expire with a TTL to bound staleness.
+//
+// Example:
+//
+//	memCache := cache.NewMemoryCache().WithDefaultTTL(time.Minute)
+//	err := redisCache.SubscribeInvalidations(ctx, "invalidations", func(keys []string) {
+//		_ = memCache.DeleteMulti(context.Background(), keys)
+//	})
+func (rc *RedisCache) SubscribeInvalidations(ctx context.Context, channel string, onInvalidate func(keys []string)) error {
+	if onInvalidate == nil {
+		return NewCacheError("subscribe", channel, apperror.NewError("invalidation callback must not be nil"))
+	}
+
+	pubsub := rc.client.Subscribe(ctx, rc.formatKey(channel))
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		rc.recordError(err)
+		apperror.Catch(pubsub.Close, "closing invalidation subscription failed")
+		return NewCacheError("subscribe", channel, err)
+	}
+
+	go func() {
+		defer apperror.Catch(pubsub.Close, "closing invalidation subscription failed")
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var keys []string
+				err := json.Unmarshal([]byte(msg.Payload), &keys)
+				if err != nil {
+					logger.Warn().Err(err).Field("channel", channel).Msg("dropping malformed invalidation message")
+					continue
+				}
+				onInvalidate(keys)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SubscribeExpirations emits an EventExpire for every key of the cache that expires in Redis,
+// until the context is canceled. The key of the event is the key without namespace and the
+// value is nil, since Redis has already removed the entry. Keys of other namespaces are ignored.
+// It returns after the subscription is confirmed by Redis, the events are emitted in a
+// background goroutine.
+//
+// The events rely on Redis keyspace notifications, which are disabled by default. They are
+// enabled with CONFIG SET notify-keyspace-events if needed, servers that reject the command,
+// like most managed Redis services, must be configured with the flags "Ex" instead.
+// Redis sends notifications fire-and-forget, expirations during a reconnect are not replayed.
+//
+// Example:
+//
+//	rc := cache.NewRedisCache(cache.DefaultRedisConfig()).WithEventHandler(func(event cache.Event) {
+//		if event.Type == cache.EventExpire {
+//			log.Info().Field("key", event.Key).Msg("cache entry expired")
+//		}
+//	})
+//	err := rc.SubscribeExpirations(ctx)
+func (rc *RedisCache) SubscribeExpirations(ctx context.Context) error {
+	channel := fmt.Sprintf("__keyevent@%d__:expired", rc.client.Options().DB)
+	if !rc.config.EnableEvents || rc.config.EventHandler == nil {
+		return NewCacheError("subscribe", channel, apperror.NewError("events are not enabled"))
+	}
+
+	err := rc.enableExpiredNotifications(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("enabling keyspace notifications failed, they must be enabled in the server configuration")
+	}
+
+	pubsub := rc.client.Subscribe(ctx, channel)
+	_, err = pubsub.Receive(ctx)
+	if err != nil {
+		rc.recordError(err)
+		apperror.Catch(pubsub.Close, "closing expiration subscription failed")
+		return NewCacheError("subscribe", channel, err)
+	}
+
+	go func() {
+		defer apperror.Catch(pubsub.Close, "closing expiration subscription failed")
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				key, ok := rc.trimKey(msg.Payload)
+				if !ok {
+					continue
+				}
+				rc.emitEvent(EventExpire, key, nil, nil)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// enableExpiredNotifications adds the keyevent and expired flags to the
+// keyspace notifications of the server, keeping the flags already set
+func (rc *RedisCache) enableExpiredNotifications(ctx context.Context) error {
+	current, err := rc.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return err
+	}
+
+	flags := current["notify-keyspace-events"]
+	expired := strings.ContainsRune(flags, 'x') || strings.ContainsRune(flags, 'A')
+	if strings.ContainsRune(flags, 'E') && expired {
+		return nil
+	}
+	if !strings.ContainsRune(flags, 'E') {
+		flags += "E"
+	}
+	if !expired {
+		flags += "x"
+	}
+	return rc.client.ConfigSet(ctx, "notify-keyspace-events", flags).Err()
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,7 +15,8 @@ import (
 type RedisCache struct {
 	*BaseCache
 
-	client *redis.Client
+	client      *redis.Client
+	jsonSupport atomic.Int32
 }
 
 // RedisConfig holds configuration for Redis cache
@@ -122,6 +124,33 @@ func (rc *RedisCache) WithDefaultTTL(ttl time.Duration) *RedisCache {
 	return rc
 }
 
+// WithCompression stores serialized values larger than the threshold in bytes gzip compressed
+func (rc *RedisCache) WithCompression(threshold int) *RedisCache {
+	rc.config.CompressThreshold = threshold
+	return rc
+}
+
+// WithKeyHashing shortens keys longer than the threshold in bytes with a hash, see Config.HashKeysOver
+func (rc *RedisCache) WithKeyHashing(threshold int) *RedisCache {
+	rc.config.HashKeysOver = threshold
+	return rc
+}
+
+// WithSchemaVersion sets the schema version stored with every value, see Config.SchemaVersion
+func (rc *RedisCache) WithSchemaVersion(version string) *RedisCache {
+	rc.config.SchemaVersion = version
+	return rc
+}
+
+// WithKeySchemaVersion sets the schema version of the keys starting with the prefix, see Config.SchemaVersions
+func (rc *RedisCache) WithKeySchemaVersion(prefix, version string) *RedisCache {
+	if rc.config.SchemaVersions == nil {
+		rc.config.SchemaVersions = make(map[string]string)
+	}
+	rc.config.SchemaVersions[prefix] = version
+	return rc
+}
+
 // WithEventHandler sets the event handler for cache events
 func (rc *RedisCache) WithEventHandler(handler EventHandler) *RedisCache {
 	rc.config.EventHandler = handler
@@ -129,9 +158,14 @@ func (rc *RedisCache) WithEventHandler(handler EventHandler) *RedisCache {
 	return rc
 }
 
-// Ping tests the Redis connection
+// Ping checks the Redis connection with the PING command
 func (rc *RedisCache) Ping(ctx context.Context) error {
-	return rc.client.Ping(ctx).Err()
+	err := rc.client.Ping(ctx).Err()
+	if err != nil {
+		rc.recordError(err)
+		return NewCacheError("ping", "", err)
+	}
+	return nil
 }
 
 // Get retrieves a value from the cache
@@ -150,8 +184,23 @@ func (rc *RedisCache) Get(ctx context.Context, key string, dest interface{}) (bo
 		return false, NewCacheError("get", key, err)
 	}
 
+	payload, current := rc.checkVersion(key, []byte(data))
+	if !current {
+		rc.deleteStale(ctx, formattedKey, data)
+		rc.updateStats(func(s *Stats) { s.Misses++ })
+		rc.emitEvent(EventGet, key, nil, nil)
+		return false, nil
+	}
+
+	raw, err := decompress(payload)
+	if err != nil {
+		rc.recordError(err)
+		rc.emitEvent(EventGet, key, nil, err)
+		return false, NewCacheError("get", key, err)
+	}
+
 	// Deserialize the value
-	err = rc.config.Serializer.Deserialize([]byte(data), dest)
+	err = rc.config.Serializer.Deserialize(raw, dest)
 	if err != nil {
 		rc.recordError(err)
 		rc.emitEvent(EventGet, key, nil, err)
@@ -176,6 +225,14 @@ func (rc *RedisCache) Set(ctx context.Context, key string, value interface{}, tt
 		return NewCacheError("set", key, err)
 	}
 
+	data, err = rc.compress(data)
+	if err != nil {
+		rc.recordError(err)
+		rc.emitEvent(EventSet, key, value, err)
+		return NewCacheError("set", key, err)
+	}
+	data = rc.withVersion(key, data)
+
 	err = rc.client.Set(ctx, formattedKey, data, effectiveTTL).Err()
 	if err != nil {
 		rc.recordError(err)
@@ -266,7 +323,18 @@ func (rc *RedisCache) GetMulti(ctx context.Context, keys []string) (map[string]i
 		if value != nil {
 			var dest interface{}
 			if data, ok := value.(string); ok {
-				err := rc.config.Serializer.Deserialize([]byte(data), &dest)
+				payload, current := rc.checkVersion(keys[i], []byte(data))
+				if !current {
+					rc.deleteStale(ctx, formattedKeys[i], data)
+					rc.updateStats(func(s *Stats) { s.Misses++ })
+					continue
+				}
+				raw, err := decompress(payload)
+				if err != nil {
+					rc.recordError(err)
+					continue
+				}
+				err = rc.config.Serializer.Deserialize(raw, &dest)
 				if err != nil {
 					rc.recordError(err)
 					continue
@@ -297,6 +365,13 @@ func (rc *RedisCache) SetMulti(ctx context.Context, items map[string]interface{}
 			continue
 		}
 
+		data, err = rc.compress(data)
+		if err != nil {
+			rc.recordError(err)
+			continue
+		}
+		data = rc.withVersion(key, data)
+
 		pipe.Set(ctx, formattedKey, data, effectiveTTL)
 	}
 
@@ -374,7 +449,19 @@ func (rc *RedisCache) SetTTL(ctx context.Context, key string, ttl time.Duration)
 
 // Close closes the Redis client
 func (rc *RedisCache) Close() error {
-	return rc.client.Close()
+	return rc.CloseContext(context.Background())
+}
+
+// CloseContext waits for the handlers of emitted events and closes the Redis client
+// The client is closed even if the context is done before the event handlers returned,
+// which also ends invalidation subscriptions
+func (rc *RedisCache) CloseContext(ctx context.Context) error {
+	err := rc.drain(ctx)
+	cerr := rc.client.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
 }
 
 // GetClient returns the underlying Redis client for advanced operations
@@ -441,6 +528,46 @@ func (rc *RedisCache) Decrement(ctx context.Context, key string, delta int64) (i
 	return result, nil
 }
 
+// incrementWithTTLScript increments the counter and sets its expiry if the increment created it
+var incrementWithTTLScript = redis.NewScript(`
+local created = redis.call("EXISTS", KEYS[1]) == 0
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if created then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// IncrementWithTTL atomically increments a numeric value like Increment and sets the TTL
+// if the key did not exist before, the TTL of an existing key is not changed. A TTL of 0
+// uses the default TTL of the cache. This is the primitive of a fixed window rate limiter:
+// the first request of a window creates the counter, which expires at the end of the window.
+//
+// Example allowing 100 requests per minute and client:
+//
+//	count, err := redisCache.IncrementWithTTL(ctx, "ratelimit:"+clientID, 1, time.Minute)
+//	if err != nil {
+//		return err
+//	}
+//	if count > 100 {
+//		return apperror.NewError("rate limit exceeded").WithKind(apperror.KindResourceExhausted)
+//	}
+func (rc *RedisCache) IncrementWithTTL(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	effectiveTTL := rc.calculateTTL(ttl)
+	if effectiveTTL <= 0 {
+		return 0, NewCacheError("increment", key, apperror.NewError("counter ttl must be positive"))
+	}
+
+	formattedKey := rc.formatKey(key)
+	result, err := incrementWithTTLScript.Run(ctx, rc.client, []string{formattedKey}, delta, effectiveTTL.Milliseconds()).Int64()
+	if err != nil {
+		rc.recordError(err)
+		return 0, NewCacheError("increment", key, err)
+	}
+
+	return result, nil
+}
+
 // SetNX sets a key only if it doesn't exist (atomic operation)
 func (rc *RedisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
 	formattedKey := rc.formatKey(key)
@@ -452,6 +579,13 @@ func (rc *RedisCache) SetNX(ctx context.Context, key string, value interface{},
 		return false, NewCacheError("setnx", key, err)
 	}
 
+	data, err = rc.compress(data)
+	if err != nil {
+		rc.recordError(err)
+		return false, NewCacheError("setnx", key, err)
+	}
+	data = rc.withVersion(key, data)
+
 	success, err := rc.client.SetNX(ctx, formattedKey, data, effectiveTTL).Result()
 	if err != nil {
 		rc.recordError(err)
@@ -476,6 +610,13 @@ func (rc *RedisCache) GetSet(ctx context.Context, key string, value interface{})
 		return nil, false, NewCacheError("getset", key, err)
 	}
 
+	data, err = rc.compress(data)
+	if err != nil {
+		rc.recordError(err)
+		return nil, false, NewCacheError("getset", key, err)
+	}
+	data = rc.withVersion(key, data)
+
 	oldData, err := rc.client.GetSet(ctx, formattedKey, data).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -487,8 +628,22 @@ func (rc *RedisCache) GetSet(ctx context.Context, key string, value interface{})
 		return nil, false, NewCacheError("getset", key, err)
 	}
 
+	// The old value of another schema version is treated like a missing one
+	payload, current := rc.checkVersion(key, []byte(oldData))
+	if !current {
+		rc.updateStats(func(s *Stats) { s.Sets++ })
+		rc.emitEvent(EventSet, key, value, nil)
+		return nil, false, nil
+	}
+
+	raw, err := decompress(payload)
+	if err != nil {
+		rc.recordError(err)
+		return nil, false, NewCacheError("getset", key, err)
+	}
+
 	var oldValue interface{}
-	err = rc.config.Serializer.Deserialize([]byte(oldData), &oldValue)
+	err = rc.config.Serializer.Deserialize(raw, &oldValue)
 	if err != nil {
 		rc.recordError(err)
 		return nil, false, NewCacheError("getset", key, err)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/valentin-kaiser/go-core/cache"
 )
 
-func setupRedisTest(t *testing.T) *cache.RedisCache {
+func setupRedisTest(t *testing.T, configure ...func(*cache.Config)) *cache.RedisCache {
 	t.Helper()
 	// Skip if Redis is not available
 	redisURL := os.Getenv("REDIS_URL")
@@ -36,6 +37,9 @@ func setupRedisTest(t *testing.T) *cache.RedisCache {
 
 	config := cache.DefaultConfig()
 	config.Namespace = fmt.Sprintf("test:%s:%d:%d", t.Name(), time.Now().UnixNano(), rand.Int63())
+	for _, f := range configure {
+		f(&config)
+	}
 
 	c := cache.NewRedisCacheWithConfig(client, config)
 
@@ -318,6 +322,61 @@ func TestRedisCache_Increment(t *testing.T) {
 	}
 }
 
+func TestRedisCache_IncrementWithTTL(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	// Test the increment creating the counter sets the TTL
+	newValue, err := c.IncrementWithTTL(ctx, "window", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to increment non-existing key: %v", err)
+	}
+	if newValue != 1 {
+		t.Errorf("Expected counter to be 1, got %d", newValue)
+	}
+
+	ttl, err := c.GetTTL(ctx, "window")
+	if err != nil {
+		t.Fatalf("Failed to get TTL: %v", err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected TTL of at most one minute, got %v", ttl)
+	}
+
+	// Test further increments keep the TTL of the window
+	newValue, err = c.IncrementWithTTL(ctx, "window", 2, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to increment existing key: %v", err)
+	}
+	if newValue != 3 {
+		t.Errorf("Expected counter to be 3, got %d", newValue)
+	}
+
+	ttl, err = c.GetTTL(ctx, "window")
+	if err != nil {
+		t.Fatalf("Failed to get TTL: %v", err)
+	}
+	if ttl > time.Minute {
+		t.Errorf("Expected TTL to be unchanged, got %v", ttl)
+	}
+
+	// Test the counter starts over after the window expired
+	_, err = c.IncrementWithTTL(ctx, "short", 1, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to increment short window: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	newValue, err = c.IncrementWithTTL(ctx, "short", 1, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to increment expired window: %v", err)
+	}
+	if newValue != 1 {
+		t.Errorf("Expected counter to start over at 1, got %d", newValue)
+	}
+}
+
 func TestRedisCache_Keys(t *testing.T) {
 	c := setupRedisTest(t)
 	defer apperror.Catch(c.Close, "Failed to close Redis cache")
@@ -580,3 +639,373 @@ func TestRedisCache_Stats(t *testing.T) {
 		t.Errorf("Expected hit ratio %f, got %f", expectedHitRatio, stats.HitRatio)
 	}
 }
+
+func TestRedisCache_Lock(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	unlock, acquired, err := c.Lock(ctx, "lock:test", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if !acquired {
+		t.Fatal("Expected lock to be acquired")
+	}
+
+	_, acquired, err = c.Lock(ctx, "lock:test", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to try lock: %v", err)
+	}
+	if acquired {
+		t.Error("Expected lock to be held by the first caller")
+	}
+
+	err = unlock()
+	if err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+
+	unlock, acquired, err = c.Lock(ctx, "lock:test", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock after release: %v", err)
+	}
+	if !acquired {
+		t.Error("Expected lock to be acquired after release")
+	}
+	_ = unlock()
+}
+
+func TestRedisCache_UpdateField(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	_, err := c.SupportsJSON(ctx)
+	if err != nil {
+		t.Fatalf("Failed to detect RedisJSON: %v", err)
+	}
+
+	user := TestUser{ID: 1, Name: "John Doe", Email: "john@example.com"}
+	err = c.Set(ctx, "user:1", user, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	err = c.UpdateField(ctx, "user:1", "email", "john.doe@example.com")
+	if err != nil {
+		t.Fatalf("Failed to update field: %v", err)
+	}
+
+	var got TestUser
+	found, err := c.Get(ctx, "user:1", &got)
+	if err != nil || !found {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if got.Email != "john.doe@example.com" || got.Name != user.Name {
+		t.Errorf("Expected only the email to change, got %+v", got)
+	}
+
+	ttl, err := c.GetTTL(ctx, "user:1")
+	if err != nil {
+		t.Fatalf("Failed to get TTL: %v", err)
+	}
+	if ttl <= 0 {
+		t.Errorf("Expected TTL to be kept, got %v", ttl)
+	}
+
+	err = c.UpdateField(ctx, "missing", "email", "x")
+	if err == nil {
+		t.Error("Expected error when updating a missing key")
+	}
+
+	err = c.UpdateField(ctx, "user:1", "name.first", "John")
+	if err == nil {
+		t.Error("Expected error when descending into a string field")
+	}
+}
+
+func TestRedisCache_UpdateFieldKeepsIntegers(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	// 2^53 + 1 cannot be represented as float64
+	const id int64 = 9007199254740993
+	err := c.Set(ctx, "user:2", map[string]interface{}{"id": id, "name": "Jane Doe"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	err = c.UpdateField(ctx, "user:2", "email", "jane@example.com")
+	if err != nil {
+		t.Fatalf("Failed to update field: %v", err)
+	}
+
+	var got struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+	}
+	found, err := c.Get(ctx, "user:2", &got)
+	if err != nil || !found {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if got.ID != id || got.Email != "jane@example.com" {
+		t.Errorf("Expected the untouched ID %d to be kept, got %+v", id, got)
+	}
+}
+
+func TestRedisCache_Invalidations(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	received := make(chan []string, 1)
+	err := c.SubscribeInvalidations(ctx, "invalidations", func(keys []string) {
+		received <- keys
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	err = c.Publish(ctx, "invalidations", "user:1", "user:2")
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	select {
+	case keys := <-received:
+		if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+			t.Errorf("Expected [user:1 user:2], got %v", keys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Invalidation was not received")
+	}
+}
+
+func TestRedisCache_Expirations(t *testing.T) {
+	expired := make(chan cache.Event, 1)
+	c := setupRedisTest(t).WithEventHandler(func(event cache.Event) {
+		if event.Type == cache.EventExpire {
+			expired <- event
+		}
+	})
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+	err := c.SubscribeExpirations(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to expirations: %v", err)
+	}
+
+	err = c.Set(ctx, "session", "value", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	select {
+	case event := <-expired:
+		if event.Key != "session" {
+			t.Errorf("Expected key session, got %s", event.Key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expiration was not received")
+	}
+}
+
+func TestRedisCache_Compression(t *testing.T) {
+	c := setupRedisTest(t).WithCompression(64)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	large := strings.Repeat("compressible ", 100)
+	err := c.Set(ctx, "large", large, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set large value: %v", err)
+	}
+	err = c.Set(ctx, "small", "tiny", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set small value: %v", err)
+	}
+
+	raw, err := c.GetClient().Get(ctx, c.GetConfig().Namespace+":large").Bytes()
+	if err != nil {
+		t.Fatalf("Failed to read raw value: %v", err)
+	}
+	if len(raw) >= len(large) {
+		t.Errorf("Expected compressed value smaller than %d bytes, got %d", len(large), len(raw))
+	}
+
+	var got string
+	found, err := c.Get(ctx, "large", &got)
+	if err != nil || !found {
+		t.Fatalf("Failed to get large value: %v", err)
+	}
+	if got != large {
+		t.Error("Expected decompressed value to match the original")
+	}
+
+	values, err := c.GetMulti(ctx, []string{"large", "small"})
+	if err != nil {
+		t.Fatalf("Failed to get multiple values: %v", err)
+	}
+	if values["large"] != large || values["small"] != "tiny" {
+		t.Errorf("Expected compressed and uncompressed values, got %v", values)
+	}
+
+	if stats := c.GetStats(); stats.Compressed != 1 {
+		t.Errorf("Expected 1 compressed entry, got %d", stats.Compressed)
+	}
+}
+
+func TestRedisCache_Ping(t *testing.T) {
+	unreachable := cache.DefaultRedisConfig()
+	unreachable.Addr = "127.0.0.1:1"
+	unreachable.MaxRetries = -1
+	unreachable.DialTimeout = 100 * time.Millisecond
+	rc := cache.NewRedisCache(unreachable)
+	defer apperror.Catch(rc.Close, "Failed to close Redis cache")
+
+	err := rc.Ping(t.Context())
+	if err == nil {
+		t.Fatal("Expected ping of an unreachable Redis server to fail")
+	}
+	if rc.GetStats().Errors != 1 {
+		t.Errorf("Expected the failed ping to be recorded, got %d errors", rc.GetStats().Errors)
+	}
+
+	mc := cache.NewMemoryCache()
+	defer apperror.Catch(mc.Close, "Failed to close memory cache")
+	err = mc.Ping(t.Context())
+	if err != nil {
+		t.Errorf("Expected ping of the memory cache to succeed, got: %v", err)
+	}
+
+	tc := cache.NewTieredCache(mc, rc)
+	err = tc.Ping(t.Context())
+	if err == nil {
+		t.Error("Expected ping of a tiered cache with an unreachable L2 cache to fail")
+	}
+
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+	err = c.Ping(t.Context())
+	if err != nil {
+		t.Errorf("Expected ping of an available Redis server to succeed, got: %v", err)
+	}
+}
+
+func TestRedisCache_PipelineTransaction(t *testing.T) {
+	c := setupRedisTest(t)
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+	ctx := t.Context()
+
+	err := c.Set(ctx, "user:1", TestUser{ID: 1, Name: "John Doe"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var user, other TestUser
+	var get, missing, incr *cache.PipeResult
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		get = p.Get("user:1", &user)
+		missing = p.Get("user:2", &other)
+		p.Delete("user:1")
+		p.Set("user:2", TestUser{ID: 2, Name: "Jane Doe"}, time.Minute)
+		p.Incr("users", 2)
+		incr = p.Incr("users", 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if !get.Found || user.Name != "John Doe" {
+		t.Errorf("Expected queued get to find the user, got %+v (%+v)", user, get)
+	}
+	if missing.Found {
+		t.Error("Expected queued get of a missing key not to find it")
+	}
+	if incr.Value != 5 {
+		t.Errorf("Expected counter to be 5, got %d", incr.Value)
+	}
+	if exists, _ := c.Exists(ctx, "user:1"); exists {
+		t.Error("Expected user:1 to be deleted")
+	}
+
+	var fail *cache.PipeResult
+	err = cache.Pipeline(ctx, c, func(p cache.Pipe) error {
+		fail = p.Incr("user:2", 1)
+		return nil
+	})
+	if err == nil || fail.Err == nil {
+		t.Errorf("Expected incrementing a non-numeric value to fail, got %v", err)
+	}
+}
+
+func TestRedisCache_SchemaVersion(t *testing.T) {
+	c := setupRedisTest(t).WithSchemaVersion("1")
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	err := c.Set(ctx, "user:1", TestUser{ID: 1, Name: "John Doe"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var user TestUser
+	found, err := c.Get(ctx, "user:1", &user)
+	if err != nil || !found || user.Name != "John Doe" {
+		t.Fatalf("Expected value of the same schema version, got %v (%v)", user, err)
+	}
+
+	c.WithSchemaVersion("2")
+	found, err = c.Get(ctx, "user:1", &user)
+	if err != nil || found {
+		t.Errorf("Expected value of another schema version to be a miss, got %v", err)
+	}
+	values, err := c.GetMulti(ctx, []string{"user:1"})
+	if err != nil || len(values) != 0 {
+		t.Errorf("Expected no values of another schema version, got %v (%v)", values, err)
+	}
+
+	c.WithKeySchemaVersion("user:", "1")
+	found, err = c.Get(ctx, "user:1", &user)
+	if err != nil || !found {
+		t.Errorf("Expected value of the key schema version to be found, got %v", err)
+	}
+}
+
+func TestRedisCache_SchemaVersionCounter(t *testing.T) {
+	c := setupRedisTest(t, func(config *cache.Config) {
+		config.DeleteStaleVersions = true
+	}).WithSchemaVersion("1")
+	defer apperror.Catch(c.Close, "Failed to close Redis cache")
+
+	ctx := t.Context()
+
+	_, err := c.IncrementWithTTL(ctx, "window", 3, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to increment: %v", err)
+	}
+
+	// Counters are stored without schema version, reading them must not delete them
+	var count int64
+	found, err := c.Get(ctx, "window", &count)
+	if err != nil || !found || count != 3 {
+		t.Fatalf("Expected counter to be read, got %d, %v (%v)", count, found, err)
+	}
+
+	count, err = c.IncrementWithTTL(ctx, "window", 1, time.Minute)
+	if err != nil || count != 4 {
+		t.Errorf("Expected counter to be kept, got %d (%v)", count, err)
+	}
+	if stats := c.GetStats(); stats.StaleVersions != 0 {
+		t.Errorf("Expected no stale versions, got %d", stats.StaleVersions)
+	}
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/logging"
+	"golang.org/x/sync/singleflight"
+)
+
+var logger = logging.GetPackageLogger("cache")
+
+// loads deduplicates concurrent loads of the same key
+var loads singleflight.Group
+
+// LoaderFunc loads the value of a cache entry from its source
+type LoaderFunc func(ctx context.Context) (interface{}, error)
+
+// MultiLoaderFunc loads the values of the missing keys from their source
+// Keys without value in the returned map are treated as not found
+type MultiLoaderFunc func(ctx context.Context, missing []string) (map[string]interface{}, error)
+
+// RememberSWR returns the cached value of the key and loads it with the loader if needed,
+// serving stale data while revalidating in the background:
+//   - entries younger than fresh are returned as they are
+//   - entries between fresh and stale are returned and refreshed in the background
+//   - missing entries or entries older than stale are loaded before returning
+//
+// Entries are stored with stale as TTL, their age is derived from the remaining TTL.
+// Concurrent loads of the same key are deduplicated, so a popular key is loaded only once.
+//
+// Example:
+//
+//	var user User
+//	err := cache.RememberSWR(ctx, redisCache, "user:123", time.Minute, 10*time.Minute, &user,
+//		func(ctx context.Context) (interface{}, error) {
+//			return db.FindUser(ctx, 123)
+//		})
+func RememberSWR(ctx context.Context, c Cache, key string, fresh, stale time.Duration, dest interface{}, loader LoaderFunc) error {
+	if fresh <= 0 || stale < fresh {
+		return NewCacheError("remember", key, apperror.NewError("fresh must be positive and not exceed stale"))
+	}
+
+	found, err := c.Get(ctx, key, dest)
+	if err == nil && found {
+		ttl, err := c.GetTTL(ctx, key)
+		if err == nil && ttl > 0 && stale-ttl >= fresh {
+			go refresh(context.WithoutCancel(ctx), c, key, stale, loader)
+		}
+		return nil
+	}
+
+	value, err := load(ctx, c, key, stale, loader)
+	if err != nil {
+		return err
+	}
+	return assign(ctx, c, key, dest, value)
+}
+
+// GetOrSetMulti returns the values of the keys, reading present keys with a single GetMulti
+// and loading all missing keys with a single call of the loader. The loaded values are stored
+// with the TTL and merged into the result. Keys the loader returns no value for are omitted.
+// Cached values are returned as deserialized by GetMulti, loaded values as returned by the loader.
+// If the cache cannot be read, all keys are loaded.
+//
+// Example:
+//
+//	users, err := cache.GetOrSetMulti(ctx, redisCache, []string{"user:1", "user:2"}, time.Hour,
+//		func(ctx context.Context, missing []string) (map[string]interface{}, error) {
+//			return db.FindUsersByKey(ctx, missing)
+//		})
+func GetOrSetMulti(ctx context.Context, c Cache, keys []string, ttl time.Duration, loader MultiLoaderFunc) (map[string]interface{}, error) {
+	result, err := c.GetMulti(ctx, keys)
+	if err != nil {
+		logger.Warn().Err(err).Msg("reading cache entries failed, loading all keys")
+		result = make(map[string]interface{}, len(keys))
+	}
+
+	seen := make(map[string]bool, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if _, ok := result[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, key)
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	loaded, err := loader(ctx, missing)
+	if err != nil {
+		return nil, NewCacheError("load", "", err)
+	}
+
+	items := make(map[string]interface{}, len(loaded))
+	for _, key := range missing {
+		if value, ok := loaded[key]; ok {
+			items[key] = value
+			result[key] = value
+		}
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	err = c.SetMulti(ctx, items, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// load calls the loader once for concurrent callers and stores the result in the cache
+func load(ctx context.Context, c Cache, key string, ttl time.Duration, loader LoaderFunc) (interface{}, error) {
+	value, err, _ := loads.Do(fmt.Sprintf("%p:%s", c, key), func() (interface{}, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, NewCacheError("load", key, err)
+		}
+
+		err = c.Set(ctx, key, value, ttl)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	})
+	return value, err
+}
+
+// refresh reloads a stale entry in the background
+func refresh(ctx context.Context, c Cache, key string, ttl time.Duration, loader LoaderFunc) {
+	_, err := load(ctx, c, key, ttl, loader)
+	if err != nil {
+		logger.Warn().Err(err).Field("key", key).Msg("refreshing stale cache entry failed")
+	}
+}
+
+// assign stores the loaded value in dest, values of a different type are read back from the cache
+func assign(ctx context.Context, c Cache, key string, dest interface{}, value interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return NewCacheError("remember", key, apperror.NewError("destination must be a non-nil pointer"))
+	}
+
+	vv := reflect.ValueOf(value)
+	if vv.IsValid() {
+		if vv.Type().AssignableTo(dv.Elem().Type()) {
+			dv.Elem().Set(vv)
+			return nil
+		}
+		if vv.Kind() == reflect.Ptr && !vv.IsNil() && vv.Elem().Type().AssignableTo(dv.Elem().Type()) {
+			dv.Elem().Set(vv.Elem())
+			return nil
+		}
+	}
+
+	_, err := c.Get(ctx, key, dest)
+	return err
+}
@@ -0,0 +1,144 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/cache"
+)
+
+func TestRememberSWR(t *testing.T) {
+	c := cache.NewMemoryCache()
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	ctx := t.Context()
+	var loads int32
+	loader := func(_ context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&loads, 1)
+		return TestUser{ID: int(n), Name: "user"}, nil
+	}
+
+	fresh := 100 * time.Millisecond
+	stale := 300 * time.Millisecond
+
+	// Missing entries are loaded before returning
+	var user TestUser
+	err := cache.RememberSWR(ctx, c, "user", fresh, stale, &user, loader)
+	if err != nil {
+		t.Fatalf("RememberSWR failed: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Expected loaded user 1, got %d", user.ID)
+	}
+
+	// Fresh entries are served from the cache
+	err = cache.RememberSWR(ctx, c, "user", fresh, stale, &user, loader)
+	if err != nil {
+		t.Fatalf("RememberSWR failed: %v", err)
+	}
+	if user.ID != 1 || atomic.LoadInt32(&loads) != 1 {
+		t.Errorf("Expected fresh user 1 without loading, got user %d after %d loads", user.ID, loads)
+	}
+
+	// Stale entries are served and refreshed in the background
+	time.Sleep(150 * time.Millisecond)
+	err = cache.RememberSWR(ctx, c, "user", fresh, stale, &user, loader)
+	if err != nil {
+		t.Fatalf("RememberSWR failed: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Expected stale user 1 to be served, got %d", user.ID)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		var cached TestUser
+		if found, _ := c.Get(ctx, "user", &cached); found && cached.ID == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = cache.RememberSWR(ctx, c, "user", fresh, stale, &user, loader)
+	if err != nil {
+		t.Fatalf("RememberSWR failed: %v", err)
+	}
+	if user.ID != 2 {
+		t.Errorf("Expected refreshed user 2, got %d", user.ID)
+	}
+
+	// Expired entries are loaded before returning
+	time.Sleep(stale + 50*time.Millisecond)
+	err = cache.RememberSWR(ctx, c, "user", fresh, stale, &user, loader)
+	if err != nil {
+		t.Fatalf("RememberSWR failed: %v", err)
+	}
+	if user.ID != 3 {
+		t.Errorf("Expected reloaded user 3, got %d", user.ID)
+	}
+
+	err = cache.RememberSWR(ctx, c, "user", stale, fresh, &user, loader)
+	if err == nil {
+		t.Error("Expected error when fresh exceeds stale")
+	}
+}
+
+func TestGetOrSetMulti(t *testing.T) {
+	c := cache.NewMemoryCache()
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	ctx := t.Context()
+	err := c.Set(ctx, "user:1", "cached", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var calls [][]string
+	loader := func(_ context.Context, missing []string) (map[string]interface{}, error) {
+		calls = append(calls, missing)
+		values := make(map[string]interface{})
+		for _, key := range missing {
+			if key != "user:4" {
+				values[key] = "loaded " + key
+			}
+		}
+		return values, nil
+	}
+
+	keys := []string{"user:1", "user:2", "user:3", "user:2", "user:4"}
+	values, err := cache.GetOrSetMulti(ctx, c, keys, time.Hour, loader)
+	if err != nil {
+		t.Fatalf("GetOrSetMulti failed: %v", err)
+	}
+	if len(calls) != 1 || len(calls[0]) != 3 || calls[0][0] != "user:2" || calls[0][1] != "user:3" || calls[0][2] != "user:4" {
+		t.Fatalf("Expected a single load of the missing keys, got %v", calls)
+	}
+	if len(values) != 3 || values["user:1"] != "cached" || values["user:2"] != "loaded user:2" || values["user:3"] != "loaded user:3" {
+		t.Errorf("Expected cached and loaded values, got %v", values)
+	}
+	if _, ok := values["user:4"]; ok {
+		t.Error("Expected keys without loaded value to be omitted")
+	}
+
+	// Loaded values are stored, only the keys still missing are loaded again
+	values, err = cache.GetOrSetMulti(ctx, c, keys, time.Hour, loader)
+	if err != nil {
+		t.Fatalf("GetOrSetMulti failed: %v", err)
+	}
+	if len(calls) != 2 || len(calls[1]) != 1 || calls[1][0] != "user:4" {
+		t.Errorf("Expected only user:4 to be loaded again, got %v", calls)
+	}
+	if values["user:3"] != "loaded user:3" {
+		t.Errorf("Expected stored value of user:3, got %v", values["user:3"])
+	}
+
+	_, err = cache.GetOrSetMulti(ctx, c, []string{"user:5"}, time.Hour, func(_ context.Context, _ []string) (map[string]interface{}, error) {
+		return nil, apperror.NewError("source unavailable")
+	})
+	if err == nil {
+		t.Error("Expected loader error to be returned")
+	}
+}
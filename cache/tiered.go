@@ -48,6 +48,7 @@ func (tc *TieredCache) WithEventHandler(handler EventHandler) *TieredCache {
 }
 
 // Get retrieves a value from the cache, checking L1 first, then L2
+// A value found in L2 is promoted to L1 for the remaining TTL of the value in L2, at most one hour
 func (tc *TieredCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
 	// Try L1 cache first
 	found, err := tc.l1Cache.Get(ctx, key, dest)
@@ -72,14 +73,8 @@ func (tc *TieredCache) Get(ctx context.Context, key string, dest interface{}) (b
 	}
 
 	if found {
-		// Backfill L1 cache with the value from L2
-		// Use a shorter TTL for L1 to avoid memory bloat
-		l1TTL := tc.config.DefaultTTL
-		if l1TTL > time.Hour {
-			l1TTL = time.Hour // Limit L1 TTL to 1 hour max
-		}
-
-		err = tc.l1Cache.Set(ctx, key, dest, l1TTL)
+		// Backfill L1 cache with the value from L2, it must not outlive the value in L2
+		err = tc.l1Cache.Set(ctx, key, dest, tc.promotionTTL(ctx, key))
 		if err != nil {
 			// Log error but don't fail the operation
 			tc.recordError(err)
@@ -125,16 +120,10 @@ func (tc *TieredCache) Set(ctx context.Context, key string, value interface{}, t
 }
 
 // Delete removes a value from both L1 and L2 caches
+// It fails if either tier fails, since the value would still be served from it
 func (tc *TieredCache) Delete(ctx context.Context, key string) error {
-	// Delete from both caches
-	var l1Err, l2Err error
-
-	l1Err = tc.l1Cache.Delete(ctx, key)
-	l2Err = tc.l2Cache.Delete(ctx, key)
-
-	// Return error if both failed
-	if l1Err != nil && l2Err != nil {
-		err := apperror.NewError("failed to delete from both L1 and L2 caches")
+	err := tiers("delete from", tc.l1Cache.Delete(ctx, key), tc.l2Cache.Delete(ctx, key))
+	if err != nil {
 		tc.recordError(err)
 		tc.emitEvent(EventDelete, key, nil, err)
 		return err
@@ -169,15 +158,10 @@ func (tc *TieredCache) Exists(ctx context.Context, key string) (bool, error) {
 }
 
 // Clear removes all entries from both L1 and L2 caches
+// It fails if either tier fails, since its entries would still be served
 func (tc *TieredCache) Clear(ctx context.Context) error {
-	var l1Err, l2Err error
-
-	l1Err = tc.l1Cache.Clear(ctx)
-	l2Err = tc.l2Cache.Clear(ctx)
-
-	// Return error if both failed
-	if l1Err != nil && l2Err != nil {
-		err := apperror.NewError("failed to clear both L1 and L2 caches")
+	err := tiers("clear", tc.l1Cache.Clear(ctx), tc.l2Cache.Clear(ctx))
+	if err != nil {
 		tc.recordError(err)
 		tc.emitEvent(EventClear, "", nil, err)
 		return err
@@ -270,15 +254,10 @@ func (tc *TieredCache) SetMulti(ctx context.Context, items map[string]interface{
 }
 
 // DeleteMulti removes multiple values from both L1 and L2 caches
+// It fails if either tier fails, like Delete
 func (tc *TieredCache) DeleteMulti(ctx context.Context, keys []string) error {
-	var l1Err, l2Err error
-
-	l1Err = tc.l1Cache.DeleteMulti(ctx, keys)
-	l2Err = tc.l2Cache.DeleteMulti(ctx, keys)
-
-	// Return error if both failed
-	if l1Err != nil && l2Err != nil {
-		err := apperror.NewError("failed to delete from both L1 and L2 caches")
+	err := tiers("delete from", tc.l1Cache.DeleteMulti(ctx, keys), tc.l2Cache.DeleteMulti(ctx, keys))
+	if err != nil {
 		tc.recordError(err)
 		return err
 	}
@@ -343,16 +322,40 @@ func (tc *TieredCache) GetStats() Stats {
 	return combined
 }
 
+// Ping checks both cache implementations, it fails if either of them is unreachable
+func (tc *TieredCache) Ping(ctx context.Context) error {
+	err := tc.l1Cache.Ping(ctx)
+	if err != nil {
+		return apperror.NewError("L1 cache is unreachable").AddError(err)
+	}
+
+	err = tc.l2Cache.Ping(ctx)
+	if err != nil {
+		return apperror.NewError("L2 cache is unreachable").AddError(err)
+	}
+	return nil
+}
+
 // Close closes both cache implementations
 func (tc *TieredCache) Close() error {
-	var l1Err, l2Err error
+	return tc.CloseContext(context.Background())
+}
+
+// CloseContext waits for the handlers of emitted events and closes both cache implementations
+func (tc *TieredCache) CloseContext(ctx context.Context) error {
+	err := tc.drain(ctx)
 
+	var l1Err, l2Err error
 	if tc.l1Cache != nil {
-		l1Err = tc.l1Cache.Close()
+		l1Err = tc.l1Cache.CloseContext(ctx)
 	}
 
 	if tc.l2Cache != nil {
-		l2Err = tc.l2Cache.Close()
+		l2Err = tc.l2Cache.CloseContext(ctx)
+	}
+
+	if err != nil {
+		return err
 	}
 
 	if l1Err != nil {
@@ -409,3 +412,30 @@ func (tc *TieredCache) GetTieredStats() map[string]Stats {
 		"L2": tc.l2Cache.GetStats(),
 	}
 }
+
+// promotionTTL returns the TTL of a value promoted from L2 to L1: the remaining TTL of the value
+// in L2, limited to one hour to keep L1 small. The default TTL is used if L2 cannot report it.
+func (tc *TieredCache) promotionTTL(ctx context.Context, key string) time.Duration {
+	ttl := tc.config.DefaultTTL
+	remaining, err := tc.l2Cache.GetTTL(ctx, key)
+	if err == nil && remaining > 0 {
+		ttl = remaining
+	}
+	if ttl > time.Hour {
+		ttl = time.Hour
+	}
+	return ttl
+}
+
+// tiers returns an error naming the tiers whose operation failed, nil if both succeeded
+func tiers(op string, l1Err, l2Err error) error {
+	switch {
+	case l1Err != nil && l2Err != nil:
+		return apperror.NewErrorf("failed to %s both L1 and L2 caches", op).AddError(l1Err).AddError(l2Err)
+	case l1Err != nil:
+		return apperror.NewErrorf("failed to %s the L1 cache", op).AddError(l1Err)
+	case l2Err != nil:
+		return apperror.NewErrorf("failed to %s the L2 cache", op).AddError(l2Err)
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package cache_test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -548,3 +550,77 @@ func abs(d time.Duration) time.Duration {
 	}
 	return d
 }
+
+// failingCache is a cache whose invalidation fails
+type failingCache struct {
+	cache.Cache
+}
+
+func (f failingCache) Delete(_ context.Context, _ string) error {
+	return apperror.NewError("connection refused")
+}
+
+func (f failingCache) Clear(_ context.Context) error {
+	return apperror.NewError("connection refused")
+}
+
+func TestTieredCache_ReadThrough(t *testing.T) {
+	ctx := t.Context()
+	l1 := cache.NewMemoryCache()
+	l2 := cache.NewMemoryCache()
+	c := cache.NewTieredCache(l1, l2)
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	err := l2.Set(ctx, "session", "token", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to set value in L2: %v", err)
+	}
+
+	var value string
+	found, err := c.Get(ctx, "session", &value)
+	if err != nil || !found || value != "token" {
+		t.Fatalf("Expected the value of L2, got %q, found %v, error %v", value, found, err)
+	}
+
+	// The promoted value expires with the value in L2
+	ttl, err := l1.GetTTL(ctx, "session")
+	if err != nil {
+		t.Fatalf("Expected the value to be promoted to L1: %v", err)
+	}
+	if ttl <= 0 || ttl > 200*time.Millisecond {
+		t.Errorf("Expected the promoted value to expire with the value in L2, got TTL %v", ttl)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	found, err = c.Get(ctx, "session", &value)
+	if err != nil || found {
+		t.Errorf("Expected the expired value not to be served from L1, found %v, error %v", found, err)
+	}
+}
+
+func TestTieredCache_InvalidationFailure(t *testing.T) {
+	ctx := t.Context()
+	l1 := cache.NewMemoryCache()
+	c := cache.NewTieredCache(l1, failingCache{Cache: cache.NewMemoryCache()})
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	err := c.Set(ctx, "user:1", "jane", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	// A value kept in L2 would be served again, so a failing tier fails the invalidation
+	err = c.Delete(ctx, "user:1")
+	if err == nil || !strings.Contains(err.Error(), "L2") {
+		t.Errorf("Expected Delete to report the failing L2 cache, got %v", err)
+	}
+	found, err := l1.Exists(ctx, "user:1")
+	if err != nil || found {
+		t.Errorf("Expected the value to be deleted from L1 anyway, found %v, error %v", found, err)
+	}
+
+	err = c.Clear(ctx)
+	if err == nil || !strings.Contains(err.Error(), "L2") {
+		t.Errorf("Expected Clear to report the failing L2 cache, got %v", err)
+	}
+}
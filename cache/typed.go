@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// GetTyped retrieves a value from the cache and returns it deserialized as T
+// The zero value of T is returned if the key is not found or an error occurred
+//
+// Example:
+//
+//	user, found, err := cache.GetTyped[User](ctx, redisCache, "user:123")
+func GetTyped[T any](ctx context.Context, c Cache, key string) (T, bool, error) {
+	var value T
+	found, err := c.Get(ctx, key, &value)
+	if err != nil || !found {
+		var zero T
+		return zero, found, err
+	}
+	return value, true, nil
+}
+
+// MustGet retrieves a value from the cache like Cache.Get, but returns an error wrapping
+// ErrNotFound instead of a false found flag if the key is not found
+//
+// Example:
+//
+//	var user User
+//	err := cache.MustGet(ctx, redisCache, "user:123", &user)
+//	if errors.Is(err, cache.ErrNotFound) {
+//		user, err = db.FindUser(ctx, 123)
+//	}
+func MustGet(ctx context.Context, c Cache, key string, dest interface{}) error {
+	found, err := c.Get(ctx, key, dest)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return NewCacheError("get", key, ErrNotFound)
+	}
+	return nil
+}
+
+// SetTyped stores a value of type T in the cache with the specified TTL
+// It is the counterpart of GetTyped and ensures the stored type matches the read type
+//
+// Example:
+//
+//	err := cache.SetTyped(ctx, redisCache, "user:123", user, time.Hour)
+func SetTyped[T any](ctx context.Context, c Cache, key string, value T, ttl time.Duration) error {
+	return c.Set(ctx, key, value, ttl)
+}
@@ -0,0 +1,81 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/cache"
+)
+
+func TestTyped(t *testing.T) {
+	c := cache.NewMemoryCache()
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	ctx := t.Context()
+	user := TestUser{ID: 1, Name: "John Doe", Email: "john@example.com"}
+	err := cache.SetTyped(ctx, c, "user", user, time.Minute)
+	if err != nil {
+		t.Fatalf("SetTyped failed: %v", err)
+	}
+
+	cached, found, err := cache.GetTyped[TestUser](ctx, c, "user")
+	if err != nil {
+		t.Fatalf("GetTyped failed: %v", err)
+	}
+	if !found || cached != user {
+		t.Errorf("Expected %+v, got %+v (found %v)", user, cached, found)
+	}
+
+	ptr, found, err := cache.GetTyped[*TestUser](ctx, c, "user")
+	if err != nil {
+		t.Fatalf("GetTyped failed: %v", err)
+	}
+	if !found || ptr == nil || *ptr != user {
+		t.Errorf("Expected pointer to %+v, got %v (found %v)", user, ptr, found)
+	}
+
+	missing, found, err := cache.GetTyped[TestUser](ctx, c, "missing")
+	if err != nil {
+		t.Fatalf("GetTyped failed: %v", err)
+	}
+	if found || missing != (TestUser{}) {
+		t.Errorf("Expected zero value for a missing key, got %+v (found %v)", missing, found)
+	}
+
+	err = cache.SetTyped(ctx, c, "count", 42, time.Minute)
+	if err != nil {
+		t.Fatalf("SetTyped failed: %v", err)
+	}
+	count, found, err := cache.GetTyped[int](ctx, c, "count")
+	if err != nil || !found || count != 42 {
+		t.Errorf("Expected 42, got %d (found %v, err %v)", count, found, err)
+	}
+}
+
+func TestMustGet(t *testing.T) {
+	c := cache.NewMemoryCache()
+	defer apperror.Catch(c.Close, "failed to close cache")
+
+	ctx := t.Context()
+	user := TestUser{ID: 1, Name: "John Doe", Email: "john@example.com"}
+	err := c.Set(ctx, "user", user, time.Minute)
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	var cached TestUser
+	err = cache.MustGet(ctx, c, "user", &cached)
+	if err != nil {
+		t.Fatalf("MustGet failed: %v", err)
+	}
+	if cached != user {
+		t.Errorf("Expected %+v, got %+v", user, cached)
+	}
+
+	err = cache.MustGet(ctx, c, "missing", &cached)
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v", err)
+	}
+}
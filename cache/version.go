@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+)
+
+// versionMarker prefixes values stored with a schema version, it is followed by the length
+// of the version as uvarint, the version and the stored data. Like compressed values, values
+// starting with the marker cannot be stored without a schema version.
+var versionMarker = []byte{0x00, 'v'}
+
+// schemaVersion returns the schema version of the key, the version of the longest
+// matching prefix in SchemaVersions or SchemaVersion
+func (bc *BaseCache) schemaVersion(key string) string {
+	version := bc.config.SchemaVersion
+	longest := -1
+	for prefix, v := range bc.config.SchemaVersions {
+		if len(prefix) > longest && strings.HasPrefix(key, prefix) {
+			version, longest = v, len(prefix)
+		}
+	}
+	return version
+}
+
+// withVersion prefixes the data stored under the key with the schema version of the key
+func (bc *BaseCache) withVersion(key string, data []byte) []byte {
+	version := bc.schemaVersion(key)
+	if version == "" {
+		return data
+	}
+
+	out := make([]byte, 0, len(versionMarker)+binary.MaxVarintLen64+len(version)+len(data))
+	out = append(out, versionMarker...)
+	out = binary.AppendUvarint(out, uint64(len(version)))
+	out = append(out, version...)
+	return append(out, data...)
+}
+
+// checkVersion returns the data stored under the key without its schema version and whether
+// the version matches the schema version of the key. Values stored without a version match
+// only if versioning is disabled for the key, except counters, see isCounter.
+// Stale versions are counted in the statistics.
+func (bc *BaseCache) checkVersion(key string, data []byte) ([]byte, bool) {
+	version, payload := splitVersion(data)
+	if version == "" && isCounter(payload) {
+		return payload, true
+	}
+	if version != bc.schemaVersion(key) {
+		bc.updateStats(func(s *Stats) { s.StaleVersions++ })
+		return nil, false
+	}
+	return payload, true
+}
+
+// isCounter reports whether the data is a decimal integer. Counters of Increment are stored
+// without a schema version, as Redis increments only plain integers, and their format does
+// not depend on the schema, so they match every version.
+func isCounter(data []byte) bool {
+	digits := bytes.TrimPrefix(data, []byte("-"))
+	if len(digits) == 0 || len(digits) > 19 {
+		return false
+	}
+	for _, b := range digits {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitVersion splits stored data into its schema version and the data, the version is
+// empty if the data was stored without one
+func splitVersion(data []byte) (string, []byte) {
+	if !bytes.HasPrefix(data, versionMarker) {
+		return "", data
+	}
+
+	rest := data[len(versionMarker):]
+	length, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < length {
+		return "", data
+	}
+	return string(rest[n : n+int(length)]), rest[n+int(length):]
+}
+
+// deleteStale deletes the value of another schema version if DeleteStaleVersions is set.
+// The value is deleted only if it was not replaced meanwhile, e.g. by a deployment using its version.
+func (rc *RedisCache) deleteStale(ctx context.Context, formattedKey, data string) {
+	if !rc.config.DeleteStaleVersions {
+		return
+	}
+
+	// unlockScript deletes the key only if it still holds the value
+	err := unlockScript.Run(ctx, rc.client, []string{formattedKey}, data).Err()
+	if err != nil {
+		rc.recordError(err)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"reflect"
+)
+
+// changeBuffer is the number of events buffered by a channel returned by Changes
+const changeBuffer = 8
+
+// ChangeEvent describes a configuration change applied by Read, Write or WriteAnnotated
+type ChangeEvent struct {
+	// Old is the configuration before the change, nil if none was applied before
+	Old Config
+	// New is the applied configuration
+	New Config
+	// Keys are the dotted keys of the values that differ between Old and New
+	Keys []string
+}
+
+// Changes returns a channel receiving an event for every configuration change applied by
+// Read, Write or WriteAnnotated. It is an alternative to OnChange for consumers with a select
+// loop. Changes rolled back by an OnChange handler are not delivered. The channel buffers a
+// few events, if the consumer falls behind the oldest event is dropped so that applying a
+// configuration never blocks. Every call returns a new channel, which is closed by Reset.
+//
+// Example:
+//
+//	changes := config.Changes()
+//	for {
+//		select {
+//		case event := <-changes:
+//			logger.Info().Field("keys", event.Keys).Msg("configuration changed")
+//		case <-ctx.Done():
+//			return
+//		}
+//	}
+func Changes() <-chan ChangeEvent {
+	return cm.Changes()
+}
+
+// Changes returns a channel receiving the configuration changes of the manager like Changes
+func (m *manager) Changes() <-chan ChangeEvent {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	ch := make(chan ChangeEvent, changeBuffer)
+	m.changes = append(m.changes, ch)
+	return ch
+}
+
+// publish delivers the change to all channels returned by Changes, dropping the oldest
+// buffered event of channels that are full
+func (m *manager) publish(o, n Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(m.changes) == 0 {
+		return
+	}
+
+	event := ChangeEvent{Old: o, New: n, Keys: changedKeys(o, n)}
+	for _, ch := range m.changes {
+		for {
+			select {
+			case ch <- event:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// closeChanges closes all channels returned by Changes
+func (m *manager) closeChanges() {
+	for _, ch := range m.changes {
+		close(ch)
+	}
+	m.changes = nil
+}
+
+// changedKeys returns the dotted keys of the values that differ between the configurations
+func changedKeys(o, n Config) []string {
+	var keys []string
+	diff(reflect.ValueOf(o), reflect.ValueOf(n), "", &keys)
+	return keys
+}
+
+// diff appends the keys of the fields that differ between the values to keys
+// Nested structs are compared field by field, all other values as a whole
+func diff(o, n reflect.Value, prefix string, keys *[]string) {
+	o, n = indirect(o), indirect(n)
+	if !n.IsValid() || n.Kind() != reflect.Struct {
+		changed := o.IsValid() != n.IsValid() || (o.IsValid() && !reflect.DeepEqual(o.Interface(), n.Interface()))
+		if prefix != "" && changed {
+			*keys = append(*keys, prefix)
+		}
+		return
+	}
+	if o.IsValid() && o.Type() != n.Type() {
+		o = reflect.Value{}
+	}
+
+	t := n.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		var of reflect.Value
+		if o.IsValid() {
+			of = o.Field(i)
+		}
+		diff(of, n.Field(i), buildLabel(prefix, getFieldName(field)), keys)
+	}
+}
+
+// indirect dereferences pointers and interfaces, it returns the zero Value for nil values
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
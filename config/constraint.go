@@ -0,0 +1,136 @@
+package config
+
+import (
+	"cmp"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// validate checks the required fields and the constraints of the configuration
+// before calling its Validate method
+func validate(change Config) error {
+	err := checkRequired(reflect.ValueOf(change), "")
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+
+	err = checkConstraints(reflect.ValueOf(change), "")
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+
+	return apperror.Wrap(change.Validate())
+}
+
+// checkConstraints checks the numeric fields tagged with min:"..." and max:"..." and the
+// string fields tagged with oneof:"a b c". All violations are returned together, each
+// one names the dotted path of its field.
+func checkConstraints(v reflect.Value, prefix string) error {
+	var errs []error
+	collectViolations(v, prefix, &errs)
+	if len(errs) > 0 {
+		return apperror.NewError("configuration values are out of range").AddErrors(errs)
+	}
+	return nil
+}
+
+// collectViolations appends the constraint violations of the struct and its nested structs to errs
+func collectViolations(v reflect.Value, prefix string, errs *[]error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		key := buildLabel(prefix, getFieldName(field))
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct || (fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct) {
+			collectViolations(fv, key, errs)
+			continue
+		}
+
+		if bound, ok := field.Tag.Lookup("min"); ok {
+			if err := checkBound(fv, key, bound, -1); err != nil {
+				*errs = append(*errs, err)
+			}
+		}
+		if bound, ok := field.Tag.Lookup("max"); ok {
+			if err := checkBound(fv, key, bound, 1); err != nil {
+				*errs = append(*errs, err)
+			}
+		}
+		if oneof, ok := field.Tag.Lookup("oneof"); ok {
+			if err := checkOneOf(fv, key, oneof); err != nil {
+				*errs = append(*errs, err)
+			}
+		}
+	}
+}
+
+// checkBound checks a numeric value against the bound of a min (sign -1) or max (sign 1) tag
+func checkBound(v reflect.Value, key, bound string, sign int) error {
+	tag := "min"
+	if sign > 0 {
+		tag = "max"
+	}
+
+	var order int
+	var err error
+	switch {
+	case v.CanInt():
+		var b int64
+		b, err = strconv.ParseInt(bound, 10, 64)
+		order = cmp.Compare(v.Int(), b)
+	case v.CanUint():
+		var b uint64
+		b, err = strconv.ParseUint(bound, 10, 64)
+		order = cmp.Compare(v.Uint(), b)
+	case v.CanFloat():
+		var b float64
+		b, err = strconv.ParseFloat(bound, 64)
+		order = cmp.Compare(v.Float(), b)
+	default:
+		return apperror.NewErrorf("%s tag of configuration value %s requires a numeric field", tag, key)
+	}
+	if err != nil {
+		return apperror.NewErrorf("invalid %s tag %q of configuration value %s", tag, bound, key).AddError(err)
+	}
+
+	if order == sign {
+		if sign < 0 {
+			return apperror.NewErrorf("configuration value %s is %v, it must be at least %s", key, v.Interface(), bound)
+		}
+		return apperror.NewErrorf("configuration value %s is %v, it must be at most %s", key, v.Interface(), bound)
+	}
+	return nil
+}
+
+// checkOneOf checks a string value against the space separated values of a oneof tag
+func checkOneOf(v reflect.Value, key, oneof string) error {
+	if v.Kind() != reflect.String {
+		return apperror.NewErrorf("oneof tag of configuration value %s requires a string field", key)
+	}
+
+	allowed := strings.Fields(oneof)
+	for _, a := range allowed {
+		if v.String() == a {
+			return nil
+		}
+	}
+	return apperror.NewErrorf("configuration value %s is %q, it must be one of %s", key, v.String(), strings.Join(allowed, ", "))
+}
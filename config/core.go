@@ -9,10 +9,20 @@
 //
 //   - Register typed configuration structs with default values.
 //   - Parse YAML configuration files and bind fields to CLI flags and environment variables.
-//   - Automatically generate flags based on struct field tags.
+//   - Automatically generate flags based on struct field tags, renamed with `flag:"name"` or omitted with `flag:"-"`.
 //   - Validate configuration using custom logic (via `Validate()` method).
+//   - Report the errors of all nested configurations at once with ValidateAll instead of only the first one.
+//   - Declare defaults and required fields with `default:"..."` and `required:"true"` tags.
+//   - Keep secrets out of the file with `env-only:"true"`, such fields are read from environment variables and flags only.
+//   - Constrain values with `min:"1"`, `max:"65535"` and `oneof:"debug info warn"` tags.
 //   - Watch configuration files for changes and hot-reload updated values.
-//   - Write current configuration back to disk.
+//   - Observe applied changes with OnChange callbacks or a Changes channel.
+//   - Write current configuration back to disk, optionally documented with usage comments.
+//   - Export a JSON Schema of the registered struct for editors and external validation.
+//   - Load the configuration from a remote source like HTTP, etcd or Consul with a Loader.
+//   - Decrypt sops/age encrypted files and ENC[...] values on read with a custom decryptor.
+//   - Split the configuration into named sections with their own files, read together with ReadAll.
+//   - Point at an explicit configuration file with the --config flag instead of <path>/<name>.yaml.
 //   - Automatically fallbacks to default config creation if no file is found.
 //
 // All configuration structs must implement the `Config` interface:
@@ -29,21 +39,20 @@
 //	    "fmt"
 //	    "github.com/valentin-kaiser/go-core/config"
 //	    "github.com/valentin-kaiser/go-core/flag"
+//	    "github.com/valentin-kaiser/go-core/logging"
 //	    "github.com/fsnotify/fsnotify"
 //	)
 //
 //	type ServerConfig struct {
-//	    Host string `yaml:"host" usage:"The host of the server"`
-//	    Port int    `yaml:"port" usage:"The port of the server"`
+//	    Host     string `yaml:"host" usage:"The host of the server"`
+//	    Port     int    `yaml:"port" min:"1" max:"65535" usage:"The port of the server"`
+//	    LogLevel string `yaml:"log_level" oneof:"trace debug info warn error" usage:"The log level"`
 //	}
 //
 //	func (c *ServerConfig) Validate() error {
 //	    if c.Host == "" {
 //	        return fmt.Errorf("host cannot be empty")
 //	    }
-//	    if c.Port <= 0 {
-//	        return fmt.Errorf("port must be greater than 0")
-//	    }
 //	    return nil
 //	}
 //
@@ -67,7 +76,7 @@
 //	        return
 //	    }
 //
-//	    // Parse flags (including --path and config-specific flags)
+//	    // Parse flags (including --path, --config and config-specific flags)
 //	    flag.Init()
 //
 //	    // Read config using the parsed --path flag
@@ -76,6 +85,21 @@
 //	        return
 //	    }
 //
+//	    // Apply log level changes at runtime without a restart
+//	    config.OnChange(func(o config.Config, n config.Config) error {
+//	        oc, _ := o.(*ServerConfig)
+//	        nc, ok := n.(*ServerConfig)
+//	        if !ok || (oc != nil && oc.LogLevel == nc.LogLevel) {
+//	            return nil
+//	        }
+//	        level, err := logging.ParseLevel(nc.LogLevel)
+//	        if err != nil {
+//	            return err
+//	        }
+//	        logging.SetLevel(level)
+//	        return nil
+//	    })
+//
 //	    config.Watch(func(e fsnotify.Event) {
 //	        if err := config.Read(); err != nil {
 //	            fmt.Println("Error reloading config:", err)
@@ -89,7 +113,8 @@
 package config
 
 import (
-	"os"
+	"errors"
+	"io/fs"
 	"reflect"
 	"strings"
 	"sync"
@@ -99,7 +124,6 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/pflag"
 	"github.com/valentin-kaiser/go-core/apperror"
-	"github.com/valentin-kaiser/go-core/flag"
 	"github.com/valentin-kaiser/go-core/logging"
 )
 
@@ -110,6 +134,7 @@ var (
 		name:     "config",
 		defaults: make(map[string]interface{}),
 		values:   make(map[string]interface{}),
+		envOnly:  make(map[string]bool),
 		flags:    make(map[string]*pflag.Flag),
 	}
 )
@@ -123,14 +148,19 @@ type Config interface {
 type manager struct {
 	path       string
 	name       string
+	section    string // name of the section, empty for the main configuration
 	config     Config
 	lastChange atomic.Int64
 	prefix     string
 	defaults   map[string]interface{}
 	values     map[string]interface{}
+	envOnly    map[string]bool
 	flags      map[string]*pflag.Flag
 	onChange   []func(o Config, n Config) error
 	watcher    *fsnotify.Watcher
+	decryptor  func(ciphertext []byte) ([]byte, error)
+	loader     Loader
+	changes    []chan ChangeEvent
 }
 
 func new() *manager {
@@ -138,6 +168,7 @@ func new() *manager {
 		name:     "config",
 		defaults: make(map[string]interface{}),
 		values:   make(map[string]interface{}),
+		envOnly:  make(map[string]bool),
 		flags:    make(map[string]*pflag.Flag),
 	}
 }
@@ -174,7 +205,12 @@ func (m *manager) Register(c Config) error {
 		return apperror.NewErrorf("the configuration provided is not a pointer to a struct, got %T", c)
 	}
 
-	err := m.parseStructTags(reflect.ValueOf(c), "")
+	err := m.checkSection()
+	if err != nil {
+		return err
+	}
+
+	err = m.parseStructTags(reflect.ValueOf(c), "")
 	if err != nil {
 		return apperror.Wrap(err)
 	}
@@ -184,122 +220,213 @@ func (m *manager) Register(c Config) error {
 }
 
 // OnChange registers a function that is called when the configuration changes
+// If it returns an error, the change is rolled back as described in Read
 func OnChange(f func(o Config, n Config) error) {
+	cm.OnChange(f)
+}
+
+// OnChange registers a function that is called when the configuration of the manager changes
+func (m *manager) OnChange(f func(o Config, n Config) error) {
 	mutex.Lock()
 	defer mutex.Unlock()
-	cm.onChange = append(cm.onChange, f)
+	m.onChange = append(m.onChange, f)
 }
 
 // Get returns the current configuration
 func Get() Config {
+	return cm.Get()
+}
+
+// Get returns the current configuration of the manager
+func (m *manager) Get() Config {
 	mutex.RLock()
 	defer mutex.RUnlock()
-	return cm.config
+	return m.config
 }
 
-// Read reads the configuration from the file, validates it and applies it
-// If the file does not exist, it creates a new one with the default values
-// The config path is resolved from flag.Path when this function is called
+// Read reads the configuration from the file, or the loader set with WithLoader, validates it and applies it
+// If the file does not exist, it creates a new one with the default values, a file that cannot
+// be decrypted or parsed is returned as error and left unchanged
+// The config path is resolved from flag.Path when this function is called,
+// the --config flag (flag.Config) sets the file explicitly instead
+// Applying a configuration is transactional: if an OnChange handler returns an error,
+// the previous configuration is restored and the handlers that were already called,
+// including the failed one, are called again in reverse order with the old and new
+// configuration swapped. The configuration file itself is left unchanged.
 func Read() error {
+	return cm.Read()
+}
+
+// Read reads the configuration of the manager from its file, validates it and applies it like Read
+func (m *manager) Read() error {
 	// Resolve the config path from flag.Path now that flags should be parsed
-	if cm.path == "" {
-		mutex.Lock()
-		cm.path = flag.Path
-		mutex.Unlock()
+	m.resolve()
+
+	if m.Get() == nil {
+		return apperror.NewErrorf("no configuration registered for %s", m.name)
 	}
 
-	err := cm.read()
+	err := m.checkSection()
 	if err != nil {
-		err := os.MkdirAll(cm.path, 0750)
-		if err != nil {
-			return apperror.NewError("creating configuration directory failed").AddError(err)
-		}
+		return err
+	}
 
-		err = cm.save()
+	loader := m.source()
+	err = m.read(loader)
+	if err != nil && loader != nil {
+		return apperror.NewError("loading configuration failed").AddError(err)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return apperror.NewError("reading configuration file failed").AddError(err)
+	}
+	if err != nil {
+		err = m.save()
 		if err != nil {
 			return apperror.NewError("writing default configuration file failed").AddError(err)
 		}
 
-		err = cm.read()
+		err = m.read(nil)
 		if err != nil {
 			return apperror.NewError("reading configuration file after creation failed").AddError(err)
 		}
 	}
 
-	change, ok := reflect.New(reflect.TypeOf(cm.config).Elem()).Interface().(Config)
+	change, ok := reflect.New(reflect.TypeOf(m.config).Elem()).Interface().(Config)
 	if !ok {
-		return apperror.NewErrorf("creating new instance of %T failed", cm.config)
+		return apperror.NewErrorf("creating new instance of %T failed", m.config)
 	}
 
-	err = cm.unmarshal(change)
+	err = m.unmarshal(change)
 	if err != nil {
-		return apperror.NewErrorf("unmarshalling configuration data in %T failed", cm.config).AddError(err)
+		return apperror.NewErrorf("unmarshalling configuration data in %T failed", m.config).AddError(err)
 	}
 
-	err = change.Validate()
+	err = validate(change)
 	if err != nil {
 		return apperror.Wrap(err)
 	}
 
-	o := Get()
-	cm.set(change)
-	for _, f := range cm.onChange {
+	o := m.Get()
+	m.set(change)
+	for i, f := range m.onChange {
 		err = f(o, change)
 		if err != nil {
-			return apperror.Wrap(err)
+			return m.rollback(o, change, i, err)
 		}
 	}
 
+	m.publish(o, change)
 	return nil
 }
 
+// rollback restores the previous configuration after the OnChange handler at index failed
+// The handlers up to the failed one are called again in reverse order with the configurations
+// swapped, so they can revert what they already applied of the change
+func (m *manager) rollback(o, n Config, index int, cause error) error {
+	m.set(o)
+
+	err := apperror.NewError("applying configuration change failed, restored previous configuration").AddError(cause)
+	for i := index; i >= 0; i-- {
+		rerr := m.onChange[i](n, o)
+		if rerr != nil {
+			err = err.AddError(apperror.NewError("rolling back configuration change failed").AddError(rerr))
+		}
+	}
+	return err
+}
+
 // Write writes the configuration to the file, validates it and applies it
-// If the file does not exist, it creates a new one with the default values
+// If the file does not exist, it creates a new one with the default values, a file that cannot
+// be decrypted or parsed is returned as error and left unchanged
 // The config path is resolved from flag.Path when this function is called
-// Write will not trigger any OnChange handlers unless the configuration is Read again
+// Write will not trigger any OnChange handlers unless the configuration is Read again,
+// the change is delivered to the channels returned by Changes though
+// Fields tagged with env-only:"true" are not written, they are read from environment variables and flags only
 func Write(change Config) error {
+	return cm.Write(change)
+}
+
+// Write writes the configuration of the manager to its file and applies it like Write
+func (m *manager) Write(change Config) error {
 	if change == nil {
 		return apperror.NewError("the configuration provided is nil")
 	}
 
 	// Resolve the config path from flag.Path if not already set
-	if cm.path == "" {
-		mutex.Lock()
-		cm.path = flag.Path
-		mutex.Unlock()
+	m.resolve()
+
+	err := validate(change)
+	if err != nil {
+		return apperror.Wrap(err)
 	}
 
-	err := change.Validate()
+	o := m.Get()
+	m.set(change)
+	err = m.save()
 	if err != nil {
 		return apperror.Wrap(err)
 	}
 
-	cm.set(change)
-	err = cm.save()
+	m.publish(o, change)
+	return nil
+}
+
+// WriteAnnotated writes the configuration to the file like Write but documents every key
+// with a comment containing the usage tag of its field. This turns the struct tags
+// into a self-documenting configuration file for new users.
+func WriteAnnotated(change Config) error {
+	return cm.WriteAnnotated(change)
+}
+
+// WriteAnnotated writes the configuration of the manager to its file with comments like WriteAnnotated
+func (m *manager) WriteAnnotated(change Config) error {
+	if change == nil {
+		return apperror.NewError("the configuration provided is nil")
+	}
+
+	// Resolve the config path from flag.Path if not already set
+	m.resolve()
+
+	err := validate(change)
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+
+	o := m.Get()
+	m.set(change)
+	err = m.saveAnnotated()
 	if err != nil {
 		return apperror.Wrap(err)
 	}
 
+	m.publish(o, change)
 	return nil
 }
 
 // Watch watches the configuration file for changes and calls Read when it changes
+// The file may be deleted and recreated, e.g. by editors or deployments, and its directory
+// may be replaced, a removed file is logged and the current configuration is kept
 // It ignores changes that happen within 1 second of each other
 // This is to prevent multiple calls when the file is saved
 func Watch() {
-	err := cm.watch(func(_ fsnotify.Event) {
-		if time.Now().UnixMilli()-cm.lastChange.Load() < 1000 {
+	cm.Watch()
+}
+
+// Watch watches the configuration file of the manager and reads it again when it changes like Watch
+func (m *manager) Watch() {
+	err := m.watch(func(_ fsnotify.Event) {
+		if time.Now().UnixMilli()-m.lastChange.Load() < 1000 {
 			return
 		}
-		cm.lastChange.Store(time.Now().UnixMilli())
-		err := Read()
+		m.lastChange.Store(time.Now().UnixMilli())
+		err := m.Read()
 		if err != nil {
-			logger.Error().Err(err).Msg("failed to read configuration")
+			logger.Error().Err(err).Field("config", m.name).Msg("failed to read configuration")
 			return
 		}
 	})
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to setup config watcher")
+		logger.Error().Err(err).Field("config", m.name).Msg("failed to setup config watcher")
 	}
 }
 
@@ -309,12 +436,13 @@ func Reset() {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	if cm.watcher != nil {
-		cm.watcher.Close()
-		cm.watcher = nil
+	cm.close()
+	for _, s := range sections {
+		s.close()
 	}
 
 	cm = new()
+	sections = make(map[string]*manager)
 }
 
 // Changed checks if two configuration values are different by comparing their reflection values.
@@ -346,6 +474,15 @@ func Changed(o, n any) bool {
 	return !reflect.DeepEqual(ov.Interface(), nv.Interface())
 }
 
+// close stops the file watcher of the manager and closes its change channels
+func (m *manager) close() {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+	m.closeChanges()
+}
+
 // set applies the configuration to the global variable
 func (m *manager) set(appConfig Config) {
 	mutex.Lock()
@@ -1,15 +1,21 @@
 package config_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/valentin-kaiser/go-core/config"
 	"github.com/valentin-kaiser/go-core/flag"
 )
@@ -602,6 +608,599 @@ func TestWriteConfigWithInvalidConfig(t *testing.T) {
 	}
 }
 
+func TestWriteAnnotated(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	tempDir := t.TempDir()
+	cfg := &NestedConfig{
+		Server:   ServerConfig{Host: "localhost", Port: 8080},
+		Database: DatabaseConfig{URL: "sqlite:///test.db", Timeout: 30},
+	}
+
+	err := config.Manager().WithPath(tempDir).WithName("annotated-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.WriteAnnotated(cfg)
+	if err != nil {
+		t.Fatalf("WriteAnnotated() should succeed with valid config: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "annotated-test.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	expected := "# Server configuration\nserver:\n  # Server host\n  host: localhost\n  # Server port\n  port: 8080\n" +
+		"# Database configuration\ndatabase:\n  # Database URL\n  url: sqlite:///test.db\n  # Connection timeout\n  timeout: 30\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected annotated config:\n%s", data)
+	}
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() of annotated config failed: %v", err)
+	}
+
+	current, ok := config.Get().(*NestedConfig)
+	if !ok {
+		t.Fatal("Expected config to be *NestedConfig")
+	}
+	if current.Server.Port != 8080 || current.Database.URL != "sqlite:///test.db" {
+		t.Errorf("Annotated config was not read back correctly: %+v", current)
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	originalConfig := flag.Config
+	defer func() { flag.Config = originalConfig }()
+
+	tempDir := t.TempDir()
+	flag.Config = filepath.Join(tempDir, "etc", "app.yaml")
+
+	cfg := &TestConfig{ApplicationName: "config-flag", ServerPort: 8080}
+	err := config.Manager().WithPath(tempDir).WithName("config-flag-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if _, err := os.Stat(flag.Config); err != nil {
+		t.Fatalf("Expected default configuration at the --config file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "config-flag-test.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected no configuration file in the path, got: %v", err)
+	}
+
+	err = os.WriteFile(flag.Config, []byte("application_name: from-flag\nserver_port: 9090\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	current, ok := config.Get().(*TestConfig)
+	if !ok || current.ApplicationName != "from-flag" || current.ServerPort != 9090 {
+		t.Errorf("Expected configuration from the --config file, got %+v", config.Get())
+	}
+
+	err = config.Write(&TestConfig{ApplicationName: "written", ServerPort: 8081})
+	if err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	data, err := os.ReadFile(flag.Config)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if !strings.Contains(string(data), "application_name: written") {
+		t.Errorf("Expected Write to save to the --config file, got:\n%s", data)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	config.Reset()
+
+	tempDir := t.TempDir()
+	cfg := &NestedConfig{
+		Server:   ServerConfig{Host: "localhost", Port: 8080},
+		Database: DatabaseConfig{URL: "sqlite:///test.db", Timeout: 30},
+	}
+	err := config.Manager().WithPath(tempDir).WithName("changes-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	changes := config.Changes()
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	event := <-changes
+	if event.Old != cfg || len(event.Keys) != 0 {
+		t.Errorf("Expected a change without differing keys from the registered config, got %+v", event)
+	}
+
+	err = config.Write(&NestedConfig{
+		Server:   ServerConfig{Host: "localhost", Port: 9090},
+		Database: DatabaseConfig{URL: "sqlite:///other.db", Timeout: 30},
+	})
+	if err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	event = <-changes
+	if !reflect.DeepEqual(event.Keys, []string{"server.port", "database.url"}) {
+		t.Errorf("Expected changed keys server.port and database.url, got %v", event.Keys)
+	}
+	if n, ok := event.New.(*NestedConfig); !ok || n.Server.Port != 9090 {
+		t.Errorf("Expected the written config as new config, got %+v", event.New)
+	}
+
+	// A consumer falling behind loses the oldest events instead of blocking writes
+	for port := 1; port <= 20; port++ {
+		err = config.Write(&NestedConfig{
+			Server:   ServerConfig{Host: "localhost", Port: port},
+			Database: DatabaseConfig{URL: "sqlite:///other.db", Timeout: 30},
+		})
+		if err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	var last config.ChangeEvent
+	count := 0
+	for len(changes) > 0 {
+		last = <-changes
+		count++
+	}
+	if count == 0 || count >= 20 {
+		t.Errorf("Expected a bounded number of buffered events, got %d", count)
+	}
+	if n, ok := last.New.(*NestedConfig); !ok || n.Server.Port != 20 {
+		t.Errorf("Expected the latest change to be kept, got %+v", last.New)
+	}
+
+	config.Reset()
+	_, ok := <-changes
+	if ok {
+		t.Error("Expected Reset to close the channel")
+	}
+}
+
+type TagConfig struct {
+	Name    string   `yaml:"name" required:"true" usage:"The name of the application"`
+	Port    int      `yaml:"port" default:"8080" usage:"The port to listen on"`
+	Host    string   `yaml:"host" default:"localhost" usage:"The host to listen on"`
+	Origins []string `yaml:"origins" default:"a,b" usage:"The allowed origins"`
+}
+
+func (c *TagConfig) Validate() error {
+	return nil
+}
+
+func TestDefaultTag(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	cfg := &TagConfig{Name: "app", Host: "example.com"}
+	err := config.Manager().WithPath(t.TempDir()).WithName("default-tag-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	current, ok := config.Get().(*TagConfig)
+	if !ok {
+		t.Fatal("Expected config to be *TagConfig")
+	}
+	if current.Port != 8080 {
+		t.Errorf("Expected default port 8080, got %d", current.Port)
+	}
+	if current.Host != "example.com" {
+		t.Errorf("Default should not override explicit value, got host %q", current.Host)
+	}
+	if len(current.Origins) != 2 || current.Origins[0] != "a" || current.Origins[1] != "b" {
+		t.Errorf("Expected default origins [a b], got %v", current.Origins)
+	}
+}
+
+type PartialListener struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port" default:"8080"`
+}
+
+type PartialTLS struct {
+	CA     string `yaml:"ca"`
+	Verify bool   `yaml:"verify"`
+}
+
+type PartialBackend struct {
+	TLS *PartialTLS `yaml:"tls"`
+}
+
+type PartialConfig struct {
+	PartialName     string          `yaml:"partial_name"`
+	PartialListener PartialListener `yaml:"partial_listener"`
+	PartialBackend  PartialBackend  `yaml:"partial_backend"`
+}
+
+func (c *PartialConfig) Validate() error {
+	return nil
+}
+
+func TestPartialFileKeepsDefaults(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	dir := t.TempDir()
+	cfg := &PartialConfig{
+		PartialName:     "app",
+		PartialListener: PartialListener{Host: "0.0.0.0"},
+		PartialBackend:  PartialBackend{TLS: &PartialTLS{CA: "ca.pem", Verify: true}},
+	}
+	err := config.Manager().WithPath(dir).WithName("partial-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	err = config.Write(cfg)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// The host and the CA are omitted, the port is left without value
+	partial := "partial_name: changed\npartial_listener:\n  port:\npartial_backend:\n  tls:\n    verify: false\n"
+	err = os.WriteFile(filepath.Join(dir, "partial-test.yaml"), []byte(partial), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	current, ok := config.Get().(*PartialConfig)
+	if !ok {
+		t.Fatal("Expected config to be *PartialConfig")
+	}
+	if current.PartialName != "changed" || current.PartialBackend.TLS.Verify {
+		t.Errorf("Expected values of the file to be applied, got %+v", current)
+	}
+	if current.PartialListener.Host != "0.0.0.0" || current.PartialListener.Port != 8080 {
+		t.Errorf("Expected defaults of omitted nested keys, got %+v", current.PartialListener)
+	}
+	if current.PartialBackend.TLS.CA != "ca.pem" {
+		t.Errorf("Expected default of key omitted in nested pointer struct, got %+v", current.PartialBackend.TLS)
+	}
+}
+
+func TestRequiredTag(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	cfg := &TagConfig{}
+	err := config.Manager().WithPath(t.TempDir()).WithName("required-tag-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.Read()
+	if err == nil {
+		t.Fatal("Read() should fail when a required field is not set")
+	}
+	if !strings.Contains(err.Error(), "name") {
+		t.Errorf("Error should name the missing field, got: %v", err)
+	}
+
+	err = config.Write(&TagConfig{})
+	if err == nil {
+		t.Error("Write() should fail when a required field is not set")
+	}
+}
+
+type Upstream struct {
+	Host    string            `yaml:"host"`
+	Port    int               `yaml:"port"`
+	Weights []int             `yaml:"weights"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+type CollectionConfig struct {
+	Upstreams []Upstream           `yaml:"upstreams"`
+	Groups    [][]Upstream         `yaml:"groups"`
+	Headers   map[string]string    `yaml:"headers"`
+	Backends  map[string]*Upstream `yaml:"backends"`
+	Limits    map[string]int       `yaml:"limits"`
+}
+
+func (c *CollectionConfig) Validate() error {
+	return nil
+}
+
+func TestMapsAndStructSlices(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	tempDir := t.TempDir()
+	data := `upstreams:
+  - host: a.example.com
+    port: 8080
+    weights: [1, 2]
+    labels:
+      Zone: eu
+  - host: b.example.com
+    port: 8081
+groups:
+  - - host: c.example.com
+headers:
+  X-Request-ID: abc
+  Content-Type: text/plain
+backends:
+  primary:
+    host: d.example.com
+    port: 9000
+limits:
+  burst: 10
+`
+	err := os.WriteFile(filepath.Join(tempDir, "collection-test.yaml"), []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	err = config.Manager().WithPath(tempDir).WithName("collection-test").Register(&CollectionConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	current, ok := config.Get().(*CollectionConfig)
+	if !ok {
+		t.Fatal("Expected config to be *CollectionConfig")
+	}
+
+	if len(current.Upstreams) != 2 {
+		t.Fatalf("Expected 2 upstreams, got %d", len(current.Upstreams))
+	}
+	first := current.Upstreams[0]
+	if first.Host != "a.example.com" || first.Port != 8080 {
+		t.Errorf("Unexpected first upstream: %+v", first)
+	}
+	if len(first.Weights) != 2 || first.Weights[1] != 2 {
+		t.Errorf("Expected weights [1 2], got %v", first.Weights)
+	}
+	if first.Labels["Zone"] != "eu" {
+		t.Errorf("Expected label Zone=eu, got %v", first.Labels)
+	}
+	if current.Upstreams[1].Port != 8081 {
+		t.Errorf("Expected second upstream port 8081, got %d", current.Upstreams[1].Port)
+	}
+	if len(current.Groups) != 1 || len(current.Groups[0]) != 1 || current.Groups[0][0].Host != "c.example.com" {
+		t.Errorf("Unexpected groups: %+v", current.Groups)
+	}
+	if current.Headers["X-Request-ID"] != "abc" || current.Headers["Content-Type"] != "text/plain" {
+		t.Errorf("Expected headers to keep the case of their keys, got %v", current.Headers)
+	}
+	if current.Backends["primary"] == nil || current.Backends["primary"].Port != 9000 {
+		t.Errorf("Unexpected backends: %+v", current.Backends)
+	}
+	if current.Limits["burst"] != 10 {
+		t.Errorf("Expected limit burst=10, got %v", current.Limits)
+	}
+}
+
+type DuplicateFlagConfig struct {
+	Limit      int `yaml:"dupLimit"`
+	OtherLimit int `yaml:"dup-limit"`
+}
+
+func (c *DuplicateFlagConfig) Validate() error {
+	return nil
+}
+
+func TestDuplicateFlags(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	// Both keys map to the flag --dup-limit
+	err := config.Manager().WithPath(t.TempDir()).WithName("duplicate-flag-test").Register(&DuplicateFlagConfig{})
+	if err == nil {
+		t.Fatal("Register should fail when two keys declare the same flag")
+	}
+	if !strings.Contains(err.Error(), "dup-limit") {
+		t.Errorf("Error should name the flag, got: %v", err)
+	}
+}
+
+type FlagTagConfig struct {
+	FlagToken  string `yaml:"flag_token" flag:"-" default:"secret"`
+	FlagListen string `yaml:"flag_listen" flag:"listen-addr" default:":8080"`
+}
+
+func (c *FlagTagConfig) Validate() error {
+	return nil
+}
+
+func TestFlagTag(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	err := config.Manager().WithPath(t.TempDir()).WithName("flag-tag-test").Register(&FlagTagConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if pflag.Lookup("flag-token") != nil {
+		t.Error("Expected no flag for a field tagged with flag:\"-\"")
+	}
+	if pflag.Lookup("flag-listen") != nil || pflag.Lookup("listen-addr") == nil {
+		t.Error("Expected the flag to be declared with the name of the flag tag")
+	}
+
+	err = pflag.Set("listen-addr", ":9090")
+	if err != nil {
+		t.Fatalf("Setting flag failed: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	current, ok := config.Get().(*FlagTagConfig)
+	if !ok {
+		t.Fatal("Expected config to be *FlagTagConfig")
+	}
+	if current.FlagToken != "secret" {
+		t.Errorf("Expected default token without flag, got %q", current.FlagToken)
+	}
+	if current.FlagListen != ":9090" {
+		t.Errorf("Expected listen address from the renamed flag, got %q", current.FlagListen)
+	}
+}
+
+type SecretConfig struct {
+	SecretUser     string `yaml:"secret_user"`
+	SecretPassword string `yaml:"secret_password"`
+	SecretPort     int    `yaml:"secret_port"`
+}
+
+func (c *SecretConfig) Validate() error {
+	return nil
+}
+
+func TestDecryptor(t *testing.T) {
+	decryptor := func(ciphertext []byte) ([]byte, error) {
+		value := string(ciphertext)
+		if strings.HasPrefix(value, "ENC[") {
+			return []byte(strings.TrimSuffix(strings.TrimPrefix(value, "ENC["), "]")), nil
+		}
+		if !strings.Contains(value, "sops:") {
+			return nil, errors.New("unexpected ciphertext")
+		}
+		return []byte("secret_user: file-user\nsecret_password: file-password\nsecret_port: 5432\n"), nil
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		user     string
+		password string
+	}{
+		{
+			name:     "values",
+			data:     "secret_user: plain-user\nsecret_password: ENC[value-password]\nsecret_port: 5432\n",
+			user:     "plain-user",
+			password: "value-password",
+		},
+		{
+			name:     "sops file",
+			data:     "secret_user: ENC[AES256_GCM,data:abc,type:str]\nsops:\n  version: 3.9.0\n",
+			user:     "file-user",
+			password: "file-password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Reset()
+			defer config.Reset()
+
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "secret-test.yaml"), []byte(tt.data), 0600)
+			if err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			err = config.Manager().WithPath(tempDir).WithName("secret-test").Register(&SecretConfig{})
+			if err != nil {
+				t.Fatalf("Register failed: %v", err)
+			}
+			config.WithDecryptor(decryptor)
+
+			err = config.Read()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+
+			current, ok := config.Get().(*SecretConfig)
+			if !ok {
+				t.Fatal("Expected config to be *SecretConfig")
+			}
+			if current.SecretUser != tt.user || current.SecretPassword != tt.password || current.SecretPort != 5432 {
+				t.Errorf("Unexpected decrypted config: %+v", current)
+			}
+		})
+	}
+
+	// Without a decryptor the values are read as they are
+	config.Reset()
+	defer config.Reset()
+
+	tempDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(tempDir, "secret-test.yaml"), []byte("secret_password: ENC[value]\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	err = config.Manager().WithPath(tempDir).WithName("secret-test").Register(&SecretConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	current, ok := config.Get().(*SecretConfig)
+	if !ok || current.SecretPassword != "ENC[value]" {
+		t.Errorf("Expected the encrypted value to be kept without decryptor, got %+v", current)
+	}
+}
+
+func TestDecryptorFailureKeepsFile(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "secret-test.yaml")
+	data := []byte("-----BEGIN AGE ENCRYPTED FILE-----\nYWdlLWVuY3J5cHRpb24=\n-----END AGE ENCRYPTED FILE-----\n")
+	err := os.WriteFile(file, data, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	err = config.Manager().WithPath(tempDir).WithName("secret-test").Register(&SecretConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	config.WithDecryptor(func(_ []byte) ([]byte, error) {
+		return nil, errors.New("no identity matched")
+	})
+
+	err = config.Read()
+	if err == nil {
+		t.Fatal("Expected Read to fail if the file cannot be decrypted")
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if string(content) != string(data) {
+		t.Errorf("Expected the encrypted file to be left unchanged, got %q", content)
+	}
+}
+
 func TestOnChangeCallbacks(t *testing.T) {
 	cfg := &TestConfig{
 		ApplicationName: "test-app",
@@ -610,7 +1209,7 @@ func TestOnChangeCallbacks(t *testing.T) {
 		DatabaseURL:     "sqlite:///test.db",
 	}
 
-	err := config.Manager().WithName("onchange-test").Register(cfg)
+	err := config.Manager().WithName("onchange-test").WithPath(t.TempDir()).Register(cfg)
 	if err != nil {
 		t.Fatalf("Register failed: %v", err)
 	}
@@ -653,7 +1252,7 @@ func TestOnChangeCallbackError(t *testing.T) {
 		DatabaseURL:     "sqlite:///test.db",
 	}
 
-	err := config.Manager().WithName("onchange-error-test").Register(cfg)
+	err := config.Manager().WithName("onchange-error-test").WithPath(t.TempDir()).Register(cfg)
 	if err != nil {
 		t.Fatalf("Register failed: %v", err)
 	}
@@ -681,6 +1280,60 @@ func TestOnChangeCallbackError(t *testing.T) {
 	}
 }
 
+func TestOnChangeCallbackRollback(t *testing.T) {
+	defer config.Reset()
+	cfg := &TestConfig{
+		ApplicationName: "test-app",
+		ServerPort:      8080,
+		EnableVerbose:   true,
+		DatabaseURL:     "sqlite:///test.db",
+	}
+
+	dir := t.TempDir()
+	err := config.Manager().WithName("onchange-rollback-test").WithPath(dir).Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	var calls []string
+	config.OnChange(func(o, n config.Config) error {
+		calls = append(calls, fmt.Sprintf("first %s -> %s", o.(*TestConfig).ApplicationName, n.(*TestConfig).ApplicationName))
+		return nil
+	})
+	config.OnChange(func(o, n config.Config) error {
+		calls = append(calls, fmt.Sprintf("second %s -> %s", o.(*TestConfig).ApplicationName, n.(*TestConfig).ApplicationName))
+		if n.(*TestConfig).ApplicationName == "updated-app" {
+			return errors.New("callback error")
+		}
+		return nil
+	})
+
+	err = os.WriteFile(filepath.Join(dir, "onchange-rollback-test.yaml"), []byte("application_name: updated-app\nserver_port: 9090\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	previous := config.Get()
+
+	err = config.Read()
+	if err == nil || !strings.Contains(err.Error(), "callback error") {
+		t.Fatalf("expected Read() to fail with the callback error, got %v", err)
+	}
+
+	if config.Get() != previous {
+		t.Errorf("expected the previous configuration to be restored, got %+v", config.Get())
+	}
+
+	expected := []string{
+		"first test-app -> updated-app",
+		"second test-app -> updated-app",
+		"second updated-app -> test-app",
+		"first updated-app -> test-app",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
 func TestWatchConfigFile(t *testing.T) {
 	tempDir := t.TempDir()
 	originalPath := flag.Path
@@ -694,24 +1347,91 @@ func TestWatchConfigFile(t *testing.T) {
 		DatabaseURL:     "sqlite:///test.db",
 	}
 
-	err := config.Manager().WithPath(tempDir).WithName("watch-test").Register(cfg)
+	err := config.Manager().WithPath(tempDir).WithName("watch-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Create initial config file
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	config.Watch()
+
+	// Give the watcher time to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Note: File watching tests are inherently flaky and platform-dependent
+	// This test mainly ensures the Watch function doesn't panic
+}
+
+func TestWatchRecreatedConfigFile(t *testing.T) {
+	tempDir := filepath.Join(t.TempDir(), "conf")
+	cfg := &TestConfig{
+		ApplicationName: "test-app",
+		ServerPort:      8080,
+		EnableVerbose:   true,
+		DatabaseURL:     "sqlite:///test.db",
+	}
+
+	err := config.Manager().WithPath(tempDir).WithName("recreate-test").Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	config.Watch()
+	defer config.Reset()
+	time.Sleep(100 * time.Millisecond)
+
+	file := filepath.Join(tempDir, "recreate-test.yaml")
+	waitFor := func(name string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			if current, ok := config.Get().(*TestConfig); ok && current.ApplicationName == name {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("expected application name %q after the file was recreated, got %+v", name, config.Get())
+	}
+
+	err = os.Remove(file)
+	if err != nil {
+		t.Fatalf("failed to remove config file: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if current, ok := config.Get().(*TestConfig); !ok || current.ApplicationName != "test-app" {
+		t.Fatalf("expected configuration to be kept after the file was removed, got %+v", config.Get())
+	}
+
+	err = os.WriteFile(file, []byte("application_name: recreated-app\nserver_port: 9090\n"), 0600)
 	if err != nil {
-		t.Fatalf("Register failed: %v", err)
+		t.Fatalf("failed to recreate config file: %v", err)
 	}
+	waitFor("recreated-app")
 
-	// Create initial config file
-	err = config.Read()
+	// Changes within a second of each other are ignored by Watch
+	time.Sleep(1100 * time.Millisecond)
+	err = os.RemoveAll(tempDir)
 	if err != nil {
-		t.Fatalf("Read() failed: %v", err)
+		t.Fatalf("failed to remove config directory: %v", err)
 	}
-
-	config.Watch()
-
-	// Give the watcher time to start
 	time.Sleep(100 * time.Millisecond)
-
-	// Note: File watching tests are inherently flaky and platform-dependent
-	// This test mainly ensures the Watch function doesn't panic
+	err = os.MkdirAll(tempDir, 0750)
+	if err != nil {
+		t.Fatalf("failed to recreate config directory: %v", err)
+	}
+	err = os.WriteFile(file, []byte("application_name: replaced-app\nserver_port: 9091\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to recreate config file: %v", err)
+	}
+	waitFor("replaced-app")
 }
 
 func TestConcurrentConfigOperations(t *testing.T) {
@@ -923,3 +1643,510 @@ func BenchmarkRead(b *testing.B) {
 		}
 	}
 }
+
+type SchemaConfig struct {
+	SchemaName   string             `yaml:"schema_name" usage:"The name of the service" default:"service" required:"true"`
+	SchemaPort   uint16             `yaml:"schema_port" usage:"The port to listen on" default:"8080"`
+	SchemaTags   []string           `yaml:"schema_tags" usage:"The tags of the service"`
+	SchemaLimits SchemaLimitsConfig `yaml:"schema_limits" usage:"The limits of the service"`
+}
+
+type SchemaLimitsConfig struct {
+	Rate float64          `yaml:"rate" usage:"Requests per second" default:"2.5"`
+	TLS  *SchemaTLSConfig `yaml:"tls" usage:"The TLS settings of the limits"`
+}
+
+type SchemaTLSConfig struct {
+	Cert string `yaml:"cert" usage:"The certificate file" default:"cert.pem"`
+}
+
+func (c *SchemaConfig) Validate() error {
+	return nil
+}
+
+func TestJSONSchema(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	_, err := config.JSONSchema()
+	if err == nil {
+		t.Error("JSONSchema() should return error without registered configuration")
+	}
+
+	err = config.Manager().WithName("schema-test").Register(&SchemaConfig{})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	data, err := config.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() failed: %v", err)
+	}
+
+	var schema struct {
+		Schema     string                            `json:"$schema"`
+		Type       string                            `json:"type"`
+		Required   []string                          `json:"required"`
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatalf("JSONSchema() returned invalid JSON: %v", err)
+	}
+
+	if schema.Schema == "" || schema.Type != "object" {
+		t.Errorf("Expected object schema with dialect, got %q and %q", schema.Schema, schema.Type)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "schema_name" {
+		t.Errorf("Expected schema_name to be required, got %v", schema.Required)
+	}
+
+	name := schema.Properties["schema_name"]
+	if name["type"] != "string" || name["default"] != "service" || name["description"] != "The name of the service" {
+		t.Errorf("Unexpected schema of schema_name: %v", name)
+	}
+
+	port := schema.Properties["schema_port"]
+	if port["type"] != "integer" || port["default"] != float64(8080) || port["minimum"] != float64(0) {
+		t.Errorf("Unexpected schema of schema_port: %v", port)
+	}
+
+	tags := schema.Properties["schema_tags"]
+	if tags["type"] != "array" || !reflect.DeepEqual(tags["items"], map[string]interface{}{"type": "string"}) {
+		t.Errorf("Unexpected schema of schema_tags: %v", tags)
+	}
+
+	limits := schema.Properties["schema_limits"]
+	rate, _ := limits["properties"].(map[string]interface{})["rate"].(map[string]interface{})
+	if limits["type"] != "object" || rate["type"] != "number" || rate["default"] != 2.5 {
+		t.Errorf("Unexpected schema of schema_limits: %v", limits)
+	}
+
+	tls, _ := limits["properties"].(map[string]interface{})["tls"].(map[string]interface{})
+	cert, _ := tls["properties"].(map[string]interface{})["cert"].(map[string]interface{})
+	if tls["type"] != "object" || cert["type"] != "string" || cert["default"] != "cert.pem" {
+		t.Errorf("Unexpected schema of nested pointer struct schema_limits.tls: %v", tls)
+	}
+}
+
+func TestEnvKeyFor(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	if key := config.EnvKeyFor("server.port"); key != "SERVER_PORT" {
+		t.Errorf("expected SERVER_PORT without name, got %s", key)
+	}
+
+	err := config.Manager().WithName("env-key-test").WithPath(t.TempDir()).Register(&TestConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	key := config.EnvKeyFor("application_name")
+	if key != "ENV_KEY_TEST_APPLICATION_NAME" {
+		t.Errorf("expected ENV_KEY_TEST_APPLICATION_NAME, got %s", key)
+	}
+	if key := config.EnvKeyFor("server.read-timeout"); key != "ENV_KEY_TEST_SERVER_READ_TIMEOUT" {
+		t.Errorf("expected ENV_KEY_TEST_SERVER_READ_TIMEOUT, got %s", key)
+	}
+	if key := config.Section("database").EnvKeyFor("section_dsn"); key != "DATABASE_SECTION_DSN" {
+		t.Errorf("expected DATABASE_SECTION_DSN, got %s", key)
+	}
+
+	// The returned key is the one looked up when reading
+	t.Setenv(key, "from-env")
+	t.Setenv(config.EnvKeyFor("server_port"), "9090")
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	cfg := config.Get().(*TestConfig)
+	if cfg.ApplicationName != "from-env" || cfg.ServerPort != 9090 {
+		t.Errorf("expected values from environment, got %+v", cfg)
+	}
+}
+
+type ConstraintLimits struct {
+	Workers int     `yaml:"constraint_workers" min:"1" max:"64"`
+	Ratio   float64 `yaml:"constraint_ratio" min:"0" max:"1"`
+	Retries uint    `yaml:"constraint_retries" max:"10"`
+}
+
+type ConstraintConfig struct {
+	Port     int              `yaml:"constraint_port" min:"1" max:"65535"`
+	LogLevel string           `yaml:"constraint_log_level" oneof:"debug info warn error"`
+	Limits   ConstraintLimits `yaml:"constraint_limits"`
+}
+
+func (c *ConstraintConfig) Validate() error {
+	return nil
+}
+
+func TestConstraintTags(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	dir := t.TempDir()
+	cfg := &ConstraintConfig{Port: 8080, LogLevel: "info", Limits: ConstraintLimits{Workers: 4, Ratio: 0.5, Retries: 3}}
+	err := config.Manager().WithName("constraint-test").WithPath(dir).Register(cfg)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed for valid defaults: %v", err)
+	}
+
+	data := "constraint_port: 70000\nconstraint_log_level: verbose\nconstraint_limits:\n  constraint_workers: 0\n  constraint_ratio: 1.5\n  constraint_retries: 11\n"
+	err = os.WriteFile(filepath.Join(dir, "constraint-test.yaml"), []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	err = config.Read()
+	if err == nil {
+		t.Fatal("Read() should fail for values out of range")
+	}
+	for _, path := range []string{"constraint_port", "constraint_log_level", "constraint_limits.constraint_workers", "constraint_limits.constraint_ratio", "constraint_limits.constraint_retries"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Error should name %s, got: %v", path, err)
+		}
+	}
+	if config.Get().(*ConstraintConfig).Port != 8080 {
+		t.Error("Invalid configuration should not be applied")
+	}
+
+	err = config.Write(&ConstraintConfig{Port: 0, LogLevel: "info", Limits: ConstraintLimits{Workers: 1}})
+	if err == nil {
+		t.Error("Write() should fail for a port below the minimum")
+	}
+
+	schema, err := config.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() failed: %v", err)
+	}
+	var properties struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	err = json.Unmarshal(schema, &properties)
+	if err != nil {
+		t.Fatalf("JSONSchema() returned invalid JSON: %v", err)
+	}
+	port := properties.Properties["constraint_port"]
+	if port["minimum"] != float64(1) || port["maximum"] != float64(65535) {
+		t.Errorf("Expected the bounds in the schema of constraint_port, got %v", port)
+	}
+	level := properties.Properties["constraint_log_level"]
+	if !reflect.DeepEqual(level["enum"], []interface{}{"debug", "info", "warn", "error"}) {
+		t.Errorf("Expected the values in the schema of constraint_log_level, got %v", level)
+	}
+}
+
+func TestHTTPLoader(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("application_name: remote-app\nserver_port: 9090\n"))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	err := config.Manager().WithPath(tempDir).WithName("remote-test").Register(&TestConfig{ApplicationName: "default-app", ServerPort: 8080})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	config.WithLoader(&config.HTTPLoader{URL: server.URL, Header: http.Header{"Authorization": {"Bearer token"}}})
+
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	current, ok := config.Get().(*TestConfig)
+	if !ok || current.ApplicationName != "remote-app" || current.ServerPort != 9090 {
+		t.Errorf("Expected the remote configuration, got %+v", config.Get())
+	}
+
+	// A failing loader keeps the current configuration and does not write defaults
+	status = http.StatusInternalServerError
+	err = config.Read()
+	if err == nil {
+		t.Fatal("Expected Read to fail if the loader fails")
+	}
+	current, ok = config.Get().(*TestConfig)
+	if !ok || current.ApplicationName != "remote-app" {
+		t.Errorf("Expected the configuration to be kept, got %+v", config.Get())
+	}
+	_, err = os.Stat(filepath.Join(tempDir, "remote-test.yaml"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected no configuration file to be written, got %v", err)
+	}
+}
+
+type EnvOnlyDatabase struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password" env-only:"true" flag:"-"`
+}
+
+type EnvOnlyConfig struct {
+	Token    string          `yaml:"token" env-only:"true" flag:"-"`
+	Database EnvOnlyDatabase `yaml:"database"`
+}
+
+func (c *EnvOnlyConfig) Validate() error {
+	return nil
+}
+
+func TestEnvOnly(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "envonly-test.yaml")
+	err := os.WriteFile(file, []byte("token: file-token\ndatabase:\n  user: app\n  password: file-password\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("ENVONLY_TEST_DATABASE_PASSWORD", "env-password")
+
+	err = config.Manager().WithPath(tempDir).WithName("envonly-test").Register(&EnvOnlyConfig{})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	current, ok := config.Get().(*EnvOnlyConfig)
+	if !ok {
+		t.Fatal("Expected config to be *EnvOnlyConfig")
+	}
+	if current.Token != "" {
+		t.Errorf("Expected the env-only token not to be read from the file, got %q", current.Token)
+	}
+	if current.Database.User != "app" || current.Database.Password != "env-password" {
+		t.Errorf("Expected the user from the file and the password from the environment, got %+v", current.Database)
+	}
+
+	for _, write := range []func(config.Config) error{config.Write, config.WriteAnnotated} {
+		err = write(&EnvOnlyConfig{Token: "secret-token", Database: EnvOnlyDatabase{User: "app", Password: "secret-password"}})
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		if strings.Contains(string(data), "secret") || strings.Contains(string(data), "token") || strings.Contains(string(data), "password") {
+			t.Errorf("Expected env-only fields to be omitted from the file, got:\n%s", data)
+		}
+		if !strings.Contains(string(data), "user: app") {
+			t.Errorf("Expected the other fields to be written, got:\n%s", data)
+		}
+	}
+}
+
+type ValidateAllConfig struct {
+	Name     string          `yaml:"name"`
+	Server   ServerConfig    `yaml:"server"`
+	Database *DatabaseConfig `yaml:"database"`
+	Cache    *DatabaseConfig `yaml:"cache"`
+	Nested   struct {
+		Replica DatabaseConfig `yaml:"replica"`
+	} `yaml:"nested"`
+	Backup BackupConfig `yaml:"backup"`
+}
+
+func (c *ValidateAllConfig) Validate() error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, &config.FieldError{Path: "name", Err: errors.New("name cannot be empty")})
+	}
+	return config.ValidationErrors(append(errs, config.FieldErrors(config.ValidateAll(c))...))
+}
+
+type BackupConfig struct {
+	Target DatabaseConfig `yaml:"target"`
+}
+
+func (c *BackupConfig) Validate() error {
+	return config.ValidateAll(c)
+}
+
+func TestValidateAll(t *testing.T) {
+	cfg := &ValidateAllConfig{Database: &DatabaseConfig{}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	var paths []string
+	for _, e := range config.FieldErrors(err) {
+		var fe *config.FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("Expected *config.FieldError, got %T", e)
+		}
+		paths = append(paths, fe.Path)
+	}
+	expected := []string{"name", "server", "database", "nested.replica", "backup.target"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected errors of %v, got %v", expected, paths)
+	}
+	if !strings.Contains(err.Error(), "backup.target: url cannot be empty") {
+		t.Errorf("Expected error to name the field path, got %q", err.Error())
+	}
+
+	cfg = &ValidateAllConfig{
+		Name:     "app",
+		Server:   ServerConfig{Host: "localhost", Port: 8080},
+		Database: &DatabaseConfig{URL: "postgres://localhost"},
+	}
+	cfg.Nested.Replica.URL = "postgres://replica"
+	cfg.Backup.Target.URL = "postgres://backup"
+	err = cfg.Validate()
+	if err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+}
+
+// SectionConfig is the configuration of a section
+type SectionConfig struct {
+	Host string `yaml:"host" usage:"Host of the section"`
+	Port int    `yaml:"port" usage:"Port of the section"`
+}
+
+func (c *SectionConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestSection(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	dir := t.TempDir()
+	err := config.Manager().WithPath(dir).WithName("section-main-test").Register(&TestConfig{ApplicationName: "app", ServerPort: 8080})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	err = config.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	// Sections may be registered after the main configuration was read
+	db := config.Section("section-db")
+	err = db.Register(&SectionConfig{Host: "localhost", Port: 5432})
+	if err != nil {
+		t.Fatalf("Register() of section failed: %v", err)
+	}
+	err = config.Section("section-cache").Register(&SectionConfig{Host: "localhost", Port: 6379})
+	if err != nil {
+		t.Fatalf("Register() of second section with the same keys failed: %v", err)
+	}
+
+	if pflag.Lookup("section-db-port") == nil || pflag.Lookup("section-cache-port") == nil {
+		t.Fatal("Expected the flags of the sections to be prefixed with their names")
+	}
+	if db.EnvKeyFor("host") != "SECTION_DB_HOST" {
+		t.Errorf("Expected the environment variables of the section to be prefixed with its name, got %s", db.EnvKeyFor("host"))
+	}
+
+	t.Setenv("SECTION_DB_HOST", "db.example.com")
+	err = pflag.Set("section-cache-port", "6380")
+	if err != nil {
+		t.Fatalf("Setting flag failed: %v", err)
+	}
+
+	err = config.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+
+	for _, name := range []string{"section-db", "section-cache"} {
+		if _, err := os.Stat(filepath.Join(dir, name+".yaml")); err != nil {
+			t.Errorf("Expected section %s to be stored in its own file in the path of the main configuration: %v", name, err)
+		}
+	}
+
+	got := db.Get().(*SectionConfig)
+	if got.Host != "db.example.com" || got.Port != 5432 {
+		t.Errorf("Expected section-db to be read from its file and environment, got %+v", got)
+	}
+	got = config.Section("section-cache").Get().(*SectionConfig)
+	if got.Host != "localhost" || got.Port != 6380 {
+		t.Errorf("Expected section-cache to be read with its own flag, got %+v", got)
+	}
+
+	err = db.Write(&SectionConfig{Host: "written.example.com", Port: 5433})
+	if err != nil {
+		t.Fatalf("Write() of section failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "section-db.yaml"))
+	if err != nil {
+		t.Fatalf("Reading section file failed: %v", err)
+	}
+	if !strings.Contains(string(data), "port: 5433") {
+		t.Errorf("Expected Write() to store the section in its file, got %q", data)
+	}
+}
+
+func TestSectionName(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	config.Manager().WithPath(t.TempDir()).WithName("section-name-test")
+	err := config.Section("section-name-test").Register(&SectionConfig{Port: 1})
+	if err == nil {
+		t.Error("Expected section named like the main configuration to be rejected")
+	}
+}
+
+func TestReadAllErrors(t *testing.T) {
+	config.Reset()
+	defer config.Reset()
+
+	dir := t.TempDir()
+	err := config.Manager().WithPath(dir).WithName("read-all-test").Register(&TestConfig{ApplicationName: "app", ServerPort: 8080})
+	if err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	for _, name := range []string{"read-all-first", "read-all-second", "read-all-valid"} {
+		err = config.Section(name).Register(&SectionConfig{Port: 1})
+		if err != nil {
+			t.Fatalf("Register() of section %s failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"read-all-first", "read-all-second"} {
+		err = os.WriteFile(filepath.Join(dir, name+".yaml"), []byte("port: 0\n"), 0600)
+		if err != nil {
+			t.Fatalf("Writing section file failed: %v", err)
+		}
+	}
+
+	err = config.ReadAll()
+	if err == nil {
+		t.Fatal("Expected ReadAll() to fail for invalid sections")
+	}
+	for _, name := range []string{"read-all-first", "read-all-second"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error of section %s, got %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "read-all-valid") {
+		t.Errorf("Expected no error of the valid section, got %v", err)
+	}
+	if config.Get() == nil || config.Section("read-all-valid").Get().(*SectionConfig).Port != 1 {
+		t.Error("Expected the valid configurations to be applied despite the failing sections")
+	}
+}
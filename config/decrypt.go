@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"gopkg.in/yaml.v2"
+)
+
+// ageArmorHeader starts an ASCII armored age encrypted file
+const ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+
+// WithDecryptor sets the function used to decrypt encrypted configuration when it is read.
+// It allows committing encrypted configuration files and decrypting them at load time.
+//
+// The decryptor is called with:
+//   - the whole file, if it is an ASCII armored age file or a sops file (a file with a top-level "sops" key)
+//   - every string value of the form ENC[...], e.g. a value encrypted by a key management service
+//
+// and returns the plaintext. Decrypted files are parsed as YAML, decrypted values are used as strings.
+// Without a decryptor the configuration is read as it is. Note that Write stores the plaintext values.
+//
+// Example using the sops CLI:
+//
+//	config.WithDecryptor(func(ciphertext []byte) ([]byte, error) {
+//		cmd := exec.Command("sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin")
+//		cmd.Stdin = bytes.NewReader(ciphertext)
+//		return cmd.Output()
+//	})
+func WithDecryptor(decryptor func(ciphertext []byte) ([]byte, error)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	cm.decryptor = decryptor
+}
+
+// decode parses the configuration file and decrypts it if a decryptor is set
+func (m *manager) decode(data []byte) (map[string]interface{}, error) {
+	var err error
+	if m.decryptor != nil && bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader)) {
+		data, err = m.decryptor(data)
+		if err != nil {
+			return nil, apperror.NewError("decrypting configuration file failed").AddError(err)
+		}
+	}
+
+	var yamlData map[string]interface{}
+	err = yaml.Unmarshal(data, &yamlData)
+	if err != nil {
+		return nil, apperror.NewError("unmarshalling configuration file failed").AddError(err)
+	}
+
+	if m.decryptor == nil {
+		return yamlData, nil
+	}
+
+	if _, ok := yamlData["sops"]; ok {
+		plaintext, err := m.decryptor(data)
+		if err != nil {
+			return nil, apperror.NewError("decrypting configuration file failed").AddError(err)
+		}
+
+		yamlData = nil
+		err = yaml.Unmarshal(plaintext, &yamlData)
+		if err != nil {
+			return nil, apperror.NewError("unmarshalling decrypted configuration file failed").AddError(err)
+		}
+	}
+
+	for key, value := range yamlData {
+		yamlData[key], err = m.decryptValue(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return yamlData, nil
+}
+
+// decryptValue decrypts the encrypted strings of a configuration value
+func (m *manager) decryptValue(key string, value interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case string:
+		if !strings.HasPrefix(v, "ENC[") || !strings.HasSuffix(v, "]") {
+			return v, nil
+		}
+		plaintext, err := m.decryptor([]byte(v))
+		if err != nil {
+			return nil, apperror.NewErrorf("decrypting configuration value %s failed", key).AddError(err)
+		}
+		return string(plaintext), nil
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k], err = m.decryptValue(key+"."+k, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, item := range v {
+			if name, ok := k.(string); ok {
+				v[k], err = m.decryptValue(key+"."+name, item)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i], err = m.decryptValue(key, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
@@ -1,41 +1,56 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/flag"
 
 	"gopkg.in/yaml.v2"
 )
 
-func (m *manager) read() error {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	if m.name == "" || m.path == "" {
-		return apperror.NewError("config name and path must be set")
+// read loads the configuration with the loader, or from the configuration file if it is nil,
+// and stores its flattened values. The data is loaded without holding the lock, so a slow
+// loader does not block readers of the current configuration.
+func (m *manager) read(loader Loader) error {
+	if loader == nil {
+		loader = fileLoader{m: m}
 	}
 
-	configFile := filepath.Join(m.path, m.name+".yaml")
-
-	data, err := os.ReadFile(filepath.Clean(configFile))
+	data, err := loader.Load(context.Background())
 	if err != nil {
-		return apperror.NewError("reading configuration file failed").AddError(err)
+		return apperror.Wrap(err)
 	}
 
-	var yamlData map[string]interface{}
-	if err := yaml.Unmarshal(data, &yamlData); err != nil {
-		return apperror.NewError("unmarshalling configuration file failed").AddError(err)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	yamlData, err := m.decode(data)
+	if err != nil {
+		return err
 	}
 
 	m.values = make(map[string]interface{})
 	m.flatten(yamlData, "")
+	for key := range m.envOnly {
+		if _, exists := m.values[key]; exists {
+			logger.Warn().Field("key", key).Msg("ignoring env-only key in config file, it is read from the environment only")
+		}
+	}
 	return nil
 }
 
+// watch calls onChange when the configuration file is written or created, e.g. by an editor
+// or a deployment replacing it. A removed file is only logged, the current configuration is
+// kept until the file is created again. The parent of the configuration directory is watched
+// as well, so that the watch is re-established if the directory itself is replaced.
 func (m *manager) watch(onChange func(fsnotify.Event)) error {
 	mutex.Lock()
 	defer mutex.Unlock()
@@ -44,24 +59,46 @@ func (m *manager) watch(onChange func(fsnotify.Event)) error {
 		m.watcher.Close()
 	}
 
-	var err error
-	m.watcher, err = fsnotify.NewWatcher()
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return apperror.NewError("creating file watcher failed").AddError(err)
 	}
+	m.watcher = watcher
 
-	configFile := filepath.Join(m.path, m.name+".yaml")
+	configFile := filepath.Clean(m.file())
+	configDir := filepath.Dir(configFile)
+	parentDir := filepath.Dir(configDir)
 	go func() {
 		for {
 			select {
-			case event, ok := <-m.watcher.Events:
+			case event, ok := <-watcher.Events:
 				if !ok {
 					return
 				}
-				if event.Name == configFile && (event.Op&fsnotify.Write == fsnotify.Write) {
+				switch {
+				case event.Name == configFile && event.Has(fsnotify.Write):
 					onChange(event)
+				case event.Name == configFile && event.Has(fsnotify.Create):
+					// A file created empty is read on its first write
+					if info, err := os.Stat(configFile); err == nil && info.Size() > 0 {
+						onChange(event)
+					}
+				case event.Name == configFile && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)):
+					logger.Warn().Field("file", configFile).Msg("config file removed, keeping the current configuration until it is created again")
+				case event.Name == configDir && event.Has(fsnotify.Create):
+					err := watcher.Add(configDir)
+					if err != nil {
+						logger.Error().Err(err).Field("path", configDir).Msg("re-establishing config directory watch failed")
+						continue
+					}
+					// The file may have been created before the directory was watched again
+					if info, err := os.Stat(configFile); err == nil && info.Size() > 0 {
+						onChange(fsnotify.Event{Name: configFile, Op: fsnotify.Create})
+					}
+				case event.Name == configDir && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)):
+					logger.Warn().Field("path", configDir).Msg("config directory removed, watching for it to be created again")
 				}
-			case err, ok := <-m.watcher.Errors:
+			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
 				}
@@ -70,18 +107,90 @@ func (m *manager) watch(onChange func(fsnotify.Event)) error {
 		}
 	}()
 
-	return m.watcher.Add(filepath.Clean(filepath.Dir(configFile)))
+	if parentDir != configDir {
+		err = watcher.Add(parentDir)
+		if err != nil {
+			logger.Warn().Err(err).Field("path", parentDir).Msg("watching parent of config directory failed, a replaced directory is not detected")
+		}
+	}
+	return watcher.Add(configDir)
+}
+
+// file returns the path of the configuration file
+// The main configuration is stored in the file given by the --config flag if it is set,
+// otherwise every configuration is stored in <path>/<name>.yaml
+func (m *manager) file() string {
+	if m == cm && flag.Config != "" {
+		return flag.Config
+	}
+	return filepath.Join(m.path, m.name+".yaml")
 }
 
 // save saves the configuration to the file
 // If the file does not exist, it creates a new one with the default values
+// Keys of env-only fields are omitted, so secrets are not persisted
 func (m *manager) save() error {
+	mutex.RLock()
+	data, err := yaml.Marshal(m.config)
+	if err == nil && len(m.envOnly) > 0 {
+		data, err = omitKeys(data, m.envOnly)
+	}
+	mutex.RUnlock()
+	if err != nil {
+		return apperror.NewError("marshalling configuration data failed").AddError(err)
+	}
+
+	return m.writeFile(data)
+}
+
+// omitKeys removes the keys, given as lower case dotted paths, from the YAML document
+func omitKeys(data []byte, keys map[string]bool) ([]byte, error) {
+	var doc yaml.MapSlice
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(omit(doc, "", keys))
+}
+
+// omit returns the mapping without the keys, nested mappings are filtered recursively
+func omit(doc yaml.MapSlice, prefix string, keys map[string]bool) yaml.MapSlice {
+	kept := make(yaml.MapSlice, 0, len(doc))
+	for _, item := range doc {
+		key := strings.ToLower(buildLabel(prefix, fmt.Sprint(item.Key)))
+		if keys[key] {
+			continue
+		}
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			item.Value = omit(nested, key, keys)
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// saveAnnotated saves the configuration to the file with the usage tag
+// of every field written as a comment above its key
+func (m *manager) saveAnnotated() error {
+	var buf bytes.Buffer
+	mutex.RLock()
+	err := annotate(&buf, reflect.ValueOf(m.config), 0)
+	mutex.RUnlock()
+	if err != nil {
+		return apperror.NewError("marshalling configuration data failed").AddError(err)
+	}
+
+	return m.writeFile(buf.Bytes())
+}
+
+// writeFile writes the data to the configuration file
+func (m *manager) writeFile(data []byte) error {
 	// Ensure the directory exists before trying to create the file
-	if err := os.MkdirAll(m.path, 0750); err != nil {
+	if err := os.MkdirAll(filepath.Dir(m.file()), 0750); err != nil {
 		return apperror.NewError("creating configuration directory failed").AddError(err)
 	}
 
-	path, err := filepath.Abs(filepath.Join(m.path, m.name+".yaml"))
+	path, err := filepath.Abs(m.file())
 	if err != nil {
 		return apperror.NewError("building absolute path of configuration file failed").AddError(err)
 	}
@@ -90,15 +199,9 @@ func (m *manager) save() error {
 		return apperror.NewError("opening configuration file failed").AddError(err)
 	}
 
-	mutex.RLock()
-	defer mutex.RUnlock()
-	data, err := yaml.Marshal(m.config)
-	if err != nil {
-		return apperror.NewError("marshalling configuration data failed").AddError(err)
-	}
-
 	_, err = file.Write(data)
 	if err != nil {
+		_ = file.Close()
 		return apperror.NewError("writing configuration data to file failed").AddError(err)
 	}
 
@@ -110,6 +213,69 @@ func (m *manager) save() error {
 	return nil
 }
 
+// annotate writes the struct as YAML with the usage tag of every field as a comment above its key
+// Nested structs are written as indented mappings, all other values are marshalled as they are by Write
+// Fields tagged with env-only:"true" are omitted like by Write
+func annotate(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("yaml") == "-" || field.Tag.Get("env-only") == "true" {
+			continue
+		}
+
+		key := yamlKey(field)
+		if usage := field.Tag.Get("usage"); usage != "" {
+			for _, line := range strings.Split(usage, "\n") {
+				buf.WriteString(prefix + "# " + line + "\n")
+			}
+		}
+
+		fv := v.Field(i)
+		nested := fv
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct {
+			buf.WriteString(prefix + key + ":\n")
+			if err := annotate(buf, nested, indent+2); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := yaml.Marshal(yaml.MapSlice{{Key: key, Value: fv.Interface()}})
+		if err != nil {
+			return apperror.NewErrorf("marshalling field %s failed", field.Name).AddError(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			buf.WriteString(prefix + line + "\n")
+		}
+	}
+
+	return nil
+}
+
+// yamlKey returns the key yaml.Marshal uses for the struct field
+func yamlKey(field reflect.StructField) string {
+	tag := field.Tag.Get("yaml")
+	if idx := strings.Index(tag, ","); idx != -1 {
+		tag = tag[:idx]
+	}
+	if tag == "" {
+		return strings.ToLower(field.Name)
+	}
+	return tag
+}
+
 func (m *manager) flatten(data map[string]interface{}, prefix string) {
 	for key, value := range data {
 		fullKey := key
@@ -117,24 +283,13 @@ func (m *manager) flatten(data map[string]interface{}, prefix string) {
 			fullKey = prefix + "." + key
 		}
 
-		// Handle map[string]interface{}
-		if nested, ok := value.(map[string]interface{}); ok {
+		// Nested maps are kept as a whole as well, so that map fields keep the case of their keys
+		if nested, ok := toStringMap(value); ok {
+			m.values[strings.ToLower(fullKey)] = nested
 			m.flatten(nested, fullKey)
 			continue
 		}
 
-		// Handle map[interface{}]interface{} (common with YAML unmarshaling)
-		if nestedInterface, ok := value.(map[interface{}]interface{}); ok {
-			nestedString := make(map[string]interface{})
-			for k, v := range nestedInterface {
-				if keyStr, ok := k.(string); ok {
-					nestedString[keyStr] = v
-				}
-			}
-			m.flatten(nestedString, fullKey)
-			continue
-		}
-
 		m.values[strings.ToLower(fullKey)] = value
 	}
 }
@@ -10,6 +10,11 @@ import (
 	"github.com/valentin-kaiser/go-core/apperror"
 )
 
+// flagOwners maps the flags declared by the config package to the configuration key they were declared for,
+// keys of sections are qualified with the name of the section, e.g. database:port
+// Flags outlive the manager, so a flag declared by an earlier registration is reused for the same key only
+var flagOwners = make(map[string]string)
+
 func (m *manager) setDefault(key string, value interface{}) {
 	mutex.Lock()
 	defer mutex.Unlock()
@@ -17,6 +22,13 @@ func (m *manager) setDefault(key string, value interface{}) {
 	m.defaults[lowerKey] = value
 }
 
+// setEnvOnly marks the key as read from environment variables and flags only
+func (m *manager) setEnvOnly(key string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	m.envOnly[strings.ToLower(key)] = true
+}
+
 func (m *manager) bind(key string, flag *pflag.Flag) error {
 	mutex.Lock()
 	defer mutex.Unlock()
@@ -35,6 +47,21 @@ func (m *manager) getFlagKey(key string) string {
 	return m.prefix + "_" + key
 }
 
+// EnvKeyFor returns the name of the environment variable that overrides the configuration key.
+// The key is the dotted path of the field built from the yaml names, e.g. "server.port", and the
+// variable name is the upper cased key with dots and dashes replaced by underscores, prefixed with
+// the name set with WithName, e.g. MY_APP_SERVER_PORT for the name "my-app".
+func EnvKeyFor(key string) string {
+	return cm.EnvKeyFor(key)
+}
+
+// EnvKeyFor returns the name of the environment variable that overrides the key of the manager
+func (m *manager) EnvKeyFor(key string) string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return m.getFlagKey(key)
+}
+
 func (m *manager) getFlagValue(flag *pflag.Flag) interface{} {
 	switch flag.Value.Type() {
 	case "string":
@@ -124,15 +151,32 @@ func (m *manager) getFlagValue(flag *pflag.Flag) interface{} {
 }
 
 // declareFlag declares a flag with the given label, usage and default value
+// The flag is named after the label in kebab-case unless a name is given,
+// flags of sections are prefixed with the name of the section, e.g. --database-port
 // It also binds the flag to the configuration
-func (m *manager) declareFlag(label string, usage string, defaultValue interface{}) error {
+func (m *manager) declareFlag(label, name, usage string, defaultValue interface{}) error {
 	m.setDefault(label, defaultValue)
 	pflagLabel := kebabCase(label)
+	if name != "" {
+		pflagLabel = name
+	}
 	label = strings.ToLower(label)
+	key := label
+	if m.section != "" {
+		pflagLabel = m.section + "-" + pflagLabel
+		key = m.section + ":" + label
+	}
 
 	// Check if flag already exists to avoid redefinition errors
 	if pflag.Lookup(pflagLabel) != nil {
-		// Flag already exists, just bind to config
+		mutex.RLock()
+		owner, declared := flagOwners[pflagLabel]
+		mutex.RUnlock()
+		if declared && owner != key {
+			return apperror.NewErrorf("flag --%s of configuration key %s is already declared for key %s", pflagLabel, key, owner).WithKind(apperror.KindAlreadyExists)
+		}
+
+		// Flag already exists for the same key, just bind to config
 		return m.bind(label, pflag.Lookup(pflagLabel))
 	}
 
@@ -171,11 +215,19 @@ func (m *manager) declareFlag(label string, usage string, defaultValue interface
 		return nil
 	}
 
+	mutex.Lock()
+	flagOwners[pflagLabel] = key
+	mutex.Unlock()
+
 	return m.bind(label, pflag.Lookup(pflagLabel))
 }
 
 // parseStructTags parses the struct tags of the given struct and registers the flags
 // It also sets the default values of the flags to the values of the struct fields
+// Fields left at their zero value are initialized from their default tag first
+// The flag tag overrides the name of the flag of a field, fields tagged with flag:"-" get no flag,
+// e.g. secrets that should neither be listed in --help nor passed on the command line
+// Fields tagged with env-only:"true" are neither read from nor written to the configuration file
 func (m *manager) parseStructTags(v reflect.Value, labelBase string) error {
 	// If the config is a pointer, we need to get the type of the element
 	if v.Kind() == reflect.Ptr {
@@ -198,7 +250,7 @@ func (m *manager) parseStructTags(v reflect.Value, labelBase string) error {
 				v.Field(i).Set(reflect.New(field.Type.Elem()))
 			}
 
-			if err := m.parseStructTags(v.Field(i).Elem(), fieldName); err != nil {
+			if err := m.parseStructTags(v.Field(i).Elem(), buildLabel(labelBase, fieldName)); err != nil {
 				return apperror.Wrap(err)
 			}
 			continue
@@ -218,8 +270,20 @@ func (m *manager) parseStructTags(v reflect.Value, labelBase string) error {
 			continue
 		}
 
+		if err := applyDefaultTag(v.Field(i), field); err != nil {
+			return apperror.Wrap(err)
+		}
+
 		tag := buildLabel(labelBase, fieldName)
-		if err := m.declareFlag(tag, field.Tag.Get("usage"), v.Field(i).Interface()); err != nil {
+		if field.Tag.Get("env-only") == "true" {
+			m.setEnvOnly(tag)
+		}
+		name := field.Tag.Get("flag")
+		if name == "-" {
+			m.setDefault(tag, v.Field(i).Interface())
+			continue
+		}
+		if err := m.declareFlag(tag, name, field.Tag.Get("usage"), v.Field(i).Interface()); err != nil {
 			return apperror.Wrap(err)
 		}
 	}
@@ -227,6 +291,25 @@ func (m *manager) parseStructTags(v reflect.Value, labelBase string) error {
 	return nil
 }
 
+// applyDefaultTag sets the value of the default tag on a field that is still at its zero value
+// String slices are given as a comma separated list
+func applyDefaultTag(v reflect.Value, field reflect.StructField) error {
+	def, ok := field.Tag.Lookup("default")
+	if !ok || !v.CanSet() || !v.IsZero() {
+		return nil
+	}
+
+	var value interface{} = def
+	if v.Kind() == reflect.Slice {
+		value = strings.Split(def, ",")
+	}
+
+	if err := setFieldValue(v, value); err != nil {
+		return apperror.NewErrorf("applying default value of field %s failed", field.Name).AddError(err)
+	}
+	return nil
+}
+
 // kebabCase converts a string to kebab-case (dash-separated lowercase)
 // Example: "ApplicationName" -> "application-name"
 func kebabCase(s string) string {
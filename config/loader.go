@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Loader loads the raw YAML configuration, e.g. from a configuration service.
+// The data is decrypted, flattened, unmarshalled and validated like the configuration file.
+type Loader interface {
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// LoaderFunc adapts a function to the Loader interface
+type LoaderFunc func(ctx context.Context) ([]byte, error)
+
+// Load calls f(ctx)
+func (f LoaderFunc) Load(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// WithLoader loads the main configuration with the loader instead of reading it from the
+// configuration file, e.g. from HTTP, etcd or Consul. Read fails if the loader fails, no
+// default configuration is written. Write still writes the configuration file and Watch
+// watches the file, remote configuration is reloaded by calling Read again.
+// A nil loader restores reading the configuration file.
+//
+// Example using etcd:
+//
+//	config.WithLoader(config.LoaderFunc(func(ctx context.Context) ([]byte, error) {
+//		resp, err := client.Get(ctx, "/config/server")
+//		if err != nil {
+//			return nil, err
+//		}
+//		if len(resp.Kvs) == 0 {
+//			return nil, errors.New("configuration not found")
+//		}
+//		return resp.Kvs[0].Value, nil
+//	}))
+func WithLoader(l Loader) {
+	cm.WithLoader(l)
+}
+
+// WithLoader loads the configuration of the manager with the loader, see WithLoader
+func (m *manager) WithLoader(l Loader) *manager {
+	mutex.Lock()
+	defer mutex.Unlock()
+	m.loader = l
+	return m
+}
+
+// HTTPLoader loads the configuration with a GET request, e.g. from a configuration service
+// or a Consul KV endpoint with the ?raw parameter.
+//
+// Example:
+//
+//	config.WithLoader(&config.HTTPLoader{
+//		URL:    "https://config.example.com/v1/server.yaml",
+//		Header: http.Header{"Authorization": {"Bearer " + token}},
+//	})
+type HTTPLoader struct {
+	// URL of the YAML configuration
+	URL string
+	// Header is added to the request, e.g. for authentication
+	Header http.Header
+	// Client sends the request, nil uses a client with a timeout of 30 seconds
+	Client *http.Client
+}
+
+// Load requests the configuration, responses with a status other than 200 OK are an error
+func (l *HTTPLoader) Load(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL, nil)
+	if err != nil {
+		return nil, apperror.NewError("creating configuration request failed").AddError(err)
+	}
+	for key, values := range l.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	client := l.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, apperror.NewErrorf("requesting configuration from %s failed", l.URL).AddError(err)
+	}
+	defer apperror.Catch(resp.Body.Close, "closing configuration response body failed")
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apperror.NewErrorf("requesting configuration from %s failed with status %s", l.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperror.NewErrorf("reading configuration from %s failed", l.URL).AddError(err)
+	}
+	return data, nil
+}
+
+// fileLoader reads the configuration file of the manager, it is used if no loader is set
+type fileLoader struct {
+	m *manager
+}
+
+// Load reads the configuration file
+func (l fileLoader) Load(_ context.Context) ([]byte, error) {
+	mutex.RLock()
+	if l.m.name == "" || l.m.path == "" {
+		mutex.RUnlock()
+		return nil, apperror.NewError("config name and path must be set")
+	}
+	configFile := l.m.file()
+	mutex.RUnlock()
+
+	data, err := os.ReadFile(filepath.Clean(configFile))
+	if err != nil {
+		return nil, apperror.NewError("reading configuration file failed").AddError(err)
+	}
+	return data, nil
+}
+
+// source returns the loader of the manager, nil if it reads its configuration file
+func (m *manager) source() Loader {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return m.loader
+}
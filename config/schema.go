@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect of the generated documents
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema document describing the registered configuration struct.
+// Properties are named like the keys of the configuration file, the usage tag of a field
+// is used as description, the registered default values as defaults and fields tagged with
+// required:"true" are listed as required. It allows editors and tooling outside the application
+// to validate and complete configuration files. Fields tagged with env-only:"true" are not part of the file and omitted.
+func JSONSchema() ([]byte, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if cm.config == nil {
+		return nil, apperror.NewError("no configuration registered")
+	}
+
+	t := reflect.TypeOf(cm.config)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := cm.schema(t, "", true)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = cm.name
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, apperror.NewError("marshalling configuration schema failed").AddError(err)
+	}
+	return data, nil
+}
+
+// schema returns the JSON Schema of a configuration type
+// The label is the key of the value in the defaults, built like in parseStructTags
+// Elements of slices and maps have no registered defaults, so they are described without defaults
+func (m *manager) schema(t reflect.Type, label string, withDefaults bool) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" || field.Tag.Get("yaml") == "-" || field.Tag.Get("env-only") == "true" {
+				continue
+			}
+
+			fieldLabel := buildLabel(label, getFieldName(field))
+			property := m.schema(field.Type, fieldLabel, withDefaults)
+			if usage := field.Tag.Get("usage"); usage != "" {
+				property["description"] = usage
+			}
+			constrain(property, field)
+			if def, ok := m.defaults[strings.ToLower(fieldLabel)]; ok && withDefaults && !isStructType(field.Type) && !isNil(def) {
+				property["default"] = def
+			}
+
+			key := yamlKey(field)
+			properties[key] = property
+			if ok, err := strconv.ParseBool(field.Tag.Get("required")); err == nil && ok {
+				required = append(required, key)
+			}
+		}
+
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": m.schema(t.Elem(), "", false)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": m.schema(t.Elem(), "", false)}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// isStructType reports whether the type is a struct or a pointer to a struct
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// isNil reports whether the default value is nil, e.g. an unset map or slice
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// constrain adds the min, max and oneof tags of a field to its schema
+func constrain(property map[string]interface{}, field reflect.StructField) {
+	if field.Type.Kind() == reflect.String {
+		if oneof, ok := field.Tag.Lookup("oneof"); ok {
+			property["enum"] = strings.Fields(oneof)
+		}
+		return
+	}
+	for tag, keyword := range map[string]string{"min": "minimum", "max": "maximum"} {
+		bound, err := strconv.ParseFloat(field.Tag.Get(tag), 64)
+		if err == nil {
+			property[keyword] = bound
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/flag"
+)
+
+// sections holds the managers of the named configuration sections
+var sections = make(map[string]*manager)
+
+// Section returns the manager of a named configuration section and creates it on first use.
+// Sections split the configuration of an application into several structs, e.g. one per module.
+// Every section is registered with its own struct, stored in its own file <name>.yaml and read
+// from environment variables prefixed with its name. Sections are stored in the path of the main
+// configuration unless a path is set with WithPath, the name must differ from the main configuration,
+// otherwise Register and Read fail. The flags of a section are prefixed with its name, e.g. --database-port,
+// so keys present in several sections get a flag each. Read, Write, WriteAnnotated, Watch, OnChange
+// and Changes are available on the section like on the main configuration.
+//
+// Example:
+//
+//	err := config.Section("database").Register(&DatabaseConfig{})
+//	if err != nil {
+//		return err
+//	}
+//	err = config.ReadAll()
+//	if err != nil {
+//		return err
+//	}
+//	db := config.Section("database").Get().(*DatabaseConfig)
+func Section(name string) *manager {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	m, ok := sections[name]
+	if !ok {
+		m = new()
+		m.name = name
+		m.section = name
+		m.prefix = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		sections[name] = m
+	}
+	return m
+}
+
+// ReadAll reads the main configuration, if one is registered, and all sections like Read.
+// Each configuration is read, validated and applied on its own, so a failing section does
+// not prevent the others from being applied. The errors of all configurations are returned together.
+func ReadAll() error {
+	mutex.RLock()
+	var managers []*manager
+	if cm.config != nil {
+		managers = append(managers, cm)
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		managers = append(managers, sections[name])
+	}
+	mutex.RUnlock()
+
+	var errs []error
+	for _, m := range managers {
+		err := m.Read()
+		if err != nil {
+			// Wrapped with fmt, as AddErrors flattens the errors of an apperror.Error and would drop the name
+			errs = append(errs, fmt.Errorf("reading configuration %s failed: %w", m.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return apperror.NewError("reading configuration failed").AddErrors(errs)
+	}
+	return nil
+}
+
+// checkSection ensures that a section is not stored in the file of the main configuration
+func (m *manager) checkSection() error {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if m.section != "" && m.name == cm.name {
+		return apperror.NewErrorf("the name of section %s must differ from the main configuration", m.section).WithKind(apperror.KindInvalidArgument)
+	}
+	return nil
+}
+
+// resolve resolves the path of the configuration from flag.Path if it is not set
+// Sections use the path and the decryptor of the main configuration by default
+func (m *manager) resolve() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if m.path == "" {
+		m.path = cm.path
+	}
+	if m.path == "" {
+		m.path = flag.Path
+	}
+	if m.decryptor == nil {
+		m.decryptor = cm.decryptor
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
 )
 
 func (m *manager) getValue(key string) interface{} {
@@ -22,7 +24,9 @@ func (m *manager) getValue(key string) interface{} {
 		return envVal
 	}
 
-	if val, exists := m.values[lowerKey]; exists {
+	// Keys without value in the file, e.g. "port:", keep their default
+	// Keys of env-only fields are never taken from the file
+	if val, exists := m.values[lowerKey]; exists && val != nil && !m.envOnly[lowerKey] {
 		return val
 	}
 
@@ -100,8 +104,50 @@ func (m *manager) unmarshalStruct(v reflect.Value, prefix string) error {
 	return nil
 }
 
+// checkRequired returns an error for the first field tagged with required:"true"
+// that is still at its zero value, i.e. was not set by file, environment, flag or default
+func checkRequired(v reflect.Value, prefix string) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		key := buildLabel(prefix, getFieldName(field))
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct || (fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct) {
+			if err := checkRequired(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		required, err := strconv.ParseBool(field.Tag.Get("required"))
+		if err != nil || !required {
+			continue
+		}
+		if fv.IsZero() {
+			return apperror.NewErrorf("required configuration value %s is not set", key)
+		}
+	}
+
+	return nil
+}
+
 func setFieldValue(field reflect.Value, value interface{}) error {
-	if !field.CanSet() {
+	if !field.CanSet() || value == nil {
 		return nil
 	}
 
@@ -257,9 +303,26 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 			}
 		}
 
+	case reflect.Map:
+		return setMapValue(field, value)
+
+	case reflect.Struct:
+		return setStructValue(field, value)
+
+	case reflect.Ptr:
+		if reflect.TypeOf(value).AssignableTo(field.Type()) {
+			field.Set(reflect.ValueOf(value))
+			return nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		if err := setFieldValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+
 	case reflect.Slice:
 		if field.Type().Elem().Kind() != reflect.String {
-			return nil
+			return setSliceValue(field, value)
 		}
 		if slice, ok := value.([]string); ok {
 			field.Set(reflect.ValueOf(slice))
@@ -276,3 +339,108 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 
 	return nil
 }
+
+// setMapValue sets a map field with string keys from a nested configuration value
+func setMapValue(field reflect.Value, value interface{}) error {
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+
+	if field.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	values, ok := toStringMap(value)
+	if !ok {
+		return nil
+	}
+
+	m := reflect.MakeMapWithSize(field.Type(), len(values))
+	for k, v := range values {
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setFieldValue(elem, v); err != nil {
+			return apperror.NewErrorf("invalid value for key %s", k).AddError(err)
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(field.Type().Key()), elem)
+	}
+	field.Set(m)
+	return nil
+}
+
+// setSliceValue sets a slice field from a configuration list, elements may be structs or nested lists
+func setSliceValue(field reflect.Value, value interface{}) error {
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setFieldValue(slice.Index(i), item); err != nil {
+			return apperror.NewErrorf("invalid value at index %d", i).AddError(err)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// setStructValue sets the fields of a struct from a nested configuration map
+// Keys are matched case-insensitively against the yaml tag or field name
+func setStructValue(field reflect.Value, value interface{}) error {
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+
+	values, ok := toStringMap(value)
+	if !ok {
+		return nil
+	}
+
+	lower := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		lower[strings.ToLower(k)] = v
+	}
+
+	t := field.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		v, exists := lower[strings.ToLower(getFieldName(sf))]
+		if !exists || v == nil {
+			continue
+		}
+		if err := setFieldValue(field.Field(i), v); err != nil {
+			return apperror.NewErrorf("invalid value for %s", getFieldName(sf)).AddError(err)
+		}
+	}
+	return nil
+}
+
+// toStringMap converts the map types produced by YAML unmarshaling to a map with string keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[fmt.Sprintf("%v", k)] = v
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
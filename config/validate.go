@@ -0,0 +1,154 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// FieldError is the validation error of a nested configuration
+type FieldError struct {
+	// Path is the dotted path of the field, e.g. server.tls
+	Path string
+	// Err is the error returned by the Validate method of the field
+	Err error
+}
+
+// Error returns the path of the field followed by its error
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the field
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateAll calls the Validate method of every field of the configuration implementing Config
+// and returns all their errors at once instead of only the first one. Nested structs not
+// implementing Config are searched for such fields. The Validate method of c itself is not
+// called, so it can delegate to ValidateAll and add checks of its own with ValidationErrors.
+// Fields whose Validate method uses ValidateAll again report their errors with the full path.
+//
+// The errors are returned as *FieldError in a single apperror.Error of kind
+// apperror.KindInvalidArgument, retrieve them with FieldErrors.
+//
+// Example:
+//
+//	func (c *AppConfig) Validate() error {
+//	    var errs []error
+//	    if c.Name == "" {
+//	        errs = append(errs, &config.FieldError{Path: "name", Err: errors.New("name cannot be empty")})
+//	    }
+//	    return config.ValidationErrors(append(errs, config.FieldErrors(config.ValidateAll(c))...))
+//	}
+func ValidateAll(c Config) error {
+	var errs []error
+	collectFieldErrors(reflect.ValueOf(c), "", &errs)
+	return ValidationErrors(errs)
+}
+
+// ValidationErrors combines the errors into the error returned by ValidateAll, nil if there are none
+func ValidationErrors(errs []error) error {
+	var fields []error
+	for _, err := range errs {
+		if err != nil {
+			fields = append(fields, err)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return apperror.NewError("configuration is invalid").AddErrors(fields).WithKind(apperror.KindInvalidArgument)
+}
+
+// FieldErrors returns the errors of the fields carried by an error of ValidateAll or ValidationErrors
+func FieldErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	_, _, errs := apperror.Split(err)
+	return errs
+}
+
+// collectFieldErrors appends the errors of the fields implementing Config in the struct and its nested structs to errs
+func collectFieldErrors(v reflect.Value, prefix string, errs *[]error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		key := buildLabel(prefix, getFieldName(field))
+		fv := v.Field(i)
+		if fv.Kind() != reflect.Struct && (fv.Kind() != reflect.Ptr || fv.Type().Elem().Kind() != reflect.Struct) {
+			continue
+		}
+
+		c, ok := fieldConfig(fv)
+		if !ok {
+			collectFieldErrors(fv, key, errs)
+			continue
+		}
+
+		err := c.Validate()
+		if err == nil {
+			continue
+		}
+
+		*errs = append(*errs, prefixFieldErrors(err, key)...)
+	}
+}
+
+// prefixFieldErrors returns the error of the field at the path as FieldError. Errors of ValidateAll
+// or ValidationErrors are split into their fields, whose paths are prefixed with the path.
+func prefixFieldErrors(err error, path string) []error {
+	_, _, nested := apperror.Split(err)
+	if !slices.ContainsFunc(nested, func(e error) bool { _, ok := e.(*FieldError); return ok }) {
+		return []error{&FieldError{Path: path, Err: err}}
+	}
+
+	errs := make([]error, 0, len(nested))
+	for _, e := range nested {
+		if fe, ok := e.(*FieldError); ok && fe.Path != "" {
+			errs = append(errs, &FieldError{Path: buildLabel(path, fe.Path), Err: fe.Err})
+			continue
+		}
+		if fe, ok := e.(*FieldError); ok {
+			e = fe.Err
+		}
+		errs = append(errs, &FieldError{Path: path, Err: e})
+	}
+	return errs
+}
+
+// fieldConfig returns the field as Config if it or a pointer to it implements the interface.
+// Nil pointers are not validated.
+func fieldConfig(v reflect.Value) (Config, bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	if v.CanAddr() {
+		if c, ok := v.Addr().Interface().(Config); ok {
+			return c, true
+		}
+	}
+	c, ok := v.Interface().(Config)
+	return c, ok
+}
@@ -6,6 +6,7 @@
 //
 // Default flags:
 //   - `--path`    (string): Sets the application’s default path (default: "./data")
+//   - `--config`  (string): Sets the configuration file, overriding the one in the path
 //   - `--help`    (bool): Displays the help message
 //   - `--version` (bool): Prints the application version
 //   - `--debug`   (bool): Enables debug mode
@@ -61,6 +62,8 @@ import (
 var (
 	// Path is the default path for the application data
 	Path string
+	// Config is the path of the configuration file, empty if it is derived from Path
+	Config string
 	// Help indicates whether the help message should be printed
 	Help bool
 	// Version indicates whether the version information should be printed
@@ -71,6 +74,7 @@ var (
 
 func init() {
 	pflag.StringVar(&Path, "path", "./data", "Sets the application working directory")
+	pflag.StringVar(&Config, "config", "", "Sets the configuration file, overriding the one in the working directory")
 	pflag.BoolVar(&Help, "help", false, "Prints the help page")
 	pflag.BoolVar(&Version, "version", false, "Prints the software version")
 	pflag.BoolVar(&Debug, "debug", false, "Enables debug mode")
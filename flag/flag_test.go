@@ -14,6 +14,10 @@ func TestDefaultFlags(t *testing.T) {
 		t.Errorf("Expected default Path to be './data', got '%s'", flag.Path)
 	}
 
+	if flag.Config != "" {
+		t.Errorf("Expected default Config to be empty, got '%s'", flag.Config)
+	}
+
 	if flag.Help != false {
 		t.Errorf("Expected default Help to be false, got %v", flag.Help)
 	}
@@ -13,8 +13,23 @@
 //
 //	logger := logging.GetGlobalAdapter()
 //	logger.Info().Str("key", "value").Msg("Application started")
+//
+//	// Attach request-scoped fields to a context and log with them
+//	ctx = logging.WithFields(ctx, logging.F("request_id", id))
+//	logging.FromContext(ctx).Info().Msg("request handled")
+//
+//	// Send warnings and errors to a rotating log file in addition to the adapter output
+//	logging.AddSink(logging.NewRotatingFileSink("app.log", 10, 28, 5, true), logging.WarnLevel)
+//
+//	// Only emit 1 in 100 identical debug messages of a noisy package
+//	logging.SetPackageSampler("queue", logging.Sample(100))
 package logging
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Level represents log levels
 type Level int
 
@@ -55,6 +70,30 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel parses a log level from its string representation as returned by Level.String
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return TraceLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	case "panic":
+		return PanicLevel, nil
+	case "disabled":
+		return DisabledLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
 // Adapter defines the interface for internal logging
 type Adapter interface {
 	// Level control
@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+)
+
+// contextKey is the type of the context keys used by this package
+type contextKey string
+
+const (
+	// contextKeyFields is the context key for request-scoped log fields
+	contextKeyFields contextKey = "logging.fields"
+	// contextKeyLogger is the context key for a request-scoped adapter
+	contextKeyLogger contextKey = "logging.logger"
+)
+
+// WithFields returns a copy of the context carrying the given fields in addition
+// to the fields already attached to it. Loggers retrieved with FromContext add
+// these fields to every event, e.g. request IDs or user names for correlation.
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	existing := FieldsFromContext(ctx)
+	merged := make([]Field, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, contextKeyFields, merged)
+}
+
+// WithLogger returns a copy of the context carrying the given adapter
+// It is returned by FromContext instead of the global adapter
+func WithLogger(ctx context.Context, adapter Adapter) context.Context {
+	return context.WithValue(ctx, contextKeyLogger, adapter)
+}
+
+// FieldsFromContext returns the fields attached to the context with WithFields
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextKeyFields).([]Field)
+	return fields
+}
+
+// FromContext returns a logger that adds the fields attached to the context to every event
+// It uses the adapter attached with WithLogger and falls back to the global adapter
+func FromContext(ctx context.Context) Adapter {
+	var adapter Adapter
+	if ctx != nil {
+		adapter, _ = ctx.Value(contextKeyLogger).(Adapter)
+	}
+	if adapter == nil {
+		adapter = GetGlobalAdapter()
+	}
+
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return adapter
+	}
+
+	return &ContextAdapter{adapter: adapter, fields: fields}
+}
+
+// ContextAdapter wraps an adapter and adds request-scoped fields to every event
+type ContextAdapter struct {
+	adapter Adapter
+	fields  []Field
+}
+
+// SetLevel sets the log level of the wrapped adapter
+func (c *ContextAdapter) SetLevel(level Level) Adapter {
+	c.adapter.SetLevel(level)
+	return c
+}
+
+// GetLevel returns the log level of the wrapped adapter
+func (c *ContextAdapter) GetLevel() Level {
+	return c.adapter.GetLevel()
+}
+
+// Trace returns a trace level event with the context fields
+func (c *ContextAdapter) Trace() Event {
+	return resolve(c.adapter).Trace().Fields(c.fields...)
+}
+
+// Debug returns a debug level event with the context fields
+func (c *ContextAdapter) Debug() Event {
+	return resolve(c.adapter).Debug().Fields(c.fields...)
+}
+
+// Info returns an info level event with the context fields
+func (c *ContextAdapter) Info() Event {
+	return resolve(c.adapter).Info().Fields(c.fields...)
+}
+
+// Warn returns a warn level event with the context fields
+func (c *ContextAdapter) Warn() Event {
+	return resolve(c.adapter).Warn().Fields(c.fields...)
+}
+
+// Error returns an error level event with the context fields
+func (c *ContextAdapter) Error() Event {
+	return resolve(c.adapter).Error().Fields(c.fields...)
+}
+
+// Fatal returns a fatal level event with the context fields
+func (c *ContextAdapter) Fatal() Event {
+	return resolve(c.adapter).Fatal().Fields(c.fields...)
+}
+
+// Panic returns a panic level event with the context fields
+func (c *ContextAdapter) Panic() Event {
+	return resolve(c.adapter).Panic().Fields(c.fields...)
+}
+
+// Printf logs a formatted message using the wrapped adapter
+func (c *ContextAdapter) Printf(format string, v ...interface{}) {
+	c.adapter.Printf(format, v...)
+}
+
+// WithPackage returns a new context adapter for the specified package
+func (c *ContextAdapter) WithPackage(pkg string) Adapter {
+	return &ContextAdapter{adapter: c.adapter.WithPackage(pkg), fields: c.fields}
+}
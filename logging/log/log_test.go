@@ -1,9 +1,20 @@
 package log_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
+	"github.com/valentin-kaiser/go-core/apperror"
 	"github.com/valentin-kaiser/go-core/logging"
 	"github.com/valentin-kaiser/go-core/logging/log"
 )
@@ -47,3 +58,238 @@ func TestLoggerSingleton(t *testing.T) {
 		log.Printf("formatted message: %s, number: %d", "test", 42)
 	})
 }
+
+func TestLoggedErrorIncludesStack(t *testing.T) {
+	apperror.SetCaptureStack(true)
+	defer apperror.SetCaptureStack(false)
+
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+
+	adapter.Error().Err(apperror.NewError("stacked error")).Msg("failed")
+	if !strings.Contains(buf.String(), "stack=") || !strings.Contains(buf.String(), "TestLoggedErrorIncludesStack") {
+		t.Errorf("Expected logged error to include the captured stack, got %q", buf.String())
+	}
+
+	buf.Reset()
+	adapter.Error().Err(errors.New("plain error")).Msg("failed")
+	if strings.Contains(buf.String(), "stack=") {
+		t.Errorf("Expected plain error to be logged without stack, got %q", buf.String())
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+
+	// Without fields or logger the global adapter is returned
+	logging.SetGlobalAdapter(adapter)
+	defer logging.SetGlobalAdapter(logging.NewNoOpAdapter())
+	if logging.FromContext(context.Background()) != adapter {
+		t.Error("Expected FromContext to fall back to the global adapter")
+	}
+
+	ctx := logging.WithFields(context.Background(), log.F("request_id", "abc123"))
+	ctx = logging.WithFields(ctx, log.F("user", "john"))
+
+	logging.FromContext(ctx).Info().Msg("handled request")
+	if !strings.Contains(buf.String(), "request_id=abc123") || !strings.Contains(buf.String(), "user=john") {
+		t.Errorf("Expected context fields in output, got %q", buf.String())
+	}
+
+	// A logger attached to the context takes precedence over the global adapter
+	var other bytes.Buffer
+	ctx = logging.WithLogger(ctx, logging.NewStandardAdapterWithLogger(stdlog.New(&other, "", 0)))
+	logging.FromContext(ctx).Info().Msg("handled request")
+	if !strings.Contains(other.String(), "request_id=abc123") {
+		t.Errorf("Expected context logger to receive the event with fields, got %q", other.String())
+	}
+
+	if len(logging.FieldsFromContext(context.Background())) != 0 {
+		t.Error("Expected no fields on a plain context")
+	}
+}
+
+func TestSinks(t *testing.T) {
+	defer logging.ClearSinks()
+
+	var debugSink, errorSink bytes.Buffer
+	logging.AddSink(&debugSink, logging.DebugLevel)
+	logging.AddSink(&errorSink, logging.ErrorLevel)
+
+	adapters := map[string]logging.Adapter{
+		"standard": logging.NewStandardAdapterWithLogger(stdlog.New(io.Discard, "", 0)),
+		"zerolog":  logging.NewZerologAdapterWithLogger(zerolog.New(io.Discard)),
+	}
+
+	for name, adapter := range adapters {
+		debugSink.Reset()
+		errorSink.Reset()
+		adapter.SetLevel(logging.TraceLevel)
+
+		adapter.Trace().Msg("trace event")
+		adapter.Info().Field("key", "value").Msg("info event")
+		adapter.Error().Err(errors.New("boom")).Msg("error event")
+
+		if strings.Contains(debugSink.String(), "trace event") {
+			t.Errorf("%s: expected debug sink to skip trace events, got %q", name, debugSink.String())
+		}
+		if !strings.Contains(debugSink.String(), "info event") || !strings.Contains(debugSink.String(), "error event") {
+			t.Errorf("%s: expected debug sink to receive info and error events, got %q", name, debugSink.String())
+		}
+		if strings.Contains(errorSink.String(), "info event") || !strings.Contains(errorSink.String(), "error event") {
+			t.Errorf("%s: expected error sink to receive only error events, got %q", name, errorSink.String())
+		}
+	}
+}
+
+func TestRotatingFileSink(t *testing.T) {
+	defer logging.ClearSinks()
+
+	filename := filepath.Join(t.TempDir(), "app.log")
+	file := logging.NewRotatingFileSink(filename, 1, 1, 1, false)
+	defer file.Close()
+
+	logging.AddSink(file, logging.InfoLevel)
+	logging.NewStandardAdapterWithLogger(stdlog.New(io.Discard, "", 0)).Info().Msg("written to file")
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "written to file") {
+		t.Errorf("Expected log file to contain the event, got %q", string(data))
+	}
+}
+
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+	adapter.SetLevel(logging.TraceLevel)
+
+	sampled := logging.WithSampler(adapter, logging.Sample(10))
+	for i := 0; i < 1000; i++ {
+		sampled.Debug().Field("attempt", i).Msg("retrying task")
+	}
+
+	emitted := strings.Count(buf.String(), "retrying task")
+	if emitted < 90 || emitted > 110 {
+		t.Errorf("Expected roughly 100 of 1000 debug events to be emitted, got %d", emitted)
+	}
+
+	// Different messages are sampled independently
+	buf.Reset()
+	sampled.Debug().Msg("first message")
+	sampled.Debug().Msg("second message")
+	if !strings.Contains(buf.String(), "first message") || !strings.Contains(buf.String(), "second message") {
+		t.Errorf("Expected the first occurrence of each message to be emitted, got %q", buf.String())
+	}
+
+	// Higher levels are never sampled
+	buf.Reset()
+	for i := 0; i < 10; i++ {
+		sampled.Warn().Msg("warning")
+	}
+	if emitted := strings.Count(buf.String(), "warning"); emitted != 10 {
+		t.Errorf("Expected all 10 warnings to be emitted, got %d", emitted)
+	}
+}
+
+func TestRateSampler(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+	adapter.SetLevel(logging.TraceLevel)
+
+	sampled := logging.WithSampler(adapter, logging.SampleRate(5, time.Hour))
+	for i := 0; i < 100; i++ {
+		sampled.Trace().Msgf("polling queue %d", i)
+	}
+
+	if emitted := strings.Count(buf.String(), "polling queue"); emitted != 5 {
+		t.Errorf("Expected 5 events within the period, got %d", emitted)
+	}
+}
+
+func TestPackageSampler(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+	adapter.SetLevel(logging.TraceLevel)
+
+	logging.SetGlobalAdapter(adapter)
+	defer logging.SetGlobalAdapter(logging.NewNoOpAdapter())
+
+	logging.SetPackageSampler("noisy", logging.Sample(4))
+	defer logging.SetPackageSampler("noisy", nil)
+
+	noisy := logging.GetPackageLogger("noisy")
+	quiet := logging.GetPackageLogger("quiet")
+	for i := 0; i < 100; i++ {
+		noisy.Debug().Msg("noisy event")
+		quiet.Debug().Msg("quiet event")
+	}
+
+	if emitted := strings.Count(buf.String(), "noisy event"); emitted != 25 {
+		t.Errorf("Expected 25 sampled noisy events, got %d", emitted)
+	}
+	if emitted := strings.Count(buf.String(), "quiet event"); emitted != 100 {
+		t.Errorf("Expected all 100 quiet events, got %d", emitted)
+	}
+}
+
+func TestSetLevelRuntime(t *testing.T) {
+	var buf bytes.Buffer
+	adapter := logging.NewStandardAdapterWithLogger(stdlog.New(&buf, "", 0))
+	logging.SetGlobalAdapter(adapter)
+	defer logging.SetGlobalAdapter(logging.NewNoOpAdapter())
+
+	pkg := logging.GetPackageLogger("runtime-level")
+	logging.SetPackageLevel("explicit-level", logging.ErrorLevel)
+	defer logging.EnablePackage("explicit-level")
+
+	logging.SetLevel(logging.WarnLevel)
+	pkg.Info().Msg("hidden info")
+	if strings.Contains(buf.String(), "hidden info") {
+		t.Errorf("Expected info event to be dropped at warn level, got %q", buf.String())
+	}
+
+	logging.SetLevel(logging.DebugLevel)
+	pkg.Debug().Msg("visible debug")
+	if !strings.Contains(buf.String(), "visible debug") {
+		t.Errorf("Expected debug event after raising verbosity, got %q", buf.String())
+	}
+	if level := logging.GetPackageLevel("explicit-level"); level != logging.DebugLevel {
+		t.Errorf("Expected package adapter level %v, got %v", logging.DebugLevel, level)
+	}
+
+	// Changing the level while logging must be race free
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pkg.Debug().Msg("concurrent")
+			}
+		}()
+	}
+	for _, level := range []logging.Level{logging.InfoLevel, logging.TraceLevel, logging.ErrorLevel, logging.DebugLevel} {
+		logging.SetLevel(level)
+	}
+	wg.Wait()
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []logging.Level{logging.TraceLevel, logging.DebugLevel, logging.InfoLevel, logging.WarnLevel, logging.ErrorLevel, logging.DisabledLevel} {
+		parsed, err := logging.ParseLevel(strings.ToUpper(level.String()))
+		if err != nil {
+			t.Errorf("ParseLevel(%q) error = %v", level.String(), err)
+		}
+		if parsed != level {
+			t.Errorf("ParseLevel(%q) = %v, expected %v", level.String(), parsed, level)
+		}
+	}
+
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("Expected ParseLevel to fail for unknown level")
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"sync"
@@ -62,12 +63,20 @@ func (d *DynamicAdapter) GetLevel() Level {
 }
 
 // Trace returns a trace level event from the current active adapter.
+// The event is sampled if a sampler is set for the package.
 func (d *DynamicAdapter) Trace() Event {
+	if sampler := packageSampler(d.pkg); sampler != nil {
+		return &SampledEvent{event: d.current().Trace(), level: TraceLevel, sampler: sampler}
+	}
 	return d.current().Trace()
 }
 
 // Debug returns a debug level event from the current active adapter.
+// The event is sampled if a sampler is set for the package.
 func (d *DynamicAdapter) Debug() Event {
+	if sampler := packageSampler(d.pkg); sampler != nil {
+		return &SampledEvent{event: d.current().Debug(), level: DebugLevel, sampler: sampler}
+	}
 	return d.current().Debug()
 }
 
@@ -150,7 +159,7 @@ func SetPackageLevel(pkg string, level Level) {
 	var newAdapter Adapter
 	switch adapter := global.(type) {
 	case *ZerologAdapter:
-		newAdapter = NewZerologAdapterWithLogger(adapter.logger)
+		newAdapter = NewZerologAdapterWithLogger(*adapter.logger.Load())
 	case *StandardAdapter:
 		newAdapter = NewStandardAdapterWithLogger(adapter.logger)
 	default:
@@ -162,6 +171,22 @@ func SetPackageLevel(pkg string, level Level) {
 	SetPackageAdapter(pkg, newAdapter.WithPackage(pkg))
 }
 
+// SetLevel sets the log level of the global adapter and of all package-specific adapters
+// It is safe for concurrent use and is honored by all package loggers immediately,
+// which allows changing the verbosity at runtime, e.g. from a config.OnChange callback
+func SetLevel(level Level) {
+	mu.RLock()
+	global.SetLevel(level)
+	mu.RUnlock()
+
+	packages.Range(func(_, adapter interface{}) bool {
+		if a, ok := adapter.(Adapter); ok {
+			a.SetLevel(level)
+		}
+		return true
+	})
+}
+
 // GetPackageLevel returns the log level for a specific package
 func GetPackageLevel(pkg string) Level {
 	return GetPackageLogger(pkg).GetLevel()
@@ -194,6 +219,15 @@ func (d *DynamicAdapter) current() Adapter {
 	return global.WithPackage(d.pkg)
 }
 
+// resolve returns the active adapter behind a dynamic adapter so that wrapping
+// adapters do not add a frame and caller tracking reports the correct caller
+func resolve(adapter Adapter) Adapter {
+	if d, ok := adapter.(*DynamicAdapter); ok {
+		return d.current()
+	}
+	return adapter
+}
+
 func track() string {
 	pc, file, line, ok := runtime.Caller(3)
 	if !ok {
@@ -208,3 +242,27 @@ func track() string {
 
 	return fmt.Sprintf("%s:%d", file, line)
 }
+
+// stackTracer is implemented by errors carrying the call stack of their creation, e.g. apperror.Error
+type stackTracer interface {
+	Stack() []runtime.Frame
+}
+
+// stack returns the formatted call stack of the error if it carries one
+func stack(err error) []string {
+	var st stackTracer
+	if err == nil || !errors.As(err, &st) {
+		return nil
+	}
+
+	frames := st.Stack()
+	if len(frames) == 0 {
+		return nil
+	}
+
+	s := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		s = append(s, fmt.Sprintf("%s:%d (%s)", frame.File, frame.Line, frame.Function))
+	}
+	return s
+}
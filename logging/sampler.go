@@ -0,0 +1,206 @@
+package logging
+
+import (
+	"sync"
+	"time"
+)
+
+// samplers stores the package-specific samplers
+var samplers sync.Map
+
+// Sampler decides whether a trace or debug event is emitted
+// The key is the message or, for Msgf, the format string of the event
+type Sampler interface {
+	Sample(level Level, key string) bool
+}
+
+// Sample returns a sampler that emits 1 in n events with the same message
+// The first occurrence of every message is always emitted
+func Sample(n uint32) Sampler {
+	return &countSampler{n: n, counts: make(map[string]uint32)}
+}
+
+// SampleRate returns a sampler that emits at most burst events with the same message per period
+func SampleRate(burst uint32, period time.Duration) Sampler {
+	return &rateSampler{burst: burst, period: period, windows: make(map[string]*rateWindow)}
+}
+
+// countSampler emits 1 in n events per message key
+type countSampler struct {
+	n      uint32
+	mu     sync.Mutex
+	counts map[string]uint32
+}
+
+// Sample implements the Sampler interface
+func (s *countSampler) Sample(_ Level, key string) bool {
+	if s.n <= 1 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.counts[key]
+	s.counts[key] = (count + 1) % s.n
+	return count == 0
+}
+
+// rateSampler emits a limited number of events per message key and period
+type rateSampler struct {
+	burst   uint32
+	period  time.Duration
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow tracks the events of a message key in the current period
+type rateWindow struct {
+	start time.Time
+	count uint32
+}
+
+// Sample implements the Sampler interface
+func (s *rateSampler) Sample(_ Level, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	window, ok := s.windows[key]
+	if !ok || now.Sub(window.start) >= s.period {
+		s.windows[key] = &rateWindow{start: now, count: 1}
+		return s.burst > 0
+	}
+
+	window.count++
+	return window.count <= s.burst
+}
+
+// WithSampler wraps the adapter so that its trace and debug events are sampled
+// Events of higher levels are always emitted
+func WithSampler(adapter Adapter, sampler Sampler) Adapter {
+	return &SampledAdapter{adapter: adapter, sampler: sampler}
+}
+
+// SetPackageSampler sets a sampler for the trace and debug events of a package logger
+// Passing nil removes the sampler
+func SetPackageSampler(pkg string, sampler Sampler) {
+	if sampler == nil {
+		samplers.Delete(pkg)
+		return
+	}
+	samplers.Store(pkg, sampler)
+}
+
+// packageSampler returns the sampler of a package or nil if none is set
+func packageSampler(pkg string) Sampler {
+	if s, ok := samplers.Load(pkg); ok {
+		sampler, ok := s.(Sampler)
+		if ok {
+			return sampler
+		}
+	}
+	return nil
+}
+
+// SampledAdapter wraps an adapter and samples its trace and debug events
+type SampledAdapter struct {
+	adapter Adapter
+	sampler Sampler
+}
+
+// SetLevel sets the log level of the wrapped adapter
+func (s *SampledAdapter) SetLevel(level Level) Adapter {
+	s.adapter.SetLevel(level)
+	return s
+}
+
+// GetLevel returns the log level of the wrapped adapter
+func (s *SampledAdapter) GetLevel() Level {
+	return s.adapter.GetLevel()
+}
+
+// Trace returns a sampled trace level event
+func (s *SampledAdapter) Trace() Event {
+	return &SampledEvent{event: resolve(s.adapter).Trace(), level: TraceLevel, sampler: s.sampler}
+}
+
+// Debug returns a sampled debug level event
+func (s *SampledAdapter) Debug() Event {
+	return &SampledEvent{event: resolve(s.adapter).Debug(), level: DebugLevel, sampler: s.sampler}
+}
+
+// Info returns an info level event from the wrapped adapter
+func (s *SampledAdapter) Info() Event {
+	return resolve(s.adapter).Info()
+}
+
+// Warn returns a warn level event from the wrapped adapter
+func (s *SampledAdapter) Warn() Event {
+	return resolve(s.adapter).Warn()
+}
+
+// Error returns an error level event from the wrapped adapter
+func (s *SampledAdapter) Error() Event {
+	return resolve(s.adapter).Error()
+}
+
+// Fatal returns a fatal level event from the wrapped adapter
+func (s *SampledAdapter) Fatal() Event {
+	return resolve(s.adapter).Fatal()
+}
+
+// Panic returns a panic level event from the wrapped adapter
+func (s *SampledAdapter) Panic() Event {
+	return resolve(s.adapter).Panic()
+}
+
+// Printf logs a formatted message using the wrapped adapter
+func (s *SampledAdapter) Printf(format string, v ...interface{}) {
+	s.adapter.Printf(format, v...)
+}
+
+// WithPackage returns a new sampled adapter for the specified package
+func (s *SampledAdapter) WithPackage(pkg string) Adapter {
+	return &SampledAdapter{adapter: s.adapter.WithPackage(pkg), sampler: s.sampler}
+}
+
+// SampledEvent wraps an event and only emits it if the sampler allows it
+type SampledEvent struct {
+	event   Event
+	level   Level
+	sampler Sampler
+}
+
+// Fields adds structured fields to the wrapped event
+func (e *SampledEvent) Fields(fields ...Field) Event {
+	e.event = e.event.Fields(fields...)
+	return e
+}
+
+// Field adds a single structured field to the wrapped event
+func (e *SampledEvent) Field(key string, value interface{}) Event {
+	e.event = e.event.Field(key, value)
+	return e
+}
+
+// Err adds an error to the wrapped event
+func (e *SampledEvent) Err(err error) Event {
+	e.event = e.event.Err(err)
+	return e
+}
+
+// Msg logs the message if the sampler allows it
+func (e *SampledEvent) Msg(msg string) {
+	if !e.sampler.Sample(e.level, msg) {
+		return
+	}
+	e.event.Msg(msg)
+}
+
+// Msgf logs the formatted message if the sampler allows it, the format is used as sampling key
+func (e *SampledEvent) Msgf(format string, v ...interface{}) {
+	if !e.sampler.Sample(e.level, format) {
+		return
+	}
+	e.event.Msgf(format, v...)
+}
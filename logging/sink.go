@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var (
+	// sinks stores the additional outputs receiving log events
+	sinks []sink
+	// sinksMu protects the sinks registry
+	sinksMu sync.RWMutex
+	// writeMu serializes writes across all sinks
+	writeMu sync.Mutex
+)
+
+// sink is an output receiving log events at or above its level
+type sink struct {
+	writer io.Writer
+	level  Level
+}
+
+// AddSink registers an additional output that receives all log events at or above the given level
+// Sinks are shared by all adapters, writes to them are serialized so that a writer
+// never receives interleaved events. Events must also pass the level of the adapter.
+func AddSink(w io.Writer, level Level) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks = append(sinks, sink{writer: w, level: level})
+}
+
+// ClearSinks removes all registered sinks
+// Sinks implementing io.Closer are not closed, this is left to the caller
+func ClearSinks() {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks = nil
+}
+
+// NewRotatingFileSink creates a file writer that rotates the file once it reaches maxSize megabytes
+// Rotated files are removed after maxAge days or when more than maxBackups files exist,
+// a value of zero keeps them forever. Rotated files are compressed if compress is set.
+func NewRotatingFileSink(filename string, maxSize, maxAge, maxBackups int, compress bool) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    maxSize,
+		MaxAge:     maxAge,
+		MaxBackups: maxBackups,
+		Compress:   compress,
+	}
+}
+
+// hasSinks reports whether any registered sink accepts events of the given level
+func hasSinks(level Level) bool {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	for _, s := range sinks {
+		if level >= s.level {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSinks writes a serialized event to all sinks accepting the given level
+func writeSinks(level Level, p []byte) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	for _, s := range sinks {
+		if level < s.level {
+			continue
+		}
+		// A failing sink must not prevent the others from receiving the event
+		_, _ = s.writer.Write(p)
+	}
+}
+
+// sinkWriter dispatches zerolog output to the registered sinks
+type sinkWriter struct{}
+
+// Write writes an event without level information to all sinks
+func (sinkWriter) Write(p []byte) (int, error) {
+	writeSinks(PanicLevel, p)
+	return len(p), nil
+}
+
+// WriteLevel writes an event to all sinks accepting its level
+func (sinkWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	writeSinks(fromZerologLevel(level), p)
+	return len(p), nil
+}
+
+// fromZerologLevel converts a zerolog.Level to our Level
+func fromZerologLevel(level zerolog.Level) Level {
+	switch level {
+	case zerolog.TraceLevel:
+		return TraceLevel
+	case zerolog.DebugLevel:
+		return DebugLevel
+	case zerolog.InfoLevel:
+		return InfoLevel
+	case zerolog.WarnLevel:
+		return WarnLevel
+	case zerolog.ErrorLevel:
+		return ErrorLevel
+	case zerolog.FatalLevel:
+		return FatalLevel
+	case zerolog.PanicLevel:
+		return PanicLevel
+	case zerolog.Disabled:
+		return DisabledLevel
+	default:
+		return InfoLevel
+	}
+}
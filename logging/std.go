@@ -4,29 +4,31 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
 )
 
 // StandardAdapter implements LogAdapter using Go's standard log package
 type StandardAdapter struct {
 	logger *log.Logger
-	level  Level
+	level  atomic.Int32
 	pkg    string
 }
 
 // NewStandardAdapter creates a new standard log adapter with the default logger
 func NewStandardAdapter() Adapter {
-	return &StandardAdapter{
-		logger: log.Default(),
-		level:  InfoLevel,
-	}
+	return newStandardAdapter(log.Default(), InfoLevel, "")
 }
 
 // NewStandardAdapterWithLogger creates a new standard log adapter with a custom logger
 func NewStandardAdapterWithLogger(logger *log.Logger) Adapter {
-	return &StandardAdapter{
-		logger: logger,
-		level:  InfoLevel,
-	}
+	return newStandardAdapter(logger, InfoLevel, "")
+}
+
+// newStandardAdapter creates a standard log adapter with the given logger, level and package
+func newStandardAdapter(logger *log.Logger, level Level, pkg string) *StandardAdapter {
+	s := &StandardAdapter{logger: logger, pkg: pkg}
+	s.level.Store(int32(level))
+	return s
 }
 
 // StandardEvent wraps standard log functionality to implement our Event interface
@@ -51,8 +53,12 @@ func (e *StandardEvent) Field(key string, value interface{}) Event {
 }
 
 // Err adds an error to the event
+// If the error carries a captured call stack it is added as the stack field
 func (e *StandardEvent) Err(err error) Event {
 	e.err = err
+	if s := stack(err); len(s) > 0 {
+		e.fields = append(e.fields, Field{Key: "stack", Value: s})
+	}
 	return e
 }
 
@@ -63,6 +69,9 @@ func (e *StandardEvent) Msg(msg string) {
 	}
 
 	logMsg := e.formatMessage(msg)
+	if hasSinks(e.level) {
+		writeSinks(e.level, []byte(logMsg+"\n"))
+	}
 
 	switch e.level {
 	case FatalLevel:
@@ -81,7 +90,7 @@ func (e *StandardEvent) Msgf(format string, v ...interface{}) {
 
 // shouldLog checks if the event should be logged based on the level
 func (e *StandardEvent) shouldLog() bool {
-	return e.level >= e.adapter.level
+	return e.level >= e.adapter.GetLevel()
 }
 
 // formatMessage formats the message with level, fields, and error
@@ -117,14 +126,15 @@ func (e *StandardEvent) formatMessage(msg string) string {
 }
 
 // SetLevel sets the log level
+// It is safe to call while other goroutines are logging
 func (s *StandardAdapter) SetLevel(level Level) Adapter {
-	s.level = level
+	s.level.Store(int32(level))
 	return s
 }
 
 // GetLevel returns the current log level
 func (s *StandardAdapter) GetLevel() Level {
-	return s.level
+	return Level(s.level.Load())
 }
 
 // Trace returns a trace level event
@@ -197,9 +207,5 @@ func (s *StandardAdapter) Printf(format string, v ...interface{}) {
 
 // WithPackage returns a new adapter with package name field
 func (s *StandardAdapter) WithPackage(pkg string) Adapter {
-	return &StandardAdapter{
-		logger: s.logger,
-		level:  s.level,
-		pkg:    pkg,
-	}
+	return newStandardAdapter(s.logger, s.GetLevel(), pkg)
 }
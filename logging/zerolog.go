@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"sync/atomic"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -8,12 +10,17 @@ import (
 // ZerologEvent wraps zerolog.Event to implement our Event interface
 type ZerologEvent struct {
 	event *zerolog.Event
+	// sink is the mirrored event written to the registered sinks, nil if none accept the level
+	sink *zerolog.Event
 }
 
 // Fields adds structured fields to the event
 func (e *ZerologEvent) Fields(fields ...Field) Event {
 	for _, field := range fields {
 		e.event = e.event.Interface(field.Key, field.Value)
+		if e.sink != nil {
+			e.sink = e.sink.Interface(field.Key, field.Value)
+		}
 	}
 	return e
 }
@@ -21,57 +28,81 @@ func (e *ZerologEvent) Fields(fields ...Field) Event {
 // Field adds a single structured field to the event
 func (e *ZerologEvent) Field(key string, value interface{}) Event {
 	e.event = e.event.Interface(key, value)
+	if e.sink != nil {
+		e.sink = e.sink.Interface(key, value)
+	}
 	return e
 }
 
 // Err adds an error to the event
+// If the error carries a captured call stack it is added as the stack field
 func (e *ZerologEvent) Err(err error) Event {
 	e.event = e.event.Err(err)
+	if e.sink != nil {
+		e.sink = e.sink.Err(err)
+	}
+	if s := stack(err); len(s) > 0 {
+		e.event = e.event.Strs("stack", s)
+		if e.sink != nil {
+			e.sink = e.sink.Strs("stack", s)
+		}
+	}
 	return e
 }
 
 // Msg logs the message
 func (e *ZerologEvent) Msg(msg string) {
+	if e.sink != nil {
+		e.sink.Msg(msg)
+	}
 	e.event.Msg(msg)
 }
 
 // Msgf logs the formatted message
 func (e *ZerologEvent) Msgf(format string, v ...interface{}) {
+	if e.sink != nil {
+		e.sink.Msgf(format, v...)
+	}
 	e.event.Msgf(format, v...)
 }
 
 // ZerologAdapter implements LogAdapter using zerolog
 type ZerologAdapter struct {
-	logger zerolog.Logger
-	level  Level
+	logger atomic.Pointer[zerolog.Logger]
+	level  atomic.Int32
+	pkg    string
 }
 
 // NewZerologAdapter creates a new zerolog adapter with the global zerolog logger
 func NewZerologAdapter() Adapter {
-	return &ZerologAdapter{
-		logger: log.Logger,
-		level:  InfoLevel,
-	}
+	return newZerologAdapter(log.Logger, InfoLevel, "")
 }
 
 // NewZerologAdapterWithLogger creates a new zerolog adapter with a custom logger
 func NewZerologAdapterWithLogger(logger zerolog.Logger) Adapter {
-	return &ZerologAdapter{
-		logger: logger,
-		level:  InfoLevel,
-	}
+	return newZerologAdapter(logger, InfoLevel, "")
+}
+
+// newZerologAdapter creates a zerolog adapter with the given logger, level and package
+func newZerologAdapter(logger zerolog.Logger, level Level, pkg string) *ZerologAdapter {
+	z := &ZerologAdapter{pkg: pkg}
+	z.logger.Store(&logger)
+	z.level.Store(int32(level))
+	return z
 }
 
 // SetLevel sets the log level
+// It is safe to call while other goroutines are logging
 func (z *ZerologAdapter) SetLevel(level Level) Adapter {
-	z.level = level
-	z.logger = z.logger.Level(z.convertLevel(level))
+	logger := z.logger.Load().Level(z.convertLevel(level))
+	z.logger.Store(&logger)
+	z.level.Store(int32(level))
 	return z
 }
 
 // GetLevel returns the current log level
 func (z *ZerologAdapter) GetLevel() Level {
-	return z.level
+	return Level(z.level.Load())
 }
 
 // convertLevel converts our Level to zerolog.Level
@@ -100,7 +131,7 @@ func (z *ZerologAdapter) convertLevel(level Level) zerolog.Level {
 
 // Trace returns a trace level event
 func (z *ZerologAdapter) Trace() Event {
-	e := &ZerologEvent{event: z.logger.Trace()}
+	e := &ZerologEvent{event: z.logger.Load().Trace(), sink: z.sink(TraceLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -109,7 +140,7 @@ func (z *ZerologAdapter) Trace() Event {
 
 // Debug returns a debug level event
 func (z *ZerologAdapter) Debug() Event {
-	e := &ZerologEvent{event: z.logger.Debug()}
+	e := &ZerologEvent{event: z.logger.Load().Debug(), sink: z.sink(DebugLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -118,7 +149,7 @@ func (z *ZerologAdapter) Debug() Event {
 
 // Info returns an info level event
 func (z *ZerologAdapter) Info() Event {
-	e := &ZerologEvent{event: z.logger.Info()}
+	e := &ZerologEvent{event: z.logger.Load().Info(), sink: z.sink(InfoLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -127,7 +158,7 @@ func (z *ZerologAdapter) Info() Event {
 
 // Warn returns a warning level event
 func (z *ZerologAdapter) Warn() Event {
-	e := &ZerologEvent{event: z.logger.Warn()}
+	e := &ZerologEvent{event: z.logger.Load().Warn(), sink: z.sink(WarnLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -136,7 +167,7 @@ func (z *ZerologAdapter) Warn() Event {
 
 // Error returns an error level event
 func (z *ZerologAdapter) Error() Event {
-	e := &ZerologEvent{event: z.logger.Error()}
+	e := &ZerologEvent{event: z.logger.Load().Error(), sink: z.sink(ErrorLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -145,7 +176,7 @@ func (z *ZerologAdapter) Error() Event {
 
 // Fatal returns a fatal level event
 func (z *ZerologAdapter) Fatal() Event {
-	e := &ZerologEvent{event: z.logger.Fatal()}
+	e := &ZerologEvent{event: z.logger.Load().Fatal(), sink: z.sink(FatalLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -154,7 +185,7 @@ func (z *ZerologAdapter) Fatal() Event {
 
 // Panic returns a panic level event
 func (z *ZerologAdapter) Panic() Event {
-	e := &ZerologEvent{event: z.logger.Panic()}
+	e := &ZerologEvent{event: z.logger.Load().Panic(), sink: z.sink(PanicLevel)}
 	if debug {
 		return e.Field("caller", track())
 	}
@@ -163,13 +194,25 @@ func (z *ZerologAdapter) Panic() Event {
 
 // Printf logs a formatted message using the underlying zerolog logger.
 func (z *ZerologAdapter) Printf(format string, v ...interface{}) {
-	z.logger.Printf(format, v...)
+	z.logger.Load().Printf(format, v...)
 }
 
 // WithPackage returns a new adapter with package name field
 func (z *ZerologAdapter) WithPackage(pkg string) Adapter {
-	return &ZerologAdapter{
-		logger: z.logger.With().Str("package", pkg).Logger(),
-		level:  z.level,
+	return newZerologAdapter(z.logger.Load().With().Str("package", pkg).Logger(), z.GetLevel(), pkg)
+}
+
+// sink creates the mirrored event for the registered sinks
+// It returns nil if the level is disabled or no sink accepts it
+func (z *ZerologAdapter) sink(level Level) *zerolog.Event {
+	if level < z.GetLevel() || !hasSinks(level) {
+		return nil
+	}
+
+	ctx := zerolog.New(sinkWriter{}).With().Timestamp()
+	if z.pkg != "" {
+		ctx = ctx.Str("package", z.pkg)
 	}
+	logger := ctx.Logger()
+	return logger.WithLevel(z.convertLevel(level))
 }
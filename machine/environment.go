@@ -0,0 +1,94 @@
+package machine
+
+import (
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// EnvType describes where the process runs
+type EnvType string
+
+const (
+	// EnvBareMetal means no hypervisor or container runtime was detected
+	EnvBareMetal EnvType = "bare-metal"
+	// EnvVirtualMachine means the process runs in a virtual machine
+	EnvVirtualMachine EnvType = "vm"
+	// EnvContainer means the process runs in a container
+	EnvContainer EnvType = "container"
+)
+
+// Env describes the execution environment of the process.
+// Hardware facts and therefore the machine ID behave differently in virtual machines
+// and containers, e.g. MAC addresses and disk serials change when a VM is recreated.
+type Env struct {
+	// Type is the kind of environment, a container takes precedence over a virtual machine
+	Type EnvType `json:"type"`
+	// Hypervisor is the detected hypervisor (e.g. "kvm", "vmware", "hyper-v"), if any
+	Hypervisor string `json:"hypervisor,omitempty"`
+	// Container is the detected container runtime (e.g. "docker", "podman", "kubernetes"), if any
+	Container string `json:"container,omitempty"`
+}
+
+// IsVirtual reports whether the process runs in a virtual machine or a container
+func (e Env) IsVirtual() bool {
+	return e.Type != EnvBareMetal
+}
+
+// Environment detects whether the process runs on bare metal, in a virtual machine or in a container.
+// The hypervisor is detected from DMI/SMBIOS data and the CPUID hypervisor flag, containers from
+// runtime marker files and control groups. The detection is heuristic and reports bare metal
+// if no indication of virtualization was found.
+func Environment() (Env, error) {
+	env, err := detectEnvironment()
+	if err != nil {
+		return Env{}, apperror.NewError("failed to detect execution environment").AddError(err)
+	}
+
+	switch {
+	case env.Container != "":
+		env.Type = EnvContainer
+	case env.Hypervisor != "":
+		env.Type = EnvVirtualMachine
+	default:
+		env.Type = EnvBareMetal
+	}
+	return env, nil
+}
+
+// hypervisorVendors maps vendor and product strings reported by the firmware to hypervisor names
+var hypervisorVendors = []struct {
+	match      string
+	hypervisor string
+}{
+	{"kvm", "kvm"},
+	{"qemu", "qemu"},
+	{"vmware", "vmware"},
+	{"virtualbox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"xen", "xen"},
+	{"parallels", "parallels"},
+	{"bhyve", "bhyve"},
+	{"bochs", "bochs"},
+	{"amazon ec2", "aws"},
+	{"google compute engine", "gce"},
+	{"openstack", "openstack"},
+	{"virtual machine", "hyper-v"},
+	{"virtualmac", "apple"},
+}
+
+// matchHypervisor returns the hypervisor identified by the first matching firmware value
+func matchHypervisor(values ...string) string {
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if value == "" {
+			continue
+		}
+		for _, vendor := range hypervisorVendors {
+			if strings.Contains(value, vendor.match) {
+				return vendor.hypervisor
+			}
+		}
+	}
+	return ""
+}
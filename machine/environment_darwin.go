@@ -0,0 +1,35 @@
+//go:build darwin
+
+package machine
+
+import (
+	"strings"
+)
+
+// detectEnvironment detects the hypervisor on macOS, containers are not supported natively
+func detectEnvironment() (Env, error) {
+	model, err := sysctl("hw.model")
+	if err != nil {
+		return Env{}, err
+	}
+
+	env := Env{Hypervisor: matchHypervisor(model)}
+	if env.Hypervisor != "" {
+		return env, nil
+	}
+
+	// kern.hv_vmm_present is set if the kernel runs under a hypervisor, Intel CPUs also report the VMM feature
+	if present, err := sysctl("kern.hv_vmm_present"); err == nil && present == "1" {
+		env.Hypervisor = "unknown"
+		return env, nil
+	}
+	if features, err := sysctl("machdep.cpu.features"); err == nil {
+		for _, feature := range strings.Fields(features) {
+			if feature == "VMM" {
+				env.Hypervisor = "unknown"
+				break
+			}
+		}
+	}
+	return env, nil
+}
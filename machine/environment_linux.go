@@ -0,0 +1,110 @@
+//go:build linux
+
+package machine
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// detectEnvironment detects the hypervisor and container runtime on Linux
+func detectEnvironment() (Env, error) {
+	cgroup, cgroupErr := os.ReadFile("/proc/self/cgroup")
+	cpuinfo, cpuinfoErr := os.ReadFile("/proc/cpuinfo")
+	if cgroupErr != nil && cpuinfoErr != nil {
+		return Env{}, fmt.Errorf("procfs is not available: %w", cpuinfoErr)
+	}
+
+	return Env{
+		Hypervisor: getLinuxHypervisor(string(cpuinfo)),
+		Container:  getLinuxContainer(string(cgroup)),
+	}, nil
+}
+
+// getLinuxHypervisor detects the hypervisor from DMI data, Xen and WSL markers and the CPUID hypervisor flag
+func getLinuxHypervisor(cpuinfo string) string {
+	var values []string
+	for _, name := range []string{"product_name", "sys_vendor", "board_vendor", "bios_vendor"} {
+		if data, err := os.ReadFile("/sys/class/dmi/id/" + name); err == nil {
+			values = append(values, strings.TrimSpace(string(data)))
+		}
+	}
+	if hypervisor := matchHypervisor(values...); hypervisor != "" {
+		return hypervisor
+	}
+
+	if data, err := os.ReadFile("/sys/hypervisor/type"); err == nil {
+		if hypervisor := strings.TrimSpace(string(data)); hypervisor != "" {
+			return hypervisor
+		}
+	}
+
+	// WSL 2 runs a Microsoft kernel in a Hyper-V utility VM without DMI data
+	if data, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil && strings.Contains(strings.ToLower(string(data)), "microsoft") {
+		return "hyper-v"
+	}
+
+	// The kernel exposes the CPUID hypervisor present bit as cpu flag
+	if hasCPUFlag(cpuinfo, "hypervisor") {
+		return "unknown"
+	}
+	return ""
+}
+
+// hasCPUFlag reports whether the first processor in /proc/cpuinfo content has the flag
+func hasCPUFlag(cpuinfo, flag string) bool {
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != "flags" {
+			continue
+		}
+		for _, f := range strings.Fields(parts[1]) {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// getLinuxContainer detects the container runtime from marker files, environment variables and control groups
+func getLinuxContainer(cgroup string) string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+
+	// systemd-nspawn, LXC and podman set the container variable for the init process
+	if runtime := os.Getenv("container"); runtime != "" {
+		return runtime
+	}
+	if data, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, entry := range strings.Split(string(data), "\x00") {
+			if runtime, ok := strings.CutPrefix(entry, "container="); ok && runtime != "" {
+				return runtime
+			}
+		}
+	}
+
+	// cgroup v1 paths and some cgroup v2 setups contain the runtime name
+	switch {
+	case strings.Contains(cgroup, "kubepods"):
+		return "kubernetes"
+	case strings.Contains(cgroup, "docker"):
+		return "docker"
+	case strings.Contains(cgroup, "libpod"):
+		return "podman"
+	case strings.Contains(cgroup, "lxc"):
+		return "lxc"
+	case strings.Contains(cgroup, "containerd"):
+		return "containerd"
+	}
+	return ""
+}
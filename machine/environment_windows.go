@@ -0,0 +1,27 @@
+//go:build windows
+
+package machine
+
+import (
+	"os"
+	"strings"
+)
+
+// detectEnvironment detects the hypervisor and Windows containers
+func detectEnvironment() (Env, error) {
+	manufacturer, err := wmic("Manufacturer=", "computersystem", "get", "Manufacturer")
+	if err != nil {
+		return Env{}, err
+	}
+	model := valueIfValid(func() (string, error) { return wmic("Model=", "computersystem", "get", "Model") })
+	bios := valueIfValid(func() (string, error) { return wmic("Manufacturer=", "bios", "get", "Manufacturer") })
+
+	env := Env{Hypervisor: matchHypervisor(model, manufacturer, bios)}
+
+	// Windows containers run the process as one of the built-in container accounts
+	switch strings.ToLower(os.Getenv("USERNAME")) {
+	case "containeradministrator", "containeruser":
+		env.Container = "windows"
+	}
+	return env, nil
+}
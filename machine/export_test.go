@@ -0,0 +1,4 @@
+package machine
+
+// IsVirtualInterface exposes isVirtualInterface to the tests
+var IsVirtualInterface = isVirtualInterface
@@ -0,0 +1,172 @@
+package machine
+
+import (
+	"sort"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Components of the hardware identifiers, reported by Collection
+const (
+	ComponentCPU         = "cpu"
+	ComponentSystemUUID  = "system_uuid"
+	ComponentMachineID   = "machine_id"
+	ComponentMotherboard = "motherboard"
+	ComponentMAC         = "mac"
+	ComponentDisk        = "disk"
+)
+
+// HardwareFacts holds the raw hardware information collected from the machine.
+// The fingerprint returned by ID is a hash over the identifying facts.
+// CPUModel, CPUCount and TotalMemory are informational and are not part of the hash.
+type HardwareFacts struct {
+	// CPUModel is the human readable CPU model name
+	CPUModel string `json:"cpu_model,omitempty"`
+	// CPUCount is the number of logical processors
+	CPUCount int `json:"cpu_count,omitempty"`
+	// CPUID is the platform specific CPU identifier used for fingerprinting
+	CPUID string `json:"cpu_id,omitempty"`
+	// TotalMemory is the total physical memory in bytes
+	TotalMemory uint64 `json:"total_memory,omitempty"`
+	// SystemUUID is the board UUID reported by the BIOS/UEFI
+	SystemUUID string `json:"system_uuid,omitempty"`
+	// MachineID is the operating system machine ID (e.g. systemd machine-id), if available
+	MachineID string `json:"machine_id,omitempty"`
+	// MotherboardSerial is the serial number of the motherboard or system
+	MotherboardSerial string `json:"motherboard_serial,omitempty"`
+	// MACAddresses are the MAC addresses of the network interfaces
+	MACAddresses []string `json:"mac_addresses,omitempty"`
+	// DiskSerials are the serial numbers of the disks
+	DiskSerials []string `json:"disk_serials,omitempty"`
+}
+
+// Facts collects all hardware facts of the current machine.
+// Virtual network interfaces are excluded from the MAC addresses.
+func Facts() (*HardwareFacts, error) {
+	return New().WithCPU().WithMotherboard().WithSystemUUID().WithMAC(false).WithDisk().Facts()
+}
+
+// Collection is the result of collecting the hardware identifiers enabled on a generator
+type Collection struct {
+	// Identifiers are the prefixed identifiers the machine ID is hashed from
+	Identifiers []string `json:"identifiers"`
+	// Components are the components that contributed identifiers, e.g. ComponentCPU
+	Components []string `json:"components"`
+	// Failed maps the components that could not be collected to their error
+	Failed map[string]error `json:"-"`
+}
+
+// Facts collects the hardware facts enabled on the generator.
+// CPU model, CPU count and total memory are always collected.
+// Facts that cannot be collected are left empty.
+func (g *generator) Facts() (*HardwareFacts, error) {
+	facts, err := collectFacts(g, collector{})
+	if err != nil {
+		return nil, apperror.NewError("failed to collect hardware facts").AddError(err)
+	}
+	return facts, nil
+}
+
+// Collect gathers the hardware identifiers enabled on the generator. A component that fails,
+// e.g. because reading the disk serials requires permissions, does not abort the collection.
+// The collection contains the identifiers of the other components and the error lists the
+// failed components, so ID can still derive a best-effort machine ID.
+//
+// Example:
+//
+//	collection, err := machine.New().WithCPU().WithSystemUUID().WithDisk().Collect()
+//	if err != nil {
+//		logger.Warn().Err(err).Field("components", collection.Components).Msg("machine ID is incomplete")
+//	}
+func (g *generator) Collect() (*Collection, error) {
+	failed := collector{}
+	facts, err := collectFacts(g, failed)
+	if err != nil {
+		return nil, apperror.NewError("failed to collect hardware identifiers").AddError(err)
+	}
+
+	collection := facts.collection(g)
+	if len(failed) == 0 {
+		return collection, nil
+	}
+
+	collection.Failed = failed
+	components := make([]string, 0, len(failed))
+	for component := range failed {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	errs := make([]error, 0, len(components))
+	for _, component := range components {
+		errs = append(errs, apperror.NewErrorf("collecting %s failed: %v", component, failed[component]))
+	}
+	return collection, apperror.NewError("failed to collect some hardware identifiers").AddErrors(errs)
+}
+
+// collection converts the facts enabled on the generator into prefixed identifiers
+func (f *HardwareFacts) collection(g *generator) *Collection {
+	collection := &Collection{}
+	add := func(component, prefix string, values ...string) {
+		contributed := false
+		for _, value := range values {
+			if value != "" {
+				collection.Identifiers = append(collection.Identifiers, prefix+value)
+				contributed = true
+			}
+		}
+		if contributed {
+			collection.Components = append(collection.Components, component)
+		}
+	}
+
+	if g.includeCPU {
+		add(ComponentCPU, "cpu:", f.CPUID)
+	}
+	if g.includeSystemUUID {
+		add(ComponentSystemUUID, "uuid:", f.SystemUUID)
+		add(ComponentMachineID, "machine:", f.MachineID)
+	}
+	if g.includeMotherboard {
+		add(ComponentMotherboard, motherboardPrefix, f.MotherboardSerial)
+	}
+	if g.includeMAC {
+		add(ComponentMAC, "mac:", f.MACAddresses...)
+	}
+	if g.includeDisk {
+		add(ComponentDisk, "disk:", f.DiskSerials...)
+	}
+
+	return collection
+}
+
+// collector records the errors of the components that could not be collected
+type collector map[string]error
+
+// value returns the value of the getter or an empty string if it failed
+func (c collector) value(component string, getValue func() (string, error)) string {
+	value, err := getValue()
+	if err != nil {
+		c[component] = err
+		return ""
+	}
+	return value
+}
+
+// values returns the values of the getter or nil if it failed
+func (c collector) values(component string, getValues func() ([]string, error)) []string {
+	values, err := getValues()
+	if err != nil {
+		c[component] = err
+		return nil
+	}
+	return values
+}
+
+// valueIfValid returns the value of the getter or an empty string if it failed
+func valueIfValid(getValue func() (string, error)) string {
+	value, err := getValue()
+	if err != nil {
+		return ""
+	}
+	return value
+}
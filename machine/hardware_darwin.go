@@ -4,75 +4,57 @@ package machine
 
 import (
 	"fmt"
+	"net"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
-// collectHardwareIdentifiersWithOptions gathers macOS-specific hardware identifiers based on generator config
-func collectHardwareIdentifiersWithOptions(g *generator) ([]string, error) {
+// motherboardPrefix is the identifier prefix used for the system serial number
+const motherboardPrefix = "serial:"
+
+// collectFacts gathers macOS-specific hardware facts based on generator config
+func collectFacts(g *generator, failed collector) (*HardwareFacts, error) {
 	if g == nil {
 		return nil, fmt.Errorf("generator cannot be nil")
 	}
 
-	var collectors []func() []string
+	facts := &HardwareFacts{
+		CPUModel: valueIfValid(func() (string, error) { return sysctl("machdep.cpu.brand_string") }),
+	}
+	if count, err := sysctl("hw.logicalcpu"); err == nil {
+		facts.CPUCount, _ = strconv.Atoi(count)
+	}
+	if memory, err := sysctl("hw.memsize"); err == nil {
+		facts.TotalMemory, _ = strconv.ParseUint(memory, 10, 64)
+	}
 
 	if g.includeSystemUUID {
-		collectors = append(collectors, func() []string { return collectIfValid(getMacOSHardwareUUID, "uuid:") })
+		facts.SystemUUID = failed.value(ComponentSystemUUID, getMacOSHardwareUUID)
 	}
 	if g.includeMotherboard {
-		collectors = append(collectors, func() []string { return collectIfValid(getMacOSSerialNumber, "serial:") })
+		facts.MotherboardSerial = failed.value(ComponentMotherboard, getMacOSSerialNumber)
 	}
 	if g.includeCPU {
-		collectors = append(collectors, func() []string { return collectIfValid(getMacOSCPUInfo, "cpu:") })
+		facts.CPUID = failed.value(ComponentCPU, getMacOSCPUInfo)
 	}
 	if g.includeMAC {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getMacOSMACAddresses, "mac:") })
+		facts.MACAddresses = failed.values(ComponentMAC, g.macAddresses)
 	}
 	if g.includeDisk {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getMacOSDiskInfo, "disk:") })
+		facts.DiskSerials = failed.values(ComponentDisk, getMacOSDiskInfo)
 	}
 
-	return collectFromAll(collectors), nil
-}
-
-// collectIfValid adds single identifier with prefix if valid
-func collectIfValid(getValue func() (string, error), prefix string) []string {
-	value, err := getValue()
-	switch {
-	case err != nil, value == "":
-		return nil
-	default:
-		return []string{prefix + value}
-	}
-}
-
-// collectMultipleIfValid adds multiple identifiers with prefix if valid
-func collectMultipleIfValid(getValues func() ([]string, error), prefix string) []string {
-	values, err := getValues()
-	switch {
-	case err != nil, len(values) == 0:
-		return nil
-	default:
-		return prefixSlice(values, prefix)
-	}
-}
-
-// prefixSlice adds prefix to each string in slice
-func prefixSlice(values []string, prefix string) []string {
-	result := make([]string, len(values))
-	for i, value := range values {
-		result[i] = prefix + value
-	}
-	return result
+	return facts, nil
 }
 
-// collectFromAll runs all collectors and combines results
-func collectFromAll(collectors []func() []string) []string {
-	var identifiers []string
-	for _, collector := range collectors {
-		identifiers = append(identifiers, collector()...)
+// sysctl reads a single value using the sysctl command
+func sysctl(name string) (string, error) {
+	output, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return "", err
 	}
-	return identifiers
+	return strings.TrimSpace(string(output)), nil
 }
 
 // getMacOSHardwareUUID retrieves hardware UUID using system_profiler
@@ -195,34 +177,15 @@ func getMacOSCPUInfo() (string, error) {
 	return cpuBrand, nil
 }
 
-// getMacOSMACAddresses retrieves MAC addresses using ifconfig
-func getMacOSMACAddresses() ([]string, error) {
-	cmd := exec.Command("ifconfig")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	var macs []string
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "ether ") {
-			parts := strings.Fields(line)
-			for i, part := range parts {
-				if part == "ether" && i+1 < len(parts) {
-					mac := parts[i+1]
-					if mac != "" && mac != "00:00:00:00:00:00" {
-						macs = append(macs, mac)
-					}
-					break
-				}
-			}
-		}
-	}
+// isPlatformVirtualInterface reports whether the interface is virtual beyond the
+// generic name and flag checks, macOS exposes no additional information for this
+func isPlatformVirtualInterface(_ net.Interface) bool {
+	return false
+}
 
-	return macs, nil
+// formatMAC formats the hardware address in lower case like ifconfig
+func formatMAC(addr net.HardwareAddr) string {
+	return addr.String()
 }
 
 // getMacOSDiskInfo retrieves disk information
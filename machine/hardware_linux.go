@@ -4,78 +4,49 @@ package machine
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// collectHardwareIdentifiersWithOptions gathers Linux-specific hardware identifiers based on generator config
-func collectHardwareIdentifiersWithOptions(g *generator) ([]string, error) {
+// motherboardPrefix is the identifier prefix used for the motherboard serial
+const motherboardPrefix = "mb:"
+
+// collectFacts gathers Linux-specific hardware facts based on generator config
+func collectFacts(g *generator, failed collector) (*HardwareFacts, error) {
 	if g == nil {
 		return nil, fmt.Errorf("generator cannot be nil")
 	}
 
-	var collectors []func() []string
+	facts := &HardwareFacts{}
+	if data, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		facts.CPUModel, facts.CPUCount = parseCPUModel(string(data))
+	}
+	if total, err := getLinuxTotalMemory(); err == nil {
+		facts.TotalMemory = total
+	}
 
 	if g.includeCPU {
-		collectors = append(collectors, func() []string { return collectIfValid(getLinuxCPUID, "cpu:") })
+		facts.CPUID = failed.value(ComponentCPU, getLinuxCPUID)
 	}
 	if g.includeSystemUUID {
-		collectors = append(collectors, func() []string { return collectIfValid(getLinuxSystemUUID, "uuid:") })
-		collectors = append(collectors, func() []string { return collectIfValid(getLinuxMachineID, "machine:") })
+		facts.SystemUUID = failed.value(ComponentSystemUUID, getLinuxSystemUUID)
+		facts.MachineID = failed.value(ComponentMachineID, getLinuxMachineID)
 	}
 	if g.includeMotherboard {
-		collectors = append(collectors, func() []string { return collectIfValid(getLinuxMotherboardSerial, "mb:") })
+		facts.MotherboardSerial = failed.value(ComponentMotherboard, getLinuxMotherboardSerial)
 	}
 	if g.includeMAC {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getLinuxMACAddresses, "mac:") })
+		facts.MACAddresses = failed.values(ComponentMAC, g.macAddresses)
 	}
 	if g.includeDisk {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getLinuxDiskSerials, "disk:") })
-	}
-
-	return collectFromAll(collectors), nil
-}
-
-// collectIfValid adds single identifier with prefix if valid
-func collectIfValid(getValue func() (string, error), prefix string) []string {
-	value, err := getValue()
-	switch {
-	case err != nil, value == "":
-		return nil
-	default:
-		return []string{prefix + value}
+		facts.DiskSerials = failed.values(ComponentDisk, getLinuxDiskSerials)
 	}
-}
 
-// collectMultipleIfValid adds multiple identifiers with prefix if valid
-func collectMultipleIfValid(getValues func() ([]string, error), prefix string) []string {
-	values, err := getValues()
-	switch {
-	case err != nil, len(values) == 0:
-		return nil
-	default:
-		return prefixSlice(values, prefix)
-	}
-}
-
-// prefixSlice adds prefix to each string in slice
-func prefixSlice(values []string, prefix string) []string {
-	result := make([]string, len(values))
-	for i, value := range values {
-		result[i] = prefix + value
-	}
-	return result
-}
-
-// collectFromAll runs all collectors and combines results
-func collectFromAll(collectors []func() []string) []string {
-	var identifiers []string
-	for _, collector := range collectors {
-		identifiers = append(identifiers, collector()...)
-	}
-	return identifiers
+	return facts, nil
 }
 
 // getLinuxCPUID retrieves CPU information from /proc/cpuinfo
@@ -115,6 +86,49 @@ func parseCPUInfo(content string) string {
 	return fmt.Sprintf("%s:%s:%s:%s", processor, vendorID, modelName, flags)
 }
 
+// parseCPUModel extracts the CPU model name and the number of logical processors from /proc/cpuinfo content
+func parseCPUModel(content string) (string, int) {
+	var model string
+	var count int
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "processor":
+			count++
+		case "model name":
+			if model == "" {
+				model = strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	return model, count
+}
+
+// getLinuxTotalMemory retrieves the total physical memory in bytes from /proc/meminfo
+func getLinuxTotalMemory() (uint64, error) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
+
 // getLinuxSystemUUID retrieves system UUID from DMI
 func getLinuxSystemUUID() (string, error) {
 	// Try multiple locations for system UUID
@@ -187,30 +201,19 @@ func isNonEmpty(value string) bool {
 	return value != ""
 }
 
-// getLinuxMACAddresses retrieves MAC addresses from network interfaces
-func getLinuxMACAddresses() ([]string, error) {
-	var macs []string
-
-	// Read from /sys/class/net
-	netDir := "/sys/class/net"
-	entries, err := os.ReadDir(netDir)
+// isPlatformVirtualInterface reports whether the kernel registered the interface
+// as a virtual device, i.e. it has no backing device under /sys/class/net
+func isPlatformVirtualInterface(iface net.Interface) bool {
+	path, err := filepath.EvalSymlinks(filepath.Join("/sys/class/net", iface.Name))
 	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() && entry.Name() != "lo" { // Skip loopback
-			macFile := filepath.Join(netDir, entry.Name(), "address")
-			if data, err := os.ReadFile(macFile); err == nil {
-				mac := strings.TrimSpace(string(data))
-				if mac != "" && mac != "00:00:00:00:00:00" {
-					macs = append(macs, mac)
-				}
-			}
-		}
+		return false
 	}
+	return strings.HasPrefix(path, "/sys/devices/virtual/")
+}
 
-	return macs, nil
+// formatMAC formats the hardware address in lower case like /sys/class/net/*/address
+func formatMAC(addr net.HardwareAddr) string {
+	return addr.String()
 }
 
 // getLinuxDiskSerials retrieves disk serial numbers using various methods
@@ -4,77 +4,60 @@ package machine
 
 import (
 	"fmt"
+	"net"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/valentin-kaiser/go-core/apperror"
 )
 
-// collectHardwareIdentifiersWithOptions gathers Windows-specific hardware identifiers based on generator config
-func collectHardwareIdentifiersWithOptions(g *generator) ([]string, error) {
+// motherboardPrefix is the identifier prefix used for the motherboard serial
+const motherboardPrefix = "mb:"
+
+// collectFacts gathers Windows-specific hardware facts based on generator config
+func collectFacts(g *generator, failed collector) (*HardwareFacts, error) {
 	if g == nil {
 		return nil, apperror.NewError("generator cannot be nil")
 	}
 
-	var collectors []func() []string
+	facts := &HardwareFacts{
+		CPUModel: valueIfValid(func() (string, error) { return wmic("Name=", "cpu", "get", "Name") }),
+	}
+	if count, err := wmic("NumberOfLogicalProcessors=", "computersystem", "get", "NumberOfLogicalProcessors"); err == nil {
+		facts.CPUCount, _ = strconv.Atoi(count)
+	}
+	if memory, err := wmic("TotalPhysicalMemory=", "computersystem", "get", "TotalPhysicalMemory"); err == nil {
+		facts.TotalMemory, _ = strconv.ParseUint(memory, 10, 64)
+	}
 
 	if g.includeCPU {
-		collectors = append(collectors, func() []string { return collectIfValid(getWindowsCPUID, "cpu:") })
+		facts.CPUID = failed.value(ComponentCPU, getWindowsCPUID)
 	}
 	if g.includeMotherboard {
-		collectors = append(collectors, func() []string { return collectIfValid(getWindowsMotherboardSerial, "mb:") })
+		facts.MotherboardSerial = failed.value(ComponentMotherboard, getWindowsMotherboardSerial)
 	}
 	if g.includeSystemUUID {
-		collectors = append(collectors, func() []string { return collectIfValid(getWindowsSystemUUID, "uuid:") })
+		facts.SystemUUID = failed.value(ComponentSystemUUID, getWindowsSystemUUID)
 	}
 	if g.includeMAC {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getWindowsMACAddresses, "mac:") })
+		facts.MACAddresses = failed.values(ComponentMAC, g.macAddresses)
 	}
 	if g.includeDisk {
-		collectors = append(collectors, func() []string { return collectMultipleIfValid(getWindowsDiskSerials, "disk:") })
+		facts.DiskSerials = failed.values(ComponentDisk, getWindowsDiskSerials)
 	}
 
-	return collectFromAll(collectors), nil
+	return facts, nil
 }
 
-// collectIfValid adds single identifier with prefix if valid
-func collectIfValid(getValue func() (string, error), prefix string) []string {
-	value, err := getValue()
-	switch {
-	case err != nil, value == "":
-		return nil
-	default:
-		return []string{prefix + value}
-	}
-}
-
-// collectMultipleIfValid adds multiple identifiers with prefix if valid
-func collectMultipleIfValid(getValues func() ([]string, error), prefix string) []string {
-	values, err := getValues()
-	switch {
-	case err != nil, len(values) == 0:
-		return nil
-	default:
-		return prefixSlice(values, prefix)
-	}
-}
-
-// prefixSlice adds prefix to each string in slice
-func prefixSlice(values []string, prefix string) []string {
-	result := make([]string, len(values))
-	for i, value := range values {
-		result[i] = prefix + value
-	}
-	return result
-}
-
-// collectFromAll runs all collectors and combines results
-func collectFromAll(collectors []func() []string) []string {
-	var identifiers []string
-	for _, collector := range collectors {
-		identifiers = append(identifiers, collector()...)
+// wmic queries a single value using the wmic command
+func wmic(prefix string, args ...string) (string, error) {
+	output, err := exec.Command("wmic", append(args, "/value")...).Output()
+	if err != nil {
+		return "", err
 	}
-	return identifiers
+	return parseWmicValue(string(output), prefix)
 }
 
 // parseWmicValue extracts value from wmic output with given prefix
@@ -150,16 +133,36 @@ func getWindowsSystemUUID() (string, error) {
 	}
 }
 
-// getWindowsMACAddresses retrieves MAC addresses using wmic
-func getWindowsMACAddresses() ([]string, error) {
+// physicalAdapters returns the MAC addresses of the physical network adapters reported by WMI
+var physicalAdapters = sync.OnceValues(func() (map[string]bool, error) {
 	cmd := exec.Command("wmic", "path", "win32_networkadapter", "where", "physicaladapter=true", "get", "MacAddress", "/value")
 	output, err := cmd.Output()
-	switch err {
-	case nil:
-		return parseWmicMultipleValues(string(output), "MacAddress="), nil
-	default:
+	if err != nil {
 		return nil, err
 	}
+
+	adapters := make(map[string]bool)
+	for _, mac := range parseWmicMultipleValues(string(output), "MacAddress=") {
+		adapters[strings.ToUpper(mac)] = true
+	}
+	return adapters, nil
+})
+
+// isPlatformVirtualInterface reports whether WMI does not list the interface as physical adapter,
+// which earlier versions used to select the MAC addresses. Without WMI, e.g. on Windows versions
+// shipping without wmic, only the generic name and flag checks apply.
+func isPlatformVirtualInterface(iface net.Interface) bool {
+	adapters, err := physicalAdapters()
+	if err != nil {
+		return false
+	}
+	return !adapters[formatMAC(iface.HardwareAddr)]
+}
+
+// formatMAC formats the hardware address in upper case like WMI,
+// which keeps the machine IDs of earlier versions stable
+func formatMAC(addr net.HardwareAddr) string {
+	return strings.ToUpper(addr.String())
 }
 
 // getWindowsDiskSerials retrieves disk serial numbers using wmic
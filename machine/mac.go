@@ -0,0 +1,90 @@
+package machine
+
+import (
+	"net"
+	"strings"
+)
+
+// virtualInterfacePrefixes lists interface name prefixes of well-known virtual,
+// container, bridge and VPN interfaces. Their addresses are usually generated
+// at runtime and would make the machine ID unstable.
+var virtualInterfacePrefixes = []string{
+	"docker",
+	"veth",
+	"br-",
+	"virbr",
+	"vboxnet",
+	"vmnet",
+	"vethernet",
+	"tun",
+	"tap",
+	"utun",
+	"wg",
+	"zt",
+	"tailscale",
+	"cni",
+	"flannel",
+	"cali",
+	"kube",
+	"lxc",
+	"lxd",
+	"podman",
+	"awdl",
+	"llw",
+	"anpi",
+	"bridge",
+	"ifb",
+	"dummy",
+}
+
+// getMACAddresses retrieves the MAC addresses of the network interfaces in the format of the platform,
+// see formatMAC. Loopback, point-to-point and virtual interfaces are skipped unless includeVirtual is set.
+func getMACAddresses(includeVirtual bool) ([]string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var macs []string
+	for _, iface := range interfaces {
+		if len(iface.HardwareAddr) == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		mac := formatMAC(iface.HardwareAddr)
+		if mac == "00:00:00:00:00:00" {
+			continue
+		}
+
+		if !includeVirtual && isVirtualInterface(iface) {
+			continue
+		}
+
+		macs = append(macs, mac)
+	}
+
+	return macs, nil
+}
+
+// isVirtualInterface reports whether the interface is considered virtual based on its flags,
+// its name and the information of the platform. Locally administered addresses are not
+// considered virtual, as cloud providers assign them to primary interfaces, e.g. 42:01:... on GCP.
+func isVirtualInterface(iface net.Interface) bool {
+	if iface.Flags&(net.FlagLoopback|net.FlagPointToPoint) != 0 {
+		return true
+	}
+
+	name := strings.ToLower(iface.Name)
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return isPlatformVirtualInterface(iface)
+}
+
+// macAddresses retrieves the MAC addresses honoring the virtual interface setting of the generator
+func (g *generator) macAddresses() ([]string, error) {
+	return getMACAddresses(g.includeVirtualMAC)
+}
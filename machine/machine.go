@@ -5,7 +5,7 @@
 // The package collects hardware information such as:
 //   - CPU information (processor ID, features)
 //   - Motherboard serial number and UUID
-//   - Network interface MAC addresses
+//   - Network interface MAC addresses (virtual interfaces filtered by default)
 //   - System UUID from BIOS/UEFI
 //   - Disk serial numbers
 //
@@ -16,7 +16,7 @@
 // Example usage:
 //
 //	// Generate machine ID with default settings
-//	id, err := machine.New().WithCPU().WithMotherboard().WithSystemUUID().WithMAC().WithDisk().ID()
+//	id, err := machine.New().WithCPU().WithMotherboard().WithSystemUUID().WithMAC(false).WithDisk().ID()
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
@@ -29,6 +29,27 @@
 //	}
 //	fmt.Printf("Machine ID: %s\n", id)
 //
+//	// Find out which components contributed to the machine ID
+//	collection, err := machine.New().WithCPU().WithSystemUUID().WithDisk().Collect()
+//	if err != nil {
+//	    log.Printf("incomplete machine ID: %v", err)
+//	}
+//	fmt.Printf("Components: %v\n", collection.Components)
+//
+//	// Inspect the raw hardware facts used for the machine ID
+//	facts, err := machine.Facts()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("CPU: %s (%d cores)\n", facts.CPUModel, facts.CPUCount)
+//
+//	// Detect virtual machines and containers
+//	env, err := machine.Environment()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("Environment: %s (hypervisor: %q, container: %q)\n", env.Type, env.Hypervisor, env.Container)
+//
 //	// Generate VM-friendly machine ID
 //	id, err := machine.New().VMFriendly().WithSalt("my-app").ID()
 //	if err != nil {
@@ -52,6 +73,7 @@ type generator struct {
 	includeMotherboard bool
 	includeSystemUUID  bool
 	includeMAC         bool
+	includeVirtualMAC  bool
 	includeDisk        bool
 }
 
@@ -85,8 +107,15 @@ func (g *generator) WithSystemUUID() *generator {
 }
 
 // WithMAC explicitly includes MAC addresses (enabled by default)
-func (g *generator) WithMAC() *generator {
+// Loopback, point-to-point and virtual interfaces (docker0, veth, bridges, VPN taps, ...)
+// are filtered out unless includeVirtual is true, since their addresses change at runtime.
+// On Windows only the physical adapters reported by WMI are used and the addresses are
+// formatted in upper case as before, so existing machine IDs do not change.
+// Note that bonding or teaming can still cause drift, as the bond may take over
+// the address of one of its members depending on the order in which they come up.
+func (g *generator) WithMAC(includeVirtual bool) *generator {
 	g.includeMAC = true
+	g.includeVirtualMAC = includeVirtual
 	return g
 }
 
@@ -107,15 +136,19 @@ func (g *generator) VMFriendly() *generator {
 }
 
 // ID generates a machine ID using the specified options
+// Components that cannot be collected are left out, the ID is derived from the others.
+// Use Collect to find out which components contributed.
 func (g *generator) ID() (string, error) {
-	identifiers, err := collectHardwareIdentifiersWithOptions(g)
+	collection, err := g.Collect()
 	switch {
-	case err != nil:
-		return "", apperror.NewError("failed to collect hardware identifiers").AddError(err)
-	case len(identifiers) == 0:
+	case collection == nil:
+		return "", err
+	case len(collection.Identifiers) == 0 && err != nil:
+		return "", apperror.NewError("no hardware identifiers found with current configuration").AddError(err)
+	case len(collection.Identifiers) == 0:
 		return "", apperror.NewError("no hardware identifiers found with current configuration")
 	default:
-		return hashIdentifiers(identifiers, g.salt), nil
+		return hashIdentifiers(collection.Identifiers, g.salt), nil
 	}
 }
 
@@ -1,6 +1,8 @@
 package machine_test
 
 import (
+	"net"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -8,7 +10,7 @@ import (
 )
 
 func TestGeneratorBasic(t *testing.T) {
-	g := machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC().WithDisk()
+	g := machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC(false).WithDisk()
 
 	id, err := g.ID()
 	if err != nil {
@@ -96,7 +98,7 @@ func TestVMFriendly(t *testing.T) {
 	}
 
 	// Test that it's different from full hardware
-	g2 := machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC().WithDisk().WithSalt("vm-test")
+	g2 := machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC(false).WithDisk().WithSalt("vm-test")
 	id2, err := g2.ID()
 	if err != nil {
 		t.Fatalf("Full hardware ID() error = %v", err)
@@ -148,3 +150,150 @@ func TestGeneratorChaining(t *testing.T) {
 		t.Error("Chained generator Validate() returned false for valid ID")
 	}
 }
+
+func TestWithMACVirtualFiltering(t *testing.T) {
+	for _, includeVirtual := range []bool{false, true} {
+		g := machine.New().WithCPU().WithMAC(includeVirtual)
+
+		id, err := g.ID()
+		if err != nil {
+			t.Fatalf("WithMAC(%v).ID() error = %v", includeVirtual, err)
+		}
+
+		if len(id) != 64 {
+			t.Errorf("WithMAC(%v).ID() returned ID of length %d, expected 64", includeVirtual, len(id))
+		}
+
+		valid, err := g.Validate(id)
+		if err != nil {
+			t.Fatalf("WithMAC(%v).Validate() error = %v", includeVirtual, err)
+		}
+
+		if !valid {
+			t.Errorf("WithMAC(%v).Validate() returned false for valid ID", includeVirtual)
+		}
+	}
+}
+
+func TestIsVirtualInterface(t *testing.T) {
+	mac := func(s string) net.HardwareAddr {
+		addr, err := net.ParseMAC(s)
+		if err != nil {
+			t.Fatalf("ParseMAC(%q) error = %v", s, err)
+		}
+		return addr
+	}
+
+	tests := []struct {
+		name    string
+		iface   net.Interface
+		virtual bool
+	}{
+		{"physical", net.Interface{Name: "physical-eth0", HardwareAddr: mac("00:1a:2b:3c:4d:5e"), Flags: net.FlagUp}, false},
+		{"locally administered cloud NIC", net.Interface{Name: "physical-ens4", HardwareAddr: mac("42:01:0a:80:00:02"), Flags: net.FlagUp}, false},
+		{"loopback", net.Interface{Name: "lo", HardwareAddr: mac("00:00:00:00:00:01"), Flags: net.FlagLoopback}, true},
+		{"point-to-point", net.Interface{Name: "ppp0", HardwareAddr: mac("00:1a:2b:3c:4d:5f"), Flags: net.FlagPointToPoint}, true},
+		{"docker bridge", net.Interface{Name: "docker0", HardwareAddr: mac("02:42:ac:11:00:02")}, true},
+		{"veth pair", net.Interface{Name: "veth1a2b3c", HardwareAddr: mac("12:34:56:78:9a:bc")}, true},
+		{"case insensitive name", net.Interface{Name: "vEthernet (WSL)", HardwareAddr: mac("00:15:5d:00:00:01")}, true},
+		{"wireguard", net.Interface{Name: "wg0", HardwareAddr: mac("00:1a:2b:3c:4d:60")}, true},
+	}
+
+	for _, tt := range tests {
+		// Windows only treats adapters listed by WMI as physical
+		if runtime.GOOS == "windows" && !tt.virtual {
+			continue
+		}
+		if got := machine.IsVirtualInterface(tt.iface); got != tt.virtual {
+			t.Errorf("isVirtualInterface(%s) = %v, expected %v", tt.name, got, tt.virtual)
+		}
+	}
+}
+
+func TestFacts(t *testing.T) {
+	facts, err := machine.Facts()
+	if err != nil {
+		t.Fatalf("Facts() error = %v", err)
+	}
+
+	if facts.CPUCount <= 0 {
+		t.Errorf("Facts() returned CPU count %d, expected > 0", facts.CPUCount)
+	}
+
+	if facts.TotalMemory == 0 {
+		t.Error("Facts() returned zero total memory")
+	}
+
+	// Facts restricted by the generator must not contain disabled components
+	facts, err = machine.New().WithCPU().Facts()
+	if err != nil {
+		t.Fatalf("generator Facts() error = %v", err)
+	}
+
+	if facts.SystemUUID != "" || facts.MotherboardSerial != "" || len(facts.MACAddresses) != 0 || len(facts.DiskSerials) != 0 {
+		t.Errorf("generator Facts() returned disabled components: %+v", facts)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	env, err := machine.Environment()
+	if err != nil {
+		t.Fatalf("Environment() error = %v", err)
+	}
+
+	switch env.Type {
+	case machine.EnvBareMetal:
+		if env.Hypervisor != "" || env.Container != "" {
+			t.Errorf("Environment() reported bare metal with hypervisor %q and container %q", env.Hypervisor, env.Container)
+		}
+	case machine.EnvVirtualMachine:
+		if env.Hypervisor == "" {
+			t.Error("Environment() reported a virtual machine without hypervisor")
+		}
+	case machine.EnvContainer:
+		if env.Container == "" {
+			t.Error("Environment() reported a container without runtime")
+		}
+	default:
+		t.Errorf("Environment() returned unknown type %q", env.Type)
+	}
+
+	if env.IsVirtual() != (env.Type != machine.EnvBareMetal) {
+		t.Errorf("IsVirtual() = %v for type %q", env.IsVirtual(), env.Type)
+	}
+	t.Logf("Environment: %+v", env)
+}
+
+func TestCollect(t *testing.T) {
+	collection, err := machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC(false).WithDisk().Collect()
+	if collection == nil {
+		t.Fatalf("Collect() returned no collection, error = %v", err)
+	}
+	if err != nil && len(collection.Failed) == 0 {
+		t.Errorf("Collect() error = %v without failed components", err)
+	}
+	if err == nil && len(collection.Failed) > 0 {
+		t.Errorf("Collect() returned failed components %v without error", collection.Failed)
+	}
+	for component, cause := range collection.Failed {
+		if !strings.Contains(err.Error(), component) {
+			t.Errorf("Collect() error should name the failed component %s: %v", component, cause)
+		}
+		for _, contributed := range collection.Components {
+			if contributed == component {
+				t.Errorf("failed component %s should not have contributed", component)
+			}
+		}
+	}
+
+	if len(collection.Identifiers) == 0 {
+		t.Skip("no hardware identifiers available")
+	}
+	if len(collection.Components) == 0 {
+		t.Error("Collect() returned identifiers without contributing components")
+	}
+	_, err = machine.New().WithCPU().WithSystemUUID().WithMotherboard().WithMAC(false).WithDisk().ID()
+	if err != nil {
+		t.Errorf("ID() should derive a best-effort ID from the collected identifiers, error = %v", err)
+	}
+}
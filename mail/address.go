@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// ValidateAddresses parses all addresses and returns the valid ones in normalized form
+// together with the parse error of every invalid address, so that callers can report
+// all bad addresses at once instead of failing on the first one during sending.
+//
+// Normalization strips comments and surrounding whitespace and lowercases the domain.
+// The local part is kept as is because it may be case-sensitive. Display names are kept,
+// addresses without a display name are returned as the bare address. Like net/mail, the
+// comment of an address without display name, e.g. "jane@example.net (Jane)", is used as name.
+//
+// Example:
+//
+//	valid, invalid := mail.ValidateAddresses([]string{"John <John@Example.COM>", "broken@"})
+//	// valid:   ["\"John\" <John@example.com>"]
+//	// invalid: {"broken@": ...}
+func ValidateAddresses(addrs []string) (valid []string, invalid map[string]error) {
+	valid = make([]string, 0, len(addrs))
+	invalid = make(map[string]error)
+
+	for _, addr := range addrs {
+		normalized, err := normalizeAddress(addr)
+		if err != nil {
+			invalid[addr] = err
+			continue
+		}
+		valid = append(valid, normalized)
+	}
+
+	return valid, invalid
+}
+
+// normalizeAddress parses a single address and returns its normalized form
+func normalizeAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(addr))
+	if err != nil {
+		return "", apperror.NewErrorf("invalid email address %q", addr).AddError(err)
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	if at <= 0 || at == len(parsed.Address)-1 {
+		return "", apperror.NewErrorf("invalid email address %q", addr)
+	}
+	parsed.Address = parsed.Address[:at] + "@" + strings.ToLower(parsed.Address[at+1:])
+
+	if parsed.Name == "" {
+		return parsed.Address, nil
+	}
+	return parsed.String(), nil
+}
+
+// validateRecipients checks all recipient addresses of the message and reports every invalid one
+func validateRecipients(message *Message) error {
+	addrs := make([]string, 0, len(message.To)+len(message.CC)+len(message.BCC))
+	addrs = append(append(append(addrs, message.To...), message.CC...), message.BCC...)
+
+	_, invalid := ValidateAddresses(addrs)
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	bad := make([]string, 0, len(invalid))
+	for addr := range invalid {
+		bad = append(bad, addr)
+	}
+	sort.Strings(bad)
+
+	errs := make([]error, 0, len(bad))
+	for _, addr := range bad {
+		errs = append(errs, invalid[addr])
+	}
+	return apperror.NewErrorf("invalid recipient addresses: %s", strings.Join(bad, ", ")).AddErrors(errs)
+}
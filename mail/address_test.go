@@ -0,0 +1,40 @@
+package mail_test
+
+import (
+	"testing"
+
+	"github.com/valentin-kaiser/go-core/mail"
+)
+
+func TestValidateAddresses(t *testing.T) {
+	valid, invalid := mail.ValidateAddresses([]string{
+		"user@Example.COM",
+		" John Doe <John.Doe@EXAMPLE.org> ",
+		"jane@example.net (Jane)",
+		"broken@",
+		"no-at-sign",
+	})
+
+	expected := []string{
+		"user@example.com",
+		`"John Doe" <John.Doe@example.org>`,
+		`"Jane" <jane@example.net>`,
+	}
+	if len(valid) != len(expected) {
+		t.Fatalf("expected %d valid addresses, got %v", len(expected), valid)
+	}
+	for i := range expected {
+		if valid[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], valid[i])
+		}
+	}
+
+	if len(invalid) != 2 {
+		t.Fatalf("expected 2 invalid addresses, got %v", invalid)
+	}
+	for _, addr := range []string{"broken@", "no-at-sign"} {
+		if invalid[addr] == nil {
+			t.Errorf("expected %q to be reported as invalid", addr)
+		}
+	}
+}
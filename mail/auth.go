@@ -0,0 +1,432 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxSPFLookups is the limit of DNS querying mechanisms of an SPF evaluation (RFC 7208 section 4.6.4)
+const maxSPFLookups = 10
+
+var (
+	// resolver is used for the DNS lookups of the message authentication
+	resolver   DNSResolver = net.DefaultResolver
+	resolverMu sync.RWMutex
+)
+
+// DNSResolver performs the DNS lookups needed for SPF, DKIM and DMARC verification
+// *net.Resolver implements this interface
+type DNSResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// SetDNSResolver sets the resolver used by the SMTP server to verify incoming messages
+// Passing nil restores net.DefaultResolver. This allows injecting static records in tests.
+func SetDNSResolver(r DNSResolver) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	resolver = r
+}
+
+// getDNSResolver returns the resolver used for message authentication
+func getDNSResolver() DNSResolver {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	return resolver
+}
+
+// AuthResult represents the outcome of an authentication check
+type AuthResult string
+
+const (
+	// AuthPass indicates that the check passed
+	AuthPass AuthResult = "pass"
+	// AuthFail indicates that the check failed
+	AuthFail AuthResult = "fail"
+	// AuthSoftFail indicates that the sender is probably not authorized (SPF ~all)
+	AuthSoftFail AuthResult = "softfail"
+	// AuthNeutral indicates that the domain makes no assertion about the sender (SPF ?all)
+	AuthNeutral AuthResult = "neutral"
+	// AuthNone indicates that no record or signature was found
+	AuthNone AuthResult = "none"
+	// AuthTempError indicates a transient error, usually a DNS failure
+	AuthTempError AuthResult = "temperror"
+	// AuthPermError indicates a malformed record or signature
+	AuthPermError AuthResult = "permerror"
+)
+
+// AuthResults holds the results of the SPF, DKIM and DMARC verification of a received message
+type AuthResults struct {
+	// SPF is the result of the SPF check of the envelope sender
+	SPF AuthResult `json:"spf"`
+	// SPFDomain is the domain checked by SPF, the MAIL FROM domain or the HELO hostname
+	SPFDomain string `json:"spf_domain"`
+	// DKIM is pass if at least one DKIM signature is valid
+	DKIM AuthResult `json:"dkim"`
+	// DKIMDomains are the signing domains of the valid DKIM signatures
+	DKIMDomains []string `json:"dkim_domains,omitempty"`
+	// DMARC is pass if SPF or DKIM passed with a domain aligned to the From header
+	DMARC AuthResult `json:"dmarc"`
+	// DMARCPolicy is the policy (none, quarantine, reject) published by the From domain
+	DMARCPolicy string `json:"dmarc_policy,omitempty"`
+	// FromDomain is the domain of the From header
+	FromDomain string `json:"from_domain"`
+}
+
+// authResultsKey is the context key for the authentication results of a received message
+type authResultsKey struct{}
+
+// AuthResultsFromContext returns the authentication results passed to a NotificationHandler
+// They are only present if the server verifies incoming messages (see SecurityConfig.VerifyAuthentication)
+func AuthResultsFromContext(ctx context.Context) (*AuthResults, bool) {
+	results, ok := ctx.Value(authResultsKey{}).(*AuthResults)
+	return results, ok
+}
+
+// withAuthResults returns a copy of the context carrying the authentication results
+func withAuthResults(ctx context.Context, results *AuthResults) context.Context {
+	return context.WithValue(ctx, authResultsKey{}, results)
+}
+
+// VerifyAuthentication checks SPF for the connecting IP and envelope sender, verifies the DKIM
+// signatures of the message and evaluates the DMARC alignment of the From header domain.
+// If mailFrom is empty (bounce messages) the HELO hostname is used for SPF.
+// SPF macros are not supported, records using them evaluate to permerror.
+func VerifyAuthentication(ctx context.Context, r DNSResolver, ip net.IP, helo, mailFrom string, data []byte) *AuthResults {
+	results := &AuthResults{}
+
+	results.SPFDomain = helo
+	if _, domain, ok := strings.Cut(mailFrom, "@"); ok {
+		results.SPFDomain = domain
+	}
+	results.SPFDomain = strings.ToLower(strings.Trim(results.SPFDomain, "<>. "))
+	results.SPF = checkSPF(ctx, r, ip, results.SPFDomain)
+
+	results.DKIM, results.DKIMDomains = verifyDKIM(ctx, r, data)
+
+	results.FromDomain = fromDomain(data)
+	results.DMARC, results.DMARCPolicy = checkDMARC(ctx, r, results)
+
+	return results
+}
+
+// fromDomain returns the lowercased domain of the From header of the message
+func fromDomain(data []byte) string {
+	headers, _ := splitMessage(data)
+	for _, field := range headers {
+		if !strings.EqualFold(field.name, "From") {
+			continue
+		}
+		_, value, _ := strings.Cut(field.raw, ":")
+		addr, err := mail.ParseAddress(strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "")))
+		if err != nil {
+			return ""
+		}
+		_, domain, _ := strings.Cut(addr.Address, "@")
+		return strings.ToLower(domain)
+	}
+	return ""
+}
+
+// checkSPF evaluates the SPF record of the domain for the IP (RFC 7208)
+func checkSPF(ctx context.Context, r DNSResolver, ip net.IP, domain string) AuthResult {
+	if ip == nil || domain == "" {
+		return AuthNone
+	}
+	lookups := 0
+	return evaluateSPF(ctx, r, ip, domain, &lookups)
+}
+
+// evaluateSPF evaluates the SPF record of a domain, lookups counts the DNS querying mechanisms
+func evaluateSPF(ctx context.Context, r DNSResolver, ip net.IP, domain string, lookups *int) AuthResult {
+	records, err := r.LookupTXT(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return AuthNone
+		}
+		return AuthTempError
+	}
+
+	var record string
+	for _, txt := range records {
+		if txt == "v=spf1" || strings.HasPrefix(strings.ToLower(txt), "v=spf1 ") {
+			if record != "" {
+				return AuthPermError
+			}
+			record = txt
+		}
+	}
+	if record == "" {
+		return AuthNone
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		if strings.Contains(term, "%") {
+			return AuthPermError
+		}
+
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			continue
+		}
+
+		qualifier := AuthPass
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = AuthFail, term[1:]
+		case '~':
+			qualifier, term = AuthSoftFail, term[1:]
+		case '?':
+			qualifier, term = AuthNeutral, term[1:]
+		}
+
+		match, result := matchSPFMechanism(ctx, r, ip, domain, term, lookups)
+		if result != "" {
+			return result
+		}
+		if match {
+			return qualifier
+		}
+	}
+
+	if redirect != "" {
+		*lookups++
+		if *lookups > maxSPFLookups {
+			return AuthPermError
+		}
+		result := evaluateSPF(ctx, r, ip, redirect, lookups)
+		if result == AuthNone {
+			return AuthPermError
+		}
+		return result
+	}
+
+	return AuthNeutral
+}
+
+// matchSPFMechanism reports whether the mechanism matches the IP
+// A non-empty result aborts the evaluation with that result
+func matchSPFMechanism(ctx context.Context, r DNSResolver, ip net.IP, domain, term string, lookups *int) (bool, AuthResult) {
+	name, arg, _ := strings.Cut(term, ":")
+	name = strings.ToLower(name)
+
+	// a and mx accept a dual CIDR length suffix without a domain, e.g. a/24//64
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "mx/") {
+		var cidr string
+		name, cidr, _ = strings.Cut(name, "/")
+		arg = "/" + cidr
+	}
+
+	switch name {
+	case "all":
+		return true, ""
+	case "ip4", "ip6":
+		if !strings.Contains(arg, "/") {
+			return ip.Equal(net.ParseIP(arg)), ""
+		}
+		_, network, err := net.ParseCIDR(arg)
+		if err != nil {
+			return false, AuthPermError
+		}
+		return network.Contains(ip), ""
+	case "include":
+		*lookups++
+		if *lookups > maxSPFLookups {
+			return false, AuthPermError
+		}
+		switch evaluateSPF(ctx, r, ip, arg, lookups) {
+		case AuthPass:
+			return true, ""
+		case AuthTempError:
+			return false, AuthTempError
+		case AuthPermError, AuthNone:
+			return false, AuthPermError
+		}
+		return false, ""
+	case "a", "mx", "exists":
+		*lookups++
+		if *lookups > maxSPFLookups {
+			return false, AuthPermError
+		}
+		target, ip4Bits, ip6Bits, err := parseSPFTarget(arg, domain)
+		if err != nil {
+			return false, AuthPermError
+		}
+
+		hosts := []string{target}
+		if name == "mx" {
+			mxs, err := r.LookupMX(ctx, target)
+			if err != nil && !isNotFound(err) {
+				return false, AuthTempError
+			}
+			hosts = hosts[:0]
+			for _, mx := range mxs {
+				hosts = append(hosts, mx.Host)
+			}
+		}
+
+		for _, host := range hosts {
+			addrs, err := r.LookupIPAddr(ctx, host)
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return false, AuthTempError
+			}
+			if name == "exists" && len(addrs) > 0 {
+				return true, ""
+			}
+			for _, addr := range addrs {
+				addrIP, bits, size := addr.IP, ip6Bits, 128
+				if v4 := addr.IP.To4(); v4 != nil {
+					addrIP, bits, size = v4, ip4Bits, 32
+				}
+				mask := net.CIDRMask(bits, size)
+				network := net.IPNet{IP: addrIP.Mask(mask), Mask: mask}
+				if network.Contains(ip) {
+					return true, ""
+				}
+			}
+		}
+		return false, ""
+	case "ptr":
+		// ptr is deprecated (RFC 7208 section 5.5) and never matches
+		return false, ""
+	default:
+		return false, AuthPermError
+	}
+}
+
+// parseSPFTarget parses the domain and CIDR lengths of an a or mx mechanism argument
+func parseSPFTarget(arg, domain string) (string, int, int, error) {
+	ip4Bits, ip6Bits := 32, 128
+	target := arg
+	if idx := strings.Index(arg, "/"); idx != -1 {
+		target = arg[:idx]
+		ip4, ip6, dual := strings.Cut(arg[idx+1:], "//")
+		if strings.HasPrefix(arg[idx:], "//") {
+			ip4, ip6, dual = "", arg[idx+2:], true
+		}
+
+		var err error
+		if ip4 != "" {
+			ip4Bits, err = strconv.Atoi(ip4)
+			if err != nil || ip4Bits < 0 || ip4Bits > 32 {
+				return "", 0, 0, apperror.NewErrorf("invalid ip4 cidr length %q", ip4)
+			}
+		}
+		if dual {
+			ip6Bits, err = strconv.Atoi(ip6)
+			if err != nil || ip6Bits < 0 || ip6Bits > 128 {
+				return "", 0, 0, apperror.NewErrorf("invalid ip6 cidr length %q", ip6)
+			}
+		}
+	}
+	if target == "" {
+		target = domain
+	}
+	return target, ip4Bits, ip6Bits, nil
+}
+
+// checkDMARC evaluates the DMARC record of the From domain against the SPF and DKIM results (RFC 7489)
+func checkDMARC(ctx context.Context, r DNSResolver, results *AuthResults) (AuthResult, string) {
+	if results.FromDomain == "" {
+		return AuthNone, ""
+	}
+
+	tags, result := lookupDMARC(ctx, r, results.FromDomain)
+	if tags == nil {
+		organizational, ok := organizationalDomain(results.FromDomain)
+		if result == AuthNone && ok && !strings.EqualFold(organizational, results.FromDomain) {
+			tags, result = lookupDMARC(ctx, r, organizational)
+		}
+		if tags == nil {
+			return result, ""
+		}
+	}
+
+	policy := strings.ToLower(tags["p"])
+	if results.SPF == AuthPass && aligned(results.SPFDomain, results.FromDomain, tags["aspf"]) {
+		return AuthPass, policy
+	}
+	for _, domain := range results.DKIMDomains {
+		if aligned(domain, results.FromDomain, tags["adkim"]) {
+			return AuthPass, policy
+		}
+	}
+	return AuthFail, policy
+}
+
+// lookupDMARC retrieves and parses the DMARC record of a domain
+func lookupDMARC(ctx context.Context, r DNSResolver, domain string) (map[string]string, AuthResult) {
+	records, err := r.LookupTXT(ctx, "_dmarc."+domain)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, AuthNone
+		}
+		return nil, AuthTempError
+	}
+
+	for _, txt := range records {
+		tags := parseTags(txt)
+		if tags["v"] == "DMARC1" {
+			return tags, ""
+		}
+	}
+	return nil, AuthNone
+}
+
+// aligned reports whether the authenticated domain is aligned with the From domain
+// Strict alignment requires identical domains, relaxed alignment identical organizational domains.
+// Relaxed alignment falls back to strict alignment if no organizational domain can be determined.
+func aligned(domain, from, mode string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	from = strings.ToLower(strings.TrimSuffix(from, "."))
+	if domain == from {
+		return true
+	}
+	if strings.EqualFold(mode, "s") {
+		return false
+	}
+	organizational, ok := organizationalDomain(domain)
+	if !ok {
+		return false
+	}
+	fromOrganizational, ok := organizationalDomain(from)
+	return ok && organizational == fromOrganizational
+}
+
+// organizationalDomain returns the organizational domain, the registered domain below its public suffix
+// according to the public suffix list, e.g. example.co.uk for mail.example.co.uk (RFC 7489, section 3.2).
+// It reports false for public suffixes themselves and invalid domains.
+func organizationalDomain(domain string) (string, bool) {
+	organizational, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimSuffix(domain, ".")))
+	if err != nil {
+		return "", false
+	}
+	return organizational, true
+}
+
+// isNotFound reports whether the DNS error indicates a missing record
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,161 @@
+package mail_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/valentin-kaiser/go-core/mail"
+)
+
+// staticResolver answers DNS lookups from static records
+type staticResolver struct {
+	txt map[string][]string
+	ip  map[string][]net.IPAddr
+	mx  map[string][]*net.MX
+}
+
+func (r *staticResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := r.txt[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if addrs, ok := r.ip[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r *staticResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if mxs, ok := r.mx[name]; ok {
+		return mxs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// signedMessage builds a message with a simple/simple rsa-sha256 DKIM signature
+func signedMessage(t *testing.T, key *rsa.PrivateKey, from, body string) string {
+	t.Helper()
+
+	headers := "From: " + from + "\r\nSubject: Hello\r\n"
+	bodyHash := sha256.Sum256([]byte(body))
+	signature := "DKIM-Signature: v=1; a=rsa-sha256; c=simple/simple; d=example.com; s=sel; h=from:subject; bh=" +
+		base64.StdEncoding.EncodeToString(bodyHash[:]) + "; b="
+
+	digest := sha256.Sum256([]byte(headers + signature))
+	b, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+
+	return signature + base64.StdEncoding.EncodeToString(b) + "\r\n" + headers + "\r\n" + body
+}
+
+func TestVerifyAuthentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	resolver := &staticResolver{
+		txt: map[string][]string{
+			"example.com":                {"v=spf1 ip4:192.0.2.0/24 include:_spf.example.net -all"},
+			"_spf.example.net":           {"v=spf1 mx ~all"},
+			"soft.example.org":           {"v=spf1 ~all"},
+			"sel._domainkey.example.com": {"v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(pub)},
+			"_dmarc.example.com":         {"v=DMARC1; p=reject; adkim=s"},
+			"_dmarc.other.org":           {"v=DMARC1; p=quarantine"},
+			"unrelated.example.org":      {"some verification token"},
+			"mixed.example.com":          {"v=spf1 a:mail.example.com/24 -all"},
+			"evil.co.uk":                 {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"mail.bank.co.uk":            {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"_dmarc.bank.co.uk":          {"v=DMARC1; p=reject"},
+		},
+		ip: map[string][]net.IPAddr{
+			"mx.example.net":   {{IP: net.ParseIP("198.51.100.7")}},
+			"mail.example.com": {{IP: net.ParseIP("203.0.113.1")}},
+		},
+		mx: map[string][]*net.MX{
+			"_spf.example.net": {{Host: "mx.example.net", Pref: 10}},
+		},
+	}
+
+	ctx := t.Context()
+	message := []byte(signedMessage(t, key, "Alice <alice@example.com>", "Hi there\r\n"))
+
+	tests := []struct {
+		name   string
+		ip     string
+		from   string
+		data   []byte
+		spf    mail.AuthResult
+		dkim   mail.AuthResult
+		dmarc  mail.AuthResult
+		policy string
+	}{
+		{"all pass", "192.0.2.10", "alice@example.com", message, mail.AuthPass, mail.AuthPass, mail.AuthPass, "reject"},
+		{"spf include mx", "198.51.100.7", "alice@example.com", message, mail.AuthPass, mail.AuthPass, mail.AuthPass, "reject"},
+		{"spf fail dkim aligned", "203.0.113.50", "alice@example.com", message, mail.AuthFail, mail.AuthPass, mail.AuthPass, "reject"},
+		{"spf softfail", "203.0.113.50", "bob@soft.example.org", message, mail.AuthSoftFail, mail.AuthPass, mail.AuthPass, "reject"},
+		{"spf none", "203.0.113.50", "bob@unrelated.example.org", message, mail.AuthNone, mail.AuthPass, mail.AuthPass, "reject"},
+		{"spf a cidr", "203.0.113.200", "bob@mixed.example.com", message, mail.AuthPass, mail.AuthPass, mail.AuthPass, "reject"},
+		{
+			"tampered body", "192.0.2.10", "alice@example.com",
+			[]byte(strings.Replace(string(message), "Hi there", "Hi thEre", 1)),
+			mail.AuthPass, mail.AuthFail, mail.AuthPass, "reject",
+		},
+		{
+			"unaligned from", "192.0.2.10", "alice@example.com",
+			[]byte(signedMessage(t, key, "Mallory <mallory@other.org>", "Hi there\r\n")),
+			mail.AuthPass, mail.AuthPass, mail.AuthFail, "quarantine",
+		},
+		{
+			// Domains below the same public suffix are different organizations
+			"public suffix not aligned", "192.0.2.10", "mallory@evil.co.uk",
+			[]byte(signedMessage(t, key, "Bank <ceo@bank.co.uk>", "Hi there\r\n")),
+			mail.AuthPass, mail.AuthPass, mail.AuthFail, "reject",
+		},
+		{
+			"relaxed subdomain aligned", "192.0.2.10", "notify@mail.bank.co.uk",
+			[]byte(signedMessage(t, key, "Bank <ceo@bank.co.uk>", "Hi there\r\n")),
+			mail.AuthPass, mail.AuthPass, mail.AuthPass, "reject",
+		},
+		{
+			"unsigned", "203.0.113.50", "alice@example.com",
+			[]byte("From: alice@example.com\r\nSubject: Hello\r\n\r\nHi there\r\n"),
+			mail.AuthFail, mail.AuthNone, mail.AuthFail, "reject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := mail.VerifyAuthentication(ctx, resolver, net.ParseIP(tt.ip), "mail.example.com", tt.from, tt.data)
+			if results.SPF != tt.spf {
+				t.Errorf("expected SPF %s, got %s", tt.spf, results.SPF)
+			}
+			if results.DKIM != tt.dkim {
+				t.Errorf("expected DKIM %s, got %s", tt.dkim, results.DKIM)
+			}
+			if results.DMARC != tt.dmarc {
+				t.Errorf("expected DMARC %s, got %s", tt.dmarc, results.DMARC)
+			}
+			if results.DMARCPolicy != tt.policy {
+				t.Errorf("expected DMARC policy %s, got %s", tt.policy, results.DMARCPolicy)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package mail
 import (
 	"crypto/tls"
 	"io/fs"
+	"strings"
 	"time"
 
 	"github.com/valentin-kaiser/go-core/apperror"
@@ -42,14 +43,18 @@ type ClientConfig struct {
 	AuthMethod string `yaml:"auth_method" json:"auth_method"`
 	// Encryption method (NONE, STARTTLS, TLS)
 	Encryption string `yaml:"encryption" json:"encryption"`
+	// TLSPolicy for STARTTLS encryption (REQUIRE, PREFER, NONE), defaults to REQUIRE
+	TLSPolicy string `yaml:"tls_policy" json:"tls_policy"`
 	// SkipCertificateVerification skips TLS certificate verification
 	SkipCertificateVerification bool `yaml:"skip_cert_verification" json:"skip_cert_verification"`
-	// Timeout for SMTP operations
+	// Timeout for connecting to the SMTP server and for the SMTP session of each send attempt
 	Timeout time.Duration `yaml:"timeout" json:"timeout"`
 	// MaxRetries for failed email sending
 	MaxRetries int `yaml:"max_retries" json:"max_retries"`
 	// RetryDelay between retries
 	RetryDelay time.Duration `yaml:"retry_delay" json:"retry_delay"`
+	// MaxMessageBytes is the maximum size of an outgoing message, zero disables the check
+	MaxMessageBytes int64 `yaml:"max_message_bytes" json:"max_message_bytes"`
 }
 
 // ServerConfig holds the SMTP server configuration
@@ -104,7 +109,7 @@ type SecurityConfig struct {
 	IPBlocklist []string `yaml:"ip_blocklist" json:"ip_blocklist"`
 	// MaxConnectionsPerIP limits connections per IP address
 	MaxConnectionsPerIP int `yaml:"max_connections_per_ip" json:"max_connections_per_ip"`
-	// RateLimitPerIP limits commands per IP per minute
+	// RateLimitPerIP limits connections and commands per IP per minute, bursts up to the limit are allowed
 	RateLimitPerIP int `yaml:"rate_limit_per_ip" json:"rate_limit_per_ip"`
 	// AuthFailureDelay adds delay after authentication failures
 	AuthFailureDelay time.Duration `yaml:"auth_failure_delay" json:"auth_failure_delay"`
@@ -114,6 +119,9 @@ type SecurityConfig struct {
 	AuthFailureWindow time.Duration `yaml:"auth_failure_window" json:"auth_failure_window"`
 	// LogSecurityEvents enables detailed security logging
 	LogSecurityEvents bool `yaml:"log_security_events" json:"log_security_events"`
+	// VerifyAuthentication enables SPF, DKIM and DMARC verification of received messages
+	// The results are available to handlers through AuthResultsFromContext
+	VerifyAuthentication bool `yaml:"verify_authentication" json:"verify_authentication"`
 }
 
 // QueueConfig holds the queue configuration for mail processing
@@ -130,6 +138,9 @@ type QueueConfig struct {
 	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
 	// JobTimeout for mail job processing
 	JobTimeout time.Duration `yaml:"job_timeout" json:"job_timeout"`
+	// StoragePath is the directory queued mail is persisted to until it is sent, empty disables persistence.
+	// It applies to the in-memory queue only, persistent queue backends such as RabbitMQ or Redis keep unsent mail themselves.
+	StoragePath string `yaml:"storage_path" json:"storage_path"`
 }
 
 // TemplateConfig holds the template configuration
@@ -157,6 +168,7 @@ func DefaultConfig() *Config {
 			Auth:                        false,
 			AuthMethod:                  "PLAIN",
 			Encryption:                  "STARTTLS",
+			TLSPolicy:                   "REQUIRE",
 			SkipCertificateVerification: false,
 			Timeout:                     30 * time.Second,
 			MaxRetries:                  3,
@@ -238,6 +250,11 @@ func (c *ClientConfig) Validate() error {
 	if c.FQDN == "" {
 		return apperror.NewError("SMTP FQDN is required")
 	}
+	switch TLSPolicy(strings.ToUpper(c.TLSPolicy)) {
+	case "", TLSPolicyRequire, TLSPolicyPrefer, TLSPolicyNone:
+	default:
+		return apperror.NewErrorf("invalid TLS policy %q", c.TLSPolicy)
+	}
 	return nil
 }
 
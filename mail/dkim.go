@@ -0,0 +1,354 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 -- rsa-sha1 signatures are still in use and only verified
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// wsp matches runs of whitespace for relaxed canonicalization
+var wsp = regexp.MustCompile(`[ \t]+`)
+
+// headerField is a raw header field of a message including folding and the trailing CRLF
+type headerField struct {
+	name string
+	raw  string
+}
+
+// splitMessage splits a message into its header fields and body
+// Bare LF line endings are normalized to CRLF
+func splitMessage(data []byte) ([]headerField, []byte) {
+	data = normalizeLineEndings(data)
+
+	var headers []headerField
+	rest := data
+	for len(rest) > 0 {
+		if bytes.HasPrefix(rest, []byte("\r\n")) {
+			return headers, rest[2:]
+		}
+
+		end := 0
+		for {
+			idx := bytes.Index(rest[end:], []byte("\r\n"))
+			if idx == -1 {
+				end = len(rest)
+				break
+			}
+			end += idx + 2
+			// Continuation lines start with whitespace
+			if end >= len(rest) || (rest[end] != ' ' && rest[end] != '\t') {
+				break
+			}
+		}
+
+		raw := string(rest[:end])
+		name := raw
+		if idx := strings.Index(raw, ":"); idx != -1 {
+			name = raw[:idx]
+		}
+		headers = append(headers, headerField{name: strings.TrimSpace(name), raw: raw})
+		rest = rest[end:]
+	}
+
+	return headers, nil
+}
+
+// normalizeLineEndings converts bare LF line endings to CRLF
+func normalizeLineEndings(data []byte) []byte {
+	if !bytes.Contains(data, []byte("\n")) {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// canonicalizeHeader canonicalizes a raw header field as described in RFC 6376 section 3.4
+func canonicalizeHeader(raw string, relaxed bool) string {
+	if !relaxed {
+		return raw
+	}
+
+	name, value, _ := strings.Cut(raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = wsp.ReplaceAllString(value, " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(value) + "\r\n"
+}
+
+// canonicalizeBody canonicalizes a message body as described in RFC 6376 section 3.4
+func canonicalizeBody(body []byte, relaxed bool) []byte {
+	if relaxed {
+		lines := strings.Split(string(body), "\r\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(wsp.ReplaceAllString(line, " "), " ")
+		}
+		body = []byte(strings.Join(lines, "\r\n"))
+	}
+
+	for bytes.HasSuffix(body, []byte("\r\n")) {
+		body = body[:len(body)-2]
+	}
+
+	if len(body) == 0 {
+		if relaxed {
+			return nil
+		}
+		return []byte("\r\n")
+	}
+	return append(body, '\r', '\n')
+}
+
+// dkimSignature holds the parsed tags of a DKIM-Signature header
+type dkimSignature struct {
+	algorithm       string
+	signature       []byte
+	bodyHash        []byte
+	domain          string
+	selector        string
+	headers         []string
+	length          int64
+	relaxedHeader   bool
+	relaxedBody     bool
+	signatureHeader string
+}
+
+// parseDKIMSignature parses the tags of a raw DKIM-Signature header field
+func parseDKIMSignature(raw string) (*dkimSignature, error) {
+	_, value, _ := strings.Cut(raw, ":")
+	tags := parseTags(value)
+
+	if tags["v"] != "1" {
+		return nil, apperror.NewErrorf("unsupported DKIM signature version %q", tags["v"])
+	}
+
+	sig := &dkimSignature{
+		algorithm: strings.ToLower(tags["a"]),
+		domain:    strings.ToLower(tags["d"]),
+		selector:  tags["s"],
+		length:    -1,
+	}
+	if sig.domain == "" || sig.selector == "" {
+		return nil, apperror.NewError("DKIM signature is missing the domain or selector")
+	}
+
+	var err error
+	sig.signature, err = base64.StdEncoding.DecodeString(stripWhitespace(tags["b"]))
+	if err != nil {
+		return nil, apperror.NewError("invalid DKIM signature data").AddError(err)
+	}
+	sig.bodyHash, err = base64.StdEncoding.DecodeString(stripWhitespace(tags["bh"]))
+	if err != nil {
+		return nil, apperror.NewError("invalid DKIM body hash").AddError(err)
+	}
+
+	for _, h := range strings.Split(tags["h"], ":") {
+		if h = strings.TrimSpace(h); h != "" {
+			sig.headers = append(sig.headers, h)
+		}
+	}
+	if len(sig.headers) == 0 {
+		return nil, apperror.NewError("DKIM signature does not sign any headers")
+	}
+
+	if l, ok := tags["l"]; ok {
+		sig.length, err = strconv.ParseInt(l, 10, 64)
+		if err != nil || sig.length < 0 {
+			return nil, apperror.NewErrorf("invalid DKIM body length %q", l)
+		}
+	}
+
+	header, body, _ := strings.Cut(tags["c"], "/")
+	sig.relaxedHeader = header == "relaxed"
+	sig.relaxedBody = body == "relaxed"
+
+	// The signature header is hashed with an empty b= tag
+	sig.signatureHeader = stripSignatureData(raw)
+
+	return sig, nil
+}
+
+// stripSignatureData removes the value of the b= tag from a raw DKIM-Signature header field
+func stripSignatureData(raw string) string {
+	name, value, _ := strings.Cut(raw, ":")
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		key, _, ok := strings.Cut(part, "=")
+		if ok && strings.TrimSpace(key) == "b" {
+			parts[i] = part[:strings.Index(part, "=")+1]
+		}
+	}
+	return name + ":" + strings.Join(parts, ";")
+}
+
+// hash returns the hash function of the signature algorithm
+func (sig *dkimSignature) hash() (crypto.Hash, func() hash.Hash, error) {
+	switch sig.algorithm {
+	case "rsa-sha256", "ed25519-sha256":
+		return crypto.SHA256, sha256.New, nil
+	case "rsa-sha1":
+		return crypto.SHA1, sha1.New, nil
+	default:
+		return 0, nil, apperror.NewErrorf("unsupported DKIM algorithm %q", sig.algorithm)
+	}
+}
+
+// verifyDKIM verifies all DKIM signatures of the message and returns the domains of the valid ones
+func verifyDKIM(ctx context.Context, resolver DNSResolver, data []byte) (AuthResult, []string) {
+	headers, body := splitMessage(data)
+
+	result := AuthNone
+	var domains []string
+	for _, field := range headers {
+		if !strings.EqualFold(field.name, "DKIM-Signature") {
+			continue
+		}
+
+		sig, err := parseDKIMSignature(field.raw)
+		if err != nil {
+			logger.Debug().Err(err).Msg("invalid DKIM signature")
+			if result == AuthNone {
+				result = AuthPermError
+			}
+			continue
+		}
+
+		err = sig.verify(ctx, resolver, headers, body)
+		if err != nil {
+			logger.Debug().Err(err).Field("domain", sig.domain).Msg("DKIM verification failed")
+			if result != AuthPass {
+				result = AuthFail
+			}
+			continue
+		}
+
+		result = AuthPass
+		domains = append(domains, sig.domain)
+	}
+
+	return result, domains
+}
+
+// verify checks the body hash and the signature of the DKIM signature
+func (sig *dkimSignature) verify(ctx context.Context, resolver DNSResolver, headers []headerField, body []byte) error {
+	cryptoHash, newHash, err := sig.hash()
+	if err != nil {
+		return err
+	}
+
+	canonicalBody := canonicalizeBody(body, sig.relaxedBody)
+	if sig.length >= 0 && sig.length < int64(len(canonicalBody)) {
+		canonicalBody = canonicalBody[:sig.length]
+	}
+	h := newHash()
+	h.Write(canonicalBody)
+	if !bytes.Equal(h.Sum(nil), sig.bodyHash) {
+		return apperror.NewError("DKIM body hash does not match")
+	}
+
+	h = newHash()
+	used := make(map[int]bool)
+	for _, name := range sig.headers {
+		// Headers are consumed from the bottom for repeated names
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(headers[i].name, name) {
+				continue
+			}
+			used[i] = true
+			h.Write([]byte(canonicalizeHeader(headers[i].raw, sig.relaxedHeader)))
+			break
+		}
+	}
+	h.Write([]byte(strings.TrimSuffix(canonicalizeHeader(sig.signatureHeader, sig.relaxedHeader), "\r\n")))
+	digest := h.Sum(nil)
+
+	key, err := lookupDKIMKey(ctx, resolver, sig.selector, sig.domain)
+	if err != nil {
+		return err
+	}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, cryptoHash, digest, sig.signature)
+		if err != nil {
+			return apperror.NewError("DKIM signature is invalid").AddError(err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, digest, sig.signature) {
+			return apperror.NewError("DKIM signature is invalid")
+		}
+	default:
+		return apperror.NewErrorf("unsupported DKIM key type %T", key)
+	}
+
+	return nil
+}
+
+// lookupDKIMKey retrieves the public key of the selector from DNS
+func lookupDKIMKey(ctx context.Context, resolver DNSResolver, selector, domain string) (crypto.PublicKey, error) {
+	records, err := resolver.LookupTXT(ctx, selector+"._domainkey."+domain)
+	if err != nil {
+		return nil, apperror.NewError("looking up DKIM key failed").AddError(err)
+	}
+
+	tags := parseTags(strings.Join(records, ""))
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, apperror.NewErrorf("unsupported DKIM key version %q", v)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(stripWhitespace(tags["p"]))
+	if err != nil || len(data) == 0 {
+		return nil, apperror.NewError("DKIM key is revoked or invalid")
+	}
+
+	if strings.EqualFold(tags["k"], "ed25519") {
+		if len(data) != ed25519.PublicKeySize {
+			return nil, apperror.NewError("invalid ed25519 DKIM key")
+		}
+		return ed25519.PublicKey(data), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		rsaKey, rsaErr := x509.ParsePKCS1PublicKey(data)
+		if rsaErr != nil {
+			return nil, apperror.NewError("parsing DKIM key failed").AddError(err)
+		}
+		return rsaKey, nil
+	}
+	return key, nil
+}
+
+// parseTags parses a tag=value list as used by DKIM and DMARC records
+func parseTags(s string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return tags
+}
+
+// stripWhitespace removes all whitespace including folding from a tag value
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,85 @@
+package email
+
+import (
+	"net/smtp"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// checkDSN checks the delivery status notification request of the email (RFC 3461, section 4)
+func (e *Email) checkDSN() []error {
+	var errs []error
+	for _, n := range e.Notify {
+		switch strings.ToUpper(n) {
+		case "SUCCESS", "FAILURE", "DELAY":
+		case "NEVER":
+			if len(e.Notify) > 1 {
+				errs = append(errs, apperror.NewError("the NEVER notification condition cannot be combined with others"))
+			}
+		default:
+			errs = append(errs, apperror.NewErrorf("invalid notification condition %q", n))
+		}
+	}
+	switch strings.ToUpper(e.Return) {
+	case "", "FULL", "HDRS":
+	default:
+		errs = append(errs, apperror.NewErrorf("invalid notification return %q, it must be FULL or HDRS", e.Return))
+	}
+	return errs
+}
+
+// dsn reports whether delivery status notifications are requested and supported by the server
+func (e *Email) dsn(c *smtp.Client) bool {
+	if len(e.Notify) == 0 && e.Return == "" {
+		return false
+	}
+	ok, _ := c.Extension("DSN")
+	return ok
+}
+
+// mail issues the MAIL command like smtp.Client.Mail, with the RET parameter if
+// delivery status notifications are requested and the server supports them
+func (e *Email) mail(c *smtp.Client, from string) error {
+	if !e.dsn(c) || e.Return == "" {
+		return c.Mail(from)
+	}
+	if strings.ContainsAny(from, "\r\n") {
+		return apperror.NewError("smtp: A line must not contain CR or LF")
+	}
+
+	cmd := "MAIL FROM:<%s>"
+	if ok, _ := c.Extension("8BITMIME"); ok {
+		cmd += " BODY=8BITMIME"
+	}
+	if ok, _ := c.Extension("SMTPUTF8"); ok {
+		cmd += " SMTPUTF8"
+	}
+	cmd += " RET=" + strings.ToUpper(e.Return)
+	return command(c, 250, cmd, from)
+}
+
+// rcpt issues the RCPT command like smtp.Client.Rcpt, with the NOTIFY parameter if
+// delivery status notifications are requested and the server supports them
+func (e *Email) rcpt(c *smtp.Client, to string) error {
+	if !e.dsn(c) || len(e.Notify) == 0 {
+		return c.Rcpt(to)
+	}
+	if strings.ContainsAny(to, "\r\n") {
+		return apperror.NewError("smtp: A line must not contain CR or LF")
+	}
+
+	return command(c, 25, "RCPT TO:<%s> NOTIFY="+strings.ToUpper(strings.Join(e.Notify, ",")), to)
+}
+
+// command sends the command and reads the response with the expected code
+func command(c *smtp.Client, code int, format string, args ...any) error {
+	id, err := c.Text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	c.Text.StartResponse(id)
+	defer c.Text.EndResponse(id)
+	_, _, err = c.Text.ReadResponse(code)
+	return err
+}
@@ -0,0 +1,56 @@
+package email
+
+import (
+	"bytes"
+	"net/smtp"
+	"unicode/utf8"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// maxLineOctets is the maximum length of a line of 8bit data without its CRLF (RFC 5322, section 2.1.1)
+const maxLineOctets = 998
+
+// eightBit reports whether the email can be sent with 8bit encoded bodies over the client.
+// This requires the 8BITMIME extension (RFC 6152) and text and HTML bodies that are 8bit safe.
+// Encrypted messages and delivery status notifications keep their encoding, as the former
+// are base64 encoded anyway.
+func (e *Email) eightBit(c *smtp.Client) bool {
+	if ok, _ := c.Extension("8BITMIME"); !ok {
+		return false
+	}
+	if e.report != nil || len(e.recipientCerts) > 0 || len(e.Text)+len(e.HTML) == 0 {
+		return false
+	}
+	return eightBitSafe(e.Text) && eightBitSafe(e.HTML)
+}
+
+// eightBitSafe reports whether msg can be sent unencoded with the 8bit transfer encoding:
+// it must be valid UTF-8 without NUL, CR only as part of CRLF and lines of at most 998 octets (RFC 2045, section 2.8)
+func eightBitSafe(msg []byte) bool {
+	if !utf8.Valid(msg) || bytes.IndexByte(msg, 0) >= 0 {
+		return false
+	}
+	for line := range bytes.Lines(msg) {
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > maxLineOctets || bytes.IndexByte(line, '\r') >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// eightBitMessage returns the email serialized with 8bit encoded bodies if the server and the email
+// allow it, nil otherwise, in which case the quoted-printable data prepared before connecting is sent.
+// The MAIL command declares the 8bit body with the BODY=8BITMIME parameter whenever the server supports the extension.
+func (e *Email) eightBitMessage(c *smtp.Client) ([]byte, error) {
+	if !e.eightBit(c) {
+		return nil, nil
+	}
+	raw, err := e.serialize(true)
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+	return raw, nil
+}
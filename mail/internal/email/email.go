@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"mime/quotedprintable"
+	"net"
 	"net/mail"
 	"net/smtp"
 	"net/textproto"
@@ -49,7 +51,20 @@ type Email struct {
 	Sender      string
 	Headers     textproto.MIMEHeader
 	Attachments []*Attachment
+	// ReadReceipt are the addresses read receipts are requested for with the Disposition-Notification-To header
 	ReadReceipt []string
+	// Notify are the conditions (SUCCESS, FAILURE, DELAY or NEVER) delivery status notifications are
+	// requested for with the DSN extension (RFC 3461), servers without the extension ignore the request
+	Notify []string
+	// Return selects whether failure notifications contain the full message (FULL) or its headers only (HDRS)
+	Return string
+	// MaxBytes is the maximum size of the serialized message checked by Validate, zero disables the check
+	MaxBytes int64
+	// Timeout limits connecting to the SMTP server and the whole SMTP session, zero disables the limit
+	Timeout time.Duration
+
+	recipientCerts []*x509.Certificate // S/MIME encryption recipients, see Encrypt
+	report         *report             // delivery status notification, see NewDSN
 }
 
 // Attachment is a struct representing an email attachment.
@@ -194,15 +209,25 @@ func (e *Email) AttachFile(filename string) (*Attachment, error) {
 }
 
 // Bytes converts the Email object to a []byte representation, including all needed MIMEHeaders, boundaries, etc.
+// The text and HTML bodies are quoted-printable encoded, so the message can be relayed by any server.
 func (e *Email) Bytes() ([]byte, error) {
+	return e.serialize(false)
+}
+
+// serialize converts the email like Bytes, the text and HTML bodies are sent unencoded
+// with the 8bit transfer encoding if eightBit is set
+func (e *Email) serialize(eightBit bool) ([]byte, error) {
 	// Estimate buffer size based on email content
 	bufferSize := e.estimateSize()
-	buf := bytes.NewBuffer(make([]byte, 0, bufferSize))
+	body := bytes.NewBuffer(make([]byte, 0, bufferSize))
 
 	headers, err := e.msgHeaders()
 	if err != nil {
 		return nil, apperror.Wrap(err)
 	}
+	if e.report != nil {
+		return e.reportBytes(headers)
+	}
 
 	htmlAttachments, otherAttachments := e.categorizeAttachments()
 	if len(e.HTML) == 0 && len(htmlAttachments) > 0 {
@@ -215,9 +240,14 @@ func (e *Email) Bytes() ([]byte, error) {
 		isRelated     = len(e.HTML) > 0 && len(htmlAttachments) > 0
 	)
 
+	encoding := "quoted-printable"
+	if eightBit {
+		encoding = "8bit"
+	}
+
 	var w *multipart.Writer
 	if isMixed || isAlternative || isRelated {
-		w = multipart.NewWriter(buf)
+		w = multipart.NewWriter(body)
 	}
 	switch {
 	case isMixed:
@@ -228,20 +258,10 @@ func (e *Email) Bytes() ([]byte, error) {
 		headers.Set("Content-Type", "multipart/related;\r\n boundary="+w.Boundary())
 	case len(e.HTML) > 0:
 		headers.Set("Content-Type", "text/html; charset=UTF-8")
-		headers.Set("Content-Transfer-Encoding", "quoted-printable")
+		headers.Set("Content-Transfer-Encoding", encoding)
 	default:
 		headers.Set("Content-Type", "text/plain; charset=UTF-8")
-		headers.Set("Content-Transfer-Encoding", "quoted-printable")
-	}
-
-	err = headerToBytes(buf, headers)
-	if err != nil {
-		return nil, apperror.Wrap(err)
-	}
-
-	_, err = io.WriteString(buf, "\r\n")
-	if err != nil {
-		return nil, apperror.NewError("could not write headers").AddError(err)
+		headers.Set("Content-Transfer-Encoding", encoding)
 	}
 
 	if len(e.Text) > 0 || len(e.HTML) > 0 {
@@ -249,7 +269,7 @@ func (e *Email) Bytes() ([]byte, error) {
 
 		subWriter = w
 		if isMixed && isAlternative {
-			subWriter = multipart.NewWriter(buf)
+			subWriter = multipart.NewWriter(body)
 			header := textproto.MIMEHeader{
 				"Content-Type": {"multipart/alternative;\r\n boundary=" + subWriter.Boundary()},
 			}
@@ -260,7 +280,7 @@ func (e *Email) Bytes() ([]byte, error) {
 		}
 
 		if len(e.Text) > 0 {
-			err := writeMessage(buf, e.Text, isMixed || isAlternative, "text/plain", subWriter)
+			err := writeMessage(body, e.Text, isMixed || isAlternative, "text/plain", encoding, subWriter)
 			if err != nil {
 				return nil, apperror.Wrap(err)
 			}
@@ -270,7 +290,7 @@ func (e *Email) Bytes() ([]byte, error) {
 			messageWriter := subWriter
 			var relatedWriter *multipart.Writer
 			if (isMixed || isAlternative) && len(htmlAttachments) > 0 {
-				relatedWriter = multipart.NewWriter(buf)
+				relatedWriter = multipart.NewWriter(body)
 				header := textproto.MIMEHeader{
 					"Content-Type": {"multipart/related;\r\n boundary=" + relatedWriter.Boundary()},
 				}
@@ -286,7 +306,7 @@ func (e *Email) Bytes() ([]byte, error) {
 				messageWriter = w
 			}
 
-			err := writeMessage(buf, e.HTML, isMixed || isAlternative || isRelated, "text/html", messageWriter)
+			err := writeMessage(body, e.HTML, isMixed || isAlternative || isRelated, "text/html", encoding, messageWriter)
 			if err != nil {
 				return nil, apperror.Wrap(err)
 			}
@@ -337,245 +357,214 @@ func (e *Email) Bytes() ([]byte, error) {
 			return nil, apperror.NewError("could not close multipart/writer").AddError(err)
 		}
 	}
-	return buf.Bytes(), nil
+	return e.assemble(headers, body.Bytes())
 }
 
-// Send an email using the given host and SMTP auth (optional), returns any error thrown by smtp.SendMail
-// This function merges the To, Cc, and Bcc fields and calls the smtp.SendMail function using the Email.Bytes() output as the message
-func (e *Email) Send(address string, auth smtp.Auth, helo string) error {
-	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
-	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
-	for i := 0; i < len(to); i++ {
-		addr, err := mail.ParseAddress(to[i])
+// assemble writes the headers followed by the body, which is encrypted first if S/MIME encryption is enabled
+func (e *Email) assemble(headers textproto.MIMEHeader, body []byte) ([]byte, error) {
+	var err error
+	if len(e.recipientCerts) > 0 {
+		headers, body, err = e.encrypt(headers, body)
 		if err != nil {
-			return err
+			return nil, apperror.Wrap(err)
 		}
-		to[i] = addr.Address
-	}
-	if e.From == "" || len(to) == 0 {
-		return apperror.NewError("at least one From address and one To address must be specified")
 	}
-	sender, err := e.parseSender()
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(body)+1024))
+	err = headerToBytes(buf, headers)
 	if err != nil {
-		return apperror.Wrap(err)
+		return nil, apperror.Wrap(err)
 	}
-	raw, err := e.Bytes()
+
+	_, err = io.WriteString(buf, "\r\n")
 	if err != nil {
-		return apperror.Wrap(err)
+		return nil, apperror.NewError("could not write headers").AddError(err)
 	}
 
-	if helo == "" {
-		err = smtp.SendMail(address, auth, sender, to, raw)
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// Validate checks the email before it is sent and reports all problems at once:
+// the sender and recipient addresses must parse, a From field with multiple addresses requires
+// a Sender (RFC 5322, section 3.6.2), HTML related attachments require an HTML body,
+// Content-Types must be well-formed and the serialized message must not exceed MaxBytes.
+func (e *Email) Validate() error {
+	var errs []error
+
+	if e.From == "" {
+		errs = append(errs, apperror.NewError("From address is required"))
+	}
+	if len(e.To)+len(e.Cc)+len(e.Bcc) == 0 {
+		errs = append(errs, apperror.NewError("at least one recipient is required"))
+	}
+
+	checkAddresses := func(field string, addrs ...string) {
+		for _, addr := range addrs {
+			_, err := mail.ParseAddress(addr)
+			if err != nil {
+				errs = append(errs, apperror.NewErrorf("invalid %s address %q", field, addr).AddError(err))
+			}
+		}
+	}
+	if e.From != "" {
+		_, err := mail.ParseAddressList(e.From)
 		if err != nil {
-			return apperror.Wrap(err)
+			errs = append(errs, apperror.NewErrorf("invalid From address %q", e.From).AddError(err))
 		}
-		return nil
 	}
-
-	// Use custom HELO with lower-level SMTP client
-	conn, err := smtp.Dial(address)
-	if err != nil {
-		return apperror.NewError("could not dial SMTP connection").AddError(err)
+	// Checked on the From field that is written, like when the headers are built
+	if _, ok := e.Headers["Sender"]; !ok && e.Sender == "" && multipleAuthors(e.author()) {
+		errs = append(errs, apperror.NewError("a Sender address is required for multiple From addresses"))
 	}
+	if e.Sender != "" {
+		checkAddresses("Sender", e.Sender)
+	}
+	checkAddresses("To", e.To...)
+	checkAddresses("Cc", e.Cc...)
+	checkAddresses("Bcc", e.Bcc...)
+	checkAddresses("Reply-To", e.ReplyTo...)
+	checkAddresses("read receipt", e.ReadReceipt...)
+	errs = append(errs, e.checkDSN()...)
 
-	// Send custom HELO
-	err = conn.Hello(helo)
-	if err != nil {
-		return apperror.NewError("could not send HELO command").AddError(err)
+	htmlAttachments, _ := e.categorizeAttachments()
+	if len(e.HTML) == 0 && len(htmlAttachments) > 0 {
+		errs = append(errs, apperror.NewError("there are HTML attachments, but no HTML body"))
 	}
 
-	if auth != nil {
-		err = conn.Auth(auth)
+	if ct := e.Headers.Get("Content-Type"); ct != "" {
+		_, _, err := mime.ParseMediaType(ct)
 		if err != nil {
-			return apperror.NewError("could not authenticate SMTP client").AddError(err)
+			errs = append(errs, apperror.NewErrorf("invalid Content-Type header %q", ct).AddError(err))
 		}
 	}
-
-	err = conn.Mail(sender)
-	if err != nil {
-		return apperror.NewError("could not set SMTP sender").AddError(err)
+	for _, a := range e.Attachments {
+		for _, ct := range []string{a.ContentType, a.Header.Get("Content-Type")} {
+			if ct == "" {
+				continue
+			}
+			_, _, err := mime.ParseMediaType(ct)
+			if err != nil {
+				errs = append(errs, apperror.NewErrorf("invalid Content-Type %q of attachment %s", ct, a.Filename).AddError(err))
+			}
+		}
 	}
 
-	for _, addr := range to {
-		err = conn.Rcpt(addr)
+	// The size is only known once the message can be serialized
+	if e.MaxBytes > 0 && len(errs) == 0 {
+		raw, err := e.Bytes()
 		if err != nil {
-			return apperror.NewError("could not add SMTP recipient").AddError(err)
+			errs = append(errs, err)
+		}
+		if err == nil && int64(len(raw)) > e.MaxBytes {
+			errs = append(errs, apperror.NewErrorf("message size of %d bytes exceeds the maximum of %d bytes", len(raw), e.MaxBytes))
 		}
 	}
 
-	w, err := conn.Data()
-	if err != nil {
-		return apperror.NewError("could not create SMTP data writer").AddError(err)
+	if len(errs) > 0 {
+		return apperror.NewError("invalid email").AddErrors(errs)
 	}
+	return nil
+}
 
-	_, err = w.Write(raw)
+// Send an email using the given host and SMTP auth (optional)
+// This function merges the To, Cc, and Bcc fields and sends the Email.Bytes() output as the message
+func (e *Email) Send(address string, auth smtp.Auth, helo string) error {
+	tx, err := e.prepare()
 	if err != nil {
-		return apperror.NewError("could not write SMTP data").AddError(err)
+		return err
 	}
 
-	err = w.Close()
+	c, err := e.dial(address, nil)
 	if err != nil {
-		return apperror.NewError("could not close SMTP data writer").AddError(err)
+		return apperror.NewError("could not dial SMTP connection").AddError(err)
 	}
 
-	err = conn.Quit()
-	if err != nil {
-		return apperror.NewError("could not quit SMTP session").AddError(err)
+	// Send custom HELO, without it the connection is upgraded if supported like smtp.SendMail does
+	if helo != "" {
+		err = c.Hello(helo)
+		if err != nil {
+			apperror.Catch(c.Close, "could not close SMTP connection")
+			return apperror.NewError("could not send HELO command").AddError(err)
+		}
+	} else {
+		err = startTLS(c, &tls.Config{ServerName: hostname(address), MinVersion: tls.VersionTLS12}, false)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return e.deliver(c, auth, tx)
 }
 
 // SendWithTLS sends an email over tls with an optional TLS config.
 func (e *Email) SendWithTLS(address string, auth smtp.Auth, config *tls.Config, helo string) error {
-	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
-	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
-	for i := 0; i < len(to); i++ {
-		addr, err := mail.ParseAddress(to[i])
-		if err != nil {
-			return apperror.NewError("could not parse To address").AddError(err)
-		}
-		to[i] = addr.Address
-	}
-	if e.From == "" || len(to) == 0 {
-		return apperror.NewError("at least one From address and one To address must be specified")
-	}
-	sender, err := e.parseSender()
-	if err != nil {
-		return apperror.Wrap(err)
-	}
-	raw, err := e.Bytes()
+	tx, err := e.prepare()
 	if err != nil {
-		return apperror.Wrap(err)
+		return err
 	}
 
-	conn, err := tls.Dial("tcp", address, config)
-	if err != nil {
-		return apperror.NewError("could not dial TLS connection").AddError(err)
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
 	}
-	defer apperror.Catch(conn.Close, "could not close TLS connection")
-	c, err := smtp.NewClient(conn, strings.Split(address, ":")[0])
+	c, err := e.dial(address, config)
 	if err != nil {
-		return apperror.NewError("could not create SMTP client").AddError(err)
+		return apperror.NewError("could not dial TLS connection").AddError(err)
 	}
-	defer apperror.Catch(c.Quit, "could not quit SMTP session")
 
 	// Send custom HELO if provided (after connection but before auth)
 	if helo != "" {
 		err = c.Hello(helo)
 		if err != nil {
+			apperror.Catch(c.Close, "could not close SMTP connection")
 			return apperror.NewError("could not send HELO command").AddError(err)
 		}
 	}
 
-	if auth != nil {
-		err = c.Auth(auth)
-		if err != nil {
-			return apperror.NewError("could not authenticate SMTP client").AddError(err)
-		}
-	}
-	err = c.Mail(sender)
-	if err != nil {
-		return apperror.NewError("could not set SMTP sender").AddError(err)
-	}
-	for _, addr := range to {
-		err = c.Rcpt(addr)
-		if err != nil {
-			return apperror.NewError("could not add SMTP recipient").AddError(err)
-		}
-	}
-	w, err := c.Data()
-	if err != nil {
-		return apperror.NewError("could not create SMTP data writer").AddError(err)
-	}
-	_, err = w.Write(raw)
-	if err != nil {
-		return apperror.NewError("could not write SMTP data").AddError(err)
-	}
-	err = w.Close()
-	if err != nil {
-		return apperror.NewError("could not close SMTP data writer").AddError(err)
-	}
-	err = c.Quit()
-	if err != nil {
-		return apperror.NewError("could not quit SMTP session").AddError(err)
-	}
-	return nil
+	return e.deliver(c, auth, tx)
 }
 
 // SendWithStartTLS sends an email over TLS using STARTTLS with an optional TLS config.
-func (e *Email) SendWithStartTLS(address string, auth smtp.Auth, config *tls.Config, helo string) error {
-	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
-	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
-	for i := 0; i < len(to); i++ {
-		addr, err := mail.ParseAddress(to[i])
-		if err != nil {
-			return apperror.NewError("could not parse To address").AddError(err)
-		}
-		to[i] = addr.Address
-	}
-	if e.From == "" || len(to) == 0 {
-		return apperror.NewError("at least one From address and one To address must be specified")
-	}
-	sender, err := e.parseSender()
-	if err != nil {
-		return apperror.Wrap(err)
-	}
-	raw, err := e.Bytes()
+// If the server does not advertise STARTTLS, sending fails when required is set and
+// continues without encryption otherwise.
+func (e *Email) SendWithStartTLS(address string, auth smtp.Auth, config *tls.Config, helo string, required bool) error {
+	tx, err := e.prepare()
 	if err != nil {
-		return apperror.Wrap(err)
+		return err
 	}
 
-	conn, err := smtp.Dial(address)
+	c, err := e.dial(address, nil)
 	if err != nil {
 		return apperror.NewError("could not dial SMTP connection").AddError(err)
 	}
 
 	// Send custom HELO if provided (before STARTTLS)
 	if helo != "" {
-		err = conn.Hello(helo)
+		err = c.Hello(helo)
 		if err != nil {
+			apperror.Catch(c.Close, "could not close SMTP connection")
 			return apperror.NewError("could not send HELO command").AddError(err)
 		}
 	}
 
-	err = conn.StartTLS(config)
-	if err != nil {
-		return apperror.NewError("could not start TLS").AddError(err)
-	}
-	if auth != nil {
-		err = conn.Auth(auth)
-		if err != nil {
-			return apperror.NewError("could not authenticate SMTP client").AddError(err)
-		}
-	}
-	err = conn.Mail(sender)
-	if err != nil {
-		return apperror.NewError("could not set SMTP sender").AddError(err)
-	}
-	for _, addr := range to {
-		err = conn.Rcpt(addr)
-		if err != nil {
-			return apperror.NewError("could not add SMTP recipient").AddError(err)
-		}
-	}
-	w, err := conn.Data()
-	if err != nil {
-		return apperror.NewError("could not create SMTP data writer").AddError(err)
-	}
-	_, err = w.Write(raw)
+	err = startTLS(c, config, required)
 	if err != nil {
-		return apperror.NewError("could not write SMTP data").AddError(err)
+		return err
 	}
-	err = w.Close()
-	if err != nil {
-		return apperror.NewError("could not close SMTP data writer").AddError(err)
-	}
-	err = conn.Quit()
+
+	return e.deliver(c, auth, tx)
+}
+
+// dial connects to the SMTP server and creates a client for the connection, which is
+// wrapped in TLS if a config is given. Connecting is limited by Timeout and the deadline
+// of the connection is set to Timeout, so a stalled server cannot block the session
+func (e *Email) dial(address string, config *tls.Config) (*smtp.Client, error) {
+	dialer := &net.Dialer{Timeout: e.Timeout}
+	conn, err := dialer.Dial("tcp", address)
 	if err != nil {
-		return apperror.NewError("could not quit SMTP session").AddError(err)
+		return nil, err
 	}
-	return nil
+	return e.client(conn, hostname(address), config)
 }
 
 // msgHeaders merges the Email's various fields and custom headers together in a
@@ -615,12 +604,22 @@ func (e *Email) msgHeaders() (textproto.MIMEHeader, error) {
 	if _, ok := res["From"]; !ok {
 		res.Set("From", e.From)
 	}
+	// Messages with multiple authors must name the one who sent it
+	if _, ok := e.Headers["Sender"]; !ok && multipleAuthors(res.Get("From")) {
+		if e.Sender == "" {
+			return nil, apperror.NewError("a Sender address is required for multiple From addresses")
+		}
+		res.Set("Sender", e.Sender)
+	}
 	if _, ok := res["Date"]; !ok {
 		res.Set("Date", time.Now().Format(time.RFC1123Z))
 	}
 	if _, ok := res["MIME-Version"]; !ok {
 		res.Set("MIME-Version", "1.0")
 	}
+	if _, ok := e.Headers["Disposition-Notification-To"]; !ok && len(e.ReadReceipt) > 0 {
+		res.Set("Disposition-Notification-To", strings.Join(e.ReadReceipt, ", "))
+	}
 	for field, vals := range e.Headers {
 		if _, ok := res[field]; !ok {
 			res[field] = vals
@@ -629,6 +628,20 @@ func (e *Email) msgHeaders() (textproto.MIMEHeader, error) {
 	return res, nil
 }
 
+// author returns the From field written to the message, a From header takes precedence over e.From
+func (e *Email) author() string {
+	if v, ok := e.Headers["From"]; ok && len(v) > 0 {
+		return v[0]
+	}
+	return e.From
+}
+
+// multipleAuthors reports whether the From field contains more than one address
+func multipleAuthors(from string) bool {
+	authors, err := mail.ParseAddressList(from)
+	return err == nil && len(authors) > 1
+}
+
 func (e *Email) categorizeAttachments() (html []*Attachment, other []*Attachment) {
 	for _, a := range e.Attachments {
 		if a.HTMLRelated {
@@ -642,9 +655,17 @@ func (e *Email) categorizeAttachments() (html []*Attachment, other []*Attachment
 }
 
 // Select and parse an SMTP envelope sender address.  Choose Email.Sender if set, or fallback to Email.From.
+// Delivery status notifications are sent with the null reverse-path (RFC 5321, section 4.5.5).
 func (e *Email) parseSender() (string, error) {
-	if e.Sender != "" {
-		sender, err := mail.ParseAddress(e.Sender)
+	if e.report != nil {
+		return "", nil
+	}
+	sender := e.Sender
+	if sender == "" {
+		sender = e.Headers.Get("Sender")
+	}
+	if sender != "" {
+		sender, err := mail.ParseAddress(sender)
 		if err != nil {
 			return "", apperror.NewError("could not parse sender address").AddError(err)
 		}
@@ -658,6 +679,28 @@ func (e *Email) parseSender() (string, error) {
 	return from.Address, nil
 }
 
+// Envelope returns the SMTP envelope of the email: the sender address and the
+// addresses of all To, Cc and Bcc recipients
+func (e *Email) Envelope() (string, []string, error) {
+	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
+	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
+	for i := 0; i < len(to); i++ {
+		addr, err := mail.ParseAddress(to[i])
+		if err != nil {
+			return "", nil, apperror.NewError("could not parse To address").AddError(err)
+		}
+		to[i] = addr.Address
+	}
+	if e.From == "" || len(to) == 0 {
+		return "", nil, apperror.NewError("at least one From address and one To address must be specified")
+	}
+	sender, err := e.parseSender()
+	if err != nil {
+		return "", nil, apperror.Wrap(err)
+	}
+	return sender, to, nil
+}
+
 // estimateSize estimates the buffer size needed for the email serialization
 func (e *Email) estimateSize() int {
 	const (
@@ -702,11 +745,15 @@ func (a *Attachment) setDefaultHeaders() {
 	}
 }
 
-func writeMessage(buf io.Writer, msg []byte, multipart bool, mediaType string, w *multipart.Writer) error {
+// writeMessage writes msg with the transfer encoding, optionally as a new part of w.
+// The quoted-printable writer inserts soft line breaks so that no encoded line
+// exceeds 76 octets as required by RFC 2045, even within long tokens.
+// With the 8bit encoding msg is written as is, see eightBitSafe.
+func writeMessage(buf io.Writer, msg []byte, multipart bool, mediaType, encoding string, w *multipart.Writer) error {
 	if multipart {
 		header := textproto.MIMEHeader{
 			"Content-Type":              {mediaType + "; charset=UTF-8"},
-			"Content-Transfer-Encoding": {"quoted-printable"},
+			"Content-Transfer-Encoding": {encoding},
 		}
 		_, err := w.CreatePart(header)
 		if err != nil {
@@ -714,6 +761,14 @@ func writeMessage(buf io.Writer, msg []byte, multipart bool, mediaType string, w
 		}
 	}
 
+	if encoding == "8bit" {
+		_, err := buf.Write(msg)
+		if err != nil {
+			return apperror.NewError("could not write message").AddError(err)
+		}
+		return nil
+	}
+
 	qp := quotedprintable.NewWriter(buf)
 	_, err := qp.Write(msg)
 	if err != nil {
@@ -1,7 +1,15 @@
 package email_test
 
 import (
+	"bytes"
 	"crypto/tls"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
 	"os"
 	"path/filepath"
 	"strings"
@@ -142,6 +150,40 @@ func TestEmail_Bytes_HTML(t *testing.T) {
 	}
 }
 
+func TestEmail_Bytes_LongHTMLLine(t *testing.T) {
+	// A 10 KB single line HTML body with long tokens and characters that need encoding
+	var body strings.Builder
+	for body.Len() < 10*1024 {
+		body.WriteString(`<td style="color:#333;font-family:Arial,sans-serif">äöü=` + strings.Repeat("x", 100) + `</td>`)
+	}
+
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Test Subject"
+	e.Text = []byte(body.String())
+	e.HTML = []byte(body.String())
+
+	data, err := e.Bytes()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > 76 {
+			t.Fatalf("Line %d exceeds 76 octets (%d): %q", i+1, len(line), line)
+		}
+	}
+
+	parsed, err := email.NewFromReader(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Expected no error parsing message, got: %v", err)
+	}
+	if string(parsed.HTML) != body.String() {
+		t.Error("Expected HTML body to survive quoted-printable encoding")
+	}
+}
+
 func TestEmail_Bytes_Alternative(t *testing.T) {
 	e := email.New()
 	e.From = "sender@example.com"
@@ -236,12 +278,365 @@ func TestEmail_SendWithTLS_ValidationErrors(t *testing.T) {
 
 func TestEmail_SendWithStartTLS_ValidationErrors(t *testing.T) {
 	e := &email.Email{}
-	err := e.SendWithStartTLS("localhost:587", nil, &tls.Config{}, "")
+	err := e.SendWithStartTLS("localhost:587", nil, &tls.Config{}, "", true)
 	if err == nil {
 		t.Fatal("Expected error for empty email")
 	}
 }
 
+// plaintextServer runs an SMTP server that does not advertise STARTTLS but the extensions
+// It returns the address, a channel receiving the delivered message data and a channel
+// receiving the MAIL and RCPT commands
+func plaintextServer(t *testing.T, extensions ...string) (string, <-chan string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	delivered := make(chan string, 1)
+	commands := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, extensions, delivered, commands)
+		}
+	}()
+
+	return listener.Addr().String(), delivered, commands
+}
+
+// serveSMTP answers the SMTP session on the connection like plaintextServer
+func serveSMTP(conn net.Conn, extensions []string, delivered, commands chan<- string) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	_ = tc.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+		case "EHLO":
+			_ = tc.PrintfLine("250-localhost")
+			for _, ext := range extensions {
+				_ = tc.PrintfLine("250-%s", ext)
+			}
+			_ = tc.PrintfLine("250 8BITMIME")
+		case "MAIL", "RCPT":
+			commands <- line
+			_ = tc.PrintfLine("250 ok")
+		case "DATA":
+			_ = tc.PrintfLine("354 go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			delivered <- string(data)
+			_ = tc.PrintfLine("250 ok")
+		case "QUIT":
+			_ = tc.PrintfLine("221 bye")
+			return
+		default:
+			_ = tc.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestEmail_SendVERP(t *testing.T) {
+	e := email.New()
+	e.From = "Newsletter <news@example.org>"
+	e.To = []string{"jane@example.com"}
+	e.Cc = []string{"John <john@example.net>"}
+	e.Subject = "VERP"
+	e.Text = []byte("Hello")
+
+	addr, delivered, commands := plaintextServer(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- e.SendVERP(addr, nil, "bounces@example.org")
+	}()
+
+	for range 2 {
+		if data := <-delivered; !strings.Contains(data, "Subject: VERP") {
+			t.Errorf("Expected message to be delivered, got %q", data)
+		}
+	}
+	err := <-done
+	if err != nil {
+		t.Fatalf("SendVERP failed: %v", err)
+	}
+
+	expected := []string{
+		"MAIL FROM:<bounces+jane=example.com@example.org> BODY=8BITMIME",
+		"RCPT TO:<jane@example.com>",
+		"MAIL FROM:<bounces+john=example.net@example.org> BODY=8BITMIME",
+		"RCPT TO:<john@example.net>",
+	}
+	for _, want := range expected {
+		if got := <-commands; got != want {
+			t.Errorf("Expected command %q, got %q", want, got)
+		}
+	}
+
+	e.To = []string{`"jane doe"@example.com`}
+	err = e.SendVERP(addr, nil, "bounces@example.org")
+	if err == nil || !strings.Contains(err.Error(), "VERP") {
+		t.Errorf("Expected recipient that cannot be encoded to fail, got %v", err)
+	}
+	e.To = []string{"jane@example.com"}
+	err = e.SendVERP(addr, nil, "not an address")
+	if err == nil {
+		t.Error("Expected invalid base sender to fail")
+	}
+}
+
+func TestEmail_SendOverConn(t *testing.T) {
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Piped"
+	e.Text = []byte("Hello over a pipe")
+
+	client, server := net.Pipe()
+	delivered := make(chan string, 1)
+	commands := make(chan string, 16)
+	go serveSMTP(server, nil, delivered, commands)
+
+	err := e.SendOverConn(client, "localhost", nil)
+	if err != nil {
+		t.Fatalf("SendOverConn failed: %v", err)
+	}
+	if mail := <-commands; mail != "MAIL FROM:<sender@example.com> BODY=8BITMIME" {
+		t.Errorf("Expected MAIL command of the sender, got %q", mail)
+	}
+	if rcpt := <-commands; rcpt != "RCPT TO:<recipient@example.com>" {
+		t.Errorf("Expected RCPT command of the recipient, got %q", rcpt)
+	}
+	if data := <-delivered; !strings.Contains(data, "Subject: Piped") {
+		t.Errorf("Expected message to be delivered, got %q", data)
+	}
+
+	client, server = net.Pipe()
+	go serveSMTP(server, nil, delivered, commands)
+	err = e.SendWithStartTLSOverConn(client, "localhost", nil, &tls.Config{MinVersion: tls.VersionTLS12}, true)
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected required STARTTLS to fail without support, got %v", err)
+	}
+}
+
+func TestEmail_EightBitMIME(t *testing.T) {
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Grüße"
+	e.Text = []byte("Schöne Grüße")
+	e.HTML = []byte("<p>Schöne Grüße</p>")
+
+	addr, delivered, commands := plaintextServer(t)
+	err := e.Send(addr, nil, "")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if mail := <-commands; !strings.HasSuffix(mail, " BODY=8BITMIME") {
+		t.Errorf("Expected MAIL command to declare the 8bit body, got %q", mail)
+	}
+	<-commands
+	data := <-delivered
+	if strings.Contains(data, "quoted-printable") || strings.Count(data, "Content-Transfer-Encoding: 8bit") != 2 {
+		t.Errorf("Expected 8bit encoded bodies, got %q", data)
+	}
+	if !strings.Contains(data, "<p>Schöne Grüße</p>") {
+		t.Errorf("Expected unencoded HTML body, got %q", data)
+	}
+
+	// Lines exceeding 998 octets cannot be sent unencoded
+	e.HTML = nil
+	e.Text = []byte(strings.Repeat("ä", 500))
+	err = e.Send(addr, nil, "")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if data := <-delivered; !strings.Contains(data, "Content-Transfer-Encoding: quoted-printable") {
+		t.Errorf("Expected long line to be quoted-printable encoded, got %q", data)
+	}
+
+	raw, err := e.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !strings.Contains(string(raw), "Content-Transfer-Encoding: quoted-printable") {
+		t.Error("Expected Bytes to keep quoted-printable encoding")
+	}
+}
+
+func TestEmail_SendWithStartTLS_Policy(t *testing.T) {
+	addr, delivered, _ := plaintextServer(t)
+
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Test Subject"
+	e.Text = []byte("Hello")
+
+	err := e.SendWithStartTLS(addr, nil, &tls.Config{}, "localhost", true)
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Expected STARTTLS to be required, got: %v", err)
+	}
+
+	err = e.SendWithStartTLS(addr, nil, &tls.Config{}, "localhost", false)
+	if err != nil {
+		t.Fatalf("Expected fallback to plaintext, got: %v", err)
+	}
+	if data := <-delivered; !strings.Contains(data, "Subject: Test Subject") {
+		t.Errorf("Expected message to be delivered, got: %q", data)
+	}
+}
+
+func TestEmail_DeliveryNotification(t *testing.T) {
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Receipts"
+	e.Text = []byte("Hello")
+	e.ReadReceipt = []string{"receipts@example.com"}
+	e.Notify = []string{"SUCCESS", "FAILURE"}
+	e.Return = "HDRS"
+
+	tests := []struct {
+		name       string
+		extensions []string
+		mail       string
+		rcpt       string
+	}{
+		{name: "DSN", extensions: []string{"DSN"}, mail: "MAIL FROM:<sender@example.com> BODY=8BITMIME RET=HDRS", rcpt: "RCPT TO:<recipient@example.com> NOTIFY=SUCCESS,FAILURE"},
+		{name: "no DSN", mail: "MAIL FROM:<sender@example.com> BODY=8BITMIME", rcpt: "RCPT TO:<recipient@example.com>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, delivered, commands := plaintextServer(t, tt.extensions...)
+			err := e.Send(addr, nil, "localhost")
+			if err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			if mail := <-commands; mail != tt.mail {
+				t.Errorf("Expected %q, got %q", tt.mail, mail)
+			}
+			if rcpt := <-commands; rcpt != tt.rcpt {
+				t.Errorf("Expected %q, got %q", tt.rcpt, rcpt)
+			}
+			if data := <-delivered; !strings.Contains(data, "Disposition-Notification-To: receipts@example.com") {
+				t.Errorf("Expected read receipt header, got: %q", data)
+			}
+		})
+	}
+
+	e.Notify = []string{"NEVER", "FAILURE"}
+	e.Return = "BODY"
+	err := e.Validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid email") {
+		t.Errorf("Expected invalid notification request to fail validation, got: %v", err)
+	}
+}
+
+func TestNewDSN(t *testing.T) {
+	original := email.New()
+	original.From = "Sender <sender@example.com>"
+	original.To = []string{"missing@example.org"}
+	original.Bcc = []string{"hidden@example.org"}
+	original.Subject = "Hello"
+	original.Text = []byte("Hello")
+	original.Headers.Set("Message-Id", "<1@example.com>")
+
+	dsn, err := email.NewDSN(original, "failed", "5.1.1", "550 5.1.1 mailbox unavailable")
+	if err != nil {
+		t.Fatalf("NewDSN failed: %v", err)
+	}
+	if len(dsn.To) != 1 || dsn.To[0] != "sender@example.com" || dsn.From != "Mail Delivery System <MAILER-DAEMON@example.org>" {
+		t.Errorf("Expected notification from the reporting domain to the sender, got from %q to %v", dsn.From, dsn.To)
+	}
+
+	raw, err := dsn.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse notification: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || params["report-type"] != "delivery-status" {
+		t.Fatalf("Expected multipart/report of type delivery-status, got %q", msg.Header.Get("Content-Type"))
+	}
+	if msg.Header.Get("In-Reply-To") != "<1@example.com>" || msg.Header.Get("Auto-Submitted") != "auto-replied" {
+		t.Errorf("Expected reference to the original and Auto-Submitted header, got %v", msg.Header)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types []string
+	var status, headers string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		contentType := strings.Split(part.Header.Get("Content-Type"), ";")[0]
+		types = append(types, contentType)
+		switch contentType {
+		case "message/delivery-status":
+			status = string(content)
+		case "text/rfc822-headers":
+			headers = string(content)
+		}
+	}
+	if strings.Join(types, ",") != "text/plain,message/delivery-status,text/rfc822-headers" {
+		t.Fatalf("Expected text, delivery status and headers parts, got %v", types)
+	}
+	for _, field := range []string{
+		"Reporting-MTA: dns; example.org",
+		"Final-Recipient: rfc822; missing@example.org\r\nAction: failed\r\nStatus: 5.1.1\r\nDiagnostic-Code: smtp; 550 5.1.1 mailbox unavailable",
+		"Final-Recipient: rfc822; hidden@example.org",
+	} {
+		if !strings.Contains(status, field) {
+			t.Errorf("Expected %q in the delivery status, got %q", field, status)
+		}
+	}
+	if !strings.Contains(headers, "Subject: Hello") || strings.Contains(headers, "hidden@example.org") {
+		t.Errorf("Expected the original header without Bcc, got %q", headers)
+	}
+
+	addr, delivered, commands := plaintextServer(t)
+	err = dsn.Send(addr, nil, "localhost")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if cmd := <-commands; cmd != "MAIL FROM:<> BODY=8BITMIME" {
+		t.Errorf("Expected the null reverse-path, got %q", cmd)
+	}
+	<-delivered
+
+	_, err = email.NewDSN(dsn, "failed", "5.1.1", "")
+	if err == nil {
+		t.Error("Expected no notification for a notification")
+	}
+	_, err = email.NewDSN(original, "bounced", "5.1", "")
+	if err == nil {
+		t.Error("Expected invalid action and status to fail")
+	}
+}
+
 func TestNewFromReader_SimpleEmail(t *testing.T) {
 	// Create a properly formatted RFC 5322 email with MIME headers
 	emailData := `From: sender@example.com
@@ -376,3 +771,101 @@ func BenchmarkEmail_Attach(b *testing.B) {
 		}
 	}
 }
+
+func TestEmail_Validate(t *testing.T) {
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Test Subject"
+	e.Text = []byte("Hello, World!")
+
+	err := e.Validate()
+	if err != nil {
+		t.Fatalf("Expected valid email, got: %v", err)
+	}
+
+	e.MaxBytes = 16
+	err = e.Validate()
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Errorf("Expected size error, got: %v", err)
+	}
+
+	e.MaxBytes = 0
+	e.To = []string{"broken@", "recipient@example.com"}
+	e.Cc = []string{"also broken"}
+	attachment, err := e.Attach(strings.NewReader("<img>"), "image.png", "image/png; =invalid")
+	if err != nil {
+		t.Fatalf("Failed to attach: %v", err)
+	}
+	attachment.HTMLRelated = true
+
+	err = e.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	var appErr apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected apperror.Error, got %T", err)
+	}
+	if len(appErr.Errors) != 4 {
+		t.Errorf("Expected 4 aggregated errors, got %d: %v", len(appErr.Errors), appErr.Errors)
+	}
+}
+
+func TestEmail_MultipleFrom(t *testing.T) {
+	e := email.New()
+	e.From = "Alice <alice@example.com>, Bob <bob@example.com>"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Test Subject"
+	e.Text = []byte("Hello, World!")
+
+	err := e.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Sender address is required") {
+		t.Errorf("Expected missing Sender error, got: %v", err)
+	}
+	_, err = e.Bytes()
+	if err == nil {
+		t.Error("Expected Bytes to fail without Sender")
+	}
+
+	e.Sender = "Alice <alice@example.com>"
+	err = e.Validate()
+	if err != nil {
+		t.Fatalf("Expected valid email, got: %v", err)
+	}
+	raw, err := e.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize email: %v", err)
+	}
+	if !bytes.Contains(raw, []byte("Sender: Alice <alice@example.com>\r\n")) {
+		t.Errorf("Expected Sender header in message:\n%s", raw)
+	}
+
+	e.Sender = ""
+	e.Headers.Set("Sender", "bob@example.com")
+	raw, err = e.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize email: %v", err)
+	}
+	if !bytes.Contains(raw, []byte("Sender: bob@example.com\r\n")) {
+		t.Errorf("Expected Sender header of the headers in message:\n%s", raw)
+	}
+
+	// A single author does not need a Sender
+	e.Headers.Del("Sender")
+	e.From = "alice@example.com"
+	raw, err = e.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to serialize email: %v", err)
+	}
+	if bytes.Contains(raw, []byte("Sender:")) {
+		t.Errorf("Expected no Sender header for a single author:\n%s", raw)
+	}
+
+	// Validation checks the From header that overrides the From field
+	e.Headers.Set("From", "Alice <alice@example.com>, Bob <bob@example.com>")
+	err = e.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Sender address is required") {
+		t.Errorf("Expected missing Sender error for multiple authors in the From header, got: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"crypto/tls"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Probe connects to the SMTP server and returns the extensions it advertises in response to EHLO,
+// keyed by their upper case keyword with their parameters as value, e.g. "SIZE" with "10240000".
+// The connection is wrapped in TLS if a config is given, otherwise it is upgraded with STARTTLS
+// if the server supports it, as servers often advertise further extensions like AUTH only over TLS.
+// It reports whether the extensions were advertised over TLS. The client announces itself with helo
+// like Send does, "localhost" if it is empty. The session ends with QUIT without sending a message,
+// the timeout limits the whole session like Email.Timeout.
+func Probe(address string, config *tls.Config, helo string, timeout time.Duration) (map[string]string, bool, error) {
+	e := &Email{Timeout: timeout}
+	c, err := e.dial(address, config)
+	if err != nil {
+		return nil, false, apperror.NewError("could not dial SMTP connection").AddError(err)
+	}
+
+	if helo == "" {
+		helo = "localhost"
+	}
+	// The name is also announced in the EHLO the client sends before STARTTLS
+	err = c.Hello(helo)
+	if err != nil {
+		apperror.Catch(c.Close, "could not close SMTP connection")
+		return nil, false, apperror.NewError("could not send HELO command").AddError(err)
+	}
+
+	if config == nil {
+		err = startTLS(c, &tls.Config{ServerName: hostname(address), MinVersion: tls.VersionTLS12}, false)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	_, secure := c.TLSConnectionState()
+
+	ext, err := ehlo(c, helo)
+	if err != nil {
+		apperror.Catch(c.Close, "could not close SMTP connection")
+		return nil, false, apperror.NewError("could not send EHLO command").AddError(err)
+	}
+
+	err = c.Quit()
+	if err != nil {
+		return nil, false, apperror.NewError("could not quit SMTP session").AddError(err)
+	}
+	return ext, secure, nil
+}
+
+// ehlo sends EHLO and parses the extensions of the response like smtp.Client does internally,
+// which does not expose them as a whole
+func ehlo(c *smtp.Client, helo string) (map[string]string, error) {
+	id, err := c.Text.Cmd("EHLO %s", helo)
+	if err != nil {
+		return nil, err
+	}
+	c.Text.StartResponse(id)
+	defer c.Text.EndResponse(id)
+	_, msg, err := c.Text.ReadResponse(250)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := make(map[string]string)
+	lines := strings.Split(msg, "\n")
+	// The first line is the greeting of the server
+	for _, line := range lines[1:] {
+		keyword, params, _ := strings.Cut(line, " ")
+		ext[strings.ToUpper(keyword)] = params
+	}
+	return ext, nil
+}
@@ -0,0 +1,214 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// statusCode matches an enhanced mail system status code (RFC 3463, section 2)
+var statusCode = regexp.MustCompile(`^[245]\.\d{1,3}\.\d{1,3}$`)
+
+// actions maps the actions of a delivery status notification (RFC 3464, section 2.3.3)
+// to the subject suffix and the introduction of the human-readable part
+var actions = map[string]struct {
+	subject string
+	text    string
+}{
+	"failed":    {"Failure", "The message could not be delivered to the following recipients:"},
+	"delayed":   {"Delay", "The delivery of the message to the following recipients has been delayed:"},
+	"delivered": {"Success", "The message has been delivered to the following recipients:"},
+	"relayed":   {"Relayed", "The message has been relayed to the following recipients, no further notifications will be sent:"},
+	"expanded":  {"Expanded", "The message has been delivered to the following recipients and forwarded to their members:"},
+}
+
+// report is the machine-readable content of a multipart/report message (RFC 6522)
+type report struct {
+	// status is the message/delivery-status part
+	status []byte
+	// headers is the text/rfc822-headers part containing the header of the original message
+	headers []byte
+}
+
+// NewDSN creates a delivery status notification (RFC 3464) for the original email, e.g. to
+// bounce a message a receiving server cannot deliver. The notification is a
+// multipart/report message with a human-readable explanation, the delivery status of
+// every recipient and the header of the original message. It is addressed to the envelope
+// sender of the original, taken from its Return-Path header if present, and is sent with the
+// null reverse-path so that it never causes another notification.
+//
+// The action is one of failed, delayed, delivered, relayed or expanded, the status an
+// enhanced status code like 5.1.1 (RFC 3463) and the optional diagnostic the reply of the
+// remote server, e.g. "550 5.1.1 mailbox unavailable".
+//
+// Example:
+//
+//	dsn, err := email.NewDSN(original, "failed", "5.1.1", "550 5.1.1 mailbox unavailable")
+//	if err != nil {
+//		return err
+//	}
+//	err = dsn.Send(address, nil, helo)
+func NewDSN(original *Email, action, status, diagnostic string) (*Email, error) {
+	if original == nil {
+		return nil, apperror.NewError("original email is required")
+	}
+
+	var errs []error
+	intro, ok := actions[strings.ToLower(action)]
+	if !ok {
+		errs = append(errs, apperror.NewErrorf("invalid action %q, it must be failed, delayed, delivered, relayed or expanded", action))
+	}
+	if !statusCode.MatchString(status) {
+		errs = append(errs, apperror.NewErrorf("invalid status %q, it must be an enhanced status code like 5.1.1", status))
+	}
+	if strings.ContainsAny(diagnostic, "\r\n") {
+		errs = append(errs, apperror.NewError("the diagnostic must not contain CR or LF"))
+	}
+
+	recipients := make([]string, 0, len(original.To)+len(original.Cc)+len(original.Bcc))
+	for _, addr := range append(append(append([]string{}, original.To...), original.Cc...), original.Bcc...) {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			errs = append(errs, apperror.NewErrorf("invalid recipient address %q", addr).AddError(err))
+			continue
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+	if len(recipients) == 0 && len(errs) == 0 {
+		errs = append(errs, apperror.NewError("the original email has no recipients"))
+	}
+
+	to, err := original.returnPath()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, apperror.NewError("failed to create delivery status notification").AddErrors(errs)
+	}
+
+	headers, err := original.msgHeaders()
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+	// Only report the identification of the original, not the one generated for it
+	for _, field := range []string{"Message-Id", "Date"} {
+		if original.Headers.Get(field) == "" {
+			headers.Del(field)
+		}
+	}
+	originalHeaders := &bytes.Buffer{}
+	err = headerToBytes(originalHeaders, headers)
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+
+	action = strings.ToLower(action)
+	domain := recipients[0][strings.LastIndex(recipients[0], "@")+1:]
+	arrival := time.Now().Format(time.RFC1123Z)
+	if date := original.Headers.Get("Date"); date != "" {
+		arrival = date
+	}
+
+	deliveryStatus := &bytes.Buffer{}
+	fmt.Fprintf(deliveryStatus, "Reporting-MTA: dns; %s\r\n", domain)
+	fmt.Fprintf(deliveryStatus, "Arrival-Date: %s\r\n", arrival)
+	text := &strings.Builder{}
+	text.WriteString(intro.text + "\r\n\r\n")
+	for _, recipient := range recipients {
+		fmt.Fprintf(deliveryStatus, "\r\nFinal-Recipient: rfc822; %s\r\n", recipient)
+		fmt.Fprintf(deliveryStatus, "Action: %s\r\n", action)
+		fmt.Fprintf(deliveryStatus, "Status: %s\r\n", status)
+		if diagnostic != "" {
+			fmt.Fprintf(deliveryStatus, "Diagnostic-Code: smtp; %s\r\n", diagnostic)
+		}
+		text.WriteString("  " + recipient + "\r\n")
+	}
+	if diagnostic != "" {
+		text.WriteString("\r\nThe remote server replied: " + diagnostic + "\r\n")
+	}
+
+	dsn := New()
+	dsn.From = "Mail Delivery System <MAILER-DAEMON@" + domain + ">"
+	dsn.To = []string{to}
+	dsn.Subject = "Delivery Status Notification (" + intro.subject + ")"
+	dsn.Text = []byte(text.String())
+	dsn.Headers.Set("Auto-Submitted", "auto-replied")
+	if id := original.Headers.Get("Message-Id"); id != "" {
+		dsn.Headers.Set("In-Reply-To", id)
+		dsn.Headers.Set("References", id)
+	}
+	dsn.report = &report{
+		status:  deliveryStatus.Bytes(),
+		headers: originalHeaders.Bytes(),
+	}
+	return dsn, nil
+}
+
+// returnPath returns the address delivery status notifications of the email are sent to,
+// the Return-Path header added by the receiving server or the envelope sender
+func (e *Email) returnPath() (string, error) {
+	if e.report != nil {
+		return "", apperror.NewError("no notification is sent for a delivery status notification")
+	}
+
+	path := strings.TrimSpace(e.Headers.Get("Return-Path"))
+	if path == "<>" {
+		return "", apperror.NewError("no notification is sent for a message with a null reverse-path")
+	}
+	if path != "" {
+		addr, err := mail.ParseAddress(path)
+		if err != nil {
+			return "", apperror.NewErrorf("invalid Return-Path %q", path).AddError(err)
+		}
+		return addr.Address, nil
+	}
+
+	sender, err := e.parseSender()
+	if err != nil {
+		return "", apperror.Wrap(err)
+	}
+	return sender, nil
+}
+
+// reportBytes writes the multipart/report message of a delivery status notification
+func (e *Email) reportBytes(headers textproto.MIMEHeader) ([]byte, error) {
+	body := bytes.NewBuffer(make([]byte, 0, e.estimateSize()+len(e.report.status)+len(e.report.headers)))
+	w := multipart.NewWriter(body)
+	headers.Set("Content-Type", "multipart/report; report-type=delivery-status;\r\n boundary="+w.Boundary())
+
+	err := writeMessage(body, e.Text, true, "text/plain", "quoted-printable", w)
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+
+	parts := []struct {
+		contentType string
+		content     []byte
+	}{
+		{"message/delivery-status", e.report.status},
+		{"text/rfc822-headers", e.report.headers},
+	}
+	for _, p := range parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, apperror.NewErrorf("could not create %s part", p.contentType).AddError(err)
+		}
+		_, err = pw.Write(p.content)
+		if err != nil {
+			return nil, apperror.NewErrorf("could not write %s part", p.contentType).AddError(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, apperror.NewError("could not close multipart/writer").AddError(err)
+	}
+	return e.assemble(headers, body.Bytes())
+}
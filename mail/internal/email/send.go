@@ -0,0 +1,295 @@
+package email
+
+import (
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// maxLocalPart is the maximum length of the local part of an address in bytes (RFC 5321, section 4.5.3.1.1)
+const maxLocalPart = 64
+
+// transaction is the SMTP envelope and the serialized message of an email ready to be sent
+type transaction struct {
+	sender string
+	to     []string
+	raw    []byte
+}
+
+// SendOverConn sends the email like Send over a connection established by the caller,
+// e.g. through a SOCKS proxy or an SSH tunnel, or over one end of net.Pipe in tests.
+// The host is the name of the SMTP server, it is used to verify its certificate and by
+// authentication mechanisms like smtp.PlainAuth. The connection is upgraded with STARTTLS
+// if the server supports it and is closed when the session ends.
+//
+// Example:
+//
+//	conn, err := proxy.Dial("tcp", "smtp.example.com:25")
+//	if err != nil {
+//		return err
+//	}
+//	err = e.SendOverConn(conn, "smtp.example.com", auth)
+func (e *Email) SendOverConn(conn net.Conn, host string, auth smtp.Auth) error {
+	tx, err := e.prepare()
+	if err != nil {
+		apperror.Catch(conn.Close, "could not close SMTP connection")
+		return err
+	}
+
+	c, err := e.client(conn, host, nil)
+	if err != nil {
+		return apperror.NewError("could not create SMTP client").AddError(err)
+	}
+
+	err = startTLS(c, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}, false)
+	if err != nil {
+		return err
+	}
+
+	return e.deliver(c, auth, tx)
+}
+
+// SendWithTLSOverConn sends the email like SendWithTLS over a connection established by the caller,
+// the connection is wrapped in TLS with the optional config. See SendOverConn.
+func (e *Email) SendWithTLSOverConn(conn net.Conn, host string, auth smtp.Auth, config *tls.Config) error {
+	tx, err := e.prepare()
+	if err != nil {
+		apperror.Catch(conn.Close, "could not close SMTP connection")
+		return err
+	}
+
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	c, err := e.client(conn, host, config)
+	if err != nil {
+		return apperror.NewError("could not create TLS client").AddError(err)
+	}
+
+	return e.deliver(c, auth, tx)
+}
+
+// SendWithStartTLSOverConn sends the email like SendWithStartTLS over a connection established
+// by the caller. See SendOverConn.
+func (e *Email) SendWithStartTLSOverConn(conn net.Conn, host string, auth smtp.Auth, config *tls.Config, required bool) error {
+	tx, err := e.prepare()
+	if err != nil {
+		apperror.Catch(conn.Close, "could not close SMTP connection")
+		return err
+	}
+
+	c, err := e.client(conn, host, nil)
+	if err != nil {
+		return apperror.NewError("could not create SMTP client").AddError(err)
+	}
+
+	err = startTLS(c, config, required)
+	if err != nil {
+		return err
+	}
+
+	return e.deliver(c, auth, tx)
+}
+
+// SendVERP sends the email like Send, but in a separate mail transaction for every recipient
+// whose envelope sender encodes the recipient (variable envelope return path). Bounces are
+// returned to that address, so they can be attributed to the recipient even if the bounce
+// message does not name it. The recipient is appended to the local part of the base sender
+// with its @ replaced by =, e.g. the base sender bounces@example.org and the recipient
+// jane@example.com result in bounces+jane=example.com@example.org. The receiving domain
+// has to deliver mail for bounces+*@example.org to the bounce mailbox, most servers do so
+// for the + subaddress. The Sender field and header are ignored for the envelope.
+//
+// Example:
+//
+//	err := e.SendVERP("smtp.example.org:25", auth, "bounces@example.org")
+func (e *Email) SendVERP(address string, auth smtp.Auth, baseSender string) error {
+	tx, err := e.prepare()
+	if err != nil {
+		return err
+	}
+
+	txs, err := verp(baseSender, tx)
+	if err != nil {
+		return err
+	}
+
+	c, err := e.dial(address, nil)
+	if err != nil {
+		return apperror.NewError("could not dial SMTP connection").AddError(err)
+	}
+
+	err = startTLS(c, &tls.Config{ServerName: hostname(address), MinVersion: tls.VersionTLS12}, false)
+	if err != nil {
+		return err
+	}
+
+	return e.deliver(c, auth, txs...)
+}
+
+// verp splits the transaction into one transaction per recipient with the VERP address of the
+// recipient as envelope sender. All addresses are validated before any transaction is returned.
+func verp(baseSender string, tx *transaction) ([]*transaction, error) {
+	base, err := mail.ParseAddress(baseSender)
+	if err != nil {
+		return nil, apperror.NewErrorf("invalid VERP base sender %q", baseSender).AddError(err)
+	}
+	at := strings.LastIndex(base.Address, "@")
+	local, domain := base.Address[:at], base.Address[at+1:]
+
+	var errs []error
+	txs := make([]*transaction, 0, len(tx.to))
+	for _, rcpt := range tx.to {
+		sender := local + "+" + strings.Replace(rcpt, "@", "=", 1) + "@" + domain
+		addr, err := mail.ParseAddress(sender)
+		if err != nil || addr.Address != sender {
+			errs = append(errs, apperror.NewErrorf("recipient %q cannot be encoded in a VERP address", rcpt))
+			continue
+		}
+		if len(sender)-len(domain)-1 > maxLocalPart {
+			errs = append(errs, apperror.NewErrorf("VERP address of recipient %q exceeds the maximum local part of %d bytes", rcpt, maxLocalPart))
+			continue
+		}
+		txs = append(txs, &transaction{sender: sender, to: []string{rcpt}, raw: tx.raw})
+	}
+	if len(errs) > 0 {
+		return nil, apperror.NewError("could not create VERP addresses").AddErrors(errs)
+	}
+	return txs, nil
+}
+
+// prepare returns the SMTP envelope and the serialized message of the email
+func (e *Email) prepare() (*transaction, error) {
+	sender, to, err := e.Envelope()
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+	raw, err := e.Bytes()
+	if err != nil {
+		return nil, apperror.Wrap(err)
+	}
+	return &transaction{sender: sender, to: to, raw: raw}, nil
+}
+
+// client creates an SMTP client for the connection, which is wrapped in TLS if a config is given.
+// The deadline of the connection is set to Timeout, so a stalled server cannot block the session.
+func (e *Email) client(conn net.Conn, host string, config *tls.Config) (*smtp.Client, error) {
+	if config != nil {
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName = host
+		}
+		conn = tls.Client(conn, config)
+	}
+
+	if e.Timeout > 0 {
+		err := conn.SetDeadline(time.Now().Add(e.Timeout))
+		if err != nil {
+			apperror.Catch(conn.Close, "could not close SMTP connection")
+			return nil, err
+		}
+	}
+
+	// The connection is closed by NewClient if the server does not greet
+	return smtp.NewClient(conn, host)
+}
+
+// startTLS upgrades the connection with STARTTLS if the server supports it.
+// It fails if the server does not support it and required is set.
+func startTLS(c *smtp.Client, config *tls.Config, required bool) error {
+	supported, _ := c.Extension("STARTTLS")
+	if !supported && required {
+		apperror.Catch(c.Close, "could not close SMTP connection")
+		return apperror.NewError("SMTP server does not support STARTTLS")
+	}
+	if !supported {
+		return nil
+	}
+
+	err := c.StartTLS(config)
+	if err != nil {
+		apperror.Catch(c.Close, "could not close SMTP connection")
+		return apperror.NewError("could not start TLS").AddError(err)
+	}
+	return nil
+}
+
+// deliver authenticates and runs the mail transactions over the client, the client is closed afterwards
+func (e *Email) deliver(c *smtp.Client, auth smtp.Auth, txs ...*transaction) error {
+	err := e.transact(c, auth, txs)
+	if err != nil {
+		apperror.Catch(c.Close, "could not close SMTP connection")
+		return err
+	}
+
+	err = c.Quit()
+	if err != nil {
+		return apperror.NewError("could not quit SMTP session").AddError(err)
+	}
+	return nil
+}
+
+// transact authenticates the client and runs the mail transactions one after another.
+// The bodies are sent 8bit encoded if the server supports 8BITMIME, see eightBitMessage.
+func (e *Email) transact(c *smtp.Client, auth smtp.Auth, txs []*transaction) error {
+	if auth != nil {
+		err := c.Auth(auth)
+		if err != nil {
+			return apperror.NewError("could not authenticate SMTP client").AddError(err)
+		}
+	}
+
+	// All transactions carry the same message, see prepare and verp
+	eightBit, err := e.eightBitMessage(c)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		err := e.mail(c, tx.sender)
+		if err != nil {
+			return apperror.NewError("could not set SMTP sender").AddError(err)
+		}
+
+		for _, addr := range tx.to {
+			err = e.rcpt(c, addr)
+			if err != nil {
+				return apperror.NewError("could not add SMTP recipient").AddError(err)
+			}
+		}
+
+		w, err := c.Data()
+		if err != nil {
+			return apperror.NewError("could not create SMTP data writer").AddError(err)
+		}
+
+		raw := tx.raw
+		if eightBit != nil {
+			raw = eightBit
+		}
+		_, err = w.Write(raw)
+		if err != nil {
+			return apperror.NewError("could not write SMTP data").AddError(err)
+		}
+
+		err = w.Close()
+		if err != nil {
+			return apperror.NewError("could not close SMTP data writer").AddError(err)
+		}
+	}
+	return nil
+}
+
+// hostname returns the host of the address of an SMTP server
+func hostname(address string) string {
+	h, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return h
+}
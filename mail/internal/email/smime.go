@@ -0,0 +1,201 @@
+package email
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+	"net/textproto"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidAES256CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// contentInfo is the CMS ContentInfo structure (RFC 5652, section 3)
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     envelopedData `asn1:"explicit,tag:0"`
+}
+
+// envelopedData is the CMS EnvelopedData structure (RFC 5652, section 6.1)
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []keyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+// keyTransRecipientInfo carries the content encryption key encrypted for one recipient (RFC 5652, section 6.2.1)
+type keyTransRecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  issuerAndSerialNumber
+	KeyEncryptionAlgorithm algorithmIdentifier
+	EncryptedKey           []byte
+}
+
+// issuerAndSerialNumber identifies the certificate of a recipient
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// algorithmIdentifier is the X.509 AlgorithmIdentifier structure
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// encryptedContentInfo carries the encrypted content (RFC 5652, section 6.1)
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm algorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+// Encrypt enables S/MIME encryption of the message for the recipients owning the certificates.
+// Bytes then wraps the MIME body as application/pkcs7-mime enveloped data, encrypted with
+// AES-256-CBC and a content key that is encrypted for every certificate with RSA (PKCS #1 v1.5).
+// Only the headers describing the body are encrypted, all other headers stay readable.
+// Include the certificate of the sender to be able to read the sent message.
+func (e *Email) Encrypt(recipientCerts []*x509.Certificate) error {
+	if len(recipientCerts) == 0 {
+		return apperror.NewError("at least one recipient certificate is required for S/MIME encryption")
+	}
+
+	for _, cert := range recipientCerts {
+		if cert == nil {
+			return apperror.NewError("recipient certificate must not be nil")
+		}
+		if _, ok := cert.PublicKey.(*rsa.PublicKey); !ok {
+			return apperror.NewErrorf("certificate of %s has an unsupported %T public key, only RSA is supported", cert.Subject, cert.PublicKey)
+		}
+	}
+
+	e.recipientCerts = recipientCerts
+	return nil
+}
+
+// encrypt replaces the body by S/MIME enveloped data and returns the outer headers
+func (e *Email) encrypt(headers textproto.MIMEHeader, body []byte) (textproto.MIMEHeader, []byte, error) {
+	// The encrypted MIME entity consists of the content headers and the body
+	inner := textproto.MIMEHeader{}
+	outer := textproto.MIMEHeader{}
+	for field, vals := range headers {
+		switch field {
+		case "Content-Type", "Content-Transfer-Encoding":
+			inner[field] = vals
+		default:
+			outer[field] = vals
+		}
+	}
+
+	entity := bytes.NewBuffer(make([]byte, 0, len(body)+256))
+	err := headerToBytes(entity, inner)
+	if err != nil {
+		return nil, nil, apperror.Wrap(err)
+	}
+	entity.WriteString("\r\n")
+	entity.Write(body)
+
+	enveloped, err := envelope(entity.Bytes(), e.recipientCerts)
+	if err != nil {
+		return nil, nil, apperror.Wrap(err)
+	}
+
+	encoded := bytes.NewBuffer(make([]byte, 0, len(enveloped)*4/3+len(enveloped)/57*2+4))
+	err = base64Wrap(encoded, enveloped)
+	if err != nil {
+		return nil, nil, apperror.Wrap(err)
+	}
+
+	outer.Set("Content-Type", "application/pkcs7-mime; smime-type=enveloped-data;\r\n name=\"smime.p7m\"")
+	outer.Set("Content-Transfer-Encoding", "base64")
+	outer.Set("Content-Disposition", "attachment; filename=\"smime.p7m\"")
+	return outer, encoded.Bytes(), nil
+}
+
+// envelope encrypts the content with AES-256-CBC and returns the DER encoded CMS enveloped data
+func envelope(content []byte, certs []*x509.Certificate) ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, apperror.NewError("could not generate content encryption key").AddError(err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, apperror.NewError("could not generate initialization vector").AddError(err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, apperror.NewError("could not create content cipher").AddError(err)
+	}
+
+	// PKCS #7 padding, a full block is added if the content is aligned
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	encrypted := make([]byte, len(content)+padding)
+	copy(encrypted, content)
+	copy(encrypted[len(content):], bytes.Repeat([]byte{byte(padding)}, padding))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, apperror.NewError("could not encode initialization vector").AddError(err)
+	}
+
+	recipients := make([]keyTransRecipientInfo, 0, len(certs))
+	for _, cert := range certs {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, apperror.NewErrorf("certificate of %s has an unsupported %T public key", cert.Subject, cert.PublicKey)
+		}
+
+		encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		if err != nil {
+			return nil, apperror.NewErrorf("could not encrypt content key for %s", cert.Subject).AddError(err)
+		}
+
+		recipients = append(recipients, keyTransRecipientInfo{
+			Version: 0,
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+				SerialNumber: cert.SerialNumber,
+			},
+			KeyEncryptionAlgorithm: algorithmIdentifier{
+				Algorithm:  oidRSAEncryption,
+				Parameters: asn1.NullRawValue,
+			},
+			EncryptedKey: encryptedKey,
+		})
+	}
+
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidEnvelopedData,
+		Content: envelopedData{
+			Version:        0,
+			RecipientInfos: recipients,
+			EncryptedContentInfo: encryptedContentInfo{
+				ContentType: oidData,
+				ContentEncryptionAlgorithm: algorithmIdentifier{
+					Algorithm:  oidAES256CBC,
+					Parameters: asn1.RawValue{FullBytes: ivParam},
+				},
+				EncryptedContent: encrypted,
+			},
+		},
+	})
+	if err != nil {
+		return nil, apperror.NewError("could not encode enveloped data").AddError(err)
+	}
+	return der, nil
+}
@@ -0,0 +1,168 @@
+package email_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/mail/internal/email"
+)
+
+type testContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     testEnvelopedData `asn1:"explicit,tag:0"`
+}
+
+type testEnvelopedData struct {
+	Version              int
+	RecipientInfos       []testRecipientInfo `asn1:"set"`
+	EncryptedContentInfo testEncryptedContentInfo
+}
+
+type testRecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  asn1.RawValue
+	KeyEncryptionAlgorithm asn1.RawValue
+	EncryptedKey           []byte
+}
+
+type testEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue
+	}
+	EncryptedContent []byte `asn1:"tag:0,optional"`
+}
+
+func newTestCertificate(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "recipient@example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func TestEmail_Encrypt(t *testing.T) {
+	cert, key := newTestCertificate(t)
+
+	e := email.New()
+	e.From = "sender@example.com"
+	e.To = []string{"recipient@example.com"}
+	e.Subject = "Secret Subject"
+	e.Text = []byte("Top secret content")
+
+	err := e.Encrypt([]*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := e.Bytes()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content := string(data)
+	if !strings.Contains(content, "Subject: Secret Subject") {
+		t.Error("Expected readable Subject header in output")
+	}
+	if !strings.Contains(content, "Content-Type: application/pkcs7-mime; smime-type=enveloped-data;") {
+		t.Error("Expected S/MIME Content-Type header in output")
+	}
+	if strings.Contains(content, "Top secret content") || strings.Contains(content, "text/plain") {
+		t.Error("Expected body and content headers to be encrypted")
+	}
+
+	parts := strings.SplitN(content, "\r\n\r\n", 2)
+	if len(parts) != 2 {
+		t.Fatal("Expected headers and body to be separated by an empty line")
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(parts[1], "\r\n", ""))
+	if err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+
+	var info testContentInfo
+	_, err = asn1.Unmarshal(der, &info)
+	if err != nil {
+		t.Fatalf("Failed to parse enveloped data: %v", err)
+	}
+	if !info.ContentType.Equal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}) {
+		t.Fatalf("Expected enveloped data content type, got %v", info.ContentType)
+	}
+	if len(info.Content.RecipientInfos) != 1 {
+		t.Fatalf("Expected 1 recipient info, got %d", len(info.Content.RecipientInfos))
+	}
+
+	eci := info.Content.EncryptedContentInfo
+	if !eci.ContentEncryptionAlgorithm.Algorithm.Equal(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}) {
+		t.Fatalf("Expected AES-256-CBC, got %v", eci.ContentEncryptionAlgorithm.Algorithm)
+	}
+
+	contentKey, err := rsa.DecryptPKCS1v15(rand.Reader, key, info.Content.RecipientInfos[0].EncryptedKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt content key: %v", err)
+	}
+	if len(contentKey) != 32 {
+		t.Fatalf("Expected 256 bit content key, got %d bytes", len(contentKey))
+	}
+
+	var iv []byte
+	_, err = asn1.Unmarshal(eci.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	if err != nil {
+		t.Fatalf("Failed to parse initialization vector: %v", err)
+	}
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	plaintext := make([]byte, len(eci.EncryptedContent))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, eci.EncryptedContent)
+	plaintext = plaintext[:len(plaintext)-int(plaintext[len(plaintext)-1])]
+
+	if !bytes.Contains(plaintext, []byte("Content-Type: text/plain; charset=UTF-8")) {
+		t.Error("Expected Content-Type header in encrypted entity")
+	}
+	if !bytes.Contains(plaintext, []byte("Top secret content")) {
+		t.Error("Expected body content in encrypted entity")
+	}
+}
+
+func TestEmail_Encrypt_InvalidCertificates(t *testing.T) {
+	e := email.New()
+
+	err := e.Encrypt(nil)
+	if err == nil {
+		t.Error("Expected error for missing certificates")
+	}
+
+	err = e.Encrypt([]*x509.Certificate{nil})
+	if err == nil {
+		t.Error("Expected error for nil certificate")
+	}
+}
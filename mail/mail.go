@@ -5,9 +5,12 @@
 //   - SMTP client for sending emails with various authentication methods
 //   - SMTP server for receiving emails with notification handlers
 //   - HTML template support with embedded and custom templates
-//   - Queue integration for asynchronous email processing
+//   - Queue integration for asynchronous email processing with optional on-disk persistence
 //   - TLS/STARTTLS encryption support
 //   - Attachment support
+//   - Read receipts and delivery status notifications (DSN) on servers advertising the extension
+//   - Probing the capabilities a server advertises in response to EHLO, e.g. SIZE, DSN and AUTH
+//   - 8bit message bodies on servers advertising 8BITMIME, quoted-printable otherwise
 //   - Statistics tracking
 //   - Retry mechanisms with exponential backoff
 //   - Configurable security features for both client and server (HELO validation, IP filtering, rate limiting)
@@ -174,12 +177,18 @@ func (m *Manager) Start(_ context.Context) error {
 	}
 
 	if m.config.Queue.Enabled && m.queueManager != nil {
+		m.queueManager.WithWorkers(m.config.Queue.WorkerCount)
+		m.queueManager.RegisterHandler("mail", m.handleMailJob)
 		err := m.queueManager.Start(m.ctx)
 		if err != nil {
 			atomic.StoreInt32(&m.running, 0)
 			return apperror.Wrap(err)
 		}
-		m.queueManager.RegisterHandler("mail", m.handleMailJob)
+
+		err = m.restoreSpool(m.ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to restore spooled mail")
+		}
 	}
 
 	if m.config.Server.Enabled && m.server != nil {
@@ -243,6 +252,7 @@ func (m *Manager) Send(ctx context.Context, message *Message) error {
 	}
 
 	logger.Debug().
+		Fields(logging.FieldsFromContext(ctx)...).
 		Field("id", message.ID).
 		Field("subject", message.Subject).
 		Field("to", message.To).
@@ -259,6 +269,7 @@ func (m *Manager) Send(ctx context.Context, message *Message) error {
 	m.updateLastSent()
 
 	logger.Info().
+		Fields(logging.FieldsFromContext(ctx)...).
 		Field("id", message.ID).
 		Field("subject", message.Subject).
 		Field("to", message.To).
@@ -269,6 +280,15 @@ func (m *Manager) Send(ctx context.Context, message *Message) error {
 
 // SendAsync sends an email message asynchronously using the queue
 func (m *Manager) SendAsync(ctx context.Context, message *Message) error {
+	return m.Enqueue(ctx, message)
+}
+
+// Enqueue submits the message to the mail queue where it is sent by the configured
+// number of workers. Failed deliveries are retried up to Queue.MaxAttempts times and
+// every attempt is limited to Queue.JobTimeout. If Queue.StoragePath is set and the queue is
+// held in memory, the message is written to the spool directory first so that unsent mail
+// survives a restart. Persistent queue backends keep unsent mail themselves and are not spooled.
+func (m *Manager) Enqueue(ctx context.Context, message *Message) error {
 	if !m.IsRunning() {
 		return apperror.NewError("mail manager is not running")
 	}
@@ -304,24 +324,22 @@ func (m *Manager) SendAsync(ctx context.Context, message *Message) error {
 		"attachments":   message.Attachments,
 		"headers":       message.Headers,
 		"priority":      int(message.Priority),
+		"read_receipt":  message.ReadReceipt,
+		"notify":        message.Notify,
+		"return":        message.Return,
 		"created_at":    message.CreatedAt,
 		"schedule_at":   message.ScheduleAt,
 		"metadata":      message.Metadata,
 	}
 
-	job := queue.NewJob("mail").
-		WithPayload(jobData).
-		WithPriority(queue.Priority(message.Priority)).
-		WithMaxAttempts(m.config.Queue.MaxAttempts)
-
-	// Schedule the job if specified
-	if message.ScheduleAt != nil {
-		job = job.WithScheduleAt(*message.ScheduleAt)
+	err := m.spool(message.ID, jobData)
+	if err != nil {
+		return apperror.Wrap(err)
 	}
 
-	// Enqueue the job
-	err := m.queueManager.Enqueue(ctx, job.Build())
+	err = m.enqueue(ctx, message, jobData)
 	if err != nil {
+		m.unspool(message.ID)
 		return apperror.Wrap(err)
 	}
 
@@ -336,6 +354,25 @@ func (m *Manager) SendAsync(ctx context.Context, message *Message) error {
 	return nil
 }
 
+// enqueue submits the job data of the message to the queue manager
+func (m *Manager) enqueue(ctx context.Context, message *Message, jobData map[string]interface{}) error {
+	builder := queue.NewJob("mail").
+		WithID(message.ID).
+		WithPayload(jobData).
+		WithPriority(queue.Priority(message.Priority)).
+		WithMaxAttempts(m.config.Queue.MaxAttempts)
+
+	// Schedule the job if specified
+	if message.ScheduleAt != nil {
+		builder = builder.WithScheduleAt(*message.ScheduleAt)
+	}
+
+	job := builder.Build()
+	job.Timeout = m.config.Queue.JobTimeout
+
+	return m.queueManager.Enqueue(ctx, job)
+}
+
 // AddNotificationHandler adds a notification handler to the SMTP server
 func (m *Manager) AddNotificationHandler(handler NotificationHandler) error {
 	if m.server == nil {
@@ -346,6 +383,17 @@ func (m *Manager) AddNotificationHandler(handler NotificationHandler) error {
 	return nil
 }
 
+// AddSessionHandler adds a handler to the SMTP server that receives the metadata of the
+// SMTP session (HELO name, remote IP, TLS state and authenticated identity) with each message
+func (m *Manager) AddSessionHandler(handler SessionHandler) error {
+	if m.server == nil {
+		return apperror.NewError("SMTP server is not configured")
+	}
+
+	m.server.AddSessionHandler(handler)
+	return nil
+}
+
 // GetStats returns the current mail statistics
 func (m *Manager) GetStats() *Stats {
 	m.statsMutex.RLock()
@@ -356,6 +404,7 @@ func (m *Manager) GetStats() *Stats {
 		SentCount:     m.stats.SentCount,
 		FailedCount:   m.stats.FailedCount,
 		QueuedCount:   m.stats.QueuedCount,
+		InFlightCount: m.stats.InFlightCount,
 		ReceivedCount: m.stats.ReceivedCount,
 	}
 
@@ -403,15 +452,36 @@ func (m *Manager) handleMailJob(ctx context.Context, job *queue.Job) error {
 	// Convert job data back to message
 	message := m.jobDataToMessage(jobData)
 
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = m.config.Queue.JobTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	m.addInFlight(1)
+	defer m.addInFlight(-1)
+
 	// Send the message
 	if err := m.sender.Send(ctx, message); err != nil {
 		m.incrementFailedCount()
+		if job.Attempts < job.MaxAttempts {
+			return queue.NewRetryableError(apperror.Wrap(err))
+		}
+
+		// The last attempt failed, the message is dropped
+		m.decrementQueuedCount()
+		m.unspool(message.ID)
 		return apperror.Wrap(err)
 	}
 
 	m.incrementSentCount()
 	m.updateLastSent()
 	m.decrementQueuedCount()
+	m.unspool(message.ID)
 
 	logger.Info().
 		Field("job_id", job.ID).
@@ -459,6 +529,13 @@ func (m *Manager) decrementQueuedCount() {
 	}
 }
 
+// addInFlight adjusts the number of mail jobs currently being sent
+func (m *Manager) addInFlight(delta int64) {
+	m.statsMutex.Lock()
+	defer m.statsMutex.Unlock()
+	m.stats.InFlightCount += delta
+}
+
 // incrementReceivedCount increments the received count
 func (m *Manager) incrementReceivedCount() {
 	m.statsMutex.Lock()
@@ -522,6 +599,15 @@ func (m *Manager) jobDataToMessage(jobData map[string]interface{}) *Message {
 	if priority, ok := jobData["priority"].(float64); ok {
 		message.Priority = Priority(int(priority))
 	}
+	if readReceipt := jobData["read_receipt"]; readReceipt != nil {
+		message.ReadReceipt = convertToStringSlice(readReceipt)
+	}
+	if notify := jobData["notify"]; notify != nil {
+		message.Notify = convertToStringSlice(notify)
+	}
+	if ret, ok := jobData["return"].(string); ok {
+		message.Return = ret
+	}
 	if headers, ok := jobData["headers"].(map[string]interface{}); ok {
 		message.Headers = make(map[string]string)
 		for k, v := range headers {
@@ -552,6 +638,16 @@ func (m *Manager) jobDataToMessage(jobData map[string]interface{}) *Message {
 	return message
 }
 
+// WithTransport delivers sent messages with the transport instead of the SMTP server
+// of the client configuration, the client does not need to be enabled then.
+// Use a MemoryTransport to capture messages in tests.
+func (m *Manager) WithTransport(t Transport) *Manager {
+	if s, ok := m.sender.(*smtpSender); ok {
+		s.transport = t
+	}
+	return m
+}
+
 // WithFS configures the template manager to load templates from a filesystem
 func (m *Manager) WithFS(filesystem fs.FS) *Manager {
 	if m.TemplateManager != nil {
@@ -576,6 +672,14 @@ func (m *Manager) WithTemplateFunc(key string, fn interface{}) *Manager {
 	return m
 }
 
+// WithTranslator sets the function the t template function looks up localized messages with
+func (m *Manager) WithTranslator(translator func(lang, key string, args ...any) string) *Manager {
+	if m.TemplateManager != nil {
+		m.TemplateManager.WithTranslator(translator)
+	}
+	return m
+}
+
 // WithDefaultFuncs adds default template functions to the template manager
 func (m *Manager) WithDefaultFuncs() *Manager {
 	if m.TemplateManager != nil {
@@ -1087,3 +1087,156 @@ func TestSMTPSenderWithPerMessageTemplateFuncs(t *testing.T) {
 		t.Error("Expected per-message custom function to work in direct rendering")
 	}
 }
+
+func TestManagerEnqueuePersistence(t *testing.T) {
+	config := mail.DefaultConfig()
+	config.Queue.Enabled = true
+	config.Queue.StoragePath = t.TempDir()
+	config.Client.Port = 1
+	config.Client.MaxRetries = 0
+
+	manager := mail.NewManager(config, queue.NewManager())
+	err := manager.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start mail manager: %v", err)
+	}
+
+	message, _ := mail.NewMessage().
+		From("sender@example.com").
+		To("recipient@example.com").
+		Subject("Persisted").
+		TextBody("Test message").
+		Build()
+	message.ID = "persisted"
+
+	err = manager.Enqueue(t.Context(), message)
+	if err != nil {
+		t.Fatalf("Expected no error enqueuing email, got: %v", err)
+	}
+
+	spoolFile := filepath.Join(config.Queue.StoragePath, "persisted.json")
+	if _, err := os.Stat(spoolFile); err != nil {
+		t.Fatalf("Expected queued email to be persisted: %v", err)
+	}
+
+	err = manager.Stop(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to stop mail manager: %v", err)
+	}
+
+	// A new manager picks up the unsent message without counting it as queued again
+	queueManager := queue.NewManager()
+	restored := mail.NewManager(config, queueManager)
+	err = restored.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start mail manager: %v", err)
+	}
+	defer restored.Stop(t.Context())
+
+	if _, err := queueManager.GetJob(t.Context(), "persisted"); err != nil {
+		t.Errorf("Expected the persisted email to be restored: %v", err)
+	}
+	if stats := restored.GetStats(); stats.QueuedCount != 0 {
+		t.Errorf("Expected restored email not to be counted as queued, got %d", stats.QueuedCount)
+	}
+
+	message.ID = "../escape"
+	err = restored.Enqueue(t.Context(), message)
+	if err == nil {
+		t.Error("Expected error enqueuing email with an invalid id")
+	}
+}
+
+// durableQueue is a queue backend that keeps its jobs across restarts
+type durableQueue struct {
+	*queue.MemoryQueue
+}
+
+func TestManagerEnqueuePersistentQueue(t *testing.T) {
+	config := mail.DefaultConfig()
+	config.Queue.Enabled = true
+	config.Queue.StoragePath = t.TempDir()
+
+	// Mail left from an in-memory queue is not sent again through a persistent backend
+	err := os.WriteFile(filepath.Join(config.Queue.StoragePath, "spooled.json"), []byte(`{"id":"spooled"}`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write spool file: %v", err)
+	}
+
+	queueManager := queue.NewManager().WithQueue(durableQueue{queue.NewMemoryQueue()})
+	manager := mail.NewManager(config, queueManager)
+	err = manager.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start mail manager: %v", err)
+	}
+	defer manager.Stop(t.Context())
+
+	if _, err := queueManager.GetJob(t.Context(), "spooled"); err == nil {
+		t.Error("Expected spooled email not to be restored into a persistent queue")
+	}
+
+	message, _ := mail.NewMessage().
+		From("sender@example.com").
+		To("recipient@example.com").
+		Subject("Durable").
+		TextBody("Test message").
+		ScheduleAt(time.Now().Add(time.Hour)).
+		Build()
+	message.ID = "durable"
+
+	err = manager.Enqueue(t.Context(), message)
+	if err != nil {
+		t.Fatalf("Expected no error enqueuing email, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.Queue.StoragePath, "durable.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected email of a persistent queue not to be spooled: %v", err)
+	}
+}
+
+func TestMessageBuilderDeliveryNotification(t *testing.T) {
+	message, err := mail.NewMessage().
+		From("sender@example.com").
+		To("recipient@example.com").
+		Subject("Receipts").
+		TextBody("Body").
+		ReadReceipt("receipts@example.com").
+		DeliveryNotification(mail.ReturnHeaders, mail.NotifySuccess, mail.NotifyFailure).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(message.ReadReceipt) != 1 || len(message.Notify) != 2 || message.Return != mail.ReturnHeaders {
+		t.Errorf("Expected read receipt and delivery notification, got %v %v %q", message.ReadReceipt, message.Notify, message.Return)
+	}
+
+	for _, b := range []*mail.MessageBuilder{
+		mail.NewMessage().DeliveryNotification("", mail.NotifyNever, mail.NotifyFailure),
+		mail.NewMessage().DeliveryNotification("", "ALWAYS"),
+		mail.NewMessage().DeliveryNotification("BODY", mail.NotifyFailure),
+	} {
+		_, err := b.Build()
+		if err == nil {
+			t.Error("Expected invalid delivery notification to fail")
+		}
+	}
+
+	config := mail.DefaultConfig()
+	config.Queue.Enabled = false
+	config.Server.Enabled = false
+	transport := mail.NewMemoryTransport()
+	manager := mail.NewManager(config, nil).WithTransport(transport)
+	err = manager.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Expected no error starting manager, got: %v", err)
+	}
+	defer apperror.Catch(func() error { return manager.Stop(t.Context()) }, "failed to stop mail manager")
+
+	err = manager.Send(t.Context(), message)
+	if err != nil {
+		t.Fatalf("Expected message to be delivered, got: %v", err)
+	}
+	messages := transport.Messages()
+	if len(messages) != 1 || !strings.Contains(string(messages[0].Data), "Disposition-Notification-To: receipts@example.com") {
+		t.Errorf("Expected Disposition-Notification-To header in the delivered message")
+	}
+}
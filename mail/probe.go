@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"crypto/tls"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/mail/internal/email"
+)
+
+// probeTimeout limits the SMTP session of Probe
+const probeTimeout = 30 * time.Second
+
+// Capabilities are the SMTP extensions advertised by a server in response to EHLO
+type Capabilities struct {
+	// TLS reports whether the capabilities were advertised over TLS
+	TLS bool
+	// StartTLS reports whether the server supports upgrading the connection with STARTTLS
+	StartTLS bool
+	// Size is the maximum message size in bytes accepted by the server, 0 if it declares no limit
+	Size int64
+	// EightBitMIME reports whether the server accepts 8-bit message bodies (RFC 6152)
+	EightBitMIME bool
+	// SMTPUTF8 reports whether the server accepts UTF-8 addresses and headers (RFC 6531)
+	SMTPUTF8 bool
+	// DSN reports whether the server supports delivery status notifications (RFC 3461)
+	DSN bool
+	// Pipelining reports whether the server supports command pipelining (RFC 2920)
+	Pipelining bool
+	// Chunking reports whether the server accepts the message in chunks with BDAT (RFC 3030)
+	Chunking bool
+	// Auth lists the authentication mechanisms of the server, e.g. PLAIN and LOGIN
+	Auth []string
+	// Extensions are all advertised extensions keyed by their upper case keyword with their parameters as value
+	Extensions map[string]string
+}
+
+// Has reports whether the server advertised the extension
+func (c Capabilities) Has(extension string) bool {
+	_, ok := c.Extensions[strings.ToUpper(extension)]
+	return ok
+}
+
+// Probe connects to the SMTP server at the address and returns the capabilities it advertises,
+// without sending a message. The connection is wrapped in TLS with the config if it is not nil,
+// like the TLS encryption of ClientConfig. Otherwise it is upgraded with STARTTLS if the server
+// supports it, as servers often advertise AUTH only over TLS; an invalid certificate fails the probe.
+// The client announces itself with helo in EHLO, which should be the FQDN of ClientConfig, so that
+// servers that restrict clients by their name report the capabilities a send sees. An empty helo
+// announces "localhost". The session is limited to 30 seconds.
+//
+// Example adapting the client configuration to the server:
+//
+//	caps, err := mail.Probe("smtp.example.com:587", nil, config.Client.FQDN)
+//	if err != nil {
+//		return err
+//	}
+//	config.Client.MaxMessageBytes = caps.Size
+//	if !slices.Contains(caps.Auth, "PLAIN") && slices.Contains(caps.Auth, "LOGIN") {
+//		config.Client.AuthMethod = "LOGIN"
+//	}
+func Probe(address string, tls *tls.Config, helo string) (Capabilities, error) {
+	ext, secure, err := email.Probe(address, tls, helo, probeTimeout)
+	if err != nil {
+		return Capabilities{}, apperror.NewErrorf("probing SMTP server %s failed", address).AddError(err)
+	}
+	return parseCapabilities(ext, secure), nil
+}
+
+// parseCapabilities interprets the EHLO extensions
+func parseCapabilities(ext map[string]string, secure bool) Capabilities {
+	caps := Capabilities{TLS: secure, Extensions: ext}
+	for keyword, params := range ext {
+		switch keyword {
+		case "STARTTLS":
+			caps.StartTLS = true
+		case "SIZE":
+			// An invalid or missing size is treated like no declared limit
+			caps.Size, _ = strconv.ParseInt(params, 10, 64)
+		case "8BITMIME":
+			caps.EightBitMIME = true
+		case "SMTPUTF8":
+			caps.SMTPUTF8 = true
+		case "DSN":
+			caps.DSN = true
+		case "PIPELINING":
+			caps.Pipelining = true
+		case "CHUNKING":
+			caps.Chunking = true
+		case "AUTH":
+			caps.Auth = strings.Fields(strings.ToUpper(params))
+		}
+	}
+	return caps
+}
@@ -0,0 +1,95 @@
+package mail_test
+
+import (
+	"net"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/valentin-kaiser/go-core/mail"
+)
+
+func TestProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	commands := make(chan string, 8)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tc := textproto.NewConn(conn)
+		_ = tc.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			commands <- line
+			switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+			case "EHLO":
+				_ = tc.PrintfLine("250-localhost greets you")
+				_ = tc.PrintfLine("250-SIZE 10240000")
+				_ = tc.PrintfLine("250-8BITMIME")
+				_ = tc.PrintfLine("250-dsn")
+				_ = tc.PrintfLine("250-PIPELINING")
+				_ = tc.PrintfLine("250-AUTH PLAIN login")
+				_ = tc.PrintfLine("250 X-CUSTOM value")
+			case "QUIT":
+				_ = tc.PrintfLine("221 bye")
+				return
+			default:
+				_ = tc.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	caps, err := mail.Probe(listener.Addr().String(), nil, "client.example.com")
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+
+	if caps.TLS || caps.StartTLS {
+		t.Errorf("expected no TLS support, got TLS %v and STARTTLS %v", caps.TLS, caps.StartTLS)
+	}
+	if caps.Size != 10240000 {
+		t.Errorf("expected size 10240000, got %d", caps.Size)
+	}
+	if !caps.EightBitMIME || !caps.DSN || !caps.Pipelining {
+		t.Errorf("expected 8BITMIME, DSN and PIPELINING, got %+v", caps)
+	}
+	if caps.SMTPUTF8 || caps.Chunking {
+		t.Errorf("expected no SMTPUTF8 and CHUNKING, got %+v", caps)
+	}
+	if !slices.Equal(caps.Auth, []string{"PLAIN", "LOGIN"}) {
+		t.Errorf("expected AUTH PLAIN LOGIN, got %v", caps.Auth)
+	}
+	if !caps.Has("x-custom") || caps.Extensions["X-CUSTOM"] != "value" {
+		t.Errorf("expected custom extension, got %v", caps.Extensions)
+	}
+
+	close(commands)
+	var sent []string
+	for cmd := range commands {
+		verb := strings.SplitN(cmd, " ", 2)[0]
+		if verb == "EHLO" && cmd != "EHLO client.example.com" {
+			t.Errorf("expected the client to announce its name, got %q", cmd)
+		}
+		sent = append(sent, verb)
+	}
+	if sent[len(sent)-1] != "QUIT" || slices.Contains(sent, "MAIL") {
+		t.Errorf("expected the session to end with QUIT without a message, got %v", sent)
+	}
+
+	listener.Close()
+	_, err = mail.Probe(listener.Addr().String(), nil, "")
+	if err == nil {
+		t.Error("expected probing a closed server to fail")
+	}
+}
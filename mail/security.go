@@ -134,6 +134,11 @@ func (sm *SecurityManager) ValidateConnection(remoteAddr string) error {
 		return &SecurityError{Type: "ip_not_allowed", Message: "IP address is not allowed"}
 	}
 
+	// Check the connection rate of the IP
+	if err := sm.takeToken(host); err != nil {
+		return err
+	}
+
 	// Check connection limits per IP
 	if sm.config.MaxConnectionsPerIP > 0 {
 		currentConnections := sm.ipConnections[ip.String()]
@@ -225,7 +230,10 @@ func (sm *SecurityManager) ValidateHelo(hostname, remoteAddr string) error {
 	return nil
 }
 
-// CheckRateLimit checks if the IP has exceeded rate limits
+// CheckRateLimit checks if the IP has exceeded rate limits.
+// Every IP has a token bucket that holds up to RateLimitPerIP tokens and is refilled
+// at RateLimitPerIP tokens per minute. Each accepted connection and each rate limited
+// command takes a token, they are rejected while the bucket is empty.
 func (sm *SecurityManager) CheckRateLimit(remoteAddr string) error {
 	if sm.config.RateLimitPerIP <= 0 {
 		return nil
@@ -239,19 +247,22 @@ func (sm *SecurityManager) CheckRateLimit(remoteAddr string) error {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
-	limiter, exists := sm.ipRateLimit[host]
-	if !exists {
-		sm.ipRateLimit[host] = struct {
-			*rate.Limiter
-			last time.Time
-		}{
-			Limiter: rate.NewLimiter(rate.Limit(sm.config.RateLimitPerIP), 1),
-			last:    time.Now(),
-		}
+	return sm.takeToken(host)
+}
+
+// takeToken takes a token from the bucket of the host, the caller must hold the lock
+func (sm *SecurityManager) takeToken(host string) error {
+	if sm.config.RateLimitPerIP <= 0 {
 		return nil
 	}
 
+	limiter, exists := sm.ipRateLimit[host]
+	if !exists {
+		limiter.Limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(sm.config.RateLimitPerIP)), sm.config.RateLimitPerIP)
+	}
 	limiter.last = time.Now()
+	sm.ipRateLimit[host] = limiter
+
 	if !limiter.Allow() {
 		if sm.config.LogSecurityEvents {
 			logger.Warn().Field("ip", host).Msg("rate limit exceeded")
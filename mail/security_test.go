@@ -1,6 +1,7 @@
 package mail_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -225,6 +226,50 @@ func TestSecurityManager_ConnectionTracking(t *testing.T) {
 	}
 }
 
+func TestSecurityManager_RateLimit(t *testing.T) {
+	// 600 per minute refills a token every 100ms
+	sm := mail.NewSecurityManager(mail.SecurityConfig{RateLimitPerIP: 600})
+	remoteAddr := "192.168.1.1:12345"
+
+	// The first connection takes a token, the burst allows the remaining commands
+	err := sm.ValidateConnection(remoteAddr)
+	if err != nil {
+		t.Fatalf("Connection should succeed, got error: %v", err)
+	}
+	for i := 1; i < 600; i++ {
+		err = sm.CheckRateLimit(remoteAddr)
+		if err != nil {
+			t.Fatalf("Command %d should be within the limit, got error: %v", i, err)
+		}
+	}
+
+	err = sm.CheckRateLimit(remoteAddr)
+	if err == nil {
+		t.Fatal("Command should be rejected after the limit is exhausted")
+	}
+	err = sm.ValidateConnection(remoteAddr)
+	if err == nil {
+		t.Fatal("Connection should be rejected after the limit is exhausted")
+	}
+	var securityErr *mail.SecurityError
+	if !errors.As(err, &securityErr) || securityErr.Type != "rate_limit" {
+		t.Errorf("Expected rate_limit error, got %v", err)
+	}
+
+	// Other IPs have their own bucket
+	err = sm.CheckRateLimit("192.168.1.2:12345")
+	if err != nil {
+		t.Errorf("Command of another IP should succeed, got error: %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	err = sm.CheckRateLimit(remoteAddr)
+	if err != nil {
+		t.Errorf("Command should succeed after a token was refilled, got error: %v", err)
+	}
+}
+
 func TestSecurityManager_GetAuthFailureDelay(t *testing.T) {
 	config := mail.SecurityConfig{
 		MaxAuthFailures:   3,
@@ -19,6 +19,7 @@ import (
 type smtpSender struct {
 	config          ClientConfig
 	templateManager *TemplateManager
+	transport       Transport // delivers messages instead of the SMTP server if set
 }
 
 // NewSMTPSender creates a new SMTP sender with configurable security
@@ -35,7 +36,7 @@ func NewSMTPSender(config ClientConfig, templateManager *TemplateManager) Sender
 
 // Send sends an email message via SMTP
 func (s *smtpSender) Send(ctx context.Context, message *Message) error {
-	if !s.config.Enabled {
+	if !s.config.Enabled && s.transport == nil {
 		return apperror.NewError("SMTP sender is disabled")
 	}
 
@@ -57,6 +58,14 @@ func (s *smtpSender) Send(ctx context.Context, message *Message) error {
 		return apperror.Wrap(err)
 	}
 
+	// Invalid messages would fail on every attempt
+	emailMsg.MaxBytes = s.config.MaxMessageBytes
+	emailMsg.Timeout = s.config.Timeout
+	err = emailMsg.Validate()
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+
 	// Send with retries
 	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
 		if attempt > 0 {
@@ -100,6 +109,11 @@ func (s *smtpSender) validateMessage(message *Message) error {
 		return apperror.NewError("at least one recipient is required")
 	}
 
+	err := validateRecipients(message)
+	if err != nil {
+		return err
+	}
+
 	if message.Subject == "" {
 		return apperror.NewError("subject is required")
 	}
@@ -150,6 +164,11 @@ func (s *smtpSender) createEmail(message *Message) (*email.Email, error) {
 	// Set subject
 	emailMsg.Subject = message.Subject
 
+	// Request read receipts and delivery status notifications
+	emailMsg.ReadReceipt = message.ReadReceipt
+	emailMsg.Notify = message.Notify
+	emailMsg.Return = message.Return
+
 	// Set body content
 	if message.TextBody != "" {
 		emailMsg.Text = []byte(message.TextBody)
@@ -219,7 +238,19 @@ func (s *smtpSender) addAttachment(emailMsg *email.Email, attachment Attachment)
 }
 
 // sendEmail sends the email using the appropriate method
-func (s *smtpSender) sendEmail(_ context.Context, emailMsg *email.Email) error {
+func (s *smtpSender) sendEmail(ctx context.Context, emailMsg *email.Email) error {
+	if s.transport != nil {
+		from, to, err := emailMsg.Envelope()
+		if err != nil {
+			return apperror.Wrap(err)
+		}
+		raw, err := emailMsg.Bytes()
+		if err != nil {
+			return apperror.Wrap(err)
+		}
+		return s.transport.Deliver(ctx, from, to, raw)
+	}
+
 	// Prepare authentication
 	var auth smtp.Auth
 	if s.config.Auth {
@@ -259,10 +290,16 @@ func (s *smtpSender) sendWithTLS(emailMsg *email.Email, addr string, auth smtp.A
 	return emailMsg.SendWithTLS(addr, auth, tlsConfig, s.config.FQDN)
 }
 
-// sendWithStartTLS sends email with STARTTLS encryption
+// sendWithStartTLS sends email with STARTTLS encryption according to the TLS policy
 func (s *smtpSender) sendWithStartTLS(emailMsg *email.Email, addr string, auth smtp.Auth) error {
-	tlsConfig := s.config.TLSConfig()
-	return emailMsg.SendWithStartTLS(addr, auth, tlsConfig, s.config.FQDN)
+	switch TLSPolicy(strings.ToUpper(s.config.TLSPolicy)) {
+	case TLSPolicyNone:
+		return s.sendPlain(emailMsg, addr, auth)
+	case TLSPolicyPrefer:
+		return emailMsg.SendWithStartTLS(addr, auth, s.config.TLSConfig(), s.config.FQDN, false)
+	default:
+		return emailMsg.SendWithStartTLS(addr, auth, s.config.TLSConfig(), s.config.FQDN, true)
+	}
 }
 
 // sendPlain sends email without encryption
@@ -2,7 +2,9 @@ package mail_test
 
 import (
 	"context"
+	"net"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -415,3 +417,84 @@ func TestSMTPSender_ContextCancellation(t *testing.T) {
 		t.Errorf("Send took too long despite context cancellation: %v", duration)
 	}
 }
+
+func TestSMTPSender_MaxMessageBytes(t *testing.T) {
+	config := mail.ClientConfig{
+		Enabled:         true,
+		Host:            "127.0.0.1",
+		Port:            1,
+		From:            "sender@example.com",
+		Encryption:      "NONE",
+		MaxRetries:      0,
+		RetryDelay:      time.Millisecond,
+		MaxMessageBytes: 512,
+	}
+	sender := mail.NewSMTPSender(config, nil)
+
+	err := sender.Send(t.Context(), &mail.Message{
+		To:       []string{"recipient@example.com"},
+		Subject:  "Large message",
+		TextBody: strings.Repeat("large body ", 100),
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Errorf("Expected message size error before sending, got: %v", err)
+	}
+}
+
+func TestSMTPSender_Timeout(t *testing.T) {
+	// The listener accepts connections but never sends the SMTP greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name       string
+		host       string
+		port       int
+		encryption string
+	}{
+		{name: "unreachable host", host: "10.255.255.1", port: 25, encryption: "NONE"},
+		{name: "silent server", host: "127.0.0.1", port: port, encryption: "NONE"},
+		{name: "silent server with STARTTLS", host: "127.0.0.1", port: port, encryption: "STARTTLS"},
+		{name: "silent server with TLS", host: "127.0.0.1", port: port, encryption: "TLS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := mail.NewSMTPSender(mail.ClientConfig{
+				Enabled:    true,
+				Host:       tt.host,
+				Port:       tt.port,
+				From:       "sender@example.com",
+				Encryption: tt.encryption,
+				Timeout:    200 * time.Millisecond,
+			}, nil)
+
+			start := time.Now()
+			err := sender.Send(context.Background(), &mail.Message{
+				From:     "sender@example.com",
+				To:       []string{"recipient@example.com"},
+				Subject:  "Timeout",
+				TextBody: "Body",
+			})
+			if err == nil {
+				t.Fatal("expected send to fail")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("expected send to fail within the timeout, took %v", elapsed)
+			}
+		})
+	}
+}
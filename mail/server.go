@@ -116,11 +116,23 @@ func (c *Conn) TLS() bool {
 	return c.tls
 }
 
+// ConnectionState returns the state of the TLS connection, or nil if the connection is not using TLS
+func (c *Conn) ConnectionState() *tls.ConnectionState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	tlsConn, ok := c.Conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	return &state
+}
+
 // smtpServer manages SMTP server operations and handles incoming messages
 type smtpServer struct {
 	config           ServerConfig
 	manager          *Manager
-	handlers         []NotificationHandler
+	handlers         []SessionHandler
 	running          int32
 	mutex            sync.RWMutex
 	handlerSemaphore chan struct{}      // Semaphore to limit concurrent handlers
@@ -138,10 +150,9 @@ type smtpServer struct {
 
 // handlerTask represents a pending notification handler task
 type handlerTask struct {
-	handler NotificationHandler
+	handler SessionHandler
 	ctx     context.Context
-	from    string
-	to      []string
+	session *SessionInfo
 	data    io.Reader
 }
 
@@ -156,7 +167,7 @@ func NewSMTPServer(config ServerConfig, manager *Manager) Server {
 	server := &smtpServer{
 		config:           config,
 		manager:          manager,
-		handlers:         make([]NotificationHandler, 0),
+		handlers:         make([]SessionHandler, 0),
 		handlerSemaphore: make(chan struct{}, config.MaxConcurrentHandlers),
 		handlerQueue:     make(chan handlerTask, config.MaxConcurrentHandlers*2), // Queue size = 2x semaphore size
 		ctx:              ctx,
@@ -278,6 +289,13 @@ func (s *smtpServer) Stop(ctx context.Context) error {
 
 // AddHandler adds a notification handler
 func (s *smtpServer) AddHandler(handler NotificationHandler) {
+	s.AddSessionHandler(func(ctx context.Context, session *SessionInfo, data io.Reader) error {
+		return handler(ctx, session.From, session.To, data)
+	})
+}
+
+// AddSessionHandler adds a handler that receives the metadata of the SMTP session
+func (s *smtpServer) AddSessionHandler(handler SessionHandler) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.handlers = append(s.handlers, handler)
@@ -385,9 +403,9 @@ createTLS:
 }
 
 // notifyHandlers notifies all registered handlers using worker pool with queueing
-func (s *smtpServer) notifyHandlers(ctx context.Context, from string, to []string, data []byte) {
+func (s *smtpServer) notifyHandlers(ctx context.Context, session *SessionInfo, data []byte) {
 	s.mutex.RLock()
-	handlers := make([]NotificationHandler, len(s.handlers))
+	handlers := make([]SessionHandler, len(s.handlers))
 	copy(handlers, s.handlers)
 	s.mutex.RUnlock()
 
@@ -395,8 +413,7 @@ func (s *smtpServer) notifyHandlers(ctx context.Context, from string, to []strin
 		task := handlerTask{
 			handler: handler,
 			ctx:     ctx,
-			from:    from,
-			to:      to,
+			session: session,
 			data:    bytes.NewReader(data),
 		}
 
@@ -407,8 +424,8 @@ func (s *smtpServer) notifyHandlers(ctx context.Context, from string, to []strin
 		case <-time.After(100 * time.Millisecond):
 			// Queue is full and timed out - log warning but continue
 			logger.Warn().
-				Field("from", from).
-				Field("to", to).
+				Field("from", session.From).
+				Field("to", session.To).
 				Field("queue_size", len(s.handlerQueue)).
 				Field("queue_capacity", cap(s.handlerQueue)).
 				Msg("notification handler queue is full, dropping task")
@@ -416,15 +433,15 @@ func (s *smtpServer) notifyHandlers(ctx context.Context, from string, to []strin
 			// Context cancelled
 			logger.Warn().
 				Err(ctx.Err()).
-				Field("from", from).
-				Field("to", to).
+				Field("from", session.From).
+				Field("to", session.To).
 				Msg("notification handler cancelled due to context")
 			return
 		case <-s.ctx.Done():
 			// Server context cancelled
 			logger.Warn().
-				Field("from", from).
-				Field("to", to).
+				Field("from", session.From).
+				Field("to", session.To).
 				Msg("notification handler cancelled due to server shutdown")
 			return
 		}
@@ -443,11 +460,11 @@ func (s *smtpServer) startWorkerPool() {
 				select {
 				case task := <-s.handlerQueue:
 					// Process the handler task
-					if err := task.handler(task.ctx, task.from, task.to, task.data); err != nil {
+					if err := task.handler(task.ctx, task.session, task.data); err != nil {
 						logger.Error().
 							Err(err).
-							Field("from", task.from).
-							Field("to", task.to).
+							Field("from", task.session.From).
+							Field("to", task.session.To).
 							Field("worker_id", workerID).
 							Msg("notification handler failed")
 					}
@@ -465,6 +482,7 @@ type session struct {
 	conn          *Conn
 	remoteAddr    string
 	authenticated bool
+	identity      string
 	from          string
 	to            []string
 	heloValidated bool
@@ -519,6 +537,7 @@ func (s *session) AuthPlain(username, password string) error {
 
 	if username == s.server.config.Username && password == s.server.config.Password {
 		s.authenticated = true
+		s.identity = username
 		s.server.security.RecordAuthSuccess(s.remoteAddr)
 		logger.Trace().Field("username", username).Msg("SMTP authentication successful")
 		return nil
@@ -610,11 +629,58 @@ func (s *session) Data(r io.Reader) error {
 
 	// Notify handlers
 	ctx := context.Background()
-	s.server.notifyHandlers(ctx, s.from, s.to, data)
+	if s.server.config.Security.VerifyAuthentication {
+		ctx = withAuthResults(ctx, s.verify(data))
+	}
+	s.server.notifyHandlers(ctx, s.info(), data)
 
 	return nil
 }
 
+// verify checks SPF, DKIM and DMARC of the received message
+func (s *session) verify(data []byte) *AuthResults {
+	ctx, cancel := context.WithTimeout(s.server.ctx, 10*time.Second)
+	defer cancel()
+
+	host, _, err := net.SplitHostPort(s.remoteAddr)
+	if err != nil {
+		host = s.remoteAddr
+	}
+
+	results := VerifyAuthentication(ctx, getDNSResolver(), net.ParseIP(host), s.conn.Hostname(), s.from, data)
+	logger.Debug().
+		Field("from", s.from).
+		Field("spf", results.SPF).
+		Field("dkim", results.DKIM).
+		Field("dmarc", results.DMARC).
+		Msg("verified message authentication")
+	return results
+}
+
+// info returns the metadata of the session for the handlers
+func (s *session) info() *SessionInfo {
+	s.conn.mutex.RLock()
+	helo, extended := s.conn.hostname, s.conn.ehlo
+	s.conn.mutex.RUnlock()
+
+	host, _, err := net.SplitHostPort(s.remoteAddr)
+	if err != nil {
+		host = s.remoteAddr
+	}
+
+	return &SessionInfo{
+		Helo:         helo,
+		Extended:     extended,
+		RemoteAddr:   s.remoteAddr,
+		RemoteIP:     net.ParseIP(host),
+		TLS:          s.conn.ConnectionState(),
+		AuthIdentity: s.identity,
+		From:         s.from,
+		To:           append([]string(nil), s.to...),
+		ReceivedAt:   time.Now(),
+	}
+}
+
 // Reset resets the session
 func (s *session) Reset() {
 	logger.Trace().Msg("SMTP session reset")
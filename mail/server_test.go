@@ -3,6 +3,8 @@ package mail_test
 import (
 	"context"
 	"io"
+	"net"
+	"net/smtp"
 	"testing"
 	"time"
 
@@ -316,3 +318,85 @@ func TestSMTPServer_GracefulShutdown(t *testing.T) {
 		t.Fatal("Expected server to be created")
 	}
 }
+
+func TestSMTPServer_SessionHandler(t *testing.T) {
+	config := mail.ServerConfig{
+		Enabled:               true,
+		Domain:                "test.local",
+		Auth:                  true,
+		Username:              "user",
+		Password:              "secret",
+		ReadTimeout:           time.Second * 10,
+		WriteTimeout:          time.Second * 10,
+		MaxConcurrentHandlers: 1,
+	}
+
+	server := mail.NewSMTPServer(config, nil)
+	sessions := make(chan *mail.SessionInfo, 1)
+	server.AddSessionHandler(func(_ context.Context, session *mail.SessionInfo, _ io.Reader) error {
+		sessions <- session
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go server.(interface{ Serve(net.Listener) error }).Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	client, err := smtp.NewClient(conn, "localhost")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	steps := []func() error{
+		func() error { return client.Hello("client.example.com") },
+		func() error { return client.Auth(smtp.PlainAuth("", "user", "secret", "localhost")) },
+		func() error { return client.Mail("sender@example.com") },
+		func() error { return client.Rcpt("recipient@test.local") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("SMTP command failed: %v", err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		t.Fatalf("DATA failed: %v", err)
+	}
+	_, err = w.Write([]byte("Subject: Test\r\n\r\nHello\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Failed to finish data: %v", err)
+	}
+
+	select {
+	case session := <-sessions:
+		if session.Helo != "client.example.com" || !session.Extended {
+			t.Errorf("Expected EHLO client.example.com, got %q (extended %v)", session.Helo, session.Extended)
+		}
+		if !session.RemoteIP.IsLoopback() {
+			t.Errorf("Expected loopback remote IP, got %v", session.RemoteIP)
+		}
+		if session.TLS != nil {
+			t.Error("Expected no TLS state for a plain text session")
+		}
+		if session.AuthIdentity != "user" {
+			t.Errorf("Expected auth identity user, got %q", session.AuthIdentity)
+		}
+		if session.From != "sender@example.com" || len(session.To) != 1 || session.To[0] != "recipient@test.local" {
+			t.Errorf("Unexpected envelope %q -> %v", session.From, session.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Session handler was not called")
+	}
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// spoolFile returns the path of the spool file for the message id
+func (m *Manager) spoolFile(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return "", apperror.NewErrorf("message id %q cannot be used as spool file name", id)
+	}
+	return filepath.Join(m.config.Queue.StoragePath, id+".json"), nil
+}
+
+// spooling reports whether queued messages are persisted to the storage path
+// Persistent queue backends keep unsent messages themselves, restoring them from the
+// spool as well would send them twice, so only messages of in-memory queues are spooled
+func (m *Manager) spooling() bool {
+	return m.config.Queue.StoragePath != "" && !m.queueManager.IsPersistent()
+}
+
+// spool persists the job data of a queued message to the storage path
+func (m *Manager) spool(id string, jobData map[string]interface{}) error {
+	if !m.spooling() {
+		return nil
+	}
+
+	path, err := m.spoolFile(id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(jobData)
+	if err != nil {
+		return apperror.NewError("failed to encode queued mail").AddError(err)
+	}
+
+	err = os.MkdirAll(m.config.Queue.StoragePath, 0750)
+	if err != nil {
+		return apperror.NewError("failed to create mail storage directory").AddError(err)
+	}
+
+	// Write to a temporary file first so that a crash never leaves a partial message behind
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return apperror.NewError("failed to write queued mail").AddError(err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return apperror.NewError("failed to write queued mail").AddError(err)
+	}
+
+	return nil
+}
+
+// unspool removes the persisted job data of a message that was sent or dropped
+func (m *Manager) unspool(id string) {
+	if m.config.Queue.StoragePath == "" {
+		return
+	}
+
+	path, err := m.spoolFile(id)
+	if err != nil {
+		return
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Error().Err(err).Field("id", id).Msg("failed to remove queued mail from storage")
+	}
+}
+
+// restoreSpool enqueues all messages that were persisted but not sent before the last shutdown
+// Messages the queue still holds, e.g. after restarting the manager, are skipped. Restored
+// messages were counted when they were enqueued, so they are not counted as queued again.
+func (m *Manager) restoreSpool(ctx context.Context) error {
+	if !m.spooling() {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.config.Queue.StoragePath, "*.json"))
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+
+	restored := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file) // #nosec G304 -- files are read from the configured storage path
+		if err != nil {
+			logger.Error().Err(err).Field("file", file).Msg("failed to read queued mail")
+			continue
+		}
+
+		var jobData map[string]interface{}
+		err = json.Unmarshal(data, &jobData)
+		if err != nil {
+			logger.Error().Err(err).Field("file", file).Msg("failed to decode queued mail")
+			continue
+		}
+
+		message := m.jobDataToMessage(jobData)
+		if message.ID == "" {
+			message.ID = strings.TrimSuffix(filepath.Base(file), ".json")
+			jobData["id"] = message.ID
+		}
+
+		if _, err := m.queueManager.GetJob(ctx, message.ID); err == nil {
+			continue
+		}
+
+		err = m.enqueue(ctx, message, jobData)
+		if err != nil {
+			logger.Error().Err(err).Field("id", message.ID).Msg("failed to restore queued mail")
+			continue
+		}
+		restored++
+	}
+
+	if restored > 0 {
+		logger.Info().Field("count", restored).Msg("restored queued mail from storage")
+	}
+
+	return nil
+}
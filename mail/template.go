@@ -18,11 +18,12 @@ import (
 
 // TemplateManager implements the TemplateManager interface
 type TemplateManager struct {
-	config    TemplateConfig
-	templates map[string]*template.Template
-	funcs     template.FuncMap
-	mutex     sync.RWMutex
-	Error     error
+	config     TemplateConfig
+	templates  map[string]*template.Template
+	funcs      template.FuncMap
+	translator func(lang, key string, args ...any) string
+	mutex      sync.RWMutex
+	Error      error
 }
 
 // NewTemplateManager creates a new template manager
@@ -351,7 +352,51 @@ func (tm *TemplateManager) parseTemplate(name, content string) (*template.Templa
 	return tmpl, nil
 }
 
-// WithDefaultFuncs adds default template functions to the manager
+// WithTranslator sets the function the t template function looks up localized messages with,
+// e.g. backed by golang.org/x/text/message or go-i18n. The arguments after the key are passed
+// through, e.g. for placeholders. Templates call it as {{t .Lang "welcome.subject" .Name}}.
+// Without a translator t returns the key. The t function is added to the manager if it is not set yet.
+//
+// Example:
+//
+//	tm.WithTranslator(func(lang, key string, args ...any) string {
+//		return message.NewPrinter(language.Make(lang)).Sprintf(key, args...)
+//	})
+func (tm *TemplateManager) WithTranslator(translator func(lang, key string, args ...any) string) *TemplateManager {
+	if tm.Error != nil {
+		return tm
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.translator = translator
+	if tm.funcs == nil {
+		tm.funcs = make(template.FuncMap)
+	}
+	if _, exists := tm.funcs["t"]; !exists {
+		tm.funcs["t"] = tm.translate
+	}
+	return tm
+}
+
+// translate looks up the message with the translator, it returns the key if no translator is set
+func (tm *TemplateManager) translate(lang, key string, args ...any) string {
+	tm.mutex.RLock()
+	translator := tm.translator
+	tm.mutex.RUnlock()
+	if translator == nil {
+		return key
+	}
+	return translator(lang, key, args...)
+}
+
+// WithDefaultFuncs adds default template functions to the manager:
+//   - math: add, sub, mul, div, mod
+//   - strings: upper, lower, title, trim, replace, contains, hasPrefix, hasSuffix, join, split, default, dict, date
+//   - output: safeHTML and safeURL mark trusted content, e.g. markup or links composed by the
+//     application, so it is not escaped. They must never be used with user input.
+//     Values are query escaped with the urlquery function of html/template, e.g. {{urlquery .Email}}.
+//   - localization: t looks up messages with the translator set with WithTranslator
 func (tm *TemplateManager) WithDefaultFuncs() *TemplateManager {
 	if tm.Error != nil {
 		return tm
@@ -457,6 +502,13 @@ func (tm *TemplateManager) WithDefaultFuncs() *TemplateManager {
 				return fmt.Sprintf("%v", date)
 			}
 		},
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(s) // #nosec G203 -- only for trusted content, see WithDefaultFuncs
+		},
+		"safeURL": func(s string) template.URL {
+			return template.URL(s) // #nosec G203 -- only for trusted content, see WithDefaultFuncs
+		},
+		"t": tm.translate,
 	}
 
 	tm.mutex.Lock()
@@ -1,8 +1,10 @@
 package mail_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -485,3 +487,60 @@ func TestTemplateManager_ChainedConfiguration(t *testing.T) {
 		t.Errorf("Should contain uppercase name, got: %s", rendered)
 	}
 }
+
+func TestTemplateManager_OutputAndTranslationFunctions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	templateContent := `<h1>{{t .Lang "welcome" .Name}}</h1>{{safeHTML .Footer}}<a href="{{safeURL .Link}}">{{.Name}}</a><a href="https://example.com/?email={{urlquery .Email}}">{{t .Lang "unsubscribe"}}</a>`
+	if err := os.WriteFile(filepath.Join(tempDir, "welcome.html"), []byte(templateContent), 0600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	tm := mail.NewTemplateManager(mail.TemplateConfig{DefaultTemplate: "welcome.html"}).
+		WithDefaultFuncs().
+		WithFileServer(tempDir)
+	if tm.Error != nil {
+		t.Fatalf("Unexpected error: %v", tm.Error)
+	}
+
+	data := map[string]interface{}{
+		"Lang":   "de",
+		"Name":   "<Jane>",
+		"Footer": "<p>Footer</p>",
+		"Link":   "tel:+49123",
+		"Email":  "jane+news@example.com",
+	}
+
+	// Without a translator the keys are rendered
+	rendered, err := tm.RenderTemplate("welcome.html", data)
+	if err != nil {
+		t.Fatalf("Failed to render template: %v", err)
+	}
+	if !strings.Contains(rendered, "<h1>welcome</h1>") {
+		t.Errorf("Expected the key without translator, got: %s", rendered)
+	}
+
+	tm.WithTranslator(func(lang, key string, args ...any) string {
+		if lang == "de" && key == "welcome" {
+			return fmt.Sprintf("Willkommen, %v", args...)
+		}
+		return lang + ":" + key
+	})
+
+	rendered, err = tm.RenderTemplate("welcome.html", data)
+	if err != nil {
+		t.Fatalf("Failed to render template: %v", err)
+	}
+	expected := []string{
+		"<h1>Willkommen, &lt;Jane&gt;</h1>",
+		"<p>Footer</p>",
+		`href="tel:&#43;49123"`,
+		"email=jane%2Bnews%40example.com",
+		"de:unsubscribe",
+	}
+	for _, e := range expected {
+		if !strings.Contains(rendered, e) {
+			t.Errorf("Expected %q in rendered template, got: %s", e, rendered)
+		}
+	}
+}
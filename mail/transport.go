@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Transport delivers rendered messages to their recipients.
+// The SMTP sender delivers messages through the configured SMTP server unless a
+// transport is set with Manager.WithTransport, e.g. a MemoryTransport in tests.
+type Transport interface {
+	// Deliver sends the raw message from the envelope sender to the envelope recipients
+	Deliver(ctx context.Context, from string, to []string, data []byte) error
+}
+
+// DeliveredMessage is a message captured by a MemoryTransport
+type DeliveredMessage struct {
+	// From is the envelope sender
+	From string
+	// To contains the envelope recipients, including Cc and Bcc recipients
+	To []string
+	// Data is the raw message including its headers
+	Data []byte
+}
+
+// MemoryTransport captures delivered messages in memory instead of sending them.
+// It makes the send path testable without network access or an SMTP server.
+//
+// Example:
+//
+//	transport := mail.NewMemoryTransport()
+//	manager := mail.NewManager(config, nil).WithTransport(transport)
+//	err := manager.Start(ctx)
+//	...
+//	err = manager.Send(ctx, message)
+//	messages := transport.Messages()
+type MemoryTransport struct {
+	mutex    sync.Mutex
+	messages []DeliveredMessage
+	err      error
+}
+
+// NewMemoryTransport creates a new in-memory transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Deliver captures the message, it returns the error set with FailWith instead if there is one
+func (t *MemoryTransport) Deliver(_ context.Context, from string, to []string, data []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+
+	t.messages = append(t.messages, DeliveredMessage{
+		From: from,
+		To:   append([]string(nil), to...),
+		Data: append([]byte(nil), data...),
+	})
+	return nil
+}
+
+// FailWith makes all following deliveries fail with the error, nil restores delivery
+func (t *MemoryTransport) FailWith(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.err = err
+}
+
+// Messages returns the captured messages in the order they were delivered
+func (t *MemoryTransport) Messages() []DeliveredMessage {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]DeliveredMessage(nil), t.messages...)
+}
+
+// Reset removes all captured messages
+func (t *MemoryTransport) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.messages = nil
+}
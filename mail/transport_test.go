@@ -0,0 +1,73 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/mail"
+)
+
+func TestMemoryTransport(t *testing.T) {
+	config := mail.DefaultConfig()
+	config.Queue.Enabled = false
+	config.Server.Enabled = false
+	config.Client.Enabled = false
+	config.Client.MaxRetries = 0
+
+	transport := mail.NewMemoryTransport()
+	manager := mail.NewManager(config, nil).WithTransport(transport)
+	err := manager.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Expected no error starting manager, got: %v", err)
+	}
+	defer apperror.Catch(func() error { return manager.Stop(t.Context()) }, "failed to stop mail manager")
+
+	message, err := mail.NewMessage().
+		From("Sender <sender@example.com>").
+		To("recipient@example.com").
+		BCC("hidden@example.com").
+		Subject("Captured").
+		TextBody("Hello from memory").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+
+	err = manager.Send(t.Context(), message)
+	if err != nil {
+		t.Fatalf("Expected message to be delivered to the transport, got: %v", err)
+	}
+
+	messages := transport.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 captured message, got %d", len(messages))
+	}
+	captured := messages[0]
+	if captured.From != "sender@example.com" {
+		t.Errorf("Expected envelope sender sender@example.com, got %s", captured.From)
+	}
+	if len(captured.To) != 2 || captured.To[0] != "recipient@example.com" || captured.To[1] != "hidden@example.com" {
+		t.Errorf("Expected envelope recipients including Bcc, got %v", captured.To)
+	}
+	data := string(captured.Data)
+	if !strings.Contains(data, "Subject: Captured") || !strings.Contains(data, "Hello from memory") {
+		t.Errorf("Expected subject and body in the raw message, got %s", data)
+	}
+	if strings.Contains(data, "hidden@example.com") {
+		t.Error("Expected Bcc recipients to be omitted from the headers")
+	}
+	if manager.GetStats().SentCount != 1 {
+		t.Errorf("Expected sent count 1, got %d", manager.GetStats().SentCount)
+	}
+
+	transport.Reset()
+	transport.FailWith(apperror.NewError("mailbox unavailable"))
+	err = manager.Send(t.Context(), message)
+	if err == nil {
+		t.Error("Expected the transport error to fail the send")
+	}
+	if len(transport.Messages()) != 0 || manager.GetStats().FailedCount != 1 {
+		t.Errorf("Expected no captured message and a failed send, got %d messages and %d failures", len(transport.Messages()), manager.GetStats().FailedCount)
+	}
+}
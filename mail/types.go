@@ -2,9 +2,11 @@ package mail
 
 import (
 	"context"
+	"crypto/tls"
 	"html/template"
 	"io"
 	"mime/multipart"
+	"net"
 	"strings"
 	"time"
 
@@ -43,6 +45,12 @@ type Message struct {
 	Headers map[string]string `json:"headers,omitempty"`
 	// Priority is the message priority
 	Priority Priority `json:"priority"`
+	// ReadReceipt are the addresses read receipts are requested for (Disposition-Notification-To)
+	ReadReceipt []string `json:"read_receipt,omitempty"`
+	// Notify are the conditions delivery status notifications are requested for, see DeliveryNotification
+	Notify []string `json:"notify,omitempty"`
+	// Return selects the content of failure notifications, see DeliveryNotification
+	Return string `json:"return,omitempty"`
 	// CreatedAt is when the message was created
 	CreatedAt time.Time `json:"created_at"`
 	// ScheduleAt is when the message should be sent (optional)
@@ -99,6 +107,26 @@ func (p Priority) String() string {
 	}
 }
 
+// Conditions of delivery status notifications (RFC 3461)
+const (
+	// NotifySuccess requests a notification on successful delivery
+	NotifySuccess = "SUCCESS"
+	// NotifyFailure requests a notification on failed delivery
+	NotifyFailure = "FAILURE"
+	// NotifyDelay requests a notification on delayed delivery
+	NotifyDelay = "DELAY"
+	// NotifyNever requests no notification at all, it cannot be combined with other conditions
+	NotifyNever = "NEVER"
+)
+
+// Content of failure notifications (RFC 3461)
+const (
+	// ReturnHeaders returns the headers of the message only
+	ReturnHeaders = "HDRS"
+	// ReturnFull returns the full message
+	ReturnFull = "FULL"
+)
+
 // Recipient represents an email recipient
 type Recipient struct {
 	// Email is the recipient's email address
@@ -359,6 +387,42 @@ func (b *MessageBuilder) Priority(priority Priority) *MessageBuilder {
 	return b
 }
 
+// ReadReceipt requests a read receipt to the addresses with the Disposition-Notification-To header
+func (b *MessageBuilder) ReadReceipt(addresses ...string) *MessageBuilder {
+	b.message.ReadReceipt = addresses
+	return b
+}
+
+// DeliveryNotification requests delivery status notifications for the conditions, e.g.
+// NotifySuccess and NotifyFailure, with failure notifications containing the content
+// selected by ret, e.g. ReturnHeaders. The request is sent as NOTIFY and RET parameters
+// of the SMTP envelope if the server advertises the DSN extension and dropped otherwise.
+func (b *MessageBuilder) DeliveryNotification(ret string, notify ...string) *MessageBuilder {
+	if b.Error != nil {
+		return b
+	}
+	for _, n := range notify {
+		switch n {
+		case NotifySuccess, NotifyFailure, NotifyDelay:
+		case NotifyNever:
+			if len(notify) > 1 {
+				b.Error = apperror.NewError("the NEVER notification condition cannot be combined with others")
+				return b
+			}
+		default:
+			b.Error = apperror.NewErrorf("invalid notification condition %q", n)
+			return b
+		}
+	}
+	if ret != "" && ret != ReturnHeaders && ret != ReturnFull {
+		b.Error = apperror.NewErrorf("invalid notification return %q", ret)
+		return b
+	}
+	b.message.Notify = notify
+	b.message.Return = ret
+	return b
+}
+
 // ScheduleAt sets when the message should be sent
 func (b *MessageBuilder) ScheduleAt(scheduleAt time.Time) *MessageBuilder {
 	b.message.ScheduleAt = &scheduleAt
@@ -379,6 +443,33 @@ func (b *MessageBuilder) Build() (*Message, error) {
 // NotificationHandler is a function that handles incoming SMTP messages
 type NotificationHandler func(ctx context.Context, from string, to []string, data io.Reader) error
 
+// SessionInfo describes the SMTP session in which a message was received.
+// It is named SessionInfo because Session is the interface of the SMTP protocol sessions.
+type SessionInfo struct {
+	// Helo is the hostname the client announced with HELO or EHLO
+	Helo string
+	// Extended is true if the client greeted with EHLO
+	Extended bool
+	// RemoteAddr is the address of the client including the port
+	RemoteAddr string
+	// RemoteIP is the IP address of the client
+	RemoteIP net.IP
+	// TLS is the state of the TLS connection, nil if the message was received in plain text
+	TLS *tls.ConnectionState
+	// AuthIdentity is the username the client authenticated with, empty if it did not authenticate
+	AuthIdentity string
+	// From is the envelope sender of the MAIL FROM command
+	From string
+	// To are the envelope recipients of the RCPT TO commands
+	To []string
+	// ReceivedAt is the time the message data was received
+	ReceivedAt time.Time
+}
+
+// SessionHandler is a function that handles incoming SMTP messages together with the
+// metadata of the session they were received in
+type SessionHandler func(ctx context.Context, session *SessionInfo, data io.Reader) error
+
 // Sender is the interface for sending emails
 type Sender interface {
 	// Send sends an email message
@@ -395,6 +486,8 @@ type Server interface {
 	Stop(ctx context.Context) error
 	// AddHandler adds a notification handler
 	AddHandler(handler NotificationHandler)
+	// AddSessionHandler adds a handler that receives the metadata of the SMTP session
+	AddSessionHandler(handler SessionHandler)
 	// IsRunning returns true if the server is running
 	IsRunning() bool
 }
@@ -423,6 +516,18 @@ const (
 	EncryptionTLS EncryptionMethod = "TLS"
 )
 
+// TLSPolicy controls how STARTTLS is negotiated with the SMTP server
+type TLSPolicy string
+
+const (
+	// TLSPolicyRequire fails if the server does not advertise STARTTLS
+	TLSPolicyRequire TLSPolicy = "REQUIRE"
+	// TLSPolicyPrefer uses STARTTLS if advertised and falls back to plaintext otherwise
+	TLSPolicyPrefer TLSPolicy = "PREFER"
+	// TLSPolicyNone never uses STARTTLS
+	TLSPolicyNone TLSPolicy = "NONE"
+)
+
 // Stats represents mail statistics
 type Stats struct {
 	// SentCount is the number of emails sent
@@ -434,6 +539,9 @@ type Stats struct {
 	// QueuedCount is the number of emails currently queued
 	QueuedCount int64 `json:"queued_count"`
 
+	// InFlightCount is the number of queued emails currently being sent
+	InFlightCount int64 `json:"in_flight_count"`
+
 	// ReceivedCount is the number of emails received by the server
 	ReceivedCount int64 `json:"received_count"`
 
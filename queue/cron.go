@@ -69,7 +69,14 @@ func (s *TaskScheduler) ValidateCronSpec(spec string) error {
 }
 
 // ParseCronSpec parses a cron specification
+// The specification may be prefixed with a time zone like "TZ=Europe/Berlin 0 9 * * *",
+// the zone is validated but not part of the returned expression.
 func (s *TaskScheduler) ParseCronSpec(cronSpec string) (*CronExpression, error) {
+	_, cronSpec, err := cronLocation(cronSpec)
+	if err != nil {
+		return nil, err
+	}
+
 	if predefined, exists := Presets[cronSpec]; exists {
 		return s.ParseCronSpec(predefined)
 	}
@@ -81,7 +88,6 @@ func (s *TaskScheduler) ParseCronSpec(cronSpec string) (*CronExpression, error)
 	}
 
 	expr := &CronExpression{}
-	var err error
 	fieldOffset := 0
 
 	if len(fields) == 6 {
@@ -305,6 +311,14 @@ func (s *TaskScheduler) parseSingleField(field string, low, high int, cronField
 	return cronField, nil
 }
 
+// NextCronRun returns the next time after the given time matching the cron specification.
+// The specification is evaluated in the location of after unless it is prefixed with a
+// time zone like "TZ=Europe/Berlin 0 9 * * *". Times skipped by a daylight saving time
+// change are moved forward by the length of the gap, times repeated by it match once.
+func (s *TaskScheduler) NextCronRun(cronSpec string, after time.Time) (time.Time, error) {
+	return s.calculateNextCronRun(cronSpec, after)
+}
+
 // calculateNextCronRunOptimized efficiently calculates the next run time
 //
 // Algorithm Overview:
@@ -317,11 +331,39 @@ func (s *TaskScheduler) parseSingleField(field string, low, high int, cronField
 // Time Complexity: O(Y*M*D*H*M*S) where each factor represents the number of
 // valid values in that field, significantly better than brute-force O(total_time_units)
 func (s *TaskScheduler) calculateNextCronRun(cronSpec string, after time.Time) (time.Time, error) {
+	loc, cronSpec, err := cronLocation(cronSpec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if loc != nil {
+		after = after.In(loc)
+	}
+
 	expr, err := s.ParseCronSpec(cronSpec)
 	if err != nil {
 		return time.Time{}, err
 	}
 
+	// Wall clock times repeated when the clock is set back resolve to a single instant,
+	// which may lie before after. They are skipped, so the task runs once per wall clock time.
+	from := after
+	for {
+		next, err := s.nextCronRun(expr, from)
+		if err != nil || next.After(after) {
+			return next, err
+		}
+		if expr.Second != nil {
+			from = from.Add(time.Second)
+			continue
+		}
+		from = from.Add(time.Minute)
+	}
+}
+
+// nextCronRun calculates the next wall clock time after the given time matching the expression
+// The time is resolved in the location of after
+func (s *TaskScheduler) nextCronRun(expr *CronExpression, after time.Time) (time.Time, error) {
+
 	// Start with the time after the given time
 	var t time.Time
 	if expr.Second != nil {
@@ -520,6 +562,23 @@ func (s *TaskScheduler) calculateNextCronRun(cronSpec string, after time.Time) (
 	return time.Time{}, apperror.NewError("could not find next run time within reasonable limits")
 }
 
+// cronLocation splits the time zone prefix "TZ=<zone>" or "CRON_TZ=<zone>" from a cron specification
+// The location is nil if the specification has no prefix
+func cronLocation(cronSpec string) (*time.Location, string, error) {
+	cronSpec = strings.TrimSpace(cronSpec)
+	if !strings.HasPrefix(cronSpec, "TZ=") && !strings.HasPrefix(cronSpec, "CRON_TZ=") {
+		return nil, cronSpec, nil
+	}
+
+	zone, spec, _ := strings.Cut(cronSpec, " ")
+	_, zone, _ = strings.Cut(zone, "=")
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, "", apperror.NewErrorf("invalid time zone %q", zone).AddError(err)
+	}
+	return loc, strings.TrimSpace(spec), nil
+}
+
 // fieldMatches checks if a value matches a cron field
 func (s *TaskScheduler) fieldMatches(field CronField, value int) bool {
 	for _, v := range field.Values {
@@ -1,7 +1,9 @@
 package queue_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/valentin-kaiser/go-core/queue"
 )
@@ -214,3 +216,70 @@ func BenchmarkParseCronSpec(b *testing.B) {
 		}
 	}
 }
+
+func TestNextCronRunTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	scheduler := queue.NewTaskScheduler()
+
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		// Clocks in Berlin jump from 02:00 CET to 03:00 CEST on 2025-03-30
+		{"wall clock time across spring forward", "0 9 * * *", time.Date(2025, 3, 29, 10, 0, 0, 0, berlin), time.Date(2025, 3, 30, 7, 0, 0, 0, time.UTC)},
+		{"time skipped by spring forward", "30 2 * * *", time.Date(2025, 3, 30, 1, 0, 0, 0, berlin), time.Date(2025, 3, 30, 1, 30, 0, 0, time.UTC)},
+		{"hourly across spring forward", "0 * * * *", time.Date(2025, 3, 30, 1, 30, 0, 0, berlin), time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC)},
+		{"zone prefix overrides location of after", "TZ=Europe/Berlin 0 9 * * *", time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 30, 7, 0, 0, 0, time.UTC)},
+		{"CRON_TZ prefix with preset", "CRON_TZ=Europe/Berlin @daily", time.Date(2025, 3, 29, 12, 0, 0, 0, time.UTC), time.Date(2025, 3, 29, 23, 0, 0, 0, time.UTC)},
+		// Clocks in Berlin fall back from 03:00 CEST to 02:00 CET on 2025-10-26
+		{"time repeated by fall back runs once", "30 2 * * *", time.Date(2025, 10, 26, 1, 30, 0, 0, time.UTC).In(berlin), time.Date(2025, 10, 27, 1, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scheduler.NextCronRun(tt.spec, tt.after)
+			if err != nil {
+				t.Fatalf("NextCronRun() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("NextCronRun() = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+
+	_, err = scheduler.NextCronRun("TZ=Mars/Olympus 0 9 * * *", time.Now())
+	if err == nil {
+		t.Error("NextCronRun() should fail for an unknown time zone")
+	}
+	err = scheduler.ValidateCronSpec("TZ=Europe/Berlin */5 * * * *")
+	if err != nil {
+		t.Errorf("ValidateCronSpec() error = %v for a spec with time zone", err)
+	}
+}
+
+func TestTaskLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	scheduler := queue.NewTaskScheduler()
+	err = scheduler.RegisterCronTaskWithOptions("tokyo-morning", "0 9 * * *", func(_ context.Context) error { return nil }, queue.TaskOptions{Location: tokyo})
+	if err != nil {
+		t.Fatalf("RegisterCronTaskWithOptions() error = %v", err)
+	}
+
+	task, err := scheduler.GetTask("tokyo-morning")
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+	next := task.NextRun.In(tokyo)
+	if next.Hour() != 9 || next.Minute() != 0 || task.Location != tokyo {
+		t.Errorf("Expected next run at 09:00 in Tokyo, got %v", next)
+	}
+}
@@ -0,0 +1,347 @@
+package queue
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"gopkg.in/yaml.v2"
+)
+
+// TaskDefinition is the schedule and the options of a task without its function,
+// as written by TaskScheduler.Export and read by TaskScheduler.Import
+type TaskDefinition struct {
+	// Name identifies the task and binds it to its function, see TaskScheduler.Bind
+	Name string `yaml:"name" json:"name"`
+	// Type is the type of the schedule, "cron" or "interval"
+	Type string `yaml:"type" json:"type"`
+	// Cron is the cron specification of cron tasks
+	Cron string `yaml:"cron,omitempty" json:"cron,omitempty"`
+	// Interval is the interval of interval tasks, e.g. "5m"
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Location is the time zone the cron specification is evaluated in, e.g. "Europe/Berlin"
+	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+	// MaxRetries is the maximum number of retries of a failed run
+	MaxRetries int `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	// RetryDelay is the delay between retries, e.g. "5s"
+	RetryDelay string `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"`
+	// Timeout is the maximum duration of a run, e.g. "5m"
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// MinInterval is the minimum time between the starts of two scheduled runs, e.g. "1m"
+	MinInterval string `yaml:"min_interval,omitempty" json:"min_interval,omitempty"`
+	// Concurrent allows runs of the task to overlap
+	Concurrent bool `yaml:"concurrent,omitempty" json:"concurrent,omitempty"`
+	// Quiet logs only the first failure of consecutive failures
+	Quiet bool `yaml:"quiet,omitempty" json:"quiet,omitempty"`
+	// Priority orders the dispatch of due tasks, higher priorities first
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+	// DependsOn are the names of the tasks the task waits for, see TaskScheduler.AddDependency
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// Disabled registers the task without scheduling it
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// schedule is the document written by Export and read by Import
+type schedule struct {
+	Tasks []TaskDefinition `yaml:"tasks" json:"tasks"`
+}
+
+// Export returns the definitions of all registered tasks as YAML, ordered by name.
+// The definitions contain the schedule and the options of the tasks but neither their
+// functions nor their state, so they can be reviewed, versioned and read with Import.
+//
+// Example output:
+//
+//	tasks:
+//	- name: cleanup
+//	  type: cron
+//	  cron: 0 0 3 * * *
+//	  location: Europe/Berlin
+//	  retry_delay: 5s
+//	  timeout: 5m0s
+func (s *TaskScheduler) Export() ([]byte, error) {
+	s.tasksMutex.RLock()
+	definitions := make([]TaskDefinition, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		task.mutex.RLock()
+		definition := TaskDefinition{
+			Name:       task.Name,
+			Type:       task.Type.String(),
+			Cron:       task.CronSpec,
+			MaxRetries: task.MaxRetries,
+			RetryDelay: task.RetryDelay.String(),
+			Timeout:    task.Timeout.String(),
+			Concurrent: task.AllowConcurrent,
+			Quiet:      task.Quiet,
+			Priority:   task.Priority,
+			DependsOn:  append([]string(nil), task.DependsOn...),
+			Disabled:   !task.Enabled,
+		}
+		if task.Type == TaskTypeInterval {
+			definition.Interval = task.Interval.String()
+		}
+		if task.Location != nil {
+			definition.Location = task.Location.String()
+		}
+		if task.MinInterval > 0 {
+			definition.MinInterval = task.MinInterval.String()
+		}
+		task.mutex.RUnlock()
+		definitions = append(definitions, definition)
+	}
+	s.tasksMutex.RUnlock()
+
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+
+	data, err := yaml.Marshal(schedule{Tasks: definitions})
+	if err != nil {
+		return nil, apperror.NewError("failed to marshal task definitions").AddError(err)
+	}
+	return data, nil
+}
+
+// Import registers the tasks defined in data, which is YAML or JSON as written by Export.
+// Tasks that are already registered are rescheduled with the definition and keep their
+// function and statistics. New tasks are registered as placeholders without a function,
+// they are not run until a function is bound to them with Bind or RegisterOrReschedule*, e.g. at boot.
+// All definitions, their dependencies on the merged schedule and the running state of rescheduled tasks
+// are checked before any task is registered or rescheduled, all problems are returned together and a
+// rejected import leaves the scheduler unchanged.
+//
+// Example:
+//
+//	err := scheduler.Import(data)
+//	if err != nil {
+//		return err
+//	}
+//	err = scheduler.Bind("cleanup", cleanup)
+func (s *TaskScheduler) Import(data []byte) error {
+	var doc schedule
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return apperror.NewError("failed to parse task definitions").AddError(err)
+	}
+
+	var errs []error
+	tasks := make([]*Task, 0, len(doc.Tasks))
+	names := make(map[string]bool, len(doc.Tasks))
+	for _, definition := range doc.Tasks {
+		task, err := s.taskFromDefinition(definition)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if names[task.Name] {
+			errs = append(errs, apperror.NewError(fmt.Sprintf("task '%s' is defined more than once", task.Name)))
+			continue
+		}
+		names[task.Name] = true
+		tasks = append(tasks, task)
+	}
+
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	// The dependencies of the merged schedule, imported definitions replace the dependencies of registered tasks
+	graph := make(map[string][]string, len(s.tasks)+len(doc.Tasks))
+	for name, task := range s.tasks {
+		task.mutex.RLock()
+		graph[name] = append([]string(nil), task.DependsOn...)
+		task.mutex.RUnlock()
+	}
+	for _, definition := range doc.Tasks {
+		graph[definition.Name] = uniqueDependencies(definition.DependsOn)
+	}
+
+	for _, definition := range doc.Tasks {
+		for _, dependency := range graph[definition.Name] {
+			if _, exists := s.tasks[dependency]; !exists && !names[dependency] {
+				errs = append(errs, apperror.NewError(fmt.Sprintf("dependency '%s' of task '%s' not found", dependency, definition.Name)))
+				continue
+			}
+			if dependency == definition.Name {
+				errs = append(errs, apperror.NewError(fmt.Sprintf("task '%s' cannot depend on itself", definition.Name)))
+				continue
+			}
+			if reaches(graph, dependency, definition.Name, make(map[string]bool)) {
+				errs = append(errs, apperror.NewError(fmt.Sprintf("dependency of task '%s' on '%s' would create a cycle", definition.Name, dependency)))
+			}
+		}
+	}
+
+	for _, task := range tasks {
+		existing, exists := s.tasks[task.Name]
+		if !exists {
+			continue
+		}
+		existing.mutex.RLock()
+		running := existing.IsRunning
+		existing.mutex.RUnlock()
+		if running {
+			errs = append(errs, apperror.NewError(fmt.Sprintf("cannot reschedule running task '%s'", task.Name)))
+		}
+	}
+	if len(errs) > 0 {
+		return apperror.NewError("invalid task definitions").AddErrors(errs)
+	}
+
+	for _, task := range tasks {
+		task.DependsOn = graph[task.Name]
+		existing, exists := s.tasks[task.Name]
+		if !exists {
+			s.tasks[task.Name] = task
+			continue
+		}
+
+		existing.mutex.Lock()
+		existing.Type = task.Type
+		existing.CronSpec = task.CronSpec
+		existing.Interval = task.Interval
+		existing.Location = task.Location
+		existing.MaxRetries = task.MaxRetries
+		existing.RetryDelay = task.RetryDelay
+		existing.Timeout = task.Timeout
+		existing.MinInterval = task.MinInterval
+		existing.AllowConcurrent = task.AllowConcurrent
+		existing.Quiet = task.Quiet
+		existing.Priority = task.Priority
+		existing.Enabled = task.Enabled
+		existing.DependsOn = task.DependsOn
+		existing.NextRun = task.NextRun
+		existing.UpdatedAt = time.Now()
+		existing.mutex.Unlock()
+	}
+
+	logger.Debug().
+		Field("tasks", len(tasks)).
+		Msg("task definitions imported")
+
+	return nil
+}
+
+// uniqueDependencies returns the dependencies without duplicates in their original order
+func uniqueDependencies(dependencies []string) []string {
+	var unique []string
+	seen := make(map[string]bool, len(dependencies))
+	for _, dependency := range dependencies {
+		if seen[dependency] {
+			continue
+		}
+		seen[dependency] = true
+		unique = append(unique, dependency)
+	}
+	return unique
+}
+
+// reaches reports whether the task directly or transitively depends on the target in the dependency graph
+func reaches(graph map[string][]string, task, target string, visited map[string]bool) bool {
+	if task == target {
+		return true
+	}
+	if visited[task] {
+		return false
+	}
+	visited[task] = true
+
+	for _, dependency := range graph[task] {
+		if reaches(graph, dependency, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// taskFromDefinition creates a task without function from the definition
+func (s *TaskScheduler) taskFromDefinition(definition TaskDefinition) (*Task, error) {
+	if definition.Name == "" {
+		return nil, apperror.NewError("task name cannot be empty")
+	}
+
+	task := &Task{
+		ID:              generateTaskID(),
+		Name:            definition.Name,
+		MaxRetries:      definition.MaxRetries,
+		RetryDelay:      s.retryDelay,
+		Timeout:         s.defaultTimeout,
+		AllowConcurrent: definition.Concurrent,
+		Quiet:           definition.Quiet,
+		Priority:        definition.Priority,
+		Enabled:         !definition.Disabled,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	var err error
+	if definition.RetryDelay != "" {
+		task.RetryDelay, err = time.ParseDuration(definition.RetryDelay)
+		if err != nil {
+			return nil, apperror.NewError(fmt.Sprintf("invalid retry delay of task '%s'", definition.Name)).AddError(err)
+		}
+	}
+	if definition.Timeout != "" {
+		task.Timeout, err = time.ParseDuration(definition.Timeout)
+		if err != nil {
+			return nil, apperror.NewError(fmt.Sprintf("invalid timeout of task '%s'", definition.Name)).AddError(err)
+		}
+	}
+	if definition.MinInterval != "" {
+		task.MinInterval, err = time.ParseDuration(definition.MinInterval)
+		if err != nil || task.MinInterval < 0 {
+			return nil, apperror.NewError(fmt.Sprintf("invalid minimum interval %q of task '%s', it must be a positive duration", definition.MinInterval, definition.Name))
+		}
+	}
+	if definition.Location != "" {
+		task.Location, err = time.LoadLocation(definition.Location)
+		if err != nil {
+			return nil, apperror.NewError(fmt.Sprintf("invalid location of task '%s'", definition.Name)).AddError(err)
+		}
+	}
+
+	switch definition.Type {
+	case TaskTypeCron.String():
+		err = s.ValidateCronSpec(definition.Cron)
+		if err != nil {
+			return nil, apperror.NewError(fmt.Sprintf("invalid cron specification of task '%s'", definition.Name)).AddError(err)
+		}
+		task.Type = TaskTypeCron
+		task.CronSpec = definition.Cron
+		task.NextRun, err = s.calculateNextCronRun(task.CronSpec, now(task.Location))
+		if err != nil {
+			return nil, apperror.NewError(fmt.Sprintf("failed to calculate next run time of task '%s'", definition.Name)).AddError(err)
+		}
+	case TaskTypeInterval.String():
+		task.Interval, err = time.ParseDuration(definition.Interval)
+		if err != nil || task.Interval <= 0 {
+			return nil, apperror.NewError(fmt.Sprintf("invalid interval %q of task '%s', it must be a positive duration", definition.Interval, definition.Name))
+		}
+		task.Type = TaskTypeInterval
+		task.NextRun = time.Now().Add(task.Interval)
+	default:
+		return nil, apperror.NewError(fmt.Sprintf("invalid type %q of task '%s', it must be cron or interval", definition.Type, definition.Name))
+	}
+
+	return task, nil
+}
+
+// Bind binds the function to the task, e.g. to a placeholder registered by Import
+func (s *TaskScheduler) Bind(name string, fn TaskFunc) error {
+	if fn == nil {
+		return apperror.NewError("task function cannot be nil")
+	}
+
+	s.tasksMutex.RLock()
+	task, exists := s.tasks[name]
+	s.tasksMutex.RUnlock()
+	if !exists {
+		return apperror.NewError(fmt.Sprintf("task '%s' not found", name))
+	}
+
+	task.mutex.Lock()
+	task.Function = fn
+	task.UpdatedAt = time.Now()
+	task.mutex.Unlock()
+	return nil
+}
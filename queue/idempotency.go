@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyStore records the occurrences of tasks that completed, so that they are not run
+// again, e.g. after a crash or a restart of the scheduler. Completed reports whether the key was
+// recorded, Complete records the key for the given time to live.
+// The store has to outlive the scheduler, e.g. Redis or a database table.
+type IdempotencyStore interface {
+	Completed(ctx context.Context, key string) (bool, error)
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// idempotencyContextKey is the context key of the idempotency key of a run
+type idempotencyContextKey struct{}
+
+// WithIdempotencyStore enables idempotency keys. Tasks declare the key of an occurrence with
+// TaskOptions.IdempotencyKey, which is derived from the time the occurrence was scheduled at,
+// e.g. the day of a nightly billing run. Before an occurrence is dispatched the scheduler looks
+// the key up in the store and skips the occurrence if it was completed before. After a
+// successful run the key is recorded for the given time to live, which has to be longer than
+// the period the key identifies. Failed runs are not recorded and are run again.
+//
+// The scheduler provides at-least-once execution, not exactly-once: the key is recorded after
+// the function returned, so if the process crashes in between, or the store fails to record the
+// key, the occurrence runs again. Recording the key before the run would turn this into
+// at-most-once, where a crash loses the occurrence instead. Exactly-once effects need the
+// cooperation of the system the task changes: the function can read the key with
+// IdempotencyKey and pass it on, e.g. as idempotency key of a payment provider or as unique
+// constraint in the same database transaction as its work.
+//
+// The lookup and the record are not atomic, so nodes running the same scheduler can both run an
+// occurrence that is not yet recorded. Combine the store with WithLocker to prevent that.
+// If the store fails to look up the key, the occurrence is retried on the next check instead of being run.
+//
+// Example:
+//
+//	scheduler := queue.NewTaskScheduler().WithIdempotencyStore(store, 48*time.Hour)
+//	err := scheduler.RegisterCronTaskWithOptions("billing", "0 0 2 * * *", bill, queue.TaskOptions{
+//		IdempotencyKey: func(occurrence time.Time) string {
+//			return "billing:" + occurrence.Format("2006-01-02")
+//		},
+//	})
+func (s *TaskScheduler) WithIdempotencyStore(store IdempotencyStore, ttl time.Duration) *TaskScheduler {
+	s.idempotencyStore = store
+	if ttl > 0 {
+		s.idempotencyTTL = ttl
+	}
+	return s
+}
+
+// IdempotencyKey returns the idempotency key of the occurrence a task function runs for.
+// It returns false if the task does not declare idempotency keys or no store is configured.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyContextKey{}).(string)
+	return key, ok
+}
+
+// idempotencyKey returns the idempotency key of the occurrence of the task scheduled at nextRun.
+// It returns false if the occurrence was completed before and must not run.
+func (s *TaskScheduler) idempotencyKey(ctx context.Context, task *Task, nextRun time.Time) (string, bool) {
+	task.mutex.RLock()
+	keyFunc := task.IdempotencyKey
+	task.mutex.RUnlock()
+	if s.idempotencyStore == nil || keyFunc == nil {
+		return "", true
+	}
+
+	key := keyFunc(nextRun)
+	if key == "" {
+		return "", true
+	}
+
+	completed, err := s.idempotencyStore.Completed(ctx, key)
+	if err != nil {
+		logger.Error().Err(err).Field("task_name", task.Name).Field("idempotency_key", key).Msg("failed to look up idempotency key")
+		return "", false
+	}
+
+	if completed {
+		logger.Debug().Field("task_name", task.Name).Field("idempotency_key", key).Msg("task occurrence already completed")
+		err = s.updateNextRun(task)
+		if err != nil {
+			logger.Error().Err(err).Field("task_name", task.Name).Msg("failed to update next run time")
+		}
+		return "", false
+	}
+
+	return key, true
+}
+
+// complete records the idempotency key of a successful run
+func (s *TaskScheduler) complete(ctx context.Context, task *Task, key string) {
+	if key == "" {
+		return
+	}
+
+	// The key is recorded even if the scheduler is stopping, the run already completed
+	err := s.idempotencyStore.Complete(context.WithoutCancel(ctx), key, s.idempotencyTTL)
+	if err != nil {
+		logger.Error().Err(err).Field("task_name", task.Name).Field("idempotency_key", key).Msg("failed to record idempotency key, the occurrence may run again")
+	}
+}
@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Locker coordinates task execution between multiple nodes running the same TaskScheduler.
+// Acquire tries to take a lease on the key that expires after the lease duration.
+// If another node holds the lease, acquired is false.
+type Locker interface {
+	Acquire(ctx context.Context, key string, lease time.Duration) (release func() error, acquired bool, err error)
+}
+
+// LockerFunc adapts a function to the Locker interface.
+// The distributed lock of the cache package can be plugged in directly:
+//
+//	redisCache := cache.NewRedisCache(cache.RedisConfig{Addr: "localhost:6379"})
+//	scheduler := queue.NewTaskScheduler().
+//		WithLocker(queue.LockerFunc(redisCache.Lock), time.Minute)
+type LockerFunc func(ctx context.Context, key string, lease time.Duration) (release func() error, acquired bool, err error)
+
+// Acquire calls f(ctx, key, lease)
+func (f LockerFunc) Acquire(ctx context.Context, key string, lease time.Duration) (func() error, bool, error) {
+	return f(ctx, key, lease)
+}
+
+// WithLocker enables distributed scheduling. Before a task is dispatched the scheduler
+// acquires a lease from the locker, so that only the node holding it runs the occurrence.
+// Nodes that do not get the lease skip the occurrence and schedule the next one.
+//
+// Cron tasks lock every occurrence separately with the given lease, which has to be longer
+// than the clock skew between the nodes plus the check interval. The lease is not released
+// after the run, otherwise a node whose clock lags behind would run the occurrence again.
+// Interval tasks are not aligned between nodes, they lock the task name for one interval,
+// so a task runs roughly once per interval cluster-wide.
+//
+// The lock is only as precise as the clocks of the nodes: if they drift apart by more than
+// the lease, an occurrence can run twice. Run NTP on all nodes and keep tasks idempotent.
+// If the locker fails, the occurrence is retried on the next check instead of being run.
+func (s *TaskScheduler) WithLocker(locker Locker, lease time.Duration) *TaskScheduler {
+	s.locker = locker
+	if lease > 0 {
+		s.lockLease = lease
+	}
+	return s
+}
+
+// acquire takes the lease for the occurrence of the task scheduled at nextRun
+// It returns true if the task may run on this node
+func (s *TaskScheduler) acquire(ctx context.Context, task *Task, nextRun time.Time) bool {
+	if s.locker == nil {
+		return true
+	}
+
+	key := "task:" + task.Name
+	lease := s.lockLease
+	switch task.Type {
+	case TaskTypeCron:
+		key += ":" + nextRun.UTC().Format(time.RFC3339)
+	case TaskTypeInterval:
+		lease = task.Interval
+	}
+
+	_, acquired, err := s.locker.Acquire(ctx, key, lease)
+	if err != nil {
+		logger.Error().Err(err).Field("task_name", task.Name).Msg("failed to acquire task lock")
+		return false
+	}
+
+	if !acquired {
+		logger.Trace().Field("task_name", task.Name).Msg("task occurrence runs on another node")
+		err = s.updateNextRun(task)
+		if err != nil {
+			logger.Error().Err(err).Field("task_name", task.Name).Msg("failed to update next run time")
+		}
+	}
+
+	return acquired
+}
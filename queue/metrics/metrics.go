@@ -0,0 +1,113 @@
+// Package metrics exports the state of a queue.TaskScheduler as Prometheus metrics.
+// It is a separate package so that the queue package does not depend on the
+// Prometheus client, only applications importing it do.
+//
+// Exported metrics, labeled with the name of the task where noted:
+//   - queue_scheduler_tasks{state}: number of registered tasks by state, "enabled" or "disabled"
+//   - queue_scheduler_running_tasks: number of tasks currently running
+//   - queue_task_runs_total{task}: successful runs of the task
+//   - queue_task_errors_total{task}: failed runs of the task after all retries
+//   - queue_task_consecutive_failures{task}: failed runs of the task since the last successful run
+//   - queue_task_running{task}: 1 if the task is running, 0 otherwise
+//   - queue_task_last_run_timestamp_seconds{task}: Unix time the last run of the task finished, 0 if it never ran
+//   - queue_task_next_run_lag_seconds{task}: seconds the next run of an enabled task is overdue,
+//     0 if it is not due yet; a growing lag indicates a stalled scheduler or unmet dependencies
+//
+// Example:
+//
+//	scheduler := queue.NewTaskScheduler()
+//	prometheus.MustRegister(metrics.Collector(scheduler))
+//	http.Handle("/metrics", promhttp.Handler())
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valentin-kaiser/go-core/queue"
+)
+
+// collector reads the metrics from the tasks of the scheduler on every scrape
+type collector struct {
+	scheduler *queue.TaskScheduler
+
+	tasks               *prometheus.Desc
+	running             *prometheus.Desc
+	runs                *prometheus.Desc
+	errors              *prometheus.Desc
+	consecutiveFailures *prometheus.Desc
+	taskRunning         *prometheus.Desc
+	lastRun             *prometheus.Desc
+	lag                 *prometheus.Desc
+}
+
+// Collector returns a prometheus.Collector exporting the metrics of the scheduler.
+// The metrics are read from a snapshot of the tasks when they are collected, so the
+// collector adds no overhead to task execution. Use prometheus.WrapRegistererWith to
+// add labels, e.g. to tell the schedulers of an application apart.
+func Collector(scheduler *queue.TaskScheduler) prometheus.Collector {
+	return &collector{
+		scheduler:           scheduler,
+		tasks:               prometheus.NewDesc("queue_scheduler_tasks", "Number of registered tasks by state.", []string{"state"}, nil),
+		running:             prometheus.NewDesc("queue_scheduler_running_tasks", "Number of tasks currently running.", nil, nil),
+		runs:                prometheus.NewDesc("queue_task_runs_total", "Successful runs of the task.", []string{"task"}, nil),
+		errors:              prometheus.NewDesc("queue_task_errors_total", "Failed runs of the task after all retries.", []string{"task"}, nil),
+		consecutiveFailures: prometheus.NewDesc("queue_task_consecutive_failures", "Failed runs of the task since the last successful run.", []string{"task"}, nil),
+		taskRunning:         prometheus.NewDesc("queue_task_running", "Whether the task is running.", []string{"task"}, nil),
+		lastRun:             prometheus.NewDesc("queue_task_last_run_timestamp_seconds", "Unix time the last run of the task finished.", []string{"task"}, nil),
+		lag:                 prometheus.NewDesc("queue_task_next_run_lag_seconds", "Seconds the next run of the enabled task is overdue.", []string{"task"}, nil),
+	}
+}
+
+// Describe sends the descriptors of all metrics of the collector
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.running
+	ch <- c.runs
+	ch <- c.errors
+	ch <- c.consecutiveFailures
+	ch <- c.taskRunning
+	ch <- c.lastRun
+	ch <- c.lag
+}
+
+// Collect sends the current metrics of the scheduler and its tasks
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	enabled, disabled, running := 0, 0, 0
+	for name, task := range c.scheduler.GetTasks() {
+		if task.Enabled {
+			enabled++
+		}
+		if !task.Enabled {
+			disabled++
+		}
+
+		isRunning := 0.0
+		if task.IsRunning {
+			running++
+			isRunning = 1
+		}
+
+		lastRun := 0.0
+		if !task.LastRun.IsZero() {
+			lastRun = float64(task.LastRun.UnixNano()) / float64(time.Second)
+		}
+
+		lag := 0.0
+		if task.Enabled && now.After(task.NextRun) {
+			lag = now.Sub(task.NextRun).Seconds()
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.runs, prometheus.CounterValue, float64(task.RunCount), name)
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(task.ErrorCount), name)
+		ch <- prometheus.MustNewConstMetric(c.consecutiveFailures, prometheus.GaugeValue, float64(task.ConsecutiveFailures), name)
+		ch <- prometheus.MustNewConstMetric(c.taskRunning, prometheus.GaugeValue, isRunning, name)
+		ch <- prometheus.MustNewConstMetric(c.lastRun, prometheus.GaugeValue, lastRun, name)
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, lag, name)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(enabled), "enabled")
+	ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(disabled), "disabled")
+	ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(running))
+}
@@ -0,0 +1,100 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valentin-kaiser/go-core/queue"
+	"github.com/valentin-kaiser/go-core/queue/metrics"
+)
+
+func TestCollector(t *testing.T) {
+	scheduler := queue.NewTaskScheduler()
+
+	err := scheduler.RegisterIntervalTask("ok", time.Hour, func(_ context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+	err = scheduler.RegisterIntervalTask("failing", time.Hour, func(_ context.Context) error { return errors.New("failed") })
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+	err = scheduler.RegisterIntervalTask("disabled", time.Hour, func(_ context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+	err = scheduler.DisableTask("disabled")
+	if err != nil {
+		t.Fatalf("failed to disable task: %v", err)
+	}
+
+	err = scheduler.RunNow(context.Background(), "ok")
+	if err != nil {
+		t.Fatalf("failed to run task: %v", err)
+	}
+	err = scheduler.RunNow(context.Background(), "failing")
+	if err == nil {
+		t.Fatal("expected the failing task to fail")
+	}
+
+	registry := prometheus.NewRegistry()
+	err = registry.Register(metrics.Collector(scheduler))
+	if err != nil {
+		t.Fatalf("failed to register collector: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range m.GetLabel() {
+				key += "/" + label.GetValue()
+			}
+			switch {
+			case m.GetCounter() != nil:
+				values[key] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[key] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	expected := map[string]float64{
+		"queue_scheduler_tasks/enabled":                  2,
+		"queue_scheduler_tasks/disabled":                 1,
+		"queue_scheduler_running_tasks":                  0,
+		"queue_task_runs_total/ok":                       1,
+		"queue_task_errors_total/ok":                     0,
+		"queue_task_runs_total/failing":                  0,
+		"queue_task_errors_total/failing":                1,
+		"queue_task_consecutive_failures/failing":        1,
+		"queue_task_running/ok":                          0,
+		"queue_task_next_run_lag_seconds/disabled":       0,
+		"queue_task_last_run_timestamp_seconds/disabled": 0,
+	}
+	for key, want := range expected {
+		got, ok := values[key]
+		if !ok {
+			t.Errorf("metric %s not collected", key)
+			continue
+		}
+		if got != want {
+			t.Errorf("metric %s: expected %v, got %v", key, want, got)
+		}
+	}
+
+	if values["queue_task_next_run_lag_seconds/ok"] > time.Minute.Seconds() {
+		t.Errorf("expected the next run of task ok to be due recently, got a lag of %v", values["queue_task_next_run_lag_seconds/ok"])
+	}
+	if values["queue_task_last_run_timestamp_seconds/ok"] < float64(time.Now().Add(-time.Minute).Unix()) {
+		t.Errorf("expected the last run timestamp of task ok to be recent, got %v", values["queue_task_last_run_timestamp_seconds/ok"])
+	}
+}
@@ -15,6 +15,9 @@
 //   - Cron-based scheduling (using enhanced cron expressions with optional seconds support)
 //   - Interval-based scheduling (using time.Duration)
 //   - Task registration and management
+//   - Export and import of task definitions as YAML or JSON, bound to functions by name
+//   - Distributed scheduling with a pluggable Locker so tasks run once cluster-wide
+//   - Idempotency keys recorded in a pluggable store so completed occurrences do not run again after a restart
 //   - Error recovery and retries
 //   - Context-aware execution
 //
@@ -429,6 +432,13 @@ func (m *Manager) GetStats() *Stats {
 	}
 }
 
+// IsPersistent reports whether queued jobs survive a restart of the process,
+// which is the case for all queues except the default in-memory queue
+func (m *Manager) IsPersistent() bool {
+	_, memory := m.queue.(*MemoryQueue)
+	return !memory
+}
+
 // IsRunning returns true if the manager is currently running
 func (m *Manager) IsRunning() bool {
 	return atomic.LoadInt32(&m.running) == 1
@@ -324,6 +324,17 @@ func TestMemoryQueuePriority(t *testing.T) {
 	}
 }
 
+func TestManagerIsPersistent(t *testing.T) {
+	if queue.NewManager().IsPersistent() {
+		t.Error("Expected the default in-memory queue not to be persistent")
+	}
+
+	custom := struct{ queue.Queue }{queue.NewMemoryQueue()}
+	if !queue.NewManager().WithQueue(custom).IsPersistent() {
+		t.Error("Expected a custom queue to be persistent")
+	}
+}
+
 func TestManager(t *testing.T) {
 	ctx := t.Context()
 	manager := queue.NewManager().
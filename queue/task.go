@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -41,41 +42,53 @@ func (t TaskType) String() string {
 
 // Task represents a scheduled task
 type Task struct {
-	ID                  string        `json:"id"`
-	Name                string        `json:"name"`
-	Type                TaskType      `json:"type"`
-	CronSpec            string        `json:"cron_spec,omitempty"`
-	Interval            time.Duration `json:"interval,omitempty"`
-	Function            TaskFunc      `json:"-"`
-	NextRun             time.Time     `json:"next_run"`
-	LastRun             time.Time     `json:"last_run"`
-	RunCount            int64         `json:"run_count"`
-	ErrorCount          int64         `json:"error_count"`
-	ConsecutiveFailures int64         `json:"consecutive_failures"`
-	LastError           string        `json:"last_error,omitempty"`
-	IsRunning           bool          `json:"is_running"`
-	Quiet               bool          `json:"log_on_first_failure_only"`
-	AllowConcurrent     bool          `json:"allow_concurrent"`
-	MaxRetries          int           `json:"max_retries"`
-	RetryDelay          time.Duration `json:"retry_delay"`
-	Timeout             time.Duration `json:"timeout"`
-	Enabled             bool          `json:"enabled"`
-	CreatedAt           time.Time     `json:"created_at"`
-	UpdatedAt           time.Time     `json:"updated_at"`
-	mutex               sync.RWMutex  `json:"-"`
+	ID                  string                 `json:"id"`
+	Name                string                 `json:"name"`
+	Type                TaskType               `json:"type"`
+	CronSpec            string                 `json:"cron_spec,omitempty"`
+	Interval            time.Duration          `json:"interval,omitempty"`
+	Function            TaskFunc               `json:"-"`
+	NextRun             time.Time              `json:"next_run"`
+	LastRun             time.Time              `json:"last_run"`
+	LastStart           time.Time              `json:"last_start"`
+	RunCount            int64                  `json:"run_count"`
+	ErrorCount          int64                  `json:"error_count"`
+	ConsecutiveFailures int64                  `json:"consecutive_failures"`
+	LastError           string                 `json:"last_error,omitempty"`
+	IsRunning           bool                   `json:"is_running"`
+	Quiet               bool                   `json:"log_on_first_failure_only"`
+	Priority            int                    `json:"priority"`
+	DependsOn           []string               `json:"depends_on,omitempty"`
+	AllowConcurrent     bool                   `json:"allow_concurrent"`
+	MaxRetries          int                    `json:"max_retries"`
+	RetryDelay          time.Duration          `json:"retry_delay"`
+	Timeout             time.Duration          `json:"timeout"`
+	MinInterval         time.Duration          `json:"min_interval,omitempty"`
+	Enabled             bool                   `json:"enabled"`
+	Location            *time.Location         `json:"-"`
+	IdempotencyKey      func(time.Time) string `json:"-"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+	mutex               sync.RWMutex           `json:"-"`
+	// runs is the number of runs in progress, including concurrent runs
+	runs int
 }
 
 // TaskScheduler manages background tasks
 type TaskScheduler struct {
-	tasks          map[string]*Task
-	tasksMutex     sync.RWMutex
-	running        int32
-	shutdownChan   chan struct{}
-	workerWg       sync.WaitGroup
-	checkInterval  time.Duration
-	defaultTimeout time.Duration
-	retryDelay     time.Duration
-	cancel         context.CancelFunc
+	tasks            map[string]*Task
+	tasksMutex       sync.RWMutex
+	running          int32
+	shutdownChan     chan struct{}
+	workerWg         sync.WaitGroup
+	checkInterval    time.Duration
+	defaultTimeout   time.Duration
+	retryDelay       time.Duration
+	locker           Locker
+	lockLease        time.Duration
+	idempotencyStore IdempotencyStore
+	idempotencyTTL   time.Duration
+	cancel           context.CancelFunc
 }
 
 // NewTaskScheduler creates a new task scheduler with default settings
@@ -86,6 +99,8 @@ func NewTaskScheduler() *TaskScheduler {
 		checkInterval:  time.Second * 10,
 		defaultTimeout: time.Minute * 5,
 		retryDelay:     time.Second * 5,
+		lockLease:      time.Minute,
+		idempotencyTTL: time.Hour * 24 * 7,
 	}
 }
 
@@ -139,6 +154,18 @@ type TaskOptions struct {
 	Immediately bool
 	// Quiet specifies whether to log only the first failure in a series of consecutive failures (default is false)
 	Quiet bool
+	// Priority specifies the order in which due tasks are dispatched, higher priorities first (default is 0)
+	Priority int
+	// Location specifies the time zone the cron specification is evaluated in (default is the local time zone)
+	// A time zone prefix of the specification like "TZ=Europe/Berlin" takes precedence
+	Location *time.Location
+	// IdempotencyKey returns the key of the occurrence scheduled at the given time, occurrences with
+	// the same key run only once, see TaskScheduler.WithIdempotencyStore (default is nil, no key)
+	IdempotencyKey func(occurrence time.Time) string
+	// MinInterval specifies the minimum time between the starts of two scheduled runs, e.g. to stay
+	// within the quota of an API, regardless of the schedule. A due run is delayed until the interval
+	// since the last start has passed (default is 0, no limit)
+	MinInterval time.Duration
 }
 
 // RegisterCronTaskWithOptions registers a new cron-based task with options
@@ -183,12 +210,16 @@ func (s *TaskScheduler) RegisterCronTaskWithOptions(name, cronSpec string, fn Ta
 		RetryDelay:      retryDelay,
 		Timeout:         timeout,
 		Quiet:           options.Quiet,
+		Priority:        options.Priority,
+		Location:        options.Location,
+		IdempotencyKey:  options.IdempotencyKey,
+		MinInterval:     options.MinInterval,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		Enabled:         true,
 	}
 
-	nextRun, err := s.calculateNextCronRun(cronSpec, time.Now())
+	nextRun, err := s.calculateNextCronRun(cronSpec, now(task.Location))
 	if err != nil {
 		return apperror.NewError(fmt.Sprintf("failed to calculate next run time: %v", err))
 	}
@@ -248,6 +279,9 @@ func (s *TaskScheduler) RegisterIntervalTaskWithOptions(name string, interval ti
 		RetryDelay:      retryDelay,
 		Timeout:         timeout,
 		Quiet:           options.Quiet,
+		Priority:        options.Priority,
+		IdempotencyKey:  options.IdempotencyKey,
+		MinInterval:     options.MinInterval,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		Enabled:         true,
@@ -309,7 +343,7 @@ func (s *TaskScheduler) RegisterOrRescheduleCronTaskWithOptions(name, cronSpec s
 			return apperror.NewError(fmt.Sprintf("cannot reschedule running task '%s'", name))
 		}
 
-		nextRun, err := s.calculateNextCronRun(cronSpec, time.Now())
+		nextRun, err := s.calculateNextCronRun(cronSpec, now(options.Location))
 		if err != nil {
 			return apperror.NewError(fmt.Sprintf("failed to calculate next run time: %v", err))
 		}
@@ -334,6 +368,10 @@ func (s *TaskScheduler) RegisterOrRescheduleCronTaskWithOptions(name, cronSpec s
 		}
 		existingTask.AllowConcurrent = options.Concurrent
 		existingTask.Quiet = options.Quiet
+		existingTask.Priority = options.Priority
+		existingTask.IdempotencyKey = options.IdempotencyKey
+		existingTask.MinInterval = options.MinInterval
+		existingTask.Location = options.Location
 		nextRunForLog := existingTask.NextRun
 		existingTask.mutex.Unlock()
 
@@ -367,12 +405,16 @@ func (s *TaskScheduler) RegisterOrRescheduleCronTaskWithOptions(name, cronSpec s
 		RetryDelay:      retryDelay,
 		Timeout:         timeout,
 		Quiet:           options.Quiet,
+		Priority:        options.Priority,
+		Location:        options.Location,
+		IdempotencyKey:  options.IdempotencyKey,
+		MinInterval:     options.MinInterval,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		Enabled:         true,
 	}
 
-	nextRun, err := s.calculateNextCronRun(cronSpec, time.Now())
+	nextRun, err := s.calculateNextCronRun(cronSpec, now(task.Location))
 	if err != nil {
 		return apperror.NewError(fmt.Sprintf("failed to calculate next run time: %v", err))
 	}
@@ -436,6 +478,9 @@ func (s *TaskScheduler) RegisterOrRescheduleIntervalTaskWithOptions(name string,
 		}
 		existingTask.AllowConcurrent = options.Concurrent
 		existingTask.Quiet = options.Quiet
+		existingTask.Priority = options.Priority
+		existingTask.IdempotencyKey = options.IdempotencyKey
+		existingTask.MinInterval = options.MinInterval
 		nextRunForLog := existingTask.NextRun
 		existingTask.mutex.Unlock()
 
@@ -469,6 +514,9 @@ func (s *TaskScheduler) RegisterOrRescheduleIntervalTaskWithOptions(name string,
 		RetryDelay:      retryDelay,
 		Timeout:         timeout,
 		Quiet:           options.Quiet,
+		Priority:        options.Priority,
+		IdempotencyKey:  options.IdempotencyKey,
+		MinInterval:     options.MinInterval,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		Enabled:         true,
@@ -498,6 +546,18 @@ func (s *TaskScheduler) Start(ctx context.Context) error {
 
 	ctx, s.cancel = context.WithCancel(ctx)
 
+	s.tasksMutex.RLock()
+	for name, task := range s.tasks {
+		task.mutex.RLock()
+		if task.Function == nil {
+			logger.Warn().
+				Field("task_name", name).
+				Msg("task is not bound to a function and will not run")
+		}
+		task.mutex.RUnlock()
+	}
+	s.tasksMutex.RUnlock()
+
 	s.workerWg.Add(1)
 	go s.schedulerLoop(ctx)
 
@@ -544,7 +604,7 @@ func (s *TaskScheduler) schedulerLoop(ctx context.Context) {
 // checkAndRunTasks checks for tasks that need to be executed and runs them
 func (s *TaskScheduler) checkAndRunTasks(ctx context.Context) {
 	s.tasksMutex.RLock()
-	var tasksToRun []*Task
+	var due []dueTask
 	now := time.Now()
 
 	for _, task := range s.tasks {
@@ -553,25 +613,77 @@ func (s *TaskScheduler) checkAndRunTasks(ctx context.Context) {
 		nextRun := task.NextRun
 		isRunning := task.IsRunning
 		allowConcurrent := task.AllowConcurrent
+		priority := task.Priority
+		lastRun := task.LastRun
+		lastStart := task.LastStart
+		minInterval := task.MinInterval
+		dependsOn := task.DependsOn
+		bound := task.Function != nil
 		task.mutex.RUnlock()
 
-		// Run task if it's enabled, scheduled to run, and either not running or concurrent execution is allowed
-		if enabled && now.After(nextRun) && (!isRunning || allowConcurrent) {
-			tasksToRun = append(tasksToRun, task)
+		// Run task if it's enabled, bound to a function, scheduled to run, and either not running or concurrent execution is allowed
+		if !enabled || !bound || !now.After(nextRun) || (isRunning && !allowConcurrent) {
+			continue
+		}
+
+		// Tasks started less than their minimum interval ago stay due until it has passed
+		if minInterval > 0 && now.Before(lastStart.Add(minInterval)) {
+			continue
+		}
+
+		// Tasks with unmet dependencies stay due and are checked again in the next cycle
+		if !s.dependenciesMet(dependsOn, lastRun) {
+			continue
 		}
+		due = append(due, dueTask{task: task, nextRun: nextRun, priority: priority})
 	}
 	s.tasksMutex.RUnlock()
 
-	for _, task := range tasksToRun {
+	// Dispatch higher priorities first, then the longest overdue tasks
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].priority != due[j].priority {
+			return due[i].priority > due[j].priority
+		}
+		if !due[i].nextRun.Equal(due[j].nextRun) {
+			return due[i].nextRun.Before(due[j].nextRun)
+		}
+		return due[i].task.Name < due[j].task.Name
+	})
+
+	for _, d := range due {
+		if !s.acquire(ctx, d.task, d.nextRun) {
+			continue
+		}
+		key, run := s.idempotencyKey(ctx, d.task, d.nextRun)
+		if !run {
+			continue
+		}
 		s.workerWg.Add(1)
-		go s.runTask(ctx, task)
+		go s.runTask(ctx, d.task, key)
 	}
 }
 
-// runTask executes a single task
-func (s *TaskScheduler) runTask(ctx context.Context, task *Task) {
+// dueTask is a task that is due for execution with the state it was selected in
+type dueTask struct {
+	task     *Task
+	nextRun  time.Time
+	priority int
+}
+
+// runTask executes a single task, the idempotency key of the occurrence is recorded after a successful run
+func (s *TaskScheduler) runTask(ctx context.Context, task *Task, key string) {
 	defer s.workerWg.Done()
 
+	task.mutex.Lock()
+	task.LastStart = time.Now()
+	task.runs++
+	task.mutex.Unlock()
+	defer func() {
+		task.mutex.Lock()
+		task.runs--
+		task.mutex.Unlock()
+	}()
+
 	// For concurrent tasks, update next run time immediately so next instance can be scheduled
 	// For non-concurrent tasks, set running state to prevent overlapping executions
 	if task.AllowConcurrent {
@@ -599,6 +711,9 @@ func (s *TaskScheduler) runTask(ctx context.Context, task *Task) {
 
 	taskCtx, cancel := context.WithTimeout(ctx, task.Timeout)
 	defer cancel()
+	if key != "" {
+		taskCtx = context.WithValue(taskCtx, idempotencyContextKey{}, key)
+	}
 
 	var lastError error
 	for attempt := 0; attempt <= task.MaxRetries; attempt++ {
@@ -617,6 +732,8 @@ func (s *TaskScheduler) runTask(ctx context.Context, task *Task) {
 		err := task.Function(taskCtx)
 
 		if err == nil {
+			s.complete(ctx, task, key)
+
 			task.mutex.Lock()
 			// Only mark as not running for non-concurrent tasks
 			if !task.AllowConcurrent {
@@ -729,13 +846,14 @@ func (s *TaskScheduler) runTask(ctx context.Context, task *Task) {
 	}
 }
 
+// updateNextRun schedules the next run of the task, not earlier than its minimum interval after the last start
 func (s *TaskScheduler) updateNextRun(task *Task) error {
 	task.mutex.Lock()
 	defer task.mutex.Unlock()
 
 	switch task.Type {
 	case TaskTypeCron:
-		nextRun, err := s.calculateNextCronRun(task.CronSpec, time.Now())
+		nextRun, err := s.calculateNextCronRun(task.CronSpec, now(task.Location))
 		if err != nil {
 			return fmt.Errorf("failed to calculate next run time: %w", err)
 		}
@@ -743,9 +861,22 @@ func (s *TaskScheduler) updateNextRun(task *Task) error {
 	case TaskTypeInterval:
 		task.NextRun = time.Now().Add(task.Interval)
 	}
+
+	earliest := task.LastStart.Add(task.MinInterval)
+	if task.MinInterval > 0 && task.NextRun.Before(earliest) {
+		task.NextRun = earliest
+	}
 	return nil
 }
 
+// now returns the current time in the location, nil is the local time zone
+func now(loc *time.Location) time.Time {
+	if loc == nil {
+		return time.Now()
+	}
+	return time.Now().In(loc)
+}
+
 // GetTask returns a task by name
 func (s *TaskScheduler) GetTask(name string) (*Task, error) {
 	s.tasksMutex.RLock()
@@ -767,17 +898,23 @@ func (s *TaskScheduler) GetTask(name string) (*Task, error) {
 		Function:            task.Function,
 		NextRun:             task.NextRun,
 		LastRun:             task.LastRun,
+		LastStart:           task.LastStart,
 		RunCount:            task.RunCount,
 		ErrorCount:          task.ErrorCount,
 		ConsecutiveFailures: task.ConsecutiveFailures,
 		LastError:           task.LastError,
 		IsRunning:           task.IsRunning,
 		Quiet:               task.Quiet,
+		Priority:            task.Priority,
+		DependsOn:           append([]string(nil), task.DependsOn...),
 		AllowConcurrent:     task.AllowConcurrent,
 		MaxRetries:          task.MaxRetries,
 		RetryDelay:          task.RetryDelay,
 		Timeout:             task.Timeout,
+		MinInterval:         task.MinInterval,
 		Enabled:             task.Enabled,
+		Location:            task.Location,
+		IdempotencyKey:      task.IdempotencyKey,
 		CreatedAt:           task.CreatedAt,
 		UpdatedAt:           task.UpdatedAt,
 		// Note: mutex is intentionally not copied
@@ -802,17 +939,23 @@ func (s *TaskScheduler) GetTasks() map[string]*Task {
 			Function:            task.Function,
 			NextRun:             task.NextRun,
 			LastRun:             task.LastRun,
+			LastStart:           task.LastStart,
 			RunCount:            task.RunCount,
 			ErrorCount:          task.ErrorCount,
 			ConsecutiveFailures: task.ConsecutiveFailures,
 			LastError:           task.LastError,
 			IsRunning:           task.IsRunning,
 			Quiet:               task.Quiet,
+			Priority:            task.Priority,
+			DependsOn:           append([]string(nil), task.DependsOn...),
 			AllowConcurrent:     task.AllowConcurrent,
 			MaxRetries:          task.MaxRetries,
 			RetryDelay:          task.RetryDelay,
 			Timeout:             task.Timeout,
+			MinInterval:         task.MinInterval,
 			Enabled:             task.Enabled,
+			Location:            task.Location,
+			IdempotencyKey:      task.IdempotencyKey,
 			CreatedAt:           task.CreatedAt,
 			UpdatedAt:           task.UpdatedAt,
 			// Note: mutex is intentionally not copied
@@ -865,6 +1008,76 @@ func (s *TaskScheduler) DisableTask(name string) error {
 	return nil
 }
 
+// RunNow executes the task immediately and waits until it finished, regardless of its schedule.
+// The execution is limited by the timeout of the task and counted in the task statistics like a
+// scheduled run, but the next scheduled run is not changed and failed executions are not retried.
+// The minimum interval of the task does not delay it, but it delays the next scheduled run.
+// It returns the error of the task or an error if the task is disabled or is already running
+// and does not allow concurrent executions. The scheduler does not need to be started.
+func (s *TaskScheduler) RunNow(ctx context.Context, name string) error {
+	s.tasksMutex.RLock()
+	task, exists := s.tasks[name]
+	if !exists {
+		s.tasksMutex.RUnlock()
+		return apperror.NewError(fmt.Sprintf("task '%s' not found", name))
+	}
+
+	task.mutex.Lock()
+	if !task.Enabled {
+		task.mutex.Unlock()
+		s.tasksMutex.RUnlock()
+		return apperror.NewError(fmt.Sprintf("task '%s' is disabled", name))
+	}
+	if task.Function == nil {
+		task.mutex.Unlock()
+		s.tasksMutex.RUnlock()
+		return apperror.NewError(fmt.Sprintf("task '%s' is not bound to a function", name))
+	}
+	if task.IsRunning && !task.AllowConcurrent {
+		task.mutex.Unlock()
+		s.tasksMutex.RUnlock()
+		return apperror.NewError(fmt.Sprintf("task '%s' is already running", name))
+	}
+	if !task.AllowConcurrent {
+		task.IsRunning = true
+	}
+	task.runs++
+	task.LastStart = time.Now()
+	task.UpdatedAt = task.LastStart
+	timeout := task.Timeout
+	task.mutex.Unlock()
+	s.tasksMutex.RUnlock()
+
+	logger.Debug().
+		Field("task_name", name).
+		Msg("executing task on demand")
+
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := task.Function(taskCtx)
+
+	task.mutex.Lock()
+	defer task.mutex.Unlock()
+
+	if !task.AllowConcurrent {
+		task.IsRunning = false
+	}
+	task.runs--
+	task.LastRun = time.Now()
+	task.UpdatedAt = task.LastRun
+	if err != nil {
+		task.ErrorCount++
+		task.ConsecutiveFailures++
+		task.LastError = err.Error()
+		return apperror.Wrap(err)
+	}
+
+	task.RunCount++
+	task.ConsecutiveFailures = 0
+	task.LastError = ""
+	return nil
+}
+
 // RemoveTask removes a task from the scheduler
 func (s *TaskScheduler) RemoveTask(name string) error {
 	s.tasksMutex.Lock()
@@ -881,6 +1094,18 @@ func (s *TaskScheduler) RemoveTask(name string) error {
 
 	delete(s.tasks, name)
 
+	// Dependent tasks would wait forever for the removed task
+	for _, other := range s.tasks {
+		other.mutex.Lock()
+		for i, dependency := range other.DependsOn {
+			if dependency == name {
+				other.DependsOn = append(other.DependsOn[:i:i], other.DependsOn[i+1:]...)
+				break
+			}
+		}
+		other.mutex.Unlock()
+	}
+
 	logger.Debug().
 		Field("task_name", name).
 		Msg("task removed")
@@ -888,6 +1113,96 @@ func (s *TaskScheduler) RemoveTask(name string) error {
 	return nil
 }
 
+// AddDependency makes the task wait for the task it depends on. A due task is only
+// dispatched once the last run of every dependency succeeded after the last run of
+// the task itself, i.e. within the current cycle, and no dependency is running.
+// Until then it stays due and is checked again on every check interval.
+// Dependencies that would create a cycle are rejected.
+func (s *TaskScheduler) AddDependency(task, dependsOn string) error {
+	if task == dependsOn {
+		return apperror.NewError(fmt.Sprintf("task '%s' cannot depend on itself", task))
+	}
+
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	t, exists := s.tasks[task]
+	if !exists {
+		return apperror.NewError(fmt.Sprintf("task '%s' not found", task))
+	}
+	if _, exists := s.tasks[dependsOn]; !exists {
+		return apperror.NewError(fmt.Sprintf("dependency '%s' not found", dependsOn))
+	}
+
+	if s.dependsOn(dependsOn, task, make(map[string]bool)) {
+		return apperror.NewError(fmt.Sprintf("dependency of task '%s' on '%s' would create a cycle", task, dependsOn))
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, dependency := range t.DependsOn {
+		if dependency == dependsOn {
+			return nil
+		}
+	}
+	t.DependsOn = append(t.DependsOn, dependsOn)
+	t.UpdatedAt = time.Now()
+
+	logger.Debug().
+		Field("task_name", task).
+		Field("depends_on", dependsOn).
+		Msg("task dependency added")
+
+	return nil
+}
+
+// dependsOn reports whether the task directly or transitively depends on the target
+// The caller must hold the tasks mutex
+func (s *TaskScheduler) dependsOn(task, target string, visited map[string]bool) bool {
+	if task == target {
+		return true
+	}
+	if visited[task] {
+		return false
+	}
+	visited[task] = true
+
+	t, exists := s.tasks[task]
+	if !exists {
+		return false
+	}
+
+	t.mutex.RLock()
+	dependencies := append([]string(nil), t.DependsOn...)
+	t.mutex.RUnlock()
+
+	for _, dependency := range dependencies {
+		if s.dependsOn(dependency, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependenciesMet reports whether every dependency succeeded after the given last run and is not running
+// The caller must hold the tasks mutex
+func (s *TaskScheduler) dependenciesMet(dependencies []string, lastRun time.Time) bool {
+	for _, name := range dependencies {
+		dependency, exists := s.tasks[name]
+		if !exists {
+			continue
+		}
+
+		dependency.mutex.RLock()
+		met := !dependency.IsRunning && dependency.LastError == "" && dependency.LastRun.After(lastRun)
+		dependency.mutex.RUnlock()
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
 // RescheduleTaskWithCron reschedules an existing task with a new cron specification
 func (s *TaskScheduler) RescheduleTaskWithCron(name, cronSpec string) error {
 	if name == "" {
@@ -913,7 +1228,7 @@ func (s *TaskScheduler) RescheduleTaskWithCron(name, cronSpec string) error {
 		return apperror.NewError(fmt.Sprintf("cannot reschedule running task '%s'", name))
 	}
 
-	nextRun, err := s.calculateNextCronRun(cronSpec, time.Now())
+	nextRun, err := s.calculateNextCronRun(cronSpec, now(task.Location))
 	if err != nil {
 		return apperror.NewError(fmt.Sprintf("failed to calculate next run time: %v", err))
 	}
@@ -969,6 +1284,48 @@ func (s *TaskScheduler) RescheduleTaskWithInterval(name string, interval time.Du
 	return nil
 }
 
+// SchedulerStats is a snapshot of the state of a TaskScheduler
+// Running counts the runs in progress, concurrent runs of a task are counted individually
+type SchedulerStats struct {
+	Tasks       int       `json:"tasks"`
+	Enabled     int       `json:"enabled"`
+	Disabled    int       `json:"disabled"`
+	Running     int       `json:"running"`
+	TotalRuns   int64     `json:"total_runs"`
+	TotalErrors int64     `json:"total_errors"`
+	NextTask    string    `json:"next_task,omitempty"`
+	NextRun     time.Time `json:"next_run,omitempty"`
+}
+
+// Stats returns a snapshot of the scheduler state. NextTask and NextRun
+// describe the enabled task that is due first, the first by name if several are due at the same time,
+// they are empty if no task is enabled.
+func (s *TaskScheduler) Stats() SchedulerStats {
+	s.tasksMutex.RLock()
+	defer s.tasksMutex.RUnlock()
+
+	stats := SchedulerStats{Tasks: len(s.tasks)}
+	for _, task := range s.tasks {
+		task.mutex.RLock()
+		if task.Enabled {
+			stats.Enabled++
+			// Tasks due at the same time are ordered by name to keep the snapshot stable
+			if stats.NextTask == "" || task.NextRun.Before(stats.NextRun) ||
+				task.NextRun.Equal(stats.NextRun) && task.Name < stats.NextTask {
+				stats.NextTask = task.Name
+				stats.NextRun = task.NextRun
+			}
+		}
+		stats.Running += task.runs
+		stats.TotalRuns += task.RunCount
+		stats.TotalErrors += task.ErrorCount
+		task.mutex.RUnlock()
+	}
+	stats.Disabled = stats.Tasks - stats.Enabled
+
+	return stats
+}
+
 // IsRunning returns true if the scheduler is running
 func (s *TaskScheduler) IsRunning() bool {
 	return atomic.LoadInt32(&s.running) == 1
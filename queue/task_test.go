@@ -734,3 +734,638 @@ func TestTaskScheduler_RunImmediately(t *testing.T) {
 
 	t.Logf("Cron executions: %d, Interval executions: %d", cronCount, intervalCount)
 }
+
+// memoryLocker is a Locker shared by schedulers in the same process
+type memoryLocker struct {
+	mu     sync.Mutex
+	leases map[string]time.Time
+}
+
+func (l *memoryLocker) Acquire(_ context.Context, key string, lease time.Duration) (func() error, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if expires, ok := l.leases[key]; ok && time.Now().Before(expires) {
+		return nil, false, nil
+	}
+	l.leases[key] = time.Now().Add(lease)
+	return func() error { return nil }, true, nil
+}
+
+func TestTaskScheduler_WithLocker(t *testing.T) {
+	locker := &memoryLocker{leases: make(map[string]time.Time)}
+
+	var executed int32
+	taskFunc := func(_ context.Context) error {
+		atomic.AddInt32(&executed, 1)
+		return nil
+	}
+
+	// Two nodes running the same task
+	for i := 0; i < 2; i++ {
+		scheduler := queue.NewTaskScheduler().
+			WithCheckInterval(50*time.Millisecond).
+			WithLocker(locker, time.Minute)
+
+		err := scheduler.RegisterCronTask("cluster-task", "* * * * * *", taskFunc)
+		if err != nil {
+			t.Fatalf("failed to register cron task: %v", err)
+		}
+
+		err = scheduler.Start(t.Context())
+		if err != nil {
+			t.Fatalf("failed to start scheduler: %v", err)
+		}
+		defer scheduler.Stop()
+	}
+
+	time.Sleep(2500 * time.Millisecond)
+
+	locker.mu.Lock()
+	occurrences := len(locker.leases)
+	locker.mu.Unlock()
+
+	runs := atomic.LoadInt32(&executed)
+	if runs == 0 {
+		t.Fatal("expected task to be executed")
+	}
+	if int(runs) > occurrences {
+		t.Errorf("expected each occurrence to run once, got %d runs for %d occurrences", runs, occurrences)
+	}
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	completed map[string]bool
+}
+
+func (m *memoryIdempotencyStore) Completed(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.completed[key], nil
+}
+
+func (m *memoryIdempotencyStore) Complete(_ context.Context, key string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completed[key] = true
+	return nil
+}
+
+func TestTaskScheduler_WithIdempotencyStore(t *testing.T) {
+	// The billing of the day completed before the restart
+	store := &memoryIdempotencyStore{completed: map[string]bool{"billing:done": true}}
+	scheduler := queue.NewTaskScheduler().
+		WithCheckInterval(50*time.Millisecond).
+		WithIdempotencyStore(store, time.Hour)
+
+	var mu sync.Mutex
+	var keys []string
+	for _, name := range []string{"done", "pending"} {
+		err := scheduler.RegisterIntervalTaskWithOptions(name, 50*time.Millisecond, func(ctx context.Context) error {
+			key, ok := queue.IdempotencyKey(ctx)
+			if !ok {
+				t.Error("expected idempotency key in task context")
+			}
+			mu.Lock()
+			keys = append(keys, key)
+			mu.Unlock()
+			return nil
+		}, queue.TaskOptions{
+			Immediately: true,
+			IdempotencyKey: func(time.Time) string {
+				return "billing:" + name
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to register task %s: %v", name, err)
+		}
+	}
+
+	err := scheduler.Start(t.Context())
+	if err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	scheduler.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 1 || keys[0] != "billing:pending" {
+		t.Errorf("expected only the pending occurrence to run once, got %v", keys)
+	}
+	if !store.completed["billing:pending"] {
+		t.Error("expected the completed occurrence to be recorded")
+	}
+}
+
+func TestTaskScheduler_Priority(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	locker := queue.LockerFunc(func(_ context.Context, key string, _ time.Duration) (func() error, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, key)
+		return func() error { return nil }, true, nil
+	})
+
+	scheduler := queue.NewTaskScheduler().
+		WithCheckInterval(50*time.Millisecond).
+		WithLocker(locker, time.Minute)
+
+	taskFunc := func(_ context.Context) error { return nil }
+	tasks := []struct {
+		name     string
+		priority int
+	}{
+		{"low", 0},
+		{"urgent", 10},
+		{"normal", 5},
+		{"critical", 10}, // same priority as urgent but due later
+	}
+	for _, task := range tasks {
+		err := scheduler.RegisterIntervalTaskWithOptions(task.name, time.Hour, taskFunc, queue.TaskOptions{
+			Immediately: true,
+			Priority:    task.priority,
+		})
+		if err != nil {
+			t.Fatalf("failed to register task %s: %v", task.name, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	err := scheduler.Start(t.Context())
+	if err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	scheduler.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"task:urgent", "task:critical", "task:normal", "task:low"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %d dispatched tasks, got %v", len(expected), order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected dispatch order %v, got %v", expected, order)
+		}
+	}
+
+	task, err := scheduler.GetTask("urgent")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if task.Priority != 10 {
+		t.Errorf("expected priority 10, got %d", task.Priority)
+	}
+}
+
+func TestTaskScheduler_Stats(t *testing.T) {
+	scheduler := queue.NewTaskScheduler()
+	taskFunc := func(_ context.Context) error { return nil }
+
+	intervals := map[string]time.Duration{
+		"hourly":   time.Hour,
+		"daily":    24 * time.Hour,
+		"frequent": time.Minute,
+	}
+	for name, interval := range intervals {
+		err := scheduler.RegisterIntervalTaskWithOptions(name, interval, taskFunc, queue.TaskOptions{})
+		if err != nil {
+			t.Fatalf("failed to register task %s: %v", name, err)
+		}
+	}
+
+	err := scheduler.DisableTask("frequent")
+	if err != nil {
+		t.Fatalf("failed to disable task: %v", err)
+	}
+
+	stats := scheduler.Stats()
+	if stats.Tasks != 3 || stats.Enabled != 2 || stats.Disabled != 1 {
+		t.Errorf("expected 3 tasks with 2 enabled and 1 disabled, got %+v", stats)
+	}
+	if stats.Running != 0 || stats.TotalRuns != 0 || stats.TotalErrors != 0 {
+		t.Errorf("expected no runs yet, got %+v", stats)
+	}
+	if stats.NextTask != "hourly" {
+		t.Errorf("expected hourly to be the next task, got %q", stats.NextTask)
+	}
+
+	task, err := scheduler.GetTask("hourly")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if !stats.NextRun.Equal(task.NextRun) {
+		t.Errorf("expected next run %v, got %v", task.NextRun, stats.NextRun)
+	}
+}
+
+func TestTaskScheduler_StatsNextTaskTie(t *testing.T) {
+	taskFunc := func(_ context.Context) error { return nil }
+
+	for range 10 {
+		scheduler := queue.NewTaskScheduler()
+		for _, name := range []string{"report", "cleanup", "backup"} {
+			err := scheduler.RegisterCronTask(name, "0 0 3 * * *", taskFunc)
+			if err != nil {
+				t.Fatalf("failed to register task %s: %v", name, err)
+			}
+		}
+
+		stats := scheduler.Stats()
+		if stats.NextTask != "backup" {
+			t.Fatalf("expected the first task by name of tasks due at the same time, got %q", stats.NextTask)
+		}
+	}
+}
+
+func TestTaskScheduler_StatsConcurrentRuns(t *testing.T) {
+	scheduler := queue.NewTaskScheduler()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := scheduler.RegisterIntervalTaskWithOptions("concurrent", time.Hour, func(_ context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, queue.TaskOptions{Concurrent: true})
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := scheduler.RunNow(t.Context(), "concurrent")
+			if err != nil {
+				t.Errorf("failed to run task: %v", err)
+			}
+		}()
+	}
+	<-started
+	<-started
+
+	if stats := scheduler.Stats(); stats.Running != 2 {
+		t.Errorf("expected 2 concurrent runs, got %d", stats.Running)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if stats := scheduler.Stats(); stats.Running != 0 || stats.TotalRuns != 2 {
+		t.Errorf("expected no runs in progress after 2 runs, got %+v", stats)
+	}
+}
+
+func TestTaskScheduler_Dependencies(t *testing.T) {
+	scheduler := queue.NewTaskScheduler().WithCheckInterval(20 * time.Millisecond)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, err error) queue.TaskFunc {
+		return func(_ context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return err
+		}
+	}
+
+	options := queue.TaskOptions{Immediately: true}
+	tasks := map[string]queue.TaskFunc{
+		"load":      record("load", nil),
+		"transform": record("transform", nil),
+		"extract":   record("extract", nil),
+		"broken":    record("broken", errors.New("failed")),
+		"blocked":   record("blocked", nil),
+	}
+	for name, fn := range tasks {
+		err := scheduler.RegisterIntervalTaskWithOptions(name, time.Hour, fn, options)
+		if err != nil {
+			t.Fatalf("failed to register task %s: %v", name, err)
+		}
+	}
+
+	dependencies := [][2]string{{"load", "transform"}, {"transform", "extract"}, {"blocked", "broken"}}
+	for _, d := range dependencies {
+		err := scheduler.AddDependency(d[0], d[1])
+		if err != nil {
+			t.Fatalf("failed to add dependency of %s on %s: %v", d[0], d[1], err)
+		}
+	}
+
+	if err := scheduler.AddDependency("extract", "load"); err == nil {
+		t.Error("expected error for dependency cycle")
+	}
+	if err := scheduler.AddDependency("load", "load"); err == nil {
+		t.Error("expected error for dependency on itself")
+	}
+	if err := scheduler.AddDependency("load", "missing"); err == nil {
+		t.Error("expected error for unknown dependency")
+	}
+
+	err := scheduler.Start(t.Context())
+	if err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	scheduler.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+	for _, name := range []string{"extract", "transform", "load", "broken"} {
+		if _, ok := position[name]; !ok {
+			t.Fatalf("expected task %s to run, got %v", name, order)
+		}
+	}
+	if position["extract"] > position["transform"] || position["transform"] > position["load"] {
+		t.Errorf("expected dependency order extract, transform, load, got %v", order)
+	}
+	if _, ok := position["blocked"]; ok {
+		t.Errorf("expected task depending on a failed task not to run, got %v", order)
+	}
+
+	task, err := scheduler.GetTask("load")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if len(task.DependsOn) != 1 || task.DependsOn[0] != "transform" {
+		t.Errorf("expected load to depend on transform, got %v", task.DependsOn)
+	}
+}
+
+func TestTaskScheduler_RunNow(t *testing.T) {
+	scheduler := queue.NewTaskScheduler()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	err := scheduler.RegisterIntervalTask("blocking", time.Hour, func(ctx context.Context) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+	err = scheduler.RegisterIntervalTaskWithOptions("slow", time.Hour, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, queue.TaskOptions{Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+
+	before, err := scheduler.GetTask("blocking")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- scheduler.RunNow(context.Background(), "blocking")
+	}()
+	<-started
+
+	err = scheduler.RunNow(context.Background(), "blocking")
+	if err == nil {
+		t.Error("expected error when running a non-concurrent task that is already running")
+	}
+
+	close(release)
+	err = <-done
+	if err != nil {
+		t.Fatalf("expected task to succeed, got %v", err)
+	}
+
+	after, err := scheduler.GetTask("blocking")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if after.RunCount != 1 || after.IsRunning || after.LastRun.IsZero() {
+		t.Errorf("expected one finished run, got run count %d, running %v", after.RunCount, after.IsRunning)
+	}
+	if !after.NextRun.Equal(before.NextRun) {
+		t.Errorf("expected next run %v to be unchanged, got %v", before.NextRun, after.NextRun)
+	}
+
+	err = scheduler.RunNow(context.Background(), "slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	slow, err := scheduler.GetTask("slow")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if slow.ErrorCount != 1 || slow.ConsecutiveFailures != 1 || slow.LastError == "" {
+		t.Errorf("expected failed run in stats, got error count %d, last error %q", slow.ErrorCount, slow.LastError)
+	}
+
+	err = scheduler.DisableTask("slow")
+	if err != nil {
+		t.Fatalf("failed to disable task: %v", err)
+	}
+	if err := scheduler.RunNow(context.Background(), "slow"); err == nil {
+		t.Error("expected error when running a disabled task")
+	}
+	if err := scheduler.RunNow(context.Background(), "non-existent"); err == nil {
+		t.Error("expected error when running a non-existent task")
+	}
+}
+
+func TestTaskScheduler_ExportImport(t *testing.T) {
+	source := queue.NewTaskScheduler()
+	noop := func(_ context.Context) error { return nil }
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	err = source.RegisterCronTaskWithOptions("report", "0 0 3 * * *", noop, queue.TaskOptions{
+		MaxRetries: 2,
+		Location:   berlin,
+		Priority:   5,
+	})
+	if err != nil {
+		t.Fatalf("failed to register cron task: %v", err)
+	}
+	err = source.RegisterIntervalTaskWithOptions("cleanup", 5*time.Minute, noop, queue.TaskOptions{Quiet: true})
+	if err != nil {
+		t.Fatalf("failed to register interval task: %v", err)
+	}
+	err = source.AddDependency("report", "cleanup")
+	if err != nil {
+		t.Fatalf("failed to add dependency: %v", err)
+	}
+	err = source.DisableTask("cleanup")
+	if err != nil {
+		t.Fatalf("failed to disable task: %v", err)
+	}
+
+	data, err := source.Export()
+	if err != nil {
+		t.Fatalf("failed to export tasks: %v", err)
+	}
+
+	target := queue.NewTaskScheduler()
+	err = target.Import(data)
+	if err != nil {
+		t.Fatalf("failed to import tasks: %v\n%s", err, data)
+	}
+
+	report, err := target.GetTask("report")
+	if err != nil {
+		t.Fatalf("failed to get imported task: %v", err)
+	}
+	if report.Type != queue.TaskTypeCron || report.CronSpec != "0 0 3 * * *" || report.MaxRetries != 2 ||
+		report.Priority != 5 || report.Location.String() != "Europe/Berlin" || len(report.DependsOn) != 1 {
+		t.Errorf("imported cron task does not match its definition: %+v", report)
+	}
+	if report.Function != nil {
+		t.Error("expected imported task to be a placeholder without function")
+	}
+	cleanup, err := target.GetTask("cleanup")
+	if err != nil {
+		t.Fatalf("failed to get imported task: %v", err)
+	}
+	if cleanup.Type != queue.TaskTypeInterval || cleanup.Interval != 5*time.Minute || !cleanup.Quiet || cleanup.Enabled {
+		t.Errorf("imported interval task does not match its definition: %+v", cleanup)
+	}
+
+	exported, err := target.Export()
+	if err != nil {
+		t.Fatalf("failed to export imported tasks: %v", err)
+	}
+	if string(exported) != string(data) {
+		t.Errorf("expected export of imported tasks to match\n%s\ngot\n%s", data, exported)
+	}
+
+	// Placeholders do not run until they are bound to a function
+	err = target.RunNow(t.Context(), "report")
+	if err == nil {
+		t.Error("expected running an unbound task to fail")
+	}
+	var runs int32
+	err = target.Bind("report", func(_ context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to bind task: %v", err)
+	}
+	err = target.RunNow(t.Context(), "report")
+	if err != nil || atomic.LoadInt32(&runs) != 1 {
+		t.Errorf("expected bound task to run, got %v and %d runs", err, atomic.LoadInt32(&runs))
+	}
+
+	// Importing reschedules existing tasks and keeps their function
+	err = target.Import([]byte(`{"tasks":[{"name":"report","type":"interval","interval":"1h"}]}`))
+	if err != nil {
+		t.Fatalf("failed to import JSON definitions: %v", err)
+	}
+	report, err = target.GetTask("report")
+	if err != nil {
+		t.Fatalf("failed to get rescheduled task: %v", err)
+	}
+	if report.Type != queue.TaskTypeInterval || report.Interval != time.Hour || report.Function == nil || report.RunCount != 1 {
+		t.Errorf("expected rescheduled task to keep its function and statistics: %+v", report)
+	}
+
+	invalid := []string{
+		`tasks: [{name: a, type: hourly}]`,
+		`tasks: [{name: a, type: cron, cron: "not a cron"}]`,
+		`tasks: [{name: a, type: interval, interval: "-1s"}]`,
+		`tasks: [{name: a, type: interval, interval: 1m, depends_on: [missing]}]`,
+		`tasks: [{name: a, type: interval, interval: 1m}, {name: a, type: interval, interval: 2m}]`,
+		`tasks: [{name: a, type: interval, interval: 1m, depends_on: [a]}]`,
+		`tasks: [{name: a, type: interval, interval: 1m, depends_on: [b]}, {name: b, type: interval, interval: 1m, depends_on: [a]}]`,
+	}
+	for _, definition := range invalid {
+		err = queue.NewTaskScheduler().Import([]byte(definition))
+		if err == nil {
+			t.Errorf("expected import of %s to fail", definition)
+		}
+	}
+
+	// A rejected import leaves registered tasks and their dependencies unchanged
+	err = target.Import([]byte(`{"tasks":[{"name":"report","type":"interval","interval":"2h","depends_on":["cleanup"]},{"name":"cleanup","type":"interval","interval":"1m","depends_on":["report"]}]}`))
+	if err == nil {
+		t.Fatal("expected import of a dependency cycle to fail")
+	}
+	err = target.AddDependency("report", "cleanup")
+	if err != nil {
+		t.Fatalf("failed to add dependency: %v", err)
+	}
+	err = target.Import([]byte(`{"tasks":[{"name":"report","type":"interval","interval":"2h"},{"name":"cleanup","type":"interval","interval":"1m","depends_on":["report"]}]}`))
+	if err != nil {
+		t.Fatalf("expected import replacing the dependencies to succeed: %v", err)
+	}
+	err = target.Import([]byte(`{"tasks":[{"name":"report","type":"interval","interval":"3h","depends_on":["cleanup"]}]}`))
+	if err == nil {
+		t.Fatal("expected import of a cycle with a registered task to fail")
+	}
+	report, err = target.GetTask("report")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if report.Interval != 2*time.Hour || len(report.DependsOn) != 0 {
+		t.Errorf("expected rejected import to leave the task unchanged: %+v", report)
+	}
+}
+
+func TestTaskScheduler_MinInterval(t *testing.T) {
+	scheduler := queue.NewTaskScheduler().WithCheckInterval(10 * time.Millisecond)
+
+	var mu sync.Mutex
+	var starts []time.Time
+	err := scheduler.RegisterIntervalTaskWithOptions("quota", 20*time.Millisecond, func(_ context.Context) error {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return nil
+	}, queue.TaskOptions{
+		Immediately: true,
+		MinInterval: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to register task: %v", err)
+	}
+
+	err = scheduler.Start(t.Context())
+	if err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	time.Sleep(650 * time.Millisecond)
+	scheduler.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(starts) < 2 || len(starts) > 4 {
+		t.Fatalf("expected 2 to 4 runs limited by the minimum interval, got %d", len(starts))
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 200*time.Millisecond {
+			t.Errorf("expected runs to start at least 200ms apart, run %d started %v after the previous one", i+1, gap)
+		}
+	}
+
+	task, err := scheduler.GetTask("quota")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if task.NextRun.Before(task.LastStart.Add(200 * time.Millisecond)) {
+		t.Errorf("expected the next run %v not before the minimum interval after the last start %v", task.NextRun, task.LastStart)
+	}
+}
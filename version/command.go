@@ -0,0 +1,102 @@
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// Cmd is a ready-made version subcommand for command line applications.
+// It does not depend on a command framework, its fields map directly to a cobra.Command.
+type Cmd struct {
+	// Use is the name of the subcommand
+	Use string
+	// Short is the one-line description of the subcommand
+	Short string
+	// Flags holds the --short and --json flags of the subcommand
+	Flags *pflag.FlagSet
+
+	short bool
+	json  bool
+}
+
+// Command returns a version subcommand that prints the release information of Get.
+// By default it prints the tag, commit, build date, Go version and platform, --short
+// prints only the tag and --json prints the full release including the modules as JSON.
+//
+// Example without a command framework:
+//
+//	if len(os.Args) > 1 && os.Args[1] == "version" {
+//		err := version.Command().Run(os.Stdout, os.Args[2:])
+//		if err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(2)
+//		}
+//		return
+//	}
+//
+// Example with cobra:
+//
+//	vc := version.Command()
+//	cmd := &cobra.Command{
+//		Use:   vc.Use,
+//		Short: vc.Short,
+//		Args:  cobra.NoArgs,
+//		RunE: func(cmd *cobra.Command, args []string) error {
+//			return vc.Run(cmd.OutOrStdout(), args)
+//		},
+//	}
+//	cmd.Flags().AddFlagSet(vc.Flags)
+//	root.AddCommand(cmd)
+func Command() *Cmd {
+	c := &Cmd{
+		Use:   "version",
+		Short: "Print the version information",
+	}
+
+	c.Flags = pflag.NewFlagSet(c.Use, pflag.ContinueOnError)
+	c.Flags.BoolVar(&c.short, "short", false, "print only the version tag")
+	c.Flags.BoolVar(&c.json, "json", false, "print the full version information as JSON")
+	return c
+}
+
+// Run parses the arguments and writes the release information to w.
+// Flags that were already parsed, e.g. by cobra, are kept.
+func (c *Cmd) Run(w io.Writer, args []string) error {
+	err := c.Flags.Parse(args)
+	if err != nil {
+		return apperror.Wrap(err)
+	}
+	if c.Flags.NArg() > 0 {
+		return apperror.NewErrorf("unexpected arguments %v", c.Flags.Args())
+	}
+	if c.short && c.json {
+		return apperror.NewError("flags --short and --json cannot be combined")
+	}
+
+	release := Get()
+	switch {
+	case c.json:
+		data, err := json.MarshalIndent(release, "", "  ")
+		if err != nil {
+			return apperror.NewError("marshaling version information failed").AddError(err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return apperror.Wrap(err)
+	case c.short:
+		_, err := fmt.Fprintln(w, release.Short())
+		return apperror.Wrap(err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Version:\t%s\n", release.Short())
+	fmt.Fprintf(tw, "Commit:\t%s\n", release.GitCommit)
+	fmt.Fprintf(tw, "Build date:\t%s\n", release.BuildDate)
+	fmt.Fprintf(tw, "Go version:\t%s\n", release.GoVersion)
+	fmt.Fprintf(tw, "Platform:\t%s\n", release.Platform)
+	return apperror.Wrap(tw.Flush())
+}
@@ -0,0 +1,58 @@
+package version_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/valentin-kaiser/go-core/version"
+)
+
+func TestCommand(t *testing.T) {
+	tag, dirty := version.GitTag, version.Dirty
+	defer func() { version.GitTag, version.Dirty = tag, dirty }()
+	version.GitTag = "v1.2.3"
+	version.Dirty = false
+
+	var out bytes.Buffer
+	err := version.Command().Run(&out, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, want := range []string{"Version:    v1.2.3", "Commit:", "Build date:", "Go version:", "Platform:"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Run() output %q does not contain %q", out.String(), want)
+		}
+	}
+
+	out.Reset()
+	err = version.Command().Run(&out, []string{"--short"})
+	if err != nil {
+		t.Fatalf("Run(--short) error = %v", err)
+	}
+	if out.String() != "v1.2.3\n" {
+		t.Errorf("Run(--short) = %q, want %q", out.String(), "v1.2.3\n")
+	}
+
+	out.Reset()
+	err = version.Command().Run(&out, []string{"--json"})
+	if err != nil {
+		t.Fatalf("Run(--json) error = %v", err)
+	}
+	var release version.Release
+	err = json.Unmarshal(out.Bytes(), &release)
+	if err != nil {
+		t.Fatalf("Run(--json) printed invalid JSON: %v", err)
+	}
+	if release.GitTag != "v1.2.3" || release.ParsedVersion == nil || release.ParsedVersion.Major != 1 {
+		t.Errorf("Run(--json) = %+v, want the parsed release v1.2.3", release)
+	}
+
+	for _, args := range [][]string{{"--short", "--json"}, {"--unknown"}, {"extra"}} {
+		err = version.Command().Run(&out, args)
+		if err == nil {
+			t.Errorf("Run(%v) should fail", args)
+		}
+	}
+}
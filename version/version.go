@@ -57,7 +57,8 @@
 //
 // The package defines the Release struct encapsulating all relevant fields,
 // the ParsedVersion struct for version components, and the VersionParser
-// interface for extensible version format support.
+// interface for extensible version format support. Command provides a ready-made
+// version subcommand with --short and --json flags for command line applications.
 //
 // Example usage with different version formats:
 //
@@ -83,6 +84,7 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -99,6 +101,9 @@ var (
 	GitShort = "unknown"
 	// BuildDate is the date and time when the application was built.
 	BuildDate = "unknown"
+	// Dirty is true if the application was built from a working tree with uncommitted changes.
+	// It is populated from the vcs.modified setting of debug.BuildInfo.
+	Dirty = false
 	// GoVersion is the version of the Go runtime used to build the application.
 	GoVersion = runtime.Version()
 	// Platform is the target platform of the application, formatted as "GOOS/GOARCH".
@@ -128,6 +133,13 @@ func init() {
 		Modules = append(Modules, (&Module{}).fromBuildInfo(mod))
 	}
 
+	// The modified state describes the build itself, so it also applies to builds with ldflags
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.modified" {
+			Dirty = setting.Value == "true"
+		}
+	}
+
 	if GitCommit != "unknown" || BuildDate != "unknown" {
 		return
 	}
@@ -230,6 +242,7 @@ type Release struct {
 	GitCommit     string         `json:"gitCommit" gorm:"uniqueIndex:idx_version_module"`
 	GitShort      string         `json:"gitShort"`
 	BuildDate     string         `json:"buildDate" gorm:"uniqueIndex:idx_version_module"`
+	Dirty         bool           `json:"dirty"`
 	GoVersion     string         `json:"goVersion" gorm:"uniqueIndex:idx_version_module"`
 	Platform      string         `json:"platform" gorm:"uniqueIndex:idx_version_module"`
 	Modules       []*Module      `json:"modules" gorm:"-"`
@@ -263,6 +276,7 @@ func Get() *Release {
 		GitCommit:     GitCommit,
 		GitShort:      GitShort,
 		BuildDate:     BuildDate,
+		Dirty:         Dirty,
 		GoVersion:     GoVersion,
 		Platform:      Platform,
 		Modules:       Modules,
@@ -271,6 +285,31 @@ func Get() *Release {
 	}
 }
 
+// ModuleVersion returns the version of the module dependency with the given path.
+// If the module is replaced by another module version, the version of the replacement is returned.
+func ModuleVersion(path string) (string, bool) {
+	for _, mod := range Modules {
+		if mod.Path != path {
+			continue
+		}
+		if mod.Replace != nil && mod.Replace.Version != "" {
+			return mod.Replace.Version, true
+		}
+		return mod.Version, true
+	}
+	return "", false
+}
+
+// SortedModules returns a copy of the module dependencies sorted by path.
+func SortedModules() []*Module {
+	modules := make([]*Module, len(Modules))
+	copy(modules, Modules)
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+	return modules
+}
+
 // Major returns the major version number from the Git tag if it follows semantic versioning.
 func Major() int {
 	if IsSemver(GitTag) {
@@ -929,6 +968,15 @@ func (p *CalVerYYYYMMDDMICROParser) Compare(tag1, tag2 string) (int, error) {
 	return 0, nil
 }
 
+// Short returns the Git tag of the release, suffixed with "-dirty" if the build included
+// uncommitted changes, so that a local build is not mistaken for the tagged release.
+func (v *Release) Short() string {
+	if v.Dirty {
+		return v.GitTag + "-dirty"
+	}
+	return v.GitTag
+}
+
 // Compare compares the Git tag and commit hash of the current version with another version.
 func (v *Release) Compare(c *Release) bool {
 	return v.CompareTag(c) && v.CompareCommit(c)
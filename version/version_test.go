@@ -42,6 +42,40 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestModuleVersion(t *testing.T) {
+	original := version.Modules
+	defer func() { version.Modules = original }()
+
+	version.Modules = []*version.Module{
+		{Path: "github.com/spf13/pflag", Version: "v1.0.10"},
+		{Path: "github.com/fsnotify/fsnotify", Version: "v1.9.0"},
+		{Path: "gopkg.in/yaml.v2", Version: "v2.4.0", Replace: &version.Module{Path: "gopkg.in/yaml.v2", Version: "v2.4.1"}},
+	}
+
+	v, ok := version.ModuleVersion("github.com/spf13/pflag")
+	if !ok || v != "v1.0.10" {
+		t.Errorf("expected pflag v1.0.10, got %q (%v)", v, ok)
+	}
+
+	v, ok = version.ModuleVersion("gopkg.in/yaml.v2")
+	if !ok || v != "v2.4.1" {
+		t.Errorf("expected replaced yaml version v2.4.1, got %q (%v)", v, ok)
+	}
+
+	_, ok = version.ModuleVersion("example.com/unknown")
+	if ok {
+		t.Error("expected unknown module not to be found")
+	}
+
+	sorted := version.SortedModules()
+	if len(sorted) != 3 || sorted[0].Path != "github.com/fsnotify/fsnotify" || sorted[2].Path != "gopkg.in/yaml.v2" {
+		t.Errorf("expected modules sorted by path, got %v", sorted)
+	}
+	if version.Modules[0].Path != "github.com/spf13/pflag" {
+		t.Error("SortedModules must not reorder the Modules variable")
+	}
+}
+
 func TestMajor(t *testing.T) {
 	// Test with default tag
 	originalTag := version.GitTag
@@ -239,6 +273,22 @@ func TestVersionCompareCommit(t *testing.T) {
 	}
 }
 
+func TestReleaseShort(t *testing.T) {
+	release := &version.Release{GitTag: "v1.2.3"}
+	if release.Short() != "v1.2.3" {
+		t.Errorf("Expected v1.2.3, got %s", release.Short())
+	}
+
+	release.Dirty = true
+	if release.Short() != "v1.2.3-dirty" {
+		t.Errorf("Expected v1.2.3-dirty, got %s", release.Short())
+	}
+
+	if version.Get().Dirty != version.Dirty {
+		t.Error("Expected Get to report the dirty state of the build")
+	}
+}
+
 func TestVersionValidate(t *testing.T) {
 	v := &version.Release{}
 
@@ -0,0 +1,150 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/logging"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// redacted replaces the values of redacted fields in the access log
+const redacted = "[REDACTED]"
+
+// AccessLogOptions configures the access log of unary calls
+type AccessLogOptions struct {
+	// Payload adds the request and response messages as JSON to the log entries
+	Payload bool
+	// Redact lists the proto field paths masked in the logged messages, e.g. "password" or "user.token".
+	// Path segments are matched against the proto and the JSON name of the fields, fields of repeated
+	// messages are masked in every element. It applies to request and response messages.
+	Redact []string
+}
+
+// WithAccessLog enables an info level entry in the package logger for every unary call.
+// The entry is written after the call completed and contains the service, method,
+// HTTP status, duration and the size of the request and response body.
+// Access logging is disabled by default. It must be called before the service handles requests.
+func (s *Service) WithAccessLog(opts AccessLogOptions) *Service {
+	s.accessLog = &opts
+	return s
+}
+
+// accessRecorder records the status and size of a response and the messages of a unary call
+type accessRecorder struct {
+	http.ResponseWriter
+	start    time.Time
+	status   int
+	size     int
+	request  proto.Message
+	response any
+}
+
+// WriteHeader records the status code
+func (a *accessRecorder) WriteHeader(status int) {
+	a.status = status
+	a.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the size of the response body
+func (a *accessRecorder) Write(b []byte) (int, error) {
+	n, err := a.ResponseWriter.Write(b)
+	a.size += n
+	return n, err
+}
+
+// Unwrap returns the original ResponseWriter for http.ResponseController
+func (a *accessRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// logAccess writes the access log entry of a unary call
+func (s *Service) logAccess(a *accessRecorder, r *http.Request) {
+	service, method, _ := s.resolve(r)
+	l := logger.Info().
+		Fields(logging.FieldsFromContext(r.Context())...).
+		Field("service", service).
+		Field("method", method).
+		Field("status", a.status).
+		Field("duration", time.Since(a.start)).
+		Field("request_size", max(r.ContentLength, 0)).
+		Field("response_size", a.size)
+
+	if s.accessLog.Payload {
+		if a.request != nil {
+			l = l.Field("request", s.redact(a.request))
+		}
+		if response, err := protoMessage(a.response); err == nil {
+			l = l.Field("response", s.redact(response))
+		}
+	}
+
+	l.Msg("jRPC access")
+}
+
+// redact returns the message as JSON with the configured fields masked
+func (s *Service) redact(msg proto.Message) string {
+	data, err := s.marshalOpts.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+	if len(s.accessLog.Redact) == 0 {
+		return string(data)
+	}
+
+	// Well-known types like wrappers are not encoded as objects, they are masked as a whole
+	var fields map[string]any
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return redacted
+	}
+
+	for _, path := range s.accessLog.Redact {
+		redactPath(fields, msg.ProtoReflect().Descriptor(), strings.Split(path, "."))
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// redactPath masks the field at the path in the JSON representation of a message
+func redactPath(fields map[string]any, md protoreflect.MessageDescriptor, path []string) {
+	fd := md.Fields().ByName(protoreflect.Name(path[0]))
+	if fd == nil {
+		fd = md.Fields().ByJSONName(path[0])
+	}
+	if fd == nil {
+		return
+	}
+
+	for _, key := range []string{fd.JSONName(), string(fd.Name())} {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if len(path) == 1 {
+			fields[key] = redacted
+			continue
+		}
+		if fd.Message() == nil || fd.IsMap() {
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			redactPath(v, fd.Message(), path[1:])
+		case []any:
+			for _, item := range v {
+				if nested, ok := item.(map[string]any); ok {
+					redactPath(nested, fd.Message(), path[1:])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jrpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the cross-origin access of browser clients
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to call the service, e.g. "https://app.example.com".
+	// The entry "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods announced in preflight responses (default is POST, GET and OPTIONS)
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers announced in preflight responses
+	// (default is Content-Type, Authorization and the timeout header)
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers browser clients may read
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication
+	AllowCredentials bool
+	// MaxAge is the duration browsers may cache preflight responses, zero omits the header
+	MaxAge time.Duration
+}
+
+// WithCORS enables cross-origin requests from browser clients. Requests with an Origin
+// header that is allowed get Access-Control-Allow-* response headers, OPTIONS preflight
+// requests are answered with the configured methods and headers.
+// WebSocket upgrades from origins that are not allowed are rejected with 403 Forbidden,
+// in addition to the CheckOrigin function of the upgrader.
+// Requests without an Origin header, like server-to-server calls, are not affected.
+// CORS is disabled by default. It must be called before the service handles requests.
+func (s *Service) WithCORS(opts CORSOptions) *Service {
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{http.MethodPost, http.MethodGet, http.MethodOptions}
+	}
+	if len(opts.AllowedHeaders) == 0 {
+		opts.AllowedHeaders = []string{"Content-Type", "Authorization"}
+		if s.timeoutHeader != "" {
+			opts.AllowedHeaders = append(opts.AllowedHeaders, s.timeoutHeader)
+		}
+	}
+	s.cors = &opts
+	return s
+}
+
+// allowOrigin reports whether requests of the origin are allowed
+func (o *CORSOptions) allowOrigin(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCORS sets the CORS headers of the response. It returns false if the
+// request has an Origin header that is not allowed.
+func (s *Service) applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if s.cors == nil || origin == "" {
+		return true
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if !s.cors.allowOrigin(origin) {
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if s.cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(s.cors.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(s.cors.ExposedHeaders, ", "))
+	}
+
+	if r.Method == http.MethodOptions {
+		header.Set("Access-Control-Allow-Methods", strings.Join(s.cors.AllowedMethods, ", "))
+		header.Set("Access-Control-Allow-Headers", strings.Join(s.cors.AllowedHeaders, ", "))
+		if s.cors.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(s.cors.MaxAge.Seconds())))
+		}
+	}
+	return true
+}
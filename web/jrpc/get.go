@@ -0,0 +1,149 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WithGET allows calling unary methods with HTTP GET in addition to POST, e.g. read-only
+// methods whose responses should be cacheable or that should be easy to call with curl.
+// Methods are named like in the URL path, e.g. "UserService.GetUser". Other methods
+// keep requiring POST and respond to GET with 405 Method Not Allowed.
+//
+// The request message of a GET request is built from the query parameters:
+//   - parameters are matched against the proto name or the JSON name of the fields
+//   - fields of nested messages are addressed with dotted paths, e.g. filter.name=abc
+//   - repeated fields take every value of a parameter, e.g. ids=1&ids=2,
+//     other fields must not be given more than once
+//   - values are decoded like JSON strings with the unmarshal options of the service,
+//     so enums accept names and numbers and well-known types like Timestamp their
+//     JSON representation, booleans accept the values of strconv.ParseBool
+//   - map fields and messages without a JSON string representation are not supported
+//   - unknown parameters are rejected unless DiscardUnknown is set in the unmarshal options
+//
+// It must be called before the service handles requests.
+//
+// Example:
+//
+//	service.WithGET("UserService.GetUser")
+//	// curl "http://localhost:8080/api/UserService/GetUser?id=42&fields=name&fields=email"
+func (s *Service) WithGET(methods ...string) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.get == nil {
+		s.get = make(map[string]bool)
+	}
+	for _, method := range methods {
+		s.get[method] = true
+	}
+	return s
+}
+
+// allowsGET reports whether the method may be called with GET
+func (s *Service) allowsGET(service, method string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.get[service+"."+method]
+}
+
+// query decodes the query parameters into the message
+func (s *Service) query(values url.Values, msg proto.Message) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make(map[string]any)
+	for _, name := range names {
+		err := s.queryField(fields, msg.ProtoReflect().Descriptor(), name, strings.Split(name, "."), values[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return apperror.NewError("failed to encode query parameters").AddError(err)
+	}
+	err = s.unmarshalOpts.Unmarshal(data, msg)
+	if err != nil {
+		return apperror.NewError("invalid query parameters").AddError(err).WithKind(apperror.KindInvalidArgument)
+	}
+	return nil
+}
+
+// queryField sets the field at the path in the JSON representation of a message
+func (s *Service) queryField(fields map[string]any, md protoreflect.MessageDescriptor, name string, path []string, vals []string) error {
+	fd := md.Fields().ByName(protoreflect.Name(path[0]))
+	if fd == nil {
+		fd = md.Fields().ByJSONName(path[0])
+	}
+	if fd == nil {
+		if s.unmarshalOpts.DiscardUnknown {
+			return nil
+		}
+		return apperror.NewErrorf("unknown query parameter %s", name).WithKind(apperror.KindInvalidArgument)
+	}
+
+	key := fd.JSONName()
+	if len(path) > 1 {
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return apperror.NewErrorf("query parameter %s does not address a field of a message", name).WithKind(apperror.KindInvalidArgument)
+		}
+		nested, ok := fields[key].(map[string]any)
+		if !ok {
+			nested = make(map[string]any)
+			fields[key] = nested
+		}
+		return s.queryField(nested, fd.Message(), name, path[1:], vals)
+	}
+
+	if fd.IsMap() {
+		return apperror.NewErrorf("query parameter %s addresses a map field, which is not supported", name).WithKind(apperror.KindInvalidArgument)
+	}
+
+	if fd.IsList() {
+		list := make([]any, 0, len(vals))
+		for _, v := range vals {
+			value, err := queryValue(fd, name, v)
+			if err != nil {
+				return err
+			}
+			list = append(list, value)
+		}
+		fields[key] = list
+		return nil
+	}
+
+	if len(vals) > 1 {
+		return apperror.NewErrorf("query parameter %s must not be given more than once", name).WithKind(apperror.KindInvalidArgument)
+	}
+	value, err := queryValue(fd, name, vals[0])
+	if err != nil {
+		return err
+	}
+	fields[key] = value
+	return nil
+}
+
+// queryValue returns the JSON value of a query parameter, protojson decodes strings for all other kinds
+func queryValue(fd protoreflect.FieldDescriptor, name, v string) (any, error) {
+	if fd.Kind() != protoreflect.BoolKind {
+		return v, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, apperror.NewErrorf("query parameter %s must be a boolean", name).WithKind(apperror.KindInvalidArgument)
+	}
+	return b, nil
+}
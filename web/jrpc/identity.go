@@ -0,0 +1,127 @@
+package jrpc
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// ContextKeyIdentity key for the Principal of the authenticated caller
+const ContextKeyIdentity ContextKey = "identity"
+
+// Identity describes the authenticated caller of a call. Applications needing further
+// claims embed it in a type of their own, which then satisfies Principal:
+//
+//	type User struct {
+//		jrpc.Identity
+//		TenantID string
+//	}
+type Identity struct {
+	// Subject identifies the caller, e.g. the user id or the client id of a service account
+	Subject string
+	// Scopes lists the permissions granted to the caller, e.g. "users:read"
+	Scopes []string
+}
+
+// HasScope reports whether the identity was granted the scope
+func (i Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+// identity returns the identity itself, it is promoted to the types embedding Identity
+func (i Identity) identity() Identity {
+	return i
+}
+
+// Principal is an Identity or a type embedding it
+type Principal interface {
+	identity() Identity
+}
+
+// Authenticator authenticates the request of a call and returns the context the method is called with,
+// usually enriched with WithIdentity. Returning an error rejects the call: unary requests and
+// WebSocket upgrades are answered with the HTTP status of the error kind, or 401 Unauthorized
+// if the error has no kind, e.g. apperror.KindPermissionDenied results in 403 Forbidden.
+type Authenticator func(ctx context.Context, r *http.Request) (context.Context, error)
+
+// WithAuthenticator authenticates every unary request and every WebSocket connection with the
+// authenticator before the method is resolved against the registered servers and the request
+// message is read. WebSocket connections are authenticated once before the upgrade, the context
+// returned by the authenticator is used by all streams and multiplexed calls of the connection.
+// It must be called before the service handles requests.
+//
+// Example:
+//
+//	service.WithAuthenticator(func(ctx context.Context, r *http.Request) (context.Context, error) {
+//		claims, err := verifier.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+//		if err != nil {
+//			return nil, apperror.NewError("invalid token").AddError(err).WithKind(apperror.KindUnauthenticated)
+//		}
+//		return jrpc.WithIdentity(ctx, jrpc.Identity{Subject: claims.Subject, Scopes: claims.Scopes}), nil
+//	})
+func (s *Service) WithAuthenticator(a Authenticator) *Service {
+	s.authenticator = a
+	return s
+}
+
+// WithIdentity adds the principal of the authenticated caller to the context
+func WithIdentity(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ContextKeyIdentity, p)
+}
+
+// GetIdentity extracts the identity of the authenticated caller from the context.
+// If the principal is a type embedding Identity, the embedded Identity is returned.
+//
+// Example:
+//
+//	func (s *Server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+//		id, ok := jrpc.GetIdentity(ctx)
+//		if !ok || !id.HasScope("users:write") {
+//			return nil, apperror.NewError("missing scope users:write").WithKind(apperror.KindPermissionDenied)
+//		}
+//		...
+//	}
+func GetIdentity(ctx context.Context) (Identity, bool) {
+	p, ok := ctx.Value(ContextKeyIdentity).(Principal)
+	if !ok {
+		return Identity{}, false
+	}
+	return p.identity(), true
+}
+
+// GetPrincipal extracts the principal of the authenticated caller from the context
+// as the type passed to WithIdentity, e.g. a type embedding Identity.
+// It returns false if there is no principal or it is of another type.
+//
+// Example:
+//
+//	user, ok := jrpc.GetPrincipal[User](ctx)
+func GetPrincipal[T Principal](ctx context.Context) (T, bool) {
+	p, ok := ctx.Value(ContextKeyIdentity).(T)
+	return p, ok
+}
+
+// authenticate runs the authenticator of the service and returns the request carrying the resulting context.
+// It answers the request and returns false if the authenticator rejects it.
+func (s *Service) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if s.authenticator == nil {
+		return r, true
+	}
+
+	ctx, err := s.authenticator(r.Context(), r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if kind := apperror.KindOf(err); kind != apperror.KindUnknown {
+			status = kind.HTTPStatus()
+		}
+		message, _, _ := apperror.Split(err)
+		http.Error(w, message, status)
+		return r, false
+	}
+	if ctx == nil {
+		return r, true
+	}
+	return r.WithContext(ctx), true
+}
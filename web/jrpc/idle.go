@@ -0,0 +1,70 @@
+package jrpc
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// CloseIdleTimeout is the close code of streams closed by the idle timeouts of WithStreamIdleTimeouts
+const CloseIdleTimeout = 4408
+
+// defaultWriteTimeout limits writing a stream message if no write idle timeout is set
+const defaultWriteTimeout = 10 * time.Second
+
+// errStreamIdle marks the errors of streams that exceeded an idle timeout
+var errStreamIdle = errors.New("stream idle timeout exceeded")
+
+// WithStreamIdleTimeouts closes streams whose peer stalls. A stream is closed if no message
+// is read within the read timeout, or if writing a message blocks longer than the write timeout,
+// e.g. because the client stopped reading. The read timeout applies to every message read
+// from the client, including the request of a server stream; bidirectional and client streams
+// whose clients send messages only occasionally need a correspondingly long read timeout.
+// Without a write timeout each write is limited to 10 seconds. Zero disables a timeout.
+//
+// The stream is closed with the close code CloseIdleTimeout and the context of the method is
+// canceled. A stalled write usually prevents the close frame from reaching the client, which
+// then only observes the closed connection. Multiplexed connections are not affected.
+// It must be called before the service handles requests.
+//
+// Example:
+//
+//	service.WithStreamIdleTimeouts(time.Minute, 30*time.Second)
+func (s *Service) WithStreamIdleTimeouts(read, write time.Duration) *Service {
+	s.streamReadIdle = read
+	s.streamWriteIdle = write
+	return s
+}
+
+// setReadDeadline limits reading the next stream message to the read idle timeout
+func (s *Service) setReadDeadline(conn *websocket.Conn) {
+	if s.streamReadIdle > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.streamReadIdle))
+	}
+}
+
+// setWriteDeadline limits writing the next stream message to the write idle timeout
+func (s *Service) setWriteDeadline(conn *websocket.Conn) {
+	timeout := defaultWriteTimeout
+	if s.streamWriteIdle > 0 {
+		timeout = s.streamWriteIdle
+	}
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+}
+
+// idle reports whether the error is caused by the idle timeout, a disabled timeout never is
+func idle(err error, timeout time.Duration) bool {
+	var ne net.Error
+	return timeout > 0 && errors.As(err, &ne) && ne.Timeout()
+}
+
+// idleError returns the error of a stream that exceeded its idle timeout of the given direction
+func idleError(err error, timeout time.Duration, direction string) error {
+	return apperror.NewErrorf("%s idle timeout of %s exceeded", direction, timeout).
+		AddError(errStreamIdle).
+		AddError(err).
+		WithKind(apperror.KindDeadlineExceeded)
+}
@@ -14,7 +14,13 @@
 //   - Automatic method resolution and dispatch with cached lookups
 //   - Protocol Buffer JSON marshaling/unmarshaling
 //   - Multiple streaming patterns (unary, server, client, bidirectional)
-//   - Context enrichment with HTTP and WebSocket components
+//   - Optional HTTP GET with query parameters for read-only unary methods
+//   - Optional concurrent processing of bidirectional stream messages
+//   - Context enrichment with HTTP and WebSocket components and the descriptor of the called method
+//   - Typed identity of the authenticated caller with WithAuthenticator and GetIdentity
+//   - Trailing metadata of streams with SetTrailer
+//   - Idle timeouts closing streams of stalled peers with WithStreamIdleTimeouts
+//   - Optional multiplexing of unary calls over a single WebSocket connection
 //   - Comprehensive error handling and connection management
 //
 // Usage:
@@ -22,6 +28,7 @@
 //  2. Generate Go code using protoc with the protoc-gen-jrpc plugin
 //  3. Implement the generated Server interface
 //  4. Create a new jRPC service with jrpc.Register(yourServer)
+//     Additional implementations can be added with service.Register(otherServer)
 //  5. Register the HandlerFunc with the web package function WithJRPC
 //
 // Example:
@@ -61,13 +68,16 @@ package jrpc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -86,7 +96,7 @@ import (
 var (
 	logger = logging.GetPackageLogger("jrpc")
 
-	errMethodNotFound           = apperror.NewError("method not found")
+	errMethodNotFound           = apperror.NewError("method not found").WithKind(apperror.KindNotFound)
 	errMethodReflectionNotFound = apperror.NewError("method reflection data not found")
 	errInvalidMethodSignature   = apperror.NewError("invalid method signature")
 	errFirstArgMustBeContext    = apperror.NewError("first argument must be context.Context")
@@ -95,12 +105,14 @@ var (
 	errNilRequest               = apperror.NewError("nil request")
 	errExpectedProtoMessage     = apperror.NewError("expected proto.Message for request")
 	errExpectedError            = apperror.NewError("expected error type in method return value")
+	errMethodPanicked           = apperror.NewError("internal server error").WithKind(apperror.KindInternal)
 
 	// Cached reflection types to avoid repeated type operations
-	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
-	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType        = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
+	dynamicMessageType = reflect.TypeOf((*dynamicpb.Message)(nil))
 
-	// Reusable marshal options to avoid allocation
+	// Default marshal options of a service, also used to convert between message types
 	marshalOpts = protojson.MarshalOptions{
 		EmitUnpopulated: true,
 	}
@@ -124,6 +136,9 @@ var bufferPool = sync.Pool{
 	},
 }
 
+// DefaultStreamReadyFrame is the control message announcing an established server stream
+const DefaultStreamReadyFrame = `{"@jrpc":"stream-ready"}`
+
 // ContextKey represents keys for context values
 type ContextKey string
 
@@ -134,6 +149,8 @@ const (
 	ContextKeyRequest ContextKey = "request"
 	// ContextKeyWebSocketConn key for *websocket.Conn
 	ContextKeyWebSocketConn ContextKey = "websocket"
+	// ContextKeyMethodDescriptor key for the protoreflect.MethodDescriptor of the called method
+	ContextKeyMethodDescriptor ContextKey = "method"
 )
 
 // Service implements the RTLS-Suite API server with support for both
@@ -141,8 +158,35 @@ const (
 // protocol buffer message handling, and context enrichment.
 type Service struct {
 	Server
+	mu      sync.RWMutex
 	methods map[string]*methodInfo                  // cached method information for faster lookup
 	types   map[protoreflect.FullName]proto.Message // cached message types
+
+	marshalOpts   protojson.MarshalOptions   // options used to encode responses
+	unmarshalOpts protojson.UnmarshalOptions // options used to decode requests
+
+	streamReady      bool   // send streamReadyFrame when a server stream is established
+	streamReadyFrame []byte // control message announcing an established server stream
+	streamWorkers    int    // inbound messages of a concurrent stream processed at the same time
+	streamErrorFrame bool   // send an error frame before closing a stream with an error
+
+	streamReadIdle  time.Duration // maximum wait for the next stream message, zero disables the limit
+	streamWriteIdle time.Duration // maximum duration of writing a stream message, zero uses defaultWriteTimeout
+
+	timeoutHeader string        // header carrying the timeout requested for a unary call, empty if disabled
+	maxTimeout    time.Duration // caps the requested timeout of a unary call, zero disables the cap
+
+	cors          *CORSOptions        // cross-origin access of browser clients, nil if disabled
+	accessLog     *AccessLogOptions   // access logging of unary calls, nil if disabled
+	upgrader      *websocket.Upgrader // WebSocket upgrader of the service, nil uses the package upgrader
+	get           map[string]bool     // unary methods callable with GET, keyed by Service.Method
+	validator     Validator           // validates requests before dispatch, nil if disabled
+	resolver      Resolver            // maps request paths to service and method, nil uses the path values
+	authenticator Authenticator       // authenticates requests before dispatch, nil if disabled
+	trailers      sync.Map            // trailers of the open streams, keyed by *websocket.Conn
+
+	maxConnections int64        // WebSocket connections handled at the same time, zero disables the limit
+	connections    atomic.Int64 // WebSocket connections currently handled
 }
 
 // Server represents a jRPC service implementation.
@@ -159,7 +203,11 @@ type methodInfo struct {
 	inputType   reflect.Type
 	outputType  reflect.Type
 	messageType proto.Message
-	validated   bool
+	validate    sync.Once // guards the signature check of unary calls, see unary
+	invalid     error     // result of the signature check
+	stub        bool      // method is promoted from an embedded Unimplemented* type
+	concurrent  bool      // inbound stream messages may be processed concurrently
+	ordering    StreamOrdering
 }
 
 // Register creates a new jrpc service instance and registers the provided
@@ -170,15 +218,46 @@ func Register(s Server) *Service {
 		Server:  s,
 		methods: make(map[string]*methodInfo),
 		types:   make(map[protoreflect.FullName]proto.Message),
+
+		marshalOpts:   marshalOpts,
+		unmarshalOpts: unmarshalOpts,
+
+		streamReadyFrame: []byte(DefaultStreamReadyFrame),
+
+		timeoutHeader: DefaultTimeoutHeader,
+		maxTimeout:    DefaultMaxTimeout,
+	}
+
+	err := service.Register(s)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to register jRPC server")
 	}
 
-	sv := reflect.ValueOf(s)
-	services := s.Descriptor().Services()
+	return service
+}
+
+// Register adds an additional service implementation to the jRPC service.
+// This allows splitting the handlers of large applications across multiple structs.
+// Each method of the services described by the descriptor is dispatched to the
+// server implementing it by name. Methods promoted from embedded Unimplemented*
+// types are overridden by servers implementing them explicitly.
+// An error is returned if a method is explicitly implemented by more than one server.
+func (s *Service) Register(srv Server) error {
+	if srv == nil {
+		return apperror.NewError("server cannot be nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	methods := make(map[string]*methodInfo)
+	sv := reflect.ValueOf(srv)
+	services := srv.Descriptor().Services()
 	for i := 0; i < services.Len(); i++ {
 		sd := services.Get(i)
-		methods := sd.Methods()
-		for j := 0; j < methods.Len(); j++ {
-			md := methods.Get(j)
+		sdMethods := sd.Methods()
+		for j := 0; j < sdMethods.Len(); j++ {
+			md := sdMethods.Get(j)
 			mn := string(md.Name())
 			sn := string(sd.Name())
 
@@ -189,15 +268,24 @@ func Register(s Server) *Service {
 				continue
 			}
 
+			stub := isUnimplementedStub(sv.Type(), mn)
+			if existing, ok := s.methods[key]; ok {
+				if stub {
+					continue
+				}
+				if !existing.stub {
+					return apperror.NewErrorf("method %s is implemented by multiple servers", key).WithKind(apperror.KindAlreadyExists)
+				}
+			}
+
 			mt := rm.Type()
 
-			var pm proto.Message
-			if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName()); err == nil {
-				pm = mt.New().Interface()
-				service.types[md.Input().FullName()] = pm
-			} else {
-				pm = dynamicpb.NewMessage(md.Input())
-				service.types[md.Input().FullName()] = pm
+			if _, ok := s.types[md.Input().FullName()]; !ok {
+				if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName()); err == nil {
+					s.types[md.Input().FullName()] = mt.New().Interface()
+				} else {
+					s.types[md.Input().FullName()] = dynamicpb.NewMessage(md.Input())
+				}
 			}
 
 			var it, ot reflect.Type
@@ -208,19 +296,116 @@ func Register(s Server) *Service {
 				ot = mt.Out(0)
 			}
 
-			service.methods[key] = &methodInfo{
+			methods[key] = &methodInfo{
 				descriptor:  md,
 				method:      rm,
 				reflectType: mt,
 				inputType:   it,
 				outputType:  ot,
-				messageType: pm,
-				validated:   false,
+				messageType: s.types[md.Input().FullName()],
+				stub:        stub,
+			}
+			if cs, ok := srv.(ConcurrentStreamer); ok {
+				methods[key].ordering, methods[key].concurrent = cs.ConcurrentStream(mn)
 			}
 		}
 	}
 
-	return service
+	for key, info := range methods {
+		s.methods[key] = info
+	}
+	return nil
+}
+
+// isUnimplementedStub reports whether the method of the server type is promoted from an
+// embedded Unimplemented* type instead of being declared by the server itself.
+// The embedded fields are walked following the selector rules of the language: a method declared
+// by a struct shadows the method of an embedded field, which is then no longer promoted into the
+// value method set of the struct, even if it is declared with a pointer receiver. Overrides declared
+// with a value receiver and stubs declared with a pointer receiver but embedded by value cannot be
+// told apart from the stub this way, they are treated as stubs.
+func isUnimplementedStub(t reflect.Type, name string) bool {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return false
+	}
+
+	_, inValueSet := st.MethodByName(name)
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if strings.HasPrefix(ft.Name(), "Unimplemented") {
+			if _, ok := reflect.PointerTo(ft).MethodByName(name); !ok {
+				continue
+			}
+		} else if !isUnimplementedStub(ft, name) {
+			continue
+		}
+
+		// The method of the embedded field is shadowed if it is missing from the value method set
+		_, promoted := field.Type.MethodByName(name)
+		return !promoted || inValueSet
+	}
+
+	return false
+}
+
+// WithMarshalOptions sets the protojson options used to encode responses.
+// By default unpopulated fields are emitted and field names are rendered in lowerCamelCase,
+// set UseProtoNames to render the names as defined in the proto file.
+// It must be called before the service handles requests.
+func (s *Service) WithMarshalOptions(opts protojson.MarshalOptions) *Service {
+	s.marshalOpts = opts
+	return s
+}
+
+// WithUnmarshalOptions sets the protojson options used to decode requests.
+// By default unknown fields are discarded.
+// It must be called before the service handles requests.
+func (s *Service) WithUnmarshalOptions(opts protojson.UnmarshalOptions) *Service {
+	s.unmarshalOpts = opts
+	return s
+}
+
+// WithStreamReadyFrame enables a control message that is sent on server streams once the
+// request was read and before the method is invoked. It allows clients to distinguish an
+// established stream that did not produce data yet from a hanging connection.
+// The message is DefaultStreamReadyFrame unless changed with WithStreamReadySentinel.
+// It must be called before the service handles requests.
+func (s *Service) WithStreamReadyFrame(enabled bool) *Service {
+	s.streamReady = enabled
+	return s
+}
+
+// WithStreamReadySentinel sets the control message sent by WithStreamReadyFrame.
+// It must be called before the service handles requests.
+func (s *Service) WithStreamReadySentinel(frame string) *Service {
+	s.streamReadyFrame = []byte(frame)
+	return s
+}
+
+// WithStreamErrorFrame enables a control message describing the error that ends a stream.
+// It is sent as text message right before the close frame, whose reason is limited to
+// 123 bytes and poorly exposed by browsers. The message has the following schema:
+//
+//	{"@jrpc":"error","code":"not_found","message":"user 42 not found"}
+//
+// The code is the apperror.Kind of the error, "unknown" for errors without a kind.
+// Clients that only expect output messages can recognize the frame by the "@jrpc" field.
+// It must be called before the service handles requests.
+func (s *Service) WithStreamErrorFrame(enabled bool) *Service {
+	s.streamErrorFrame = enabled
+	return s
 }
 
 // SetUpgrader allows setting a custom WebSocket upgrader with specific options.
@@ -232,7 +417,7 @@ func SetUpgrader(u websocket.Upgrader) {
 // It automatically detects whether the request is a WebSocket upgrade request
 // and routes to the appropriate handler (unary or websocket).
 //
-// URL format: /{service}/{method}
+// URL format: /{service}/{method}, other formats are resolved with WithResolver
 // Content-Type: application/json (Protocol Buffer JSON format)
 //
 // For WebSocket requests, the Connection header must contain "Upgrade" and
@@ -244,21 +429,14 @@ func SetUpgrader(u websocket.Upgrader) {
 func (s *Service) HandlerFunc(w http.ResponseWriter, r *http.Request) {
 	defer interruption.Catch()
 
+	allowed := s.applyCORS(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
 	if s.isWebSocketRequest(r) {
-		conn, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to upgrade connection to websocket")
-			http.Error(w, "Failed to upgrade to WebSocket", http.StatusBadRequest)
-			return
-		}
-		defer conn.Close()
-
-		s.websocket(w, r, conn)
+		s.upgrade(w, r, allowed, s.websocket)
 		return
 	}
 
@@ -280,19 +458,51 @@ func (s *Service) isWebSocketRequest(r *http.Request) bool {
 // URL format: /{service}/{method}
 // Content-Type: application/json (Protocol Buffer JSON format)
 //
+// Methods enabled with WithGET also accept GET requests carrying the message in the query,
+// other HTTP methods are answered with 405 Method Not Allowed.
+//
+// Clients can bound the execution time with the X-Request-Timeout header (see WithTimeoutHeader).
+// The method context is canceled once the timeout expires and 504 Gateway Timeout is returned.
+//
 // Parameters:
 //   - w: HTTP ResponseWriter for sending the response
 //   - r: HTTP Request containing the API call
 func (s *Service) unary(w http.ResponseWriter, r *http.Request) {
+	var access *accessRecorder
+	if s.accessLog != nil {
+		access = &accessRecorder{ResponseWriter: w, start: time.Now(), status: http.StatusOK}
+		defer s.logAccess(access, r)
+		w = access
+	}
+
+	r, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
 	ctx := WithHTTPContext(r.Context(), w, r)
 
-	service := r.PathValue("service")
-	method := r.PathValue("method")
+	service, method, ok := s.resolve(r)
+	if !ok {
+		http.Error(w, errMethodNotFound.Error(), http.StatusNotFound)
+		return
+	}
 	md, err := s.find(service, method)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	ctx = context.WithValue(ctx, ContextKeyMethodDescriptor, md.descriptor)
+
+	get := s.allowsGET(service, method)
+	if r.Method != http.MethodPost && (r.Method != http.MethodGet || !get) {
+		w.Header().Set("Allow", http.MethodPost)
+		if get {
+			w.Header().Add("Allow", http.MethodGet)
+		}
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
 	msg, err := s.message(md)
 	if err != nil {
@@ -300,7 +510,15 @@ func (s *Service) unary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.ContentLength > 0 {
+	if r.Method == http.MethodGet {
+		err = s.query(r.URL.Query(), msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.ContentLength > 0 && r.Method == http.MethodPost {
 		// Use buffer pool for body reading
 		buf := bufferPool.Get().([]byte)
 		defer bufferPool.Put(buf[:0])
@@ -318,7 +536,7 @@ func (s *Service) unary(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = unmarshalOpts.Unmarshal(buf, msg)
+		err = s.unmarshalOpts.Unmarshal(buf, msg)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -326,9 +544,33 @@ func (s *Service) unary(w http.ResponseWriter, r *http.Request) {
 	}
 	defer apperror.Catch(r.Body.Close, "closing request body failed")
 
+	err = s.validate(msg)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	timeout, err := s.requestTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	resp, err := s.call(ctx, service, method, msg)
+	if access != nil {
+		access.request, access.response = msg, resp
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		http.Error(w, "request timeout exceeded", http.StatusGatewayTimeout)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), apperror.KindOf(err).HTTPStatus())
 		return
 	}
 
@@ -362,8 +604,11 @@ func (s *Service) unary(w http.ResponseWriter, r *http.Request) {
 //   - r: HTTP Request from the WebSocket upgrade
 //   - conn: Established WebSocket connection
 func (s *Service) websocket(w http.ResponseWriter, r *http.Request, conn *websocket.Conn) {
-	service := r.PathValue("service")
-	method := r.PathValue("method")
+	service, method, ok := s.resolve(r)
+	if !ok {
+		s.closeWS(conn, websocket.CloseInternalServerErr, errMethodNotFound)
+		return
+	}
 
 	md, err := s.find(service, method)
 	if err != nil {
@@ -385,15 +630,23 @@ func (s *Service) websocket(w http.ResponseWriter, r *http.Request, conn *websoc
 		return
 	}
 
+	ctx := s.withTrailer(WithWebSocketContext(r.Context(), w, r, conn), conn)
+	ctx = context.WithValue(ctx, ContextKeyMethodDescriptor, md.descriptor)
+	defer s.trailers.Delete(conn)
+
 	switch streamingType {
 	case StreamingTypeBidirectional:
-		s.handleBidirectionalStream(WithWebSocketContext(r.Context(), w, r, conn), conn, m, mt)
+		if md.concurrent && s.streamWorkers > 0 {
+			s.handleConcurrentStream(ctx, conn, m, mt, md.ordering)
+			return
+		}
+		s.handleBidirectionalStream(ctx, conn, m, mt)
 	case StreamingTypeServerStream:
-		s.handleServerStream(WithWebSocketContext(r.Context(), w, r, conn), conn, m, mt, md)
+		s.handleServerStream(ctx, conn, m, mt, md)
 	case StreamingTypeClientStream:
-		s.handleClientStream(WithWebSocketContext(r.Context(), w, r, conn), conn, m, mt)
+		s.handleClientStream(ctx, conn, m, mt)
 	case StreamingTypeUnary:
-		s.closeWS(conn, websocket.CloseInternalServerErr, apperror.NewError("unary methods are not supported over WebSocket"))
+		s.closeWS(conn, websocket.CloseInternalServerErr, apperror.NewError("unary methods are not supported over WebSocket, use the MultiplexHandler"))
 	default:
 		s.closeWS(conn, websocket.CloseInternalServerErr, apperror.NewError("unsupported streaming type"))
 	}
@@ -476,10 +729,50 @@ func GetWebSocketConn(ctx context.Context) (*websocket.Conn, bool) {
 	return conn, ok
 }
 
+// GetMethodDescriptor extracts the descriptor of the called method from the context.
+// It gives access to method-level metadata without resolving the method again, e.g. to
+// read custom proto options like a required scope or the deprecated option.
+//
+// Parameters:
+//   - ctx: The context of a unary call, a multiplexed call or a stream
+//
+// Returns:
+//   - protoreflect.MethodDescriptor: The descriptor of the called method if found
+//   - bool: True if the descriptor was found in the context
+//
+// Example:
+//
+//	md, ok := jrpc.GetMethodDescriptor(ctx)
+//	if ok && md.Options().(*descriptorpb.MethodOptions).GetDeprecated() {
+//		w, _ := jrpc.GetResponseWriter(ctx)
+//		w.Header().Set("Deprecation", "true")
+//	}
+func GetMethodDescriptor(ctx context.Context) (protoreflect.MethodDescriptor, bool) {
+	md, ok := ctx.Value(ContextKeyMethodDescriptor).(protoreflect.MethodDescriptor)
+	return md, ok
+}
+
+// unary checks the signature of a unary method once, concurrent calls of multiplexed
+// connections wait for the first check and share its result
+func (mi *methodInfo) unary() error {
+	mi.validate.Do(func() {
+		mt := mi.reflectType
+		switch {
+		case mt.NumIn() != 2 || mt.NumOut() != 2:
+			mi.invalid = errInvalidMethodSignature
+		case !mt.In(0).Implements(contextType):
+			mi.invalid = errFirstArgMustBeContext
+		case !mt.Out(1).Implements(errorType):
+			mi.invalid = errSecondReturnMustBeError
+		}
+	})
+	return mi.invalid
+}
+
 func (s *Service) call(ctx context.Context, service, method string, req proto.Message) (any, error) {
-	methodInfo, exists := s.methods[service+"."+method]
-	if !exists {
-		return nil, errMethodNotFound
+	methodInfo, err := s.find(service, method)
+	if err != nil {
+		return nil, err
 	}
 
 	if !methodInfo.method.IsValid() {
@@ -489,22 +782,14 @@ func (s *Service) call(ctx context.Context, service, method string, req proto.Me
 	m := methodInfo.method
 	mt := methodInfo.reflectType
 
-	// Validate method signature if not already validated
-	if !methodInfo.validated {
-		if mt.NumIn() != 2 || mt.NumOut() != 2 {
-			return nil, errInvalidMethodSignature
-		}
-		if !mt.In(0).Implements(contextType) {
-			return nil, errFirstArgMustBeContext
-		}
-		if !mt.Out(1).Implements(errorType) {
-			return nil, errSecondReturnMustBeError
-		}
-		methodInfo.validated = true
+	err = methodInfo.unary()
+	if err != nil {
+		return nil, err
 	}
 
+	// Handlers of services without generated code may accept proto.Message or *dynamicpb.Message
 	wanted := mt.In(1)
-	if wanted.Kind() != reflect.Ptr {
+	if wanted.Kind() != reflect.Ptr && wanted.Kind() != reflect.Interface {
 		return nil, errRequestMustBePointer
 	}
 
@@ -514,29 +799,41 @@ func (s *Service) call(ctx context.Context, service, method string, req proto.Me
 	}
 
 	if !reqVal.Type().AssignableTo(wanted) {
+		if wanted.Kind() == reflect.Interface {
+			return nil, apperror.NewErrorf("request message %T does not implement %s", req, wanted)
+		}
+
 		// Convert via JSON round-trip using protojson to the expected type.
 		// Use buffer pool for better performance
 		buf := bufferPool.Get().([]byte)
 		defer bufferPool.Put(buf[:0])
 
-		reqPtr := reflect.New(wanted.Elem())
+		var pm proto.Message
+		if wanted == dynamicMessageType {
+			pm = dynamicpb.NewMessage(req.ProtoReflect().Descriptor())
+		} else {
+			var ok bool
+			pm, ok = reflect.New(wanted.Elem()).Interface().(proto.Message)
+			if !ok {
+				return nil, errExpectedProtoMessage
+			}
+		}
+
 		b, err := marshalOpts.Marshal(req)
 		if err != nil {
 			return nil, err
 		}
-		pm, ok := reqPtr.Interface().(proto.Message)
-		if !ok {
-			return nil, errExpectedProtoMessage
-		}
 		if err := unmarshalOpts.Unmarshal(b, pm); err != nil {
 			return nil, err
 		}
-		reqVal = reqPtr
+		reqVal = reflect.ValueOf(pm)
 	}
 
-	outs := m.Call([]reflect.Value{reflect.ValueOf(ctx), reqVal})
+	outs, err := invoke(m, reflect.ValueOf(ctx), reqVal)
+	if err != nil {
+		return nil, err
+	}
 	res := outs[0].Interface()
-	var err error
 	if e := outs[1].Interface(); e != nil {
 		var ok bool
 		err, ok = e.(error)
@@ -550,11 +847,27 @@ func (s *Service) call(ctx context.Context, service, method string, req proto.Me
 		l = logger.Warn().Err(err)
 	}
 
-	l.Field("service", service).Field("method", method).Msg("jRPC method called")
+	l.Fields(logging.FieldsFromContext(ctx)...).Field("service", service).Field("method", method).Msg("jRPC method called")
 	return res, err
 }
 
+// invoke calls the method and recovers from panics in the service implementation
+// The panic is logged with its stack, the caller only receives a generic internal error
+func invoke(m reflect.Value, args ...reflect.Value) (outs []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error().Field("panic", r).Field("stack", string(debug.Stack())).Msg("jRPC method panicked")
+			err = errMethodPanicked
+		}
+	}()
+
+	return m.Call(args), nil
+}
+
 func (s *Service) find(service, method string) (*methodInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	md, exists := s.methods[service+"."+method]
 	if !exists {
 		return nil, errMethodNotFound
@@ -571,39 +884,66 @@ func (s *Service) message(md *methodInfo) (proto.Message, error) {
 	return proto.Clone(md.messageType), nil
 }
 
+// marshal encodes the result of a method, which is a pointer to a proto message or a
+// message returned by value as permitted by validateMethodSignature
 func (s *Service) marshal(m any) ([]byte, error) {
+	msg, err := protoMessage(m)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.marshalOpts.Marshal(msg)
+	if err != nil {
+		return nil, apperror.NewError("failed to marshal response").AddError(err)
+	}
+
+	return out, nil
+}
+
+// protoMessage returns the proto message of a method result
+// Messages returned by value are copied to a new message, since only pointers implement proto.Message.
+// Nil pointers are returned as they are and encoded as empty messages.
+func protoMessage(m any) (proto.Message, error) {
 	if m == nil {
 		return nil, apperror.NewError("cannot marshal nil message")
 	}
 
-	switch msg := m.(type) {
-	case proto.Message:
-		out, err := marshalOpts.Marshal(msg)
-		if err != nil {
-			return nil, apperror.NewError("failed to marshal response").AddError(err)
-		}
+	if msg, ok := m.(proto.Message); ok {
+		return msg, nil
+	}
 
-		return out, nil
-	default:
-		return nil, apperror.NewError("failed to marshal response")
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Struct {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		if msg, ok := ptr.Interface().(proto.Message); ok {
+			return msg, nil
+		}
 	}
+
+	return nil, apperror.NewErrorf("failed to marshal response of type %T, it is not a proto message", m)
 }
 
 // handleBidirectionalStream handles bidirectional streaming WebSocket connections
 func (s *Service) handleBidirectionalStream(ctx context.Context, conn *websocket.Conn, m reflect.Value, mt reflect.Type) {
 	inType, outType := mt.In(1), mt.In(2)
-	inPtr, outPtr := inType.Elem(), outType.Elem()
+	inPtr := inType.Elem()
 	in, out := reflect.MakeChan(inType, 0), reflect.MakeChan(outType, 0)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	read := s.startMessageReader(ctx, conn, in, inPtr)
-	write := s.startMessageWriter(ctx, conn, out, outPtr)
+	write := s.startMessageWriter(ctx, cancel, conn, out)
 
 	done := make(chan error, 1)
 	go func() {
-		outs := m.Call([]reflect.Value{reflect.ValueOf(ctx), in, out})
+		outs, err := invoke(m, reflect.ValueOf(ctx), in, out)
+		if err != nil {
+			done <- err
+			out.Close()
+			return
+		}
 		e := outs[0].Interface()
 		if e != nil {
 			err, ok := e.(error)
@@ -633,9 +973,7 @@ func (s *Service) handleBidirectionalStream(ctx context.Context, conn *websocket
 
 // handleServerStream handles server streaming WebSocket connections
 func (s *Service) handleServerStream(ctx context.Context, conn *websocket.Conn, m reflect.Value, mt reflect.Type, md *methodInfo) {
-	outType := mt.In(2)
-	outPtr := outType.Elem()
-	out := reflect.MakeChan(outType, 0)
+	out := reflect.MakeChan(mt.In(2), 0)
 
 	msg, err := s.message(md)
 	if err != nil {
@@ -665,11 +1003,6 @@ func (s *Service) handleServerStream(ctx context.Context, conn *websocket.Conn,
 		}
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	write := s.startMessageWriter(ctx, conn, out, outPtr)
-
 	wanted := mt.In(1)
 	reqVal := reflect.ValueOf(msg)
 	if !reqVal.Type().AssignableTo(wanted) {
@@ -677,9 +1010,38 @@ func (s *Service) handleServerStream(ctx context.Context, conn *websocket.Conn,
 		return
 	}
 
+	err = s.validate(msg)
+	if err != nil {
+		s.closeWS(conn, websocket.ClosePolicyViolation, apperror.NewError(err.Error()).WithKind(apperror.KindInvalidArgument))
+		return
+	}
+
+	// Tell the client that the stream is established before the first message is produced
+	if s.streamReady {
+		s.setWriteDeadline(conn)
+		err = conn.WriteMessage(websocket.TextMessage, s.streamReadyFrame)
+		if idle(err, s.streamWriteIdle) {
+			err = idleError(err, s.streamWriteIdle, "write")
+		}
+		if err != nil {
+			s.closeWS(conn, websocket.CloseInternalServerErr, apperror.Wrap(err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	write := s.startMessageWriter(ctx, cancel, conn, out)
+
 	done := make(chan error, 1)
 	go func() {
-		outs := m.Call([]reflect.Value{reflect.ValueOf(ctx), reqVal, out})
+		outs, err := invoke(m, reflect.ValueOf(ctx), reqVal, out)
+		if err != nil {
+			done <- err
+			out.Close()
+			return
+		}
 		e := outs[0].Interface()
 		if e != nil {
 			err, ok := e.(error)
@@ -719,7 +1081,14 @@ func (s *Service) handleClientStream(ctx context.Context, conn *websocket.Conn,
 		err  error
 	}, 1)
 	go func() {
-		outs := m.Call([]reflect.Value{reflect.ValueOf(ctx), in})
+		outs, err := invoke(m, reflect.ValueOf(ctx), in)
+		if err != nil {
+			done <- struct {
+				resp any
+				err  error
+			}{nil, err}
+			return
+		}
 		var res any = outs[0].Interface()
 		e := outs[1].Interface()
 		if e != nil {
@@ -755,7 +1124,7 @@ func (s *Service) handleClientStream(ctx context.Context, conn *websocket.Conn,
 	}
 
 	if final.resp != nil {
-		err := s.writeWSMessage(conn, reflect.ValueOf(final.resp), reflect.TypeOf(final.resp))
+		err := s.writeWSMessage(conn, final.resp)
 		if err != nil {
 			s.closeWS(conn, websocket.CloseInternalServerErr, apperror.Wrap(err))
 			return
@@ -764,8 +1133,12 @@ func (s *Service) handleClientStream(ctx context.Context, conn *websocket.Conn,
 	s.closeWS(conn, websocket.CloseNormalClosure, nil)
 }
 func (s *Service) readWSMessage(conn *websocket.Conn, msgPtr reflect.Value) error {
+	s.setReadDeadline(conn)
 	messageType, payload, err := conn.ReadMessage()
 	if err != nil {
+		if idle(err, s.streamReadIdle) {
+			return idleError(err, s.streamReadIdle, "read")
+		}
 		return apperror.NewError("failed to read websocket message").AddError(err)
 	}
 
@@ -783,7 +1156,7 @@ func (s *Service) readWSMessage(conn *websocket.Conn, msgPtr reflect.Value) erro
 	}
 
 	if len(payload) > 0 {
-		err = unmarshalOpts.Unmarshal(payload, msg)
+		err = s.unmarshalOpts.Unmarshal(payload, msg)
 		if err != nil {
 			return apperror.NewError("failed to unmarshal websocket message").AddError(err)
 		}
@@ -826,31 +1199,27 @@ func (s *Service) startMessageReader(ctx context.Context, conn *websocket.Conn,
 	return read
 }
 
-// writeWSMessage marshals and writes a proto message to the WebSocket
-func (s *Service) writeWSMessage(conn *websocket.Conn, val reflect.Value, t reflect.Type) error {
-	var out any
-	switch t.Kind() {
-	case reflect.Ptr, reflect.Struct:
-		out = val.Interface()
-	default:
-		return apperror.NewError("unsupported type for websocket message")
-	}
-
-	data, err := s.marshal(out)
+// writeWSMessage marshals and writes a proto message, or a pointer to one, to the WebSocket
+func (s *Service) writeWSMessage(conn *websocket.Conn, msg any) error {
+	data, err := s.marshal(msg)
 	if err != nil {
 		return apperror.Wrap(err)
 	}
 
-	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	s.setWriteDeadline(conn)
 	err = conn.WriteMessage(websocket.TextMessage, data)
+	if idle(err, s.streamWriteIdle) {
+		return idleError(err, s.streamWriteIdle, "write")
+	}
 	if err != nil {
 		return apperror.Wrap(err)
 	}
 	return nil
 }
 
-// startMessageWriter starts a goroutine to write messages from a channel to WebSocket
-func (s *Service) startMessageWriter(ctx context.Context, conn *websocket.Conn, outChan reflect.Value, outPtr reflect.Type) <-chan struct{} {
+// startMessageWriter starts a goroutine to write messages from a channel to WebSocket.
+// If a write fails the connection is closed and cancel is called to end the stream.
+func (s *Service) startMessageWriter(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, outChan reflect.Value) <-chan struct{} {
 	write := make(chan struct{})
 	go func() {
 		defer close(write)
@@ -861,14 +1230,18 @@ func (s *Service) startMessageWriter(ctx context.Context, conn *websocket.Conn,
 			default:
 			}
 
-			val, ok := outChan.Recv()
-			if !ok {
+			chosen, val, ok := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectRecv, Chan: outChan},
+				{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			})
+			if chosen != 0 || !ok {
 				return
 			}
 
-			err := s.writeWSMessage(conn, val, outPtr)
+			err := s.writeWSMessage(conn, val.Interface())
 			if err != nil {
 				s.closeWS(conn, websocket.CloseInternalServerErr, apperror.Wrap(err))
+				cancel()
 				return
 			}
 		}
@@ -877,14 +1250,30 @@ func (s *Service) startMessageWriter(ctx context.Context, conn *websocket.Conn,
 }
 
 func (s *Service) closeWS(conn *websocket.Conn, code int, err error) {
+	if errors.Is(err, errStreamIdle) {
+		code = CloseIdleTimeout
+	}
+
 	var reason string
-	if err != nil && !errors.Is(err, websocket.ErrCloseSent) && !errors.Is(err, net.ErrClosed) {
+	failed := err != nil && !errors.Is(err, websocket.ErrCloseSent) && !errors.Is(err, net.ErrClosed)
+	metadata := s.trailer(conn)
+	if len(metadata) > 0 {
+		if failed || len(metadata) > maxCloseReason {
+			s.writeTrailerFrame(conn, metadata)
+		} else {
+			reason = string(metadata)
+		}
+	}
+	if failed {
 		reason, _, _ = apperror.Split(err)
 		log.Trace().Field("code", code).Err(err).Msg("websocket connection closing with error")
+		if s.streamErrorFrame {
+			s.writeErrorFrame(conn, err, reason)
+		}
 	}
-	if len(reason) > 123 {
+	if len(reason) > maxCloseReason {
 		log.Warn().Field("length", len(reason)).Field("code", code).Field("reason", reason).Msg("close reason too long, truncating to 123 bytes")
-		reason = reason[:123] // Close reason must be <= 123 bytes
+		reason = reason[:maxCloseReason]
 	}
 	err = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
 	if err != nil && !errors.Is(err, websocket.ErrCloseSent) && !errors.Is(err, net.ErrClosed) {
@@ -896,6 +1285,28 @@ func (s *Service) closeWS(conn *websocket.Conn, code int, err error) {
 	}
 }
 
+// errorFrame is the control message describing the error that ends a stream
+type errorFrame struct {
+	Type    string `json:"@jrpc"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeErrorFrame sends the error frame enabled by WithStreamErrorFrame
+func (s *Service) writeErrorFrame(conn *websocket.Conn, cause error, message string) {
+	frame, err := json.Marshal(errorFrame{Type: "error", Code: string(apperror.KindOf(cause)), Message: message})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode websocket error frame")
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	err = conn.WriteMessage(websocket.TextMessage, frame)
+	if err != nil {
+		log.Trace().Err(err).Msg("failed to send websocket error frame")
+	}
+}
+
 // StreamingType represents the type of streaming for a method
 type StreamingType int
 
@@ -0,0 +1,1441 @@
+package jrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/logging"
+	"github.com/valentin-kaiser/go-core/web/jrpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testDescriptor describes a service with methods using google.protobuf.Empty messages
+// Methods with a name ending in Stream are server streaming, methods with a name ending
+// in Bidi are bidirectional streaming and use google.protobuf.StringValue messages,
+// methods with a name ending in Upload are client streaming and use google.protobuf.StringValue messages,
+// methods with a name ending in Echo are unary and use google.protobuf.FieldDescriptorProto messages,
+// methods with a name ending in Mirror are unary and use google.protobuf.FileDescriptorProto messages
+func testDescriptor(t *testing.T, methods ...string) protoreflect.FileDescriptor {
+	t.Helper()
+
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("Test")}
+	for _, m := range methods {
+		if strings.HasSuffix(m, "Bidi") {
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:            proto.String(m),
+				InputType:       proto.String(".google.protobuf.StringValue"),
+				OutputType:      proto.String(".google.protobuf.StringValue"),
+				ClientStreaming: proto.Bool(true),
+				ServerStreaming: proto.Bool(true),
+			})
+			continue
+		}
+		if strings.HasSuffix(m, "Upload") {
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:            proto.String(m),
+				InputType:       proto.String(".google.protobuf.StringValue"),
+				OutputType:      proto.String(".google.protobuf.StringValue"),
+				ClientStreaming: proto.Bool(true),
+			})
+			continue
+		}
+		if strings.HasSuffix(m, "Mirror") {
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:       proto.String(m),
+				InputType:  proto.String(".google.protobuf.FileDescriptorProto"),
+				OutputType: proto.String(".google.protobuf.FileDescriptorProto"),
+			})
+			continue
+		}
+		if strings.HasSuffix(m, "Echo") {
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:       proto.String(m),
+				InputType:  proto.String(".google.protobuf.FieldDescriptorProto"),
+				OutputType: proto.String(".google.protobuf.FieldDescriptorProto"),
+			})
+			continue
+		}
+		service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+			Name:            proto.String(m),
+			InputType:       proto.String(".google.protobuf.Empty"),
+			OutputType:      proto.String(".google.protobuf.Empty"),
+			ServerStreaming: proto.Bool(strings.HasSuffix(m, "Stream")),
+		})
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("jrpc_test.proto"),
+		Package:    proto.String("jrpctest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/empty.proto", "google/protobuf/wrappers.proto", "google/protobuf/descriptor.proto"},
+		Service:    []*descriptorpb.ServiceDescriptorProto{service},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build file descriptor: %v", err)
+	}
+	return fd
+}
+
+type panicServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (p *panicServer) Descriptor() protoreflect.FileDescriptor {
+	return p.fd
+}
+
+func (p *panicServer) Panic(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	panic("handler failure")
+}
+
+func (p *panicServer) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func (p *panicServer) Echo(_ context.Context, in *descriptorpb.FieldDescriptorProto) (*descriptorpb.FieldDescriptorProto, error) {
+	return &descriptorpb.FieldDescriptorProto{Name: proto.String("echo"), JsonName: in.JsonName}, nil
+}
+
+func (p *panicServer) FileMirror(_ context.Context, in *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	return in, nil
+}
+
+func (p *panicServer) WatchStream(_ context.Context, _ *emptypb.Empty, out chan *emptypb.Empty) error {
+	out <- &emptypb.Empty{}
+	return nil
+}
+
+func (p *panicServer) FailStream(_ context.Context, _ *emptypb.Empty, out chan *emptypb.Empty) error {
+	out <- &emptypb.Empty{}
+	return apperror.NewError("watched item not found").WithKind(apperror.KindNotFound)
+}
+
+func TestHandlerRecoversFromPanic(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Panic", "Ping")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/Test/Panic", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	resp, err = http.Post(server.URL+"/Test/Ping", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("server did not stay up after panic: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Ping")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Unknown fields are discarded by default
+	resp, err := http.Post(server.URL+"/Test/Ping", "application/json", strings.NewReader(`{"unknown":1}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	service.WithUnmarshalOptions(protojson.UnmarshalOptions{})
+	resp, err = http.Post(server.URL+"/Test/Ping", "application/json", strings.NewReader(`{"unknown":1}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestStreamReadyFrame(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "WatchStream")}).
+		WithStreamReadyFrame(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/Test/WatchStream", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte("{}"))
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	expected := []string{jrpc.DefaultStreamReadyFrame, "{}"}
+	for _, want := range expected {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("expected message %s, got %s", want, data)
+		}
+	}
+}
+
+// concurrentServer processes the messages of its bidirectional stream concurrently
+type concurrentServer struct {
+	fd       protoreflect.FileDescriptor
+	ordering jrpc.StreamOrdering
+	active   int32
+	peak     int32
+}
+
+func (c *concurrentServer) Descriptor() protoreflect.FileDescriptor {
+	return c.fd
+}
+
+func (c *concurrentServer) ConcurrentStream(method string) (jrpc.StreamOrdering, bool) {
+	return c.ordering, method == "EchoBidi"
+}
+
+func (c *concurrentServer) EchoBidi(_ context.Context, in chan *wrapperspb.StringValue, out chan *wrapperspb.StringValue) error {
+	for msg := range in {
+		active := atomic.AddInt32(&c.active, 1)
+		for {
+			peak := atomic.LoadInt32(&c.peak)
+			if active <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, active) {
+				break
+			}
+		}
+
+		// Earlier messages take longer so that they finish last
+		delay, _ := strconv.Atoi(msg.Value)
+		time.Sleep(time.Duration(5-delay) * 20 * time.Millisecond)
+		atomic.AddInt32(&c.active, -1)
+		out <- msg
+	}
+	return nil
+}
+
+func TestStreamWorkers(t *testing.T) {
+	for _, ordering := range []jrpc.StreamOrdering{jrpc.StreamOrdered, jrpc.StreamUnordered} {
+		server := &concurrentServer{fd: testDescriptor(t, "EchoBidi"), ordering: ordering}
+		service := jrpc.Register(server).WithStreamWorkers(4)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+		httpServer := httptest.NewServer(mux)
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/Test/EchoBidi", nil)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+
+		for i := 1; i <= 4; i++ {
+			err = conn.WriteMessage(websocket.TextMessage, []byte(`"`+strconv.Itoa(i)+`"`))
+			if err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+		}
+
+		var received []string
+		for i := 0; i < 4; i++ {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			received = append(received, string(data))
+		}
+		conn.Close()
+		httpServer.Close()
+
+		if peak := atomic.LoadInt32(&server.peak); peak < 2 {
+			t.Errorf("expected messages to be processed concurrently, peak was %d", peak)
+		}
+
+		got := strings.Join(received, ",")
+		if ordering == jrpc.StreamOrdered && got != `"1","2","3","4"` {
+			t.Errorf("expected ordered responses, got %s", got)
+		}
+		if ordering == jrpc.StreamUnordered && got == `"1","2","3","4"` {
+			t.Errorf("expected responses in completion order, got %s", got)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Ping", "WatchStream")}).
+		WithCORS(jrpc.CORSOptions{
+			AllowedOrigins: []string{"https://app.example.com"},
+			MaxAge:         time.Hour,
+		})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodOptions, server.URL+"/Test/Ping", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("preflight failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected allowed origin, got %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPost) {
+		t.Errorf("expected POST to be allowed, got %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("expected max age 3600, got %q", got)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/Test/Ping", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers for a foreign origin, got %q", got)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/Test/WatchStream"
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://evil.example.com"}})
+	if err == nil {
+		t.Fatal("expected websocket upgrade from a foreign origin to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status %d for a foreign origin", http.StatusForbidden)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("expected websocket upgrade from an allowed origin to succeed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestStreamErrorFrame(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "FailStream")}).
+		WithStreamErrorFrame(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/Test/FailStream", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte("{}"))
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "{}" {
+		t.Fatalf("expected output message, got %s (%v)", data, err)
+	}
+
+	_, data, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("expected error frame, got %v", err)
+	}
+	var frame map[string]string
+	err = json.Unmarshal(data, &frame)
+	if err != nil {
+		t.Fatalf("failed to decode error frame %s: %v", data, err)
+	}
+	if frame["@jrpc"] != "error" || frame["code"] != string(apperror.KindNotFound) || frame["message"] != "watched item not found" {
+		t.Errorf("unexpected error frame %s", data)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+		t.Errorf("expected close with internal server error, got %v", err)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logging.SetPackageAdapter("jrpc", logging.NewStandardAdapterWithLogger(log.New(&buf, "", 0)))
+	defer logging.SetPackageAdapter("jrpc", logging.NewNoOpAdapter())
+
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Echo")}).
+		WithAccessLog(jrpc.AccessLogOptions{Payload: true, Redact: []string{"name", "options.ctype"}})
+
+	body := `{"name":"secret","jsonName":"visible","options":{"ctype":"CORD"}}`
+	r := httptest.NewRequest(http.MethodPost, "/Test/Echo", strings.NewReader(body))
+	r.SetPathValue("service", "Test")
+	r.SetPathValue("method", "Echo")
+	w := httptest.NewRecorder()
+	service.HandlerFunc(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"name":"echo"`) {
+		t.Errorf("expected unredacted response body, got %s", w.Body.String())
+	}
+
+	entry := buf.String()
+	for _, field := range []string{"jRPC access", "service=Test", "method=Echo", "status=200", "request_size=" + strconv.Itoa(len(body)), "response_size=" + strconv.Itoa(w.Body.Len()), "duration=", `"jsonName":"visible"`, `"ctype":"[REDACTED]"`} {
+		if !strings.Contains(entry, field) {
+			t.Errorf("expected %q in access log, got %q", field, entry)
+		}
+	}
+	if strings.Contains(entry, "secret") || strings.Contains(entry, "echo") || strings.Contains(entry, "CORD") {
+		t.Errorf("expected redacted messages in access log, got %q", entry)
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "WatchStream")})
+	service.WithUpgrader(service.Upgrader(jrpc.UpgraderOptions{
+		EnableCompression: true,
+		Subprotocols:      []string{"jrpc"},
+		AllowedOrigins:    []string{"https://app.example.com"},
+	}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/{service}/{method}", service.WebSocketHandler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ws/Test/WatchStream")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("expected status %d, got %d", http.StatusUpgradeRequired, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Upgrade"); got != "websocket" {
+		t.Errorf("expected Upgrade header websocket, got %q", got)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/Test/WatchStream"
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://evil.example.com"}})
+	if err == nil {
+		t.Fatal("expected websocket upgrade from a foreign origin to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status %d for a foreign origin", http.StatusForbidden)
+	}
+
+	dialer := websocket.Dialer{EnableCompression: true, Subprotocols: []string{"jrpc"}}
+	conn, _, err := dialer.Dial(url, http.Header{"Origin": []string{"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("expected websocket upgrade from an allowed origin to succeed: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "jrpc" {
+		t.Errorf("expected subprotocol jrpc, got %q", conn.Subprotocol())
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("expected websocket upgrade without origin to succeed: %v", err)
+	}
+	conn.Close()
+}
+
+// dynamicServer implements a service whose messages are only available as descriptors
+type dynamicServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (d *dynamicServer) Descriptor() protoreflect.FileDescriptor {
+	return d.fd
+}
+
+func (d *dynamicServer) Shout(_ context.Context, in proto.Message) (proto.Message, error) {
+	text := in.ProtoReflect().Get(in.ProtoReflect().Descriptor().Fields().ByName("text")).String()
+	if text == "" {
+		return nil, apperror.NewError("text is required").WithKind(apperror.KindInvalidArgument)
+	}
+
+	out := dynamicpb.NewMessage(d.fd.Messages().ByName("ShoutResponse"))
+	out.Set(out.Descriptor().Fields().ByName("text"), protoreflect.ValueOfString(strings.ToUpper(text)))
+	return out, nil
+}
+
+func (d *dynamicServer) Whisper(_ context.Context, in *dynamicpb.Message) (*dynamicpb.Message, error) {
+	out := dynamicpb.NewMessage(d.fd.Messages().ByName("ShoutResponse"))
+	out.Set(out.Descriptor().Fields().ByName("text"), protoreflect.ValueOfString(strings.ToLower(in.Get(in.Descriptor().Fields().ByName("text")).String())))
+	return out, nil
+}
+
+func TestDynamicMessages(t *testing.T) {
+	message := func(name string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("text"),
+				JsonName: proto.String("text"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}},
+		}
+	}
+	method := func(name string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".jrpcdynamic.ShoutRequest"),
+			OutputType: proto.String(".jrpcdynamic.ShoutResponse"),
+		}
+	}
+
+	// The file is not registered, so its messages have no Go types
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("jrpc_dynamic_test.proto"),
+		Package:     proto.String("jrpcdynamic"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{message("ShoutRequest"), message("ShoutResponse")},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("Dynamic"),
+			Method: []*descriptorpb.MethodDescriptorProto{method("Shout"), method("Whisper")},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build file descriptor: %v", err)
+	}
+
+	service := jrpc.Register(&dynamicServer{fd: fd})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		body   string
+		status int
+		want   string
+	}{
+		{method: "Shout", body: `{"text":"hello"}`, status: http.StatusOK, want: `{"text":"HELLO"}`},
+		{method: "Shout", body: `{}`, status: http.StatusBadRequest},
+		{method: "Shout", body: `{"unknown":1,"text":"hi"}`, status: http.StatusOK, want: `{"text":"HI"}`},
+		{method: "Whisper", body: `{"text":"HELLO"}`, status: http.StatusOK, want: `{"text":"hello"}`},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Post(server.URL+"/Dynamic/"+tt.method, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tt.method, tt.body, tt.status, resp.StatusCode, buf.String())
+			continue
+		}
+		if tt.want == "" {
+			continue
+		}
+
+		var got, want map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response %s: %v", buf.String(), err)
+		}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatalf("failed to decode expected response: %v", err)
+		}
+		if got["text"] != want["text"] {
+			t.Errorf("%s %s: expected %s, got %s", tt.method, tt.body, tt.want, buf.String())
+		}
+	}
+}
+
+func TestGET(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Ping", "FileMirror")}).
+		WithGET("Test.FileMirror")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	query := "name=a.proto&dependency=b.proto&dependency=c.proto&publicDependency=1" +
+		"&options.java_multiple_files=true&options.optimizeFor=CODE_SIZE&unknown=1"
+	resp, err := http.Get(server.URL + "/Test/FileMirror?" + query)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, resp.StatusCode, buf.String())
+	}
+
+	var got descriptorpb.FileDescriptorProto
+	err = protojson.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("failed to decode response %s: %v", buf.String(), err)
+	}
+	if got.GetName() != "a.proto" || len(got.GetDependency()) != 2 || got.GetDependency()[1] != "c.proto" {
+		t.Errorf("expected name and dependencies from the query, got %s", buf.String())
+	}
+	if len(got.GetPublicDependency()) != 1 || got.GetPublicDependency()[0] != 1 {
+		t.Errorf("expected public dependency by its JSON name, got %v", got.GetPublicDependency())
+	}
+	if !got.GetOptions().GetJavaMultipleFiles() || got.GetOptions().GetOptimizeFor() != descriptorpb.FileOptions_CODE_SIZE {
+		t.Errorf("expected nested options from the query, got %s", buf.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		status int
+	}{
+		{name: "repeated singular field", method: http.MethodGet, url: "/Test/FileMirror?name=a&name=b", status: http.StatusBadRequest},
+		{name: "invalid boolean", method: http.MethodGet, url: "/Test/FileMirror?options.java_multiple_files=yes", status: http.StatusBadRequest},
+		{name: "invalid number", method: http.MethodGet, url: "/Test/FileMirror?public_dependency=x", status: http.StatusBadRequest},
+		{name: "path into scalar", method: http.MethodGet, url: "/Test/FileMirror?name.value=a", status: http.StatusBadRequest},
+		{name: "GET of a POST method", method: http.MethodGet, url: "/Test/Ping", status: http.StatusMethodNotAllowed},
+		{name: "PUT of a GET method", method: http.MethodPut, url: "/Test/FileMirror", status: http.StatusMethodNotAllowed},
+		{name: "POST of a GET method", method: http.MethodPost, url: "/Test/FileMirror", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, server.URL+tt.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+		if tt.status == http.StatusMethodNotAllowed && !strings.Contains(strings.Join(resp.Header.Values("Allow"), ","), http.MethodPost) {
+			t.Errorf("%s: expected POST in Allow header, got %v", tt.name, resp.Header.Values("Allow"))
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Echo")}).
+		WithValidation(jrpc.ValidatorFunc(func(msg proto.Message) error {
+			field, ok := msg.(*descriptorpb.FieldDescriptorProto)
+			if !ok {
+				return apperror.NewError("unexpected message type")
+			}
+			if len(field.GetName()) < 3 {
+				return &jrpc.ValidationError{Violations: []jrpc.FieldViolation{
+					{Field: "name", Rule: "string.min_len", Message: "value length must be at least 3 characters"},
+				}}
+			}
+			return nil
+		}))
+
+	call := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/Test/Echo", strings.NewReader(body))
+		r.SetPathValue("service", "Test")
+		r.SetPathValue("method", "Echo")
+		w := httptest.NewRecorder()
+		service.HandlerFunc(w, r)
+		return w
+	}
+
+	w := call(`{"name":"ab"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var body struct {
+		Message    string                `json:"message"`
+		Violations []jrpc.FieldViolation `json:"violations"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("expected JSON error body, got %s: %v", w.Body.String(), err)
+	}
+	if len(body.Violations) != 1 || body.Violations[0].Field != "name" || body.Violations[0].Rule != "string.min_len" {
+		t.Errorf("expected the name violation, got %+v", body.Violations)
+	}
+
+	w = call(`{"name":"abc"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for a valid request, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestResolver(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Ping", "FileMirror")}).
+		WithResolver(func(path string) (string, string, bool) {
+			parts := strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/")
+			if len(parts) != 2 {
+				return "", "", false
+			}
+			pascal := func(s string) string {
+				var b strings.Builder
+				for _, word := range strings.Split(s, "-") {
+					if word != "" {
+						b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+					}
+				}
+				return b.String()
+			}
+			return pascal(parts[0]), pascal(parts[1]), true
+		})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/api/v1/test/ping", status: http.StatusOK},
+		{path: "/api/v1/test/file-mirror", status: http.StatusOK},
+		{path: "/api/v1/test/unknown", status: http.StatusNotFound},
+		{path: "/api/v1/test", status: http.StatusNotFound},
+		{path: "/api/v1/test/ping/extra", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(server.URL+tt.path, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+// uploadServer joins the first two messages of its client streams
+type uploadServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (u *uploadServer) Descriptor() protoreflect.FileDescriptor {
+	return u.fd
+}
+
+func (u *uploadServer) join(in chan *wrapperspb.StringValue) string {
+	var parts []string
+	for msg := range in {
+		parts = append(parts, msg.GetValue())
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+func (u *uploadServer) JoinUpload(_ context.Context, in chan *wrapperspb.StringValue) (wrapperspb.StringValue, error) {
+	return wrapperspb.StringValue{Value: u.join(in)}, nil
+}
+
+func (u *uploadServer) JoinPtrUpload(_ context.Context, in chan *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String(u.join(in)), nil
+}
+
+func TestClientStreamResponse(t *testing.T) {
+	service := jrpc.Register(&uploadServer{fd: testDescriptor(t, "JoinUpload", "JoinPtrUpload")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, method := range []string{"JoinUpload", "JoinPtrUpload"} {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/Test/"+method, nil)
+		if err != nil {
+			t.Fatalf("%s: dial failed: %v", method, err)
+		}
+
+		for _, msg := range []string{`"a"`, `"b"`} {
+			err = conn.WriteMessage(websocket.TextMessage, []byte(msg))
+			if err != nil {
+				t.Fatalf("%s: write failed: %v", method, err)
+			}
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", method, err)
+		}
+		if string(data) != `"a+b"` {
+			t.Errorf("%s: expected response \"a+b\", got %s", method, data)
+		}
+
+		_, _, err = conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			t.Errorf("%s: expected normal closure after the response, got %v", method, err)
+		}
+		conn.Close()
+	}
+}
+
+type trailerServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (s *trailerServer) Descriptor() protoreflect.FileDescriptor {
+	return s.fd
+}
+
+func (s *trailerServer) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, jrpc.SetTrailer(ctx, "X-Processed", "1")
+}
+
+func (s *trailerServer) SummaryStream(ctx context.Context, _ *emptypb.Empty, out chan *emptypb.Empty) error {
+	out <- &emptypb.Empty{}
+	return jrpc.SetTrailer(ctx, "processed", "1")
+}
+
+func (s *trailerServer) LargeStream(ctx context.Context, _ *emptypb.Empty, out chan *emptypb.Empty) error {
+	out <- &emptypb.Empty{}
+	return jrpc.SetTrailer(ctx, "summary", strings.Repeat("x", 200))
+}
+
+func TestTrailer(t *testing.T) {
+	service := jrpc.Register(&trailerServer{fd: testDescriptor(t, "Ping", "SummaryStream", "LargeStream")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/Test/Ping", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Processed") != "1" {
+		t.Errorf("expected trailer as response header of unary call, got %v", resp.Header)
+	}
+
+	stream := func(method string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/Test/"+method, nil)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		err = conn.WriteMessage(websocket.TextMessage, []byte("{}"))
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil || string(data) != "{}" {
+			t.Fatalf("expected output message, got %s (%v)", data, err)
+		}
+		return conn
+	}
+
+	conn := stream("SummaryStream")
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != `{"processed":"1"}` {
+		t.Errorf("expected trailer as close reason, got %v", err)
+	}
+
+	conn = stream("LargeStream")
+	defer conn.Close()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("expected trailer frame, got %v", err)
+	}
+	var frame struct {
+		Type     string            `json:"@jrpc"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	err = json.Unmarshal(data, &frame)
+	if err != nil || frame.Type != "trailer" || len(frame.Metadata["summary"]) != 200 {
+		t.Errorf("unexpected trailer frame %s (%v)", data, err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != "" {
+		t.Errorf("expected normal closure without reason, got %v", err)
+	}
+
+	err = jrpc.SetTrailer(context.Background(), "key", "value")
+	if err == nil {
+		t.Error("expected error outside of a call")
+	}
+}
+
+type holdServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (h *holdServer) Descriptor() protoreflect.FileDescriptor {
+	return h.fd
+}
+
+func (h *holdServer) HoldBidi(_ context.Context, in chan *wrapperspb.StringValue, _ chan *wrapperspb.StringValue) error {
+	for range in {
+	}
+	return nil
+}
+
+func TestMaxConnections(t *testing.T) {
+	service := jrpc.Register(&holdServer{fd: testDescriptor(t, "HoldBidi")}).WithMaxConnections(1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/Test/HoldBidi"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if service.Connections() != 1 {
+		t.Errorf("expected 1 connection, got %d", service.Connections())
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected upgrade beyond the limit to be rejected with 503, got %v (%v)", resp, err)
+	}
+
+	err = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for service.Connections() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if service.Connections() != 0 {
+		t.Fatalf("expected no connections after close, got %d", service.Connections())
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("expected dial to succeed once a connection is closed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestMultiplexHandler(t *testing.T) {
+	service := jrpc.Register(&panicServer{fd: testDescriptor(t, "Echo", "Panic", "WatchStream")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", service.MultiplexHandler())
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	calls := []string{
+		`{"id":1,"method":"Test.Echo","message":{"jsonName":"first"}}`,
+		`{"id":"two","method":"Test.Echo","message":{"jsonName":"second"},"timeout":"1s"}`,
+		`{"id":3,"method":"Test.Missing"}`,
+		`{"id":4,"method":"Test.WatchStream"}`,
+		`{"id":5,"method":"Test.Panic"}`,
+	}
+	for _, call := range calls {
+		err = conn.WriteMessage(websocket.TextMessage, []byte(call))
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	type result struct {
+		Message map[string]any `json:"message"`
+		Error   *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	results := make(map[string]result)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for range calls {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		var frame struct {
+			ID json.RawMessage `json:"id"`
+			result
+		}
+		err = json.Unmarshal(data, &frame)
+		if err != nil {
+			t.Fatalf("invalid result %s: %v", data, err)
+		}
+		results[string(frame.ID)] = frame.result
+	}
+
+	for id, name := range map[string]string{`1`: "first", `"two"`: "second"} {
+		if results[id].Error != nil || results[id].Message["jsonName"] != name {
+			t.Errorf("expected result %s to echo %q, got %+v", id, name, results[id])
+		}
+	}
+	for id, code := range map[string]apperror.Kind{`3`: apperror.KindNotFound, `4`: apperror.KindInvalidArgument, `5`: apperror.KindInternal} {
+		if results[id].Error == nil || results[id].Error.Code != string(code) {
+			t.Errorf("expected result %s to fail with %s, got %+v", id, code, results[id])
+		}
+	}
+
+	// The streaming routes still reject unary methods
+	stream, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/Test/Echo", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer stream.Close()
+	_, _, err = stream.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+		t.Errorf("expected unary method to be rejected on the streaming route, got %v", err)
+	}
+}
+
+type tenantUser struct {
+	jrpc.Identity
+	Tenant string
+}
+
+type identityServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (s *identityServer) Descriptor() protoreflect.FileDescriptor {
+	return s.fd
+}
+
+func (s *identityServer) Echo(ctx context.Context, _ *descriptorpb.FieldDescriptorProto) (*descriptorpb.FieldDescriptorProto, error) {
+	id, ok := jrpc.GetIdentity(ctx)
+	if !ok || !id.HasScope("echo") {
+		return nil, apperror.NewError("missing scope echo").WithKind(apperror.KindPermissionDenied)
+	}
+	user, ok := jrpc.GetPrincipal[tenantUser](ctx)
+	if !ok {
+		return nil, apperror.NewError("principal is not a tenant user")
+	}
+	return &descriptorpb.FieldDescriptorProto{Name: proto.String(id.Subject), JsonName: proto.String(user.Tenant)}, nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	service := jrpc.Register(&identityServer{fd: testDescriptor(t, "Echo")})
+	service.WithAuthenticator(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer jane":
+			return jrpc.WithIdentity(ctx, tenantUser{Identity: jrpc.Identity{Subject: "jane", Scopes: []string{"echo"}}, Tenant: "acme"}), nil
+		case "Bearer john":
+			return jrpc.WithIdentity(ctx, tenantUser{Identity: jrpc.Identity{Subject: "john"}}), nil
+		}
+		return nil, apperror.NewError("invalid token")
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", service.MultiplexHandler())
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/Test/Echo", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("jane")
+	var out map[string]any
+	err := json.NewDecoder(resp.Body).Decode(&out)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if out["name"] != "jane" || out["jsonName"] != "acme" {
+		t.Errorf("expected the identity of jane of tenant acme, got %v", out)
+	}
+
+	resp = post("john")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status %d without scope, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp = post("unknown")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d for an invalid token, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer unknown"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the upgrade to be rejected with status %d, got %v", http.StatusUnauthorized, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer jane"}})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"id":1,"method":"Test.Echo"}`))
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"name":"jane"`) {
+		t.Errorf("expected the multiplexed call to see the identity of the connection, got %s", data)
+	}
+}
+
+type descriptorServer struct {
+	fd protoreflect.FileDescriptor
+}
+
+func (s *descriptorServer) Descriptor() protoreflect.FileDescriptor {
+	return s.fd
+}
+
+func (s *descriptorServer) Echo(ctx context.Context, _ *descriptorpb.FieldDescriptorProto) (*descriptorpb.FieldDescriptorProto, error) {
+	md, ok := jrpc.GetMethodDescriptor(ctx)
+	if !ok {
+		return nil, apperror.NewError("method descriptor not found")
+	}
+	return &descriptorpb.FieldDescriptorProto{Name: proto.String(string(md.FullName()))}, nil
+}
+
+func (s *descriptorServer) WatchStream(ctx context.Context, _ *emptypb.Empty, out chan *emptypb.Empty) error {
+	md, ok := jrpc.GetMethodDescriptor(ctx)
+	if !ok {
+		return apperror.NewError("method descriptor not found")
+	}
+	out <- &emptypb.Empty{}
+	return jrpc.SetTrailer(ctx, "method", string(md.FullName()))
+}
+
+func TestMethodDescriptor(t *testing.T) {
+	service := jrpc.Register(&descriptorServer{fd: testDescriptor(t, "Echo", "WatchStream")})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", service.MultiplexHandler())
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/Test/Echo", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := new(bytes.Buffer)
+	_, _ = body.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(body.String(), `"name":"jrpctest.Test.Echo"`) {
+		t.Errorf("expected the descriptor of the unary method, got %s", body)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"id":1,"method":"Test.Echo"}`))
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil || !strings.Contains(string(data), `"name":"jrpctest.Test.Echo"`) {
+		t.Errorf("expected the descriptor of the multiplexed method, got %s (%v)", data, err)
+	}
+
+	stream, _, err := websocket.DefaultDialer.Dial(url+"/Test/WatchStream", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer stream.Close()
+	err = stream.WriteMessage(websocket.TextMessage, []byte("{}"))
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	stream.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = stream.ReadMessage()
+	if err != nil {
+		t.Fatalf("expected output message, got %v", err)
+	}
+	_, _, err = stream.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Text != `{"method":"jrpctest.Test.WatchStream"}` {
+		t.Errorf("expected the descriptor of the streaming method, got %v", err)
+	}
+}
+
+func TestStreamIdleTimeouts(t *testing.T) {
+	service := jrpc.Register(&holdServer{fd: testDescriptor(t, "HoldBidi")}).WithStreamIdleTimeouts(200*time.Millisecond, time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{service}/{method}", service.HandlerFunc)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/Test/HoldBidi"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Messages within the read timeout keep the stream open
+	for range 5 {
+		err = conn.WriteMessage(websocket.TextMessage, []byte(`"ping"`))
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) || ce.Code != jrpc.CloseIdleTimeout {
+		t.Fatalf("expected close code %d, got %v", jrpc.CloseIdleTimeout, err)
+	}
+	if !strings.Contains(ce.Text, "read idle timeout") {
+		t.Errorf("expected close reason to name the read idle timeout, got %q", ce.Text)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the stream to be closed after the read timeout, took %v", elapsed)
+	}
+}
+
+// deadlineServer reports the deadline of its method context and waits for short deadlines to expire
+type deadlineServer struct {
+	fd        protoreflect.FileDescriptor
+	deadlines chan time.Duration
+}
+
+func (d *deadlineServer) Descriptor() protoreflect.FileDescriptor {
+	return d.fd
+}
+
+func (d *deadlineServer) Wait(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		d.deadlines <- 0
+		return &emptypb.Empty{}, nil
+	}
+	remaining := time.Until(deadline)
+	d.deadlines <- remaining
+	if remaining < time.Second {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := &deadlineServer{fd: testDescriptor(t, "Wait"), deadlines: make(chan time.Duration, 1)}
+	service := jrpc.Register(server).WithMaxTimeout(2 * time.Second)
+
+	call := func(header, value string) int {
+		r := httptest.NewRequest(http.MethodPost, "/Test/Wait", strings.NewReader(`{}`))
+		r.SetPathValue("service", "Test")
+		r.SetPathValue("method", "Wait")
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		service.HandlerFunc(w, r)
+		return w.Code
+	}
+
+	if code := call("", ""); code != http.StatusOK {
+		t.Fatalf("expected status %d without timeout, got %d", http.StatusOK, code)
+	}
+	if d := <-server.deadlines; d != 0 {
+		t.Errorf("expected no deadline without timeout header, got %v", d)
+	}
+
+	if code := call(jrpc.DefaultTimeoutHeader, "1h"); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if d := <-server.deadlines; d <= time.Second || d > 2*time.Second {
+		t.Errorf("expected timeout to be capped at 2s, got %v", d)
+	}
+
+	if code := call(jrpc.DefaultTimeoutHeader, "50ms"); code != http.StatusGatewayTimeout {
+		t.Errorf("expected status %d on expiry, got %d", http.StatusGatewayTimeout, code)
+	}
+	<-server.deadlines
+
+	for _, value := range []string{"soon", "-1s", "0"} {
+		if code := call(jrpc.DefaultTimeoutHeader, value); code != http.StatusBadRequest {
+			t.Errorf("expected status %d for timeout %q, got %d", http.StatusBadRequest, value, code)
+		}
+	}
+
+	service.WithTimeoutHeader("X-Deadline")
+	if code := call("X-Deadline", "50ms"); code != http.StatusGatewayTimeout {
+		t.Errorf("expected status %d with custom header, got %d", http.StatusGatewayTimeout, code)
+	}
+	<-server.deadlines
+	if code := call(jrpc.DefaultTimeoutHeader, "soon"); code != http.StatusOK {
+		t.Errorf("expected default header to be ignored, got %d", code)
+	}
+	if d := <-server.deadlines; d != 0 {
+		t.Errorf("expected no deadline from the default header, got %v", d)
+	}
+}
+
+// UnimplementedTestServer mimics a generated type implementing every method as unimplemented
+type UnimplementedTestServer struct{}
+
+func (UnimplementedTestServer) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, apperror.NewError("method Ping not implemented").WithKind(apperror.KindUnimplemented)
+}
+
+func (UnimplementedTestServer) Pong(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, apperror.NewError("method Pong not implemented").WithKind(apperror.KindUnimplemented)
+}
+
+type pingServer struct {
+	UnimplementedTestServer
+	fd protoreflect.FileDescriptor
+}
+
+func (p *pingServer) Descriptor() protoreflect.FileDescriptor {
+	return p.fd
+}
+
+func (p *pingServer) Ping(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+type pongServer struct {
+	UnimplementedTestServer
+	fd protoreflect.FileDescriptor
+}
+
+func (p *pongServer) Descriptor() protoreflect.FileDescriptor {
+	return p.fd
+}
+
+func (p *pongServer) Pong(_ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+func TestMultipleServers(t *testing.T) {
+	fd := testDescriptor(t, "Ping", "Pong")
+
+	call := func(service *jrpc.Service, method string) int {
+		r := httptest.NewRequest(http.MethodPost, "/Test/"+method, strings.NewReader(`{}`))
+		r.SetPathValue("service", "Test")
+		r.SetPathValue("method", method)
+		w := httptest.NewRecorder()
+		service.HandlerFunc(w, r)
+		return w.Code
+	}
+
+	// Explicit implementations override the stubs of the other server in either order
+	orders := [][]jrpc.Server{
+		{&pingServer{fd: fd}, &pongServer{fd: fd}},
+		{&pongServer{fd: fd}, &pingServer{fd: fd}},
+	}
+	for _, servers := range orders {
+		service := jrpc.Register(servers[0])
+		err := service.Register(servers[1])
+		if err != nil {
+			t.Fatalf("failed to register second server: %v", err)
+		}
+		for _, method := range []string{"Ping", "Pong"} {
+			if code := call(service, method); code != http.StatusOK {
+				t.Errorf("expected %s to be dispatched to its implementation, got status %d", method, code)
+			}
+		}
+	}
+
+	// A stub is used if no server implements the method
+	service := jrpc.Register(&pingServer{fd: fd})
+	if code := call(service, "Pong"); code != apperror.KindUnimplemented.HTTPStatus() {
+		t.Errorf("expected stub of Pong to be called, got status %d", code)
+	}
+
+	err := service.Register(&pingServer{fd: fd})
+	if err == nil || apperror.KindOf(err) != apperror.KindAlreadyExists {
+		t.Errorf("expected method implemented by multiple servers to be rejected, got %v", err)
+	}
+}
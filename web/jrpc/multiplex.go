@@ -0,0 +1,201 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/interruption"
+	"github.com/valentin-kaiser/go-core/logging/log"
+)
+
+// maxMultiplexedCalls is the number of calls of a multiplexed connection processed at the same time,
+// further calls are read once a call completed
+const maxMultiplexedCalls = 32
+
+// callFrame is a unary call sent over a multiplexed connection
+type callFrame struct {
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Timeout string          `json:"timeout,omitempty"`
+}
+
+// resultFrame is the response to a call sent over a multiplexed connection
+type resultFrame struct {
+	ID      json.RawMessage `json:"id"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   *callError      `json:"error,omitempty"`
+}
+
+// callError describes the error of a call sent over a multiplexed connection
+type callError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MultiplexHandler returns a handler for WebSocket connections carrying unary calls, so clients
+// can send all their requests over a single connection. It is independent of the streaming
+// routes and has to be mounted on a path of its own. Every text message is a call and is
+// answered with a result carrying the same id, which is any JSON value chosen by the client:
+//
+//	-> {"id":1,"method":"UserService.GetUser","message":{"id":"42"},"timeout":"5s"}
+//	<- {"id":1,"message":{"id":"42","name":"Jane"}}
+//	<- {"id":2,"error":{"code":"not_found","message":"user 43 not found"}}
+//
+// The method is given as Service.Method, the optional timeout like the X-Request-Timeout header.
+// Calls are dispatched like unary HTTP requests, including validation, and are processed
+// concurrently, so results can arrive in a different order than the calls were sent.
+// Streaming methods are rejected. Pending calls are canceled once the connection is closed.
+//
+// Example:
+//
+//	service := jrpc.Register(&MyService{})
+//	mux.HandleFunc("/ws", service.MultiplexHandler())
+func (s *Service) MultiplexHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer interruption.Catch()
+
+		if !s.isWebSocketRequest(r) {
+			w.Header().Set("Upgrade", "websocket")
+			http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+
+		s.upgrade(w, r, s.applyCORS(w, r), s.multiplex)
+	}
+}
+
+// multiplex reads the calls of a multiplexed connection and dispatches them until the connection is closed
+func (s *Service) multiplex(w http.ResponseWriter, r *http.Request, conn *websocket.Conn) {
+	ctx, cancel := context.WithCancel(WithWebSocketContext(r.Context(), w, r, conn))
+	defer cancel()
+
+	var writeMutex sync.Mutex
+	var wg sync.WaitGroup
+	calls := make(chan struct{}, maxMultiplexedCalls)
+
+	var final error
+	code := websocket.ClosePolicyViolation
+	for {
+		messageType, payload, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				final = apperror.NewError("failed to read websocket message").AddError(err)
+				code = websocket.CloseInternalServerErr
+			}
+			break
+		}
+		if messageType != websocket.TextMessage {
+			final = apperror.NewError("only text messages are supported").WithKind(apperror.KindInvalidArgument)
+			break
+		}
+
+		var call callFrame
+		err = json.Unmarshal(payload, &call)
+		if err != nil || len(call.ID) == 0 {
+			final = apperror.NewError("invalid call, it must be a JSON object with id and method").WithKind(apperror.KindInvalidArgument)
+			break
+		}
+
+		calls <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-calls }()
+
+			result := s.dispatch(ctx, call)
+			data, err := json.Marshal(result)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to encode websocket call result")
+				return
+			}
+
+			writeMutex.Lock()
+			defer writeMutex.Unlock()
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			err = conn.WriteMessage(websocket.TextMessage, data)
+			if err != nil {
+				log.Trace().Err(err).Msg("failed to send websocket call result")
+			}
+		}()
+	}
+	cancel()
+	wg.Wait()
+
+	if final != nil {
+		s.closeWS(conn, code, final)
+		return
+	}
+	s.closeWS(conn, websocket.CloseNormalClosure, nil)
+}
+
+// dispatch runs a call of a multiplexed connection through the unary call path
+func (s *Service) dispatch(ctx context.Context, call callFrame) resultFrame {
+	result := resultFrame{ID: call.ID}
+	fail := func(err error) resultFrame {
+		message, _, _ := apperror.Split(err)
+		result.Error = &callError{Code: string(apperror.KindOf(err)), Message: message}
+		return result
+	}
+
+	i := strings.LastIndex(call.Method, ".")
+	if i < 0 {
+		return fail(errMethodNotFound)
+	}
+	service, method := call.Method[:i], call.Method[i+1:]
+	md, err := s.find(service, method)
+	if err != nil {
+		return fail(err)
+	}
+	if md.descriptor.IsStreamingClient() || md.descriptor.IsStreamingServer() {
+		return fail(apperror.NewErrorf("method %s is a streaming method, only unary methods can be multiplexed", call.Method).WithKind(apperror.KindInvalidArgument))
+	}
+	ctx = context.WithValue(ctx, ContextKeyMethodDescriptor, md.descriptor)
+
+	msg, err := s.message(md)
+	if err != nil {
+		return fail(err)
+	}
+	if len(call.Message) > 0 {
+		err = s.unmarshalOpts.Unmarshal(call.Message, msg)
+		if err != nil {
+			return fail(apperror.NewError(err.Error()).WithKind(apperror.KindInvalidArgument))
+		}
+	}
+
+	err = s.validate(msg)
+	if err != nil {
+		return fail(apperror.NewError(err.Error()).WithKind(apperror.KindInvalidArgument))
+	}
+
+	if call.Timeout != "" {
+		timeout, err := s.timeout(call.Timeout)
+		if err != nil {
+			return fail(err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := s.call(ctx, service, method, msg)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fail(apperror.NewError("request timeout exceeded").WithKind(apperror.KindDeadlineExceeded))
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Message, err = s.marshal(resp)
+	if err != nil {
+		return fail(err)
+	}
+	return result
+}
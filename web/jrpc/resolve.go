@@ -0,0 +1,38 @@
+package jrpc
+
+import "net/http"
+
+// Resolver maps the URL path of a request to the proto service and method name.
+// It reports false if the path does not name a method, the request is then answered
+// with 404 Not Found or the WebSocket connection is closed.
+type Resolver func(path string) (service, method string, ok bool)
+
+// WithResolver resolves the service and method of requests from the URL path with the
+// resolver instead of the {service} and {method} path values, e.g. to serve kebab-case
+// or versioned paths. The resolver receives the path as seen by the handler, so the
+// prefix of handlers mounted with http.StripPrefix is removed. The resolved names are
+// matched against the proto names and also identify methods in WithGET and the access log.
+// It must be called before the service handles requests.
+//
+// Example:
+//
+//	service.WithResolver(func(path string) (string, string, bool) {
+//		parts := strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/")
+//		if len(parts) != 2 {
+//			return "", "", false
+//		}
+//		return kebabToPascal(parts[0]), kebabToPascal(parts[1]), true
+//	})
+//	mux.HandleFunc("/api/v1/", service.HandlerFunc)
+func (s *Service) WithResolver(r Resolver) *Service {
+	s.resolver = r
+	return s
+}
+
+// resolve returns the service and method name of the request
+func (s *Service) resolve(r *http.Request) (string, string, bool) {
+	if s.resolver == nil {
+		return r.PathValue("service"), r.PathValue("method"), true
+	}
+	return s.resolver(r.URL.Path)
+}
@@ -0,0 +1,190 @@
+package jrpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// StreamOrdering defines the order in which the responses of concurrently processed
+// inbound stream messages are written to the client
+type StreamOrdering int
+
+const (
+	// StreamOrdered writes the responses in the order the inbound messages were received.
+	// Responses of a message are held back until all earlier messages were processed.
+	StreamOrdered StreamOrdering = iota
+	// StreamUnordered writes the responses as soon as they are produced.
+	// Responses of different messages may interleave.
+	StreamUnordered
+)
+
+// ConcurrentStreamer is implemented by servers whose bidirectional streaming methods
+// may process inbound messages concurrently. ConcurrentStream returns the ordering of
+// the responses and true for every method that opts in.
+//
+// For these methods and a service configured with WithStreamWorkers, the method is
+// invoked once per inbound message: its input channel yields exactly that message and
+// is closed afterwards, its output channel carries the responses to that message.
+// At most the configured number of invocations run at the same time per connection.
+// The first error returned by an invocation ends the stream.
+type ConcurrentStreamer interface {
+	ConcurrentStream(method string) (StreamOrdering, bool)
+}
+
+// WithStreamWorkers sets the number of inbound messages of a bidirectional stream that are
+// processed concurrently per connection. It only applies to methods of servers implementing
+// ConcurrentStreamer, all other methods receive the inbound messages on a single channel.
+// It must be called before the service handles requests.
+func (s *Service) WithStreamWorkers(n int) *Service {
+	s.streamWorkers = n
+	return s
+}
+
+// streamCall is a single invocation of a concurrent stream method
+type streamCall struct {
+	responses []reflect.Value
+	done      chan struct{}
+}
+
+// handleConcurrentStream handles a bidirectional stream by invoking the method once per
+// inbound message with at most streamWorkers invocations running at the same time
+func (s *Service) handleConcurrentStream(ctx context.Context, conn *websocket.Conn, m reflect.Value, mt reflect.Type, ordering StreamOrdering) {
+	inType, outType := mt.In(1), mt.In(2)
+	inPtr := inType.Elem()
+	in, out := reflect.MakeChan(inType, 0), reflect.MakeChan(outType, 0)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	read := s.startMessageReader(ctx, conn, in, inPtr)
+	write := s.startMessageWriter(ctx, cancel, conn, out)
+
+	done := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+		cancel()
+	}
+
+	// Responses of ordered calls are forwarded in the order the messages were received
+	calls := make(chan *streamCall, s.streamWorkers)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for call := range calls {
+			<-call.done
+			for _, response := range call.responses {
+				if !send(ctx, out, response) {
+					break
+				}
+			}
+		}
+	}()
+
+	go func() {
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, s.streamWorkers)
+		defer func() {
+			wg.Wait()
+			close(calls)
+			<-forwarded
+			out.Close()
+			fail(nil)
+		}()
+
+		for {
+			msg, ok := in.Recv()
+			if !ok {
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			call := &streamCall{done: make(chan struct{})}
+			if ordering == StreamOrdered {
+				calls <- call
+			}
+
+			callIn := reflect.MakeChan(inType, 1)
+			callIn.Send(msg)
+			callIn.Close()
+			callOut := reflect.MakeChan(outType, 0)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ordering == StreamUnordered {
+					defer func() { <-slots }()
+				}
+
+				collected := make(chan struct{})
+				go func() {
+					defer close(collected)
+					for {
+						response, ok := callOut.Recv()
+						if !ok {
+							return
+						}
+						if ordering == StreamUnordered {
+							send(ctx, out, response)
+							continue
+						}
+						call.responses = append(call.responses, response)
+					}
+				}()
+
+				outs, err := invoke(m, reflect.ValueOf(ctx), callIn, callOut)
+				callOut.Close()
+				<-collected
+				if ordering == StreamOrdered {
+					close(call.done)
+					<-slots
+				}
+
+				if err == nil {
+					err, _ = outs[0].Interface().(error)
+				}
+				if err != nil {
+					fail(err)
+				}
+			}()
+		}
+	}()
+
+	var final error
+	select {
+	case final = <-done:
+	case final = <-read:
+		if final == nil {
+			// The client closed its side, wait for the pending messages to be processed
+			final = <-done
+		}
+		cancel()
+	}
+	<-write
+
+	if final != nil && !websocket.IsCloseError(final, websocket.CloseNormalClosure) {
+		s.closeWS(conn, websocket.CloseInternalServerErr, apperror.Wrap(final))
+		return
+	}
+	s.closeWS(conn, websocket.CloseNormalClosure, nil)
+}
+
+// send writes the value to the channel unless the context is canceled
+func send(ctx context.Context, ch reflect.Value, v reflect.Value) bool {
+	chosen, _, _ := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: ch, Send: v},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	})
+	return chosen == 0
+}
@@ -0,0 +1,68 @@
+package jrpc
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/valentin-kaiser/go-core/apperror"
+)
+
+// DefaultTimeoutHeader is the header clients use to request a timeout for unary calls by default
+const DefaultTimeoutHeader = "X-Request-Timeout"
+
+// DefaultMaxTimeout caps the timeout clients can request for unary calls by default
+const DefaultMaxTimeout = 5 * time.Minute
+
+// WithTimeoutHeader sets the name of the header clients use to request a timeout for unary calls,
+// DefaultTimeoutHeader by default. The header value is parsed as a duration, e.g. "1.5s" or "300ms".
+// An empty name disables the header. WithCORS allows the header of the service at the time it is
+// called, so it must be called before WithCORS and before the service handles requests.
+//
+// Example:
+//
+//	service.WithTimeoutHeader("X-Deadline").WithMaxTimeout(30 * time.Second)
+func (s *Service) WithTimeoutHeader(name string) *Service {
+	s.timeoutHeader = name
+	return s
+}
+
+// WithMaxTimeout caps the timeout clients can request for unary calls, DefaultMaxTimeout by default.
+// Longer timeouts are shortened to the cap, zero allows arbitrary timeouts.
+// It must be called before the service handles requests.
+func (s *Service) WithMaxTimeout(d time.Duration) *Service {
+	s.maxTimeout = d
+	return s
+}
+
+// requestTimeout returns the timeout requested with the timeout header of the request
+// It returns zero if the request does not carry the header
+func (s *Service) requestTimeout(r *http.Request) (time.Duration, error) {
+	if s.timeoutHeader == "" {
+		return 0, nil
+	}
+
+	value := strings.TrimSpace(r.Header.Get(s.timeoutHeader))
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := s.timeout(value)
+	if err != nil {
+		return 0, apperror.NewErrorf("invalid %s header", s.timeoutHeader).AddError(err).WithKind(apperror.KindInvalidArgument)
+	}
+	return timeout, nil
+}
+
+// timeout parses a timeout requested by a client and caps it by the maximum timeout
+func (s *Service) timeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, apperror.NewErrorf("invalid timeout %q", value).WithKind(apperror.KindInvalidArgument)
+	}
+
+	if s.maxTimeout > 0 && timeout > s.maxTimeout {
+		timeout = s.maxTimeout
+	}
+	return timeout, nil
+}
@@ -0,0 +1,122 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/apperror"
+	"github.com/valentin-kaiser/go-core/logging/log"
+)
+
+// maxCloseReason is the maximum length of the reason of a close frame (RFC 6455, section 5.5)
+const maxCloseReason = 123
+
+// contextKeyTrailer is the context key of the trailer of a stream
+const contextKeyTrailer ContextKey = "trailer"
+
+// trailer collects the metadata sent when a stream ends
+type trailer struct {
+	mutex  sync.Mutex
+	values map[string]string
+	sent   bool
+}
+
+// trailerFrame is the control message carrying a trailer that does not fit into the close reason
+type trailerFrame struct {
+	Type     string          `json:"@jrpc"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// SetTrailer sets metadata that is sent to the client when the call ends, e.g. a summary of a stream.
+// Setting a key again replaces its value.
+//
+// Streams send the metadata as JSON object when they are closed:
+//   - as reason of the close frame if the stream ends without error and the object fits into
+//     the 123 bytes a close reason is limited to, e.g. {"processed":"42"}
+//   - otherwise as text message right before the close frame with the following schema:
+//     {"@jrpc":"trailer","metadata":{"processed":"42"}}
+//
+// Unary calls set the metadata as response header, as long as the method has not returned.
+// It returns an error if the context is not the one of a call or the stream is already closed.
+//
+// Example:
+//
+//	func (s *Server) Import(ctx context.Context, in chan *pb.Record, out chan *pb.Result) error {
+//		count := 0
+//		for record := range in {
+//			count++
+//			out <- s.store(record)
+//		}
+//		return jrpc.SetTrailer(ctx, "imported", strconv.Itoa(count))
+//	}
+func SetTrailer(ctx context.Context, key, value string) error {
+	t, ok := ctx.Value(contextKeyTrailer).(*trailer)
+	if !ok {
+		w, ok := GetResponseWriter(ctx)
+		if !ok {
+			return apperror.NewError("trailers can only be set within a call")
+		}
+		w.Header().Set(key, value)
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.sent {
+		return apperror.NewError("trailer already sent, the stream is closed")
+	}
+	if t.values == nil {
+		t.values = make(map[string]string)
+	}
+	t.values[key] = value
+	return nil
+}
+
+// withTrailer adds a trailer for the stream on the connection to the context
+func (s *Service) withTrailer(ctx context.Context, conn *websocket.Conn) context.Context {
+	t := &trailer{}
+	s.trailers.Store(conn, t)
+	return context.WithValue(ctx, contextKeyTrailer, t)
+}
+
+// trailer returns the encoded trailer of the stream on the connection, nil if none was set.
+// The trailer is sent only once, later calls to SetTrailer fail.
+func (s *Service) trailer(conn *websocket.Conn) []byte {
+	v, ok := s.trailers.LoadAndDelete(conn)
+	if !ok {
+		return nil
+	}
+
+	t := v.(*trailer)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sent = true
+	if len(t.values) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(t.values)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode websocket trailer")
+		return nil
+	}
+	return data
+}
+
+// writeTrailerFrame sends a trailer that does not fit into the close reason
+func (s *Service) writeTrailerFrame(conn *websocket.Conn, metadata []byte) {
+	frame, err := json.Marshal(trailerFrame{Type: "trailer", Metadata: metadata})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode websocket trailer frame")
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	err = conn.WriteMessage(websocket.TextMessage, frame)
+	if err != nil {
+		log.Trace().Err(err).Msg("failed to send websocket trailer frame")
+	}
+}
@@ -0,0 +1,141 @@
+package jrpc
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valentin-kaiser/go-core/interruption"
+)
+
+// UpgraderOptions configures the WebSocket upgrader of a service
+type UpgraderOptions struct {
+	// ReadBufferSize and WriteBufferSize are the I/O buffer sizes in bytes, zero uses the buffers of the HTTP server
+	ReadBufferSize  int
+	WriteBufferSize int
+	// HandshakeTimeout limits the duration of the upgrade handshake, zero disables the limit
+	HandshakeTimeout time.Duration
+	// Subprotocols lists the supported protocols in order of preference, the first one requested by the client is selected
+	Subprotocols []string
+	// EnableCompression negotiates per-message compression (RFC 7692) with clients that support it
+	EnableCompression bool
+	// AllowedOrigins lists the origins allowed to open connections, e.g. "https://app.example.com".
+	// The entry "*" allows any origin. If empty, the origins allowed by WithCORS are used and
+	// without CORS only same-origin connections are accepted.
+	AllowedOrigins []string
+}
+
+// Upgrader returns a WebSocket upgrader configured with the options.
+// Connections without Origin header, like connections of non-browser clients, are always accepted.
+// Use it with WithUpgrader or to upgrade connections in custom handlers.
+func (s *Service) Upgrader(opts UpgraderOptions) websocket.Upgrader {
+	origins := opts.AllowedOrigins
+	if len(origins) == 0 && s.cors != nil {
+		origins = s.cors.AllowedOrigins
+	}
+
+	return websocket.Upgrader{
+		ReadBufferSize:    opts.ReadBufferSize,
+		WriteBufferSize:   opts.WriteBufferSize,
+		HandshakeTimeout:  opts.HandshakeTimeout,
+		Subprotocols:      opts.Subprotocols,
+		EnableCompression: opts.EnableCompression,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			if len(origins) == 0 {
+				u, err := url.Parse(origin)
+				return err == nil && strings.EqualFold(u.Host, r.Host)
+			}
+			return (&CORSOptions{AllowedOrigins: origins}).allowOrigin(origin)
+		},
+	}
+}
+
+// WithUpgrader sets the WebSocket upgrader of the service, it takes precedence over SetUpgrader.
+// It must be called before the service handles requests.
+func (s *Service) WithUpgrader(u websocket.Upgrader) *Service {
+	s.upgrader = &u
+	return s
+}
+
+// WebSocketHandler returns a handler that only accepts WebSocket connections.
+// It upgrades the connection with the upgrader of the service and routes it to the
+// streaming method given by the {service} and {method} path values. Requests that
+// are not WebSocket upgrades are answered with 426 Upgrade Required.
+//
+// Example:
+//
+//	service := jrpc.Register(&MyService{})
+//	service.WithUpgrader(service.Upgrader(jrpc.UpgraderOptions{
+//		EnableCompression: true,
+//		AllowedOrigins:    []string{"https://app.example.com"},
+//	}))
+//	mux.HandleFunc("/ws/{service}/{method}", service.WebSocketHandler())
+func (s *Service) WebSocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer interruption.Catch()
+
+		if !s.isWebSocketRequest(r) {
+			w.Header().Set("Upgrade", "websocket")
+			http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+
+		s.upgrade(w, r, s.applyCORS(w, r), s.websocket)
+	}
+}
+
+// WithMaxConnections limits the number of WebSocket connections the service handles at the same time.
+// Upgrades beyond the limit are rejected with 503 Service Unavailable, so a client cannot exhaust
+// goroutines and file descriptors by opening streams. Zero or a negative number disables the limit.
+// It must be called before the service handles requests.
+func (s *Service) WithMaxConnections(n int) *Service {
+	s.maxConnections = int64(n)
+	return s
+}
+
+// Connections returns the number of WebSocket connections the service currently handles
+func (s *Service) Connections() int64 {
+	return s.connections.Load()
+}
+
+// upgrade upgrades the connection and hands it to serve, e.g. websocket to route it to the streaming method
+func (s *Service) upgrade(w http.ResponseWriter, r *http.Request, allowed bool, serve func(http.ResponseWriter, *http.Request, *websocket.Conn)) {
+	if !allowed {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	r, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	active := s.connections.Add(1)
+	defer s.connections.Add(-1)
+	if s.maxConnections > 0 && active > s.maxConnections {
+		logger.Warn().Field("limit", s.maxConnections).Msg("websocket connection rejected, too many connections")
+		http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	u := &upgrader
+	if s.upgrader != nil {
+		u = s.upgrader
+	}
+
+	conn, err := u.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to upgrade connection to websocket")
+		http.Error(w, "Failed to upgrade to WebSocket", http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	serve(w, r, conn)
+}
@@ -0,0 +1,121 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Validator validates request messages before they are dispatched to a method.
+// Validators report invalid fields with a *ValidationError, any other error is
+// returned to the client as a bad request as well.
+type Validator interface {
+	Validate(msg proto.Message) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface
+type ValidatorFunc func(msg proto.Message) error
+
+// Validate calls f(msg)
+func (f ValidatorFunc) Validate(msg proto.Message) error {
+	return f(msg)
+}
+
+// FieldViolation describes a field of a request message that failed validation
+type FieldViolation struct {
+	// Field is the path of the field, e.g. "user.email"
+	Field string `json:"field"`
+	// Rule identifies the violated rule, e.g. "string.email"
+	Rule string `json:"rule,omitempty"`
+	// Message describes the violation
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by validators for request messages with invalid fields.
+// It is answered with 400 Bad Request and a JSON body listing the violations:
+//
+//	{"message":"request validation failed","violations":[{"field":"email","rule":"string.email","message":"value must be a valid email address"}]}
+type ValidationError struct {
+	Violations []FieldViolation `json:"violations"`
+}
+
+// Error returns the violations as a single line
+func (e *ValidationError) Error() string {
+	violations := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		violations = append(violations, v.Field+": "+v.Message)
+	}
+	return "request validation failed: " + strings.Join(violations, "; ")
+}
+
+// WithValidation validates every unary request and the request of server streams with
+// the validator before the method is called. Invalid unary requests are answered with
+// 400 Bad Request, streams are closed with a policy violation.
+// The validator is not tied to a library, e.g. protovalidate is used with an adapter.
+// It must be called before the service handles requests.
+//
+// Example:
+//
+//	v, err := protovalidate.New()
+//	if err != nil {
+//		return err
+//	}
+//	service.WithValidation(jrpc.ValidatorFunc(func(msg proto.Message) error {
+//		err := v.Validate(msg)
+//		var verr *protovalidate.ValidationError
+//		if !errors.As(err, &verr) {
+//			return err
+//		}
+//		violations := make([]jrpc.FieldViolation, 0, len(verr.Violations))
+//		for _, v := range verr.Violations {
+//			violations = append(violations, jrpc.FieldViolation{
+//				Field:   protovalidate.FieldPathString(v.Proto.GetField()),
+//				Rule:    v.Proto.GetRuleId(),
+//				Message: v.Proto.GetMessage(),
+//			})
+//		}
+//		return &jrpc.ValidationError{Violations: violations}
+//	}))
+func (s *Service) WithValidation(v Validator) *Service {
+	s.validator = v
+	return s
+}
+
+// validate validates the request message if validation is enabled
+func (s *Service) validate(msg proto.Message) error {
+	if s.validator == nil {
+		return nil
+	}
+	return s.validator.Validate(msg)
+}
+
+// writeValidationError answers a request that failed validation
+func writeValidationError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, merr := json.Marshal(struct {
+		Message    string           `json:"message"`
+		Violations []FieldViolation `json:"violations"`
+	}{
+		Message:    "request validation failed",
+		Violations: verr.Violations,
+	})
+	if merr != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_, merr = w.Write(body)
+	if merr != nil {
+		logger.Error().Err(merr).Msg("failed to write validation error")
+	}
+}